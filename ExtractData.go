@@ -2,12 +2,15 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/csv"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -15,44 +18,142 @@ import (
 	"time"
 
 	log "github.com/charmbracelet/log"
+	"github.com/godror/godror"
 )
 
 // extractData performs the data extraction for a single procedure and SOL ID.
 // It uses a prepared statement for querying and a sync.Pool for slice reuse to optimize performance.
-func extractData(ctx context.Context, stmt *sql.Stmt, slicePool *sync.Pool, procName, solID string, cfg *ExtractionConfig, templates map[string][]ColumnConfig) error {
-	cols, ok := templates[procName]
+// Rows that fail to scan or format are diverted to bad when it is non-nil instead of failing the job.
+func extractData(ctx context.Context, stmt *sql.Stmt, slicePool *sync.Pool, job Job, cfg *ExtractionConfig, templates map[string][]ColumnConfig, bad *badRecordWriter) (JobStats, error) {
+	cols, ok := templates[job.Proc]
 	if !ok {
-		return fmt.Errorf("missing template for procedure %s", procName)
+		return JobStats{}, &TemplateError{Proc: job.Proc, Err: fmt.Errorf("missing template for procedure %s", job.Proc)}
 	}
 
-	start := time.Now()
-	rows, err := stmt.QueryContext(ctx, solID)
+	rows, err := queryRows(ctx, stmt, job, cfg)
 	if err != nil {
-		return fmt.Errorf("prepared statement query failed for procedure %s: %w", procName, err)
+		return JobStats{}, err
 	}
 	defer rows.Close()
-	log.Debug("Query executed", "procedure", procName, "sol_id", solID, "duration", time.Since(start).Round(time.Millisecond))
 
-	spoolPath := filepath.Join(cfg.SpoolOutputPath, fmt.Sprintf("%s_%s.spool", procName, solID))
+	spoolPath, err := cfg.spoolFilePath(job.Proc, job.spoolID())
+	if err != nil {
+		return JobStats{}, err
+	}
+	if err := cfg.ensureParentDir(spoolPath); err != nil {
+		return JobStats{}, err
+	}
 	f, err := os.Create(spoolPath)
 	if err != nil {
-		return fmt.Errorf("failed to create spool file %s: %w", spoolPath, err)
+		return JobStats{}, &WriteError{Proc: job.Proc, Err: fmt.Errorf("failed to create spool file %s: %w", spoolPath, err)}
+	}
+
+	views, err := openViewSinks(cfg, job.Proc, spoolPath, cols)
+	if err != nil {
+		f.Close()
+		return JobStats{}, &WriteError{Proc: job.Proc, Err: err}
 	}
-	defer f.Close()
 
-	buf := bufio.NewWriter(f)
+	stats, err := writeRows(f, rows, slicePool, job.Proc, job.SolID, cols, cfg, bad, f.Sync, views)
+	if err != nil {
+		f.Close()
+		closeViewSinks(cfg, views)
+		return stats, err
+	}
+	if err := closeViewSinks(cfg, views); err != nil {
+		f.Close()
+		return stats, &WriteError{Proc: job.Proc, Err: err}
+	}
+	if err := cfg.closeOutput(f); err != nil {
+		return stats, &WriteError{Proc: job.Proc, Err: err}
+	}
+	return stats, nil
+}
+
+// extractToWriter runs the extraction for a single procedure and SOL ID and streams
+// the formatted rows to w instead of a spool file. It backs the -stdout mode.
+func extractToWriter(ctx context.Context, w io.Writer, stmt *sql.Stmt, slicePool *sync.Pool, procName, solID string, cfg *ExtractionConfig, templates map[string][]ColumnConfig) error {
+	cols, ok := templates[procName]
+	if !ok {
+		return &TemplateError{Proc: procName, Err: fmt.Errorf("missing template for procedure %s", procName)}
+	}
+
+	rows, err := queryRows(ctx, stmt, Job{SolID: solID, Proc: procName}, cfg)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	_, err = writeRows(w, rows, slicePool, procName, solID, cols, cfg, nil, nil, nil)
+	return err
+}
+
+func queryRows(ctx context.Context, stmt *sql.Stmt, job Job, cfg *ExtractionConfig) (*sql.Rows, error) {
+	start := time.Now()
+	args := cfg.jobArgs(job)
+	if n := cfg.fetchArraySize; n > 0 {
+		args = append(args, godror.FetchArraySize(n), godror.PrefetchCount(n+1))
+	}
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, newQueryError(job.Proc, fmt.Errorf("prepared statement query failed for procedure %s: %w", job.Proc, err))
+	}
+	log.Debug("Query executed", "procedure", job.Proc, "sol_id", job.spoolID(), "duration", time.Since(start).Round(time.Millisecond))
+	return rows, nil
+}
+
+// writeRows scans every row from rows and writes it to w in the configured output format,
+// and its projection to each of views. When syncFile is set it is called after flushing
+// every fsync interval's worth of rows.
+func writeRows(w io.Writer, rows *sql.Rows, slicePool *sync.Pool, procName, solID string, cols []ColumnConfig, cfg *ExtractionConfig, bad *badRecordWriter, syncFile func() error, views []*viewSink) (JobStats, error) {
+	stats := JobStats{Truncations: make(map[string]int64), Sanitized: make(map[string]int64)}
+	if err := checkColumns(rows, cols); err != nil {
+		return stats, &TemplateError{Proc: procName, Err: fmt.Errorf("template mismatch for procedure %s: %w", procName, err)}
+	}
+	if cfg.ColumnStats {
+		types, err := rows.ColumnTypes()
+		if err != nil {
+			return stats, newQueryError(procName, fmt.Errorf("failed to read result columns for procedure %s: %w", procName, err))
+		}
+		stats.Profile = newColumnProfiles(cols, types)
+	}
+	buf := bufio.NewWriter(w)
 	defer buf.Flush()
 
-	// Setup writer based on format
+	// flush writes out everything buffered so far, reporting any write error.
 	var csvWriter *csv.Writer
+	flush := func() error {
+		if csvWriter != nil {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return err
+			}
+		}
+		for _, v := range views {
+			if err := v.flush(); err != nil {
+				return err
+			}
+		}
+		return buf.Flush()
+	}
+	var syncEvery int64
+	if syncFile != nil {
+		syncEvery = cfg.fsyncInterval()
+	}
+
+	// Setup writer based on format
+	strict := cfg.Format == "delimited" && cfg.strictCSV(procName)
+	term := cfg.forOutput(procName).recordEnd()
 	if cfg.Format == "delimited" {
 		csvWriter = csv.NewWriter(buf)
-		if len(cfg.Delimiter) == 1 {
-			csvWriter.Comma = []rune(cfg.Delimiter)[0]
-		} else {
+		if !strict && string(term) != "\n" {
+			csvWriter = csv.NewWriter(&recordEndWriter{w: buf, term: term})
+		}
+		if len(cfg.Delimiter) != 1 {
 			log.Warn("Delimiter is not a single character, using default comma", "delimiter", cfg.Delimiter)
-			// Default is comma, so no action needed
 		}
+		csvWriter.Comma = cfg.delimiterRune()
+		csvWriter.UseCRLF = strict
 		defer csvWriter.Flush()
 	}
 
@@ -70,108 +171,616 @@ func extractData(ctx context.Context, stmt *sql.Stmt, slicePool *sync.Pool, proc
 		scanArgs[i] = &values[i]
 	}
 
-	for rows.Next() {
-		if err := rows.Scan(scanArgs[:len(cols)]...); err != nil {
-			return fmt.Errorf("failed to scan row for procedure %s: %w", procName, err)
+	// rejectRow diverts a row-level failure to the bad record file, or fails the job when
+	// bad records are not being collected.
+	rejectRow := func(strValues []string, cause error) error {
+		if bad == nil {
+			return cause
 		}
+		if err := bad.Write(procName, solID, strValues, cause); err != nil {
+			return err
+		}
+		stats.BadRecords++
+		return nil
+	}
 
+	sanitizers := cfg.columnSanitizers(cols)
+	checkCollisions := cfg.Format == "delimited" && cfg.DelimiterPolicy != "" && !strict
+	collisions := make(map[string]int64)
+	var rowNum int64
+
+	for rows.Next() {
+		scanErr := rows.Scan(scanArgs[:len(cols)]...)
+
+		rowNum++
 		var strValues []string
-		for _, v := range values {
-			if v.Valid {
-				strValues = append(strValues, sanitize(v.String))
-			} else {
+		var collisionErr error
+		for i, v := range values {
+			if !v.Valid {
 				strValues = append(strValues, "")
+				continue
+			}
+			val := v.String
+			if checkCollisions {
+				var collided bool
+				var err error
+				val, collided, err = cfg.resolveDelimiterCollision(val)
+				if collided {
+					collisions[cols[i].Name]++
+				}
+				if err != nil && collisionErr == nil {
+					collisionErr = fmt.Errorf("row %d column %s: %w", rowNum, cols[i].Name, err)
+				}
+			}
+			if clean := sanitizers[i]; clean != nil && !strict {
+				if s := clean(val); s != val {
+					stats.Sanitized[cols[i].Name]++
+					val = s
+				}
+			}
+			strValues = append(strValues, val)
+		}
+
+		if scanErr != nil {
+			if err := rejectRow(strValues, fmt.Errorf("failed to scan row for procedure %s: %w", procName, scanErr)); err != nil {
+				return stats, err
 			}
+			continue
+		}
+		if collisionErr != nil {
+			if err := rejectRow(strValues, fmt.Errorf("delimiter collision for procedure %s at %w", procName, collisionErr)); err != nil {
+				return stats, err
+			}
+			continue
 		}
 
 		switch cfg.Format {
 		case "delimited":
 			if err := csvWriter.Write(strValues); err != nil {
-				return fmt.Errorf("failed to write csv row for procedure %s: %w", procName, err)
+				return stats, &WriteError{Proc: procName, Err: fmt.Errorf("failed to write csv row for procedure %s: %w", procName, err)}
 			}
 		case "fixed":
-			var out strings.Builder
-			for i, col := range cols {
-				var val string
-				if i < len(strValues) {
-					val = strValues[i]
-				}
-
-				if len(val) > col.Length {
-					val = val[:col.Length]
-				}
-
-				if col.Align == "right" {
-					out.WriteString(fmt.Sprintf("%*s", col.Length, val))
-				} else {
-					out.WriteString(fmt.Sprintf("%-*s", col.Length, val))
+			line, err := formatFixed(cols, strValues, cfg.LengthPolicy, stats.Truncations)
+			if err != nil {
+				if err := rejectRow(strValues, fmt.Errorf("failed to format row for procedure %s: %w", procName, err)); err != nil {
+					return stats, err
 				}
+				continue
+			}
+			buf.WriteString(line)
+			if _, err := buf.Write(term); err != nil {
+				return stats, &WriteError{Proc: procName, Err: fmt.Errorf("failed to write fixed-width row for procedure %s: %w", procName, err)}
 			}
-			if _, err := buf.WriteString(out.String() + "\n"); err != nil {
-				return fmt.Errorf("failed to write fixed-width row for procedure %s: %w", procName, err)
+		}
+		for _, v := range views {
+			if err := v.write(values, strValues); err != nil {
+				return stats, &WriteError{Proc: procName, Err: fmt.Errorf("failed to write view row for procedure %s: %w", procName, err)}
+			}
+		}
+		for i := range stats.Profile {
+			stats.Profile[i].observe(values[i])
+		}
+		stats.Rows++
+		if syncEvery > 0 && stats.Rows%syncEvery == 0 {
+			if err := flush(); err != nil {
+				return stats, &WriteError{Proc: procName, Err: fmt.Errorf("failed to write rows for procedure %s: %w", procName, err)}
+			}
+			if err := syncFile(); err != nil {
+				return stats, &WriteError{Proc: procName, Err: fmt.Errorf("failed to sync output for procedure %s: %w", procName, err)}
 			}
 		}
 	}
 	if err := rows.Err(); err != nil {
-			return fmt.Errorf("error iterating rows for procedure %s: %w", procName, err)
+		return stats, newQueryError(procName, fmt.Errorf("error iterating rows for procedure %s: %w", procName, err))
 	}
-	return nil
+	if err := flush(); err != nil {
+		return stats, &WriteError{Proc: procName, Err: fmt.Errorf("failed to write rows for procedure %s: %w", procName, err)}
+	}
+
+	if n := sumCounts(collisions); n > 0 && cfg.DelimiterPolicy != DelimiterPolicyFail {
+		log.Warn("Delimiter collisions resolved", "procedure", procName, "sol_id", solID, "policy", cfg.DelimiterPolicy, "columns", formatCounts(collisions), "count", n)
+	}
+	if n := sumCounts(stats.Sanitized); n > 0 {
+		log.Debug("Values sanitised", "procedure", procName, "sol_id", solID, "columns", formatCounts(stats.Sanitized), "count", n)
+	}
+	for _, col := range cols {
+		if n := stats.Truncations[col.Name]; n > 0 && col.effectiveLengthPolicy(cfg.LengthPolicy) == LengthPolicyWarn {
+			log.Warn("Values truncated to fit fixed-width column", "procedure", procName, "sol_id", solID, "column", col.Name, "length", col.Length, "count", n)
+		}
+	}
+	return stats, nil
 }
 
+var plainIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$#]*$`)
 
-func mergeFiles(cfg *ExtractionConfig) error {
-	for _, proc := range cfg.Procedures {
-		log.Info("📦 Starting merge", "procedure", proc)
+// checkColumns compares the result set columns against the template so an edited
+// template or view fails loudly instead of scanning values into the wrong fields.
+// Names are only compared for template columns that are plain identifiers, since
+// expression columns come back with driver-generated names.
+func checkColumns(rows *sql.Rows, cols []ColumnConfig) error {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return fmt.Errorf("failed to read result columns: %w", err)
+	}
 
-		pattern := filepath.Join(cfg.SpoolOutputPath, fmt.Sprintf("%s_*.spool", proc))
-		finalFile := filepath.Join(cfg.SpoolOutputPath, fmt.Sprintf("%s.txt", proc))
+	var diffs []string
+	if len(types) != len(cols) {
+		diffs = append(diffs, fmt.Sprintf("template has %d columns, query returned %d", len(cols), len(types)))
+	}
+	for i := 0; i < len(types) || i < len(cols); i++ {
+		var want, got string
+		if i < len(cols) {
+			want = cols[i].Name
+		}
+		if i < len(types) {
+			got = types[i].Name()
+		}
+		switch {
+		case want == "":
+			diffs = append(diffs, fmt.Sprintf("position %d: unexpected query column %s", i+1, got))
+		case got == "":
+			diffs = append(diffs, fmt.Sprintf("position %d: template column %s missing from query", i+1, want))
+		case plainIdentifier.MatchString(want) && !strings.EqualFold(want, got):
+			diffs = append(diffs, fmt.Sprintf("position %d: template column %s, query column %s", i+1, want, got))
+		}
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("column mismatch: %s", strings.Join(diffs, "; "))
+	}
+	return nil
+}
 
-		files, err := filepath.Glob(pattern)
-		if err != nil {
-			return fmt.Errorf("glob failed for pattern %s: %w", pattern, err)
+// formatFixed lays out a row as fixed-width fields according to the column template.
+// Oversize values are truncated and counted in truncated, unless the column's length
+// policy is "fail", in which case the row is rejected with an error. Numeric columns are
+// laid out by formatNumeric, which rejects values that do not fit instead.
+func formatFixed(cols []ColumnConfig, strValues []string, defaultPolicy string, truncated map[string]int64) (string, error) {
+	var out strings.Builder
+	var overflowed []string
+	for i, col := range cols {
+		var val string
+		if i < len(strValues) {
+			val = strValues[i]
 		}
-		if len(files) == 0 {
-			log.Warn("No spool files found to merge", "procedure", proc, "pattern", pattern)
+		if col.numeric() {
+			num, err := formatNumeric(col, val)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(num)
 			continue
 		}
-		sort.Strings(files)
 
-		outFile, err := os.Create(finalFile)
+		if len(val) > col.Length {
+			if col.effectiveLengthPolicy(defaultPolicy) == LengthPolicyFail {
+				return "", fmt.Errorf("value for column %s is %d characters, exceeds length %d", col.Name, len(val), col.Length)
+			}
+			overflowed = append(overflowed, col.Name)
+			val = val[:col.Length]
+		}
+
+		if col.Align == "right" {
+			out.WriteString(fmt.Sprintf("%*s", col.Length, val))
+		} else {
+			out.WriteString(fmt.Sprintf("%-*s", col.Length, val))
+		}
+	}
+	// Only count once the whole row is known to be written.
+	for _, name := range overflowed {
+		truncated[name]++
+	}
+	return out.String(), nil
+}
+
+// mergeFiles merges the spool files of each of procs into its final output file. It
+// returns the spool files each procedure's merge had to leave out.
+func mergeFiles(cfg *ExtractionConfig, procs []string) (map[string][]string, error) {
+	gaps := make(map[string][]string)
+	for _, proc := range procs {
+		missing, err := mergeProcedure(cfg, proc)
+		if len(missing) > 0 {
+			gaps[proc] = missing
+		}
+		if err != nil {
+			return gaps, err
+		}
+	}
+	return gaps, nil
+}
+
+// mergeProcedure merges the spool files of one procedure, and of each of its views, into
+// its final output file, or into one file per SOL or group of SOLs for partitioned
+// outputs. Spool files that cannot be read are quarantined and returned as gaps; unless
+// -force-merge is set they fail the merge, since the output would be missing their rows.
+// The spools are only removed once every output of the procedure has been merged, so a
+// failed merge can always be run again from all of them.
+func mergeProcedure(cfg *ExtractionConfig, proc string) (gaps []string, err error) {
+	if err := cfg.journal.Merging(proc); err != nil {
+		return nil, err
+	}
+	views := []string{""}
+	for _, v := range cfg.Views[proc] {
+		views = append(views, v.Name)
+	}
+	var spools []string
+	for _, view := range views {
+		merged, missing, err := mergeOutput(cfg, proc, view)
+		gaps = append(gaps, missing...)
 		if err != nil {
-			return fmt.Errorf("failed to create final output file %s: %w", finalFile, err)
+			return gaps, err
+		}
+		spools = append(spools, merged...)
+	}
+	if err := cfg.journal.Merged(proc); err != nil {
+		return gaps, err
+	}
+	for _, f := range spools {
+		if err := os.Remove(f); err != nil {
+			log.Warn("Failed to remove spool file", "file", f, "error", err)
+		}
+	}
+	return gaps, nil
+}
+
+// jobSpoolsPresent reports whether the spool files of job, and of each view of its
+// procedure, are all in the spool directory.
+func (c *ExtractionConfig) jobSpoolsPresent(job Job) bool {
+	spool, err := c.spoolFilePath(job.Proc, job.spoolID())
+	if err != nil {
+		return false
+	}
+	views := []string{""}
+	for _, v := range c.Views[job.Proc] {
+		views = append(views, v.Name)
+	}
+	for _, view := range views {
+		if _, err := os.Stat(spool + viewSpoolSuffix(view)); err != nil {
+			return false
 		}
-		defer outFile.Close()
+	}
+	return true
+}
+
+// mergeOutput merges the spool files of proc, or of its view when view is set, and
+// returns the spools it merged.
+func mergeOutput(cfg *ExtractionConfig, proc, view string) (spools, gaps []string, err error) {
+	name, suffix := proc, viewSpoolSuffix(view)
+	if view != "" {
+		name = viewOutputName(proc, view)
+	}
+	log.Info("📦 Starting merge", "procedure", name)
 
-		writer := bufio.NewWriter(outFile)
-		start := time.Now()
+	parts, err := cfg.outputParts(name)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		var mergedCount int
-		for _, file := range files {
-			in, err := os.Open(file)
+	// The spools are the exact files of the run's jobs. A pattern would also match the
+	// spools of a procedure whose name extends this one, such as GAM_HIST's for GAM.
+	var files []string
+	for _, sol := range cfg.sols {
+		for _, job := range cfg.procJobs(proc, sol) {
+			spool, err := cfg.spoolFilePath(proc, job.spoolID())
 			if err != nil {
-				log.Error("Failed to open spool file for merging, skipping", "file", file, "error", err)
-				continue
+				return nil, nil, err
 			}
+			if _, err := os.Lstat(spool + suffix); err == nil {
+				files = append(files, spool+suffix)
+			}
+		}
+	}
+	if err := quarantineStaleSpools(cfg, proc, suffix, files); err != nil {
+		return nil, nil, err
+	}
+	if len(files) == 0 {
+		log.Warn("No spool files found to merge", "procedure", name)
+		return nil, nil, nil
+	}
+	sort.Strings(files)
+	if len(parts) == 1 && parts[0].sols == nil {
+		gaps, err := mergeSpools(cfg, name, files, parts[0].Path)
+		return files, gaps, err
+	}
 
-			scanner := bufio.NewScanner(in)
-			for scanner.Scan() {
-				if _, err := writer.WriteString(scanner.Text() + "\n"); err != nil {
-					in.Close() // Close before returning
-					return fmt.Errorf("failed to write to merged file %s: %w", finalFile, err)
+	// Assign the spool files to the parts by the SOLs (and chunks) they were written for.
+	present := make(map[string]bool, len(files))
+	for _, f := range files {
+		present[f] = true
+	}
+	start := time.Now()
+	var merged int
+	for _, part := range parts {
+		var partFiles []string
+		for _, sol := range part.sols {
+			for _, job := range cfg.procJobs(proc, sol) {
+				spool, err := cfg.spoolFilePath(proc, job.spoolID())
+				if err != nil {
+					return spools, gaps, err
+				}
+				spool += suffix
+				if present[spool] {
+					partFiles = append(partFiles, spool)
+					delete(present, spool)
 				}
 			}
-			in.Close()
-			if err := os.Remove(file); err != nil {
-				log.Warn("Failed to remove spool file", "file", file, "error", err)
+		}
+		if len(partFiles) == 0 {
+			continue
+		}
+		missing, err := mergeSpools(cfg, name, partFiles, part.Path)
+		gaps = append(gaps, missing...)
+		if err != nil {
+			return spools, gaps, err
+		}
+		spools = append(spools, partFiles...)
+		merged++
+	}
+	log.Info("📑 Merged partitioned files", "procedure", name, "files", merged, "duration", time.Since(start).Round(time.Second))
+	return spools, gaps, nil
+}
+
+// mergeSpools merges files, in order, into finalFile. The spools are left in place for
+// the caller to remove.
+func mergeSpools(cfg *ExtractionConfig, proc string, files []string, finalFile string) (gaps []string, err error) {
+	// In copy mode the merge runs on the spool disk and the result is copied to the final
+	// location with verification; otherwise it writes a partial file next to the final
+	// one and renames it into place, so a failed merge never leaves a cut-short output
+	// where downstream systems pick it up.
+	target := finalFile + ".partial"
+	term := cfg.forOutput(proc).recordEnd()
+	staged := cfg.FinalOutputCopy && cfg.outputDir() != cfg.SpoolOutputPath
+	if staged {
+		target = filepath.Join(cfg.SpoolOutputPath, filepath.Base(finalFile)+".merging")
+	}
+	if err := cfg.ensureParentDir(target); err != nil {
+		return nil, err
+	}
+	if cfg.appends(proc) {
+		if cd := cfg.codecFor(proc); cd != nil && !cd.Appendable {
+			return nil, fmt.Errorf("cannot append to %s output %s", cd.Name, finalFile)
+		}
+		if err := prepareAppend(finalFile, term); err != nil {
+			return nil, err
+		}
+	}
+	outFile, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create final output file %s: %w", target, err)
+	}
+	defer outFile.Close()
+	if !staged {
+		defer func() {
+			if err != nil {
+				os.Remove(target)
+			}
+		}()
+		if cfg.appends(proc) {
+			// The partial file starts as a copy of the output it adds to.
+			if err := copyInto(outFile, finalFile); err != nil {
+				return nil, fmt.Errorf("failed to copy %s for appending: %w", finalFile, err)
+			}
+		}
+	}
+
+	buffered := bufio.NewWriter(outFile)
+	var writer io.Writer = buffered
+	// Spools are compressed as they are merged, so the output is never written twice.
+	var compressor io.WriteCloser
+	if cd := cfg.codecFor(proc); cd != nil {
+		if compressor, err = cd.NewWriter(buffered); err != nil {
+			return nil, fmt.Errorf("failed to start %s compression of %s: %w", cd.Name, target, err)
+		}
+		writer = compressor
+	}
+	if cfg.needsBOM(proc, finalFile) {
+		if _, err := writer.Write(utf8BOM); err != nil {
+			return nil, fmt.Errorf("failed to write merged file %s: %w", target, err)
+		}
+	}
+	start := time.Now()
+
+	var mergedCount int
+	for _, file := range files {
+		in, err := os.Open(file)
+		if err != nil {
+			dest, qerr := quarantineFile(cfg, file)
+			if qerr != nil {
+				log.Error("Failed to quarantine unreadable spool file", "file", file, "error", qerr)
+				dest = file
+			}
+			log.Error("❌ Unreadable spool file left out of merge", "procedure", proc, "file", file, "moved_to", dest, "error", err)
+			gaps = append(gaps, dest)
+			continue
+		}
+
+		err = appendSpool(writer, in, term)
+		in.Close()
+		if err != nil {
+			return gaps, fmt.Errorf("failed to merge spool file %s into %s: %w", file, finalFile, err)
+		}
+		mergedCount++
+	}
+	if compressor != nil {
+		if err := compressor.Close(); err != nil {
+			return gaps, fmt.Errorf("failed to write merged file %s: %w", target, err)
+		}
+	}
+	if err := buffered.Flush(); err != nil {
+		return gaps, fmt.Errorf("failed to write merged file %s: %w", target, err)
+	}
+	if len(gaps) > 0 && !cfg.forceMerge {
+		return gaps, fmt.Errorf("%d unreadable spool file(s) of %s quarantined, output %s is incomplete (all other spools are kept: rerun with -resume to extract the missing jobs again, or use -force-merge)", len(gaps), proc, target)
+	}
+	if !staged {
+		if err := cfg.closeOutput(outFile); err != nil {
+			return gaps, err
+		}
+		if err := os.Rename(target, finalFile); err != nil {
+			return gaps, fmt.Errorf("failed to move %s into place: %w", target, err)
+		}
+	} else {
+		if err := outFile.Close(); err != nil {
+			return gaps, fmt.Errorf("failed to write merged file %s: %w", target, err)
+		}
+		if err := cfg.ensureParentDir(finalFile); err != nil {
+			return gaps, err
+		}
+		if err := copyVerified(target, finalFile, cfg.appends(proc)); err != nil {
+			return gaps, fmt.Errorf("failed to finalise %s (merged data kept in %s): %w", finalFile, target, err)
+		}
+		if err := os.Remove(target); err != nil {
+			log.Warn("Failed to remove staged merge file", "file", target, "error", err)
+		}
+	}
+	if len(gaps) > 0 {
+		log.Warn("⚠️ Merged with gaps (-force-merge)", "procedure", proc, "missing_files", len(gaps), "output_file", finalFile)
+	}
+	log.Info("📑 Merged files", "count", mergedCount, "output_file", finalFile, "duration", time.Since(start).Round(time.Second))
+	return gaps, nil
+}
+
+// copyInto copies the file at path, when there is one, to w.
+func copyInto(w io.Writer, path string) error {
+	in, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	_, err = io.Copy(w, in)
+	return err
+}
+
+// tailWriter remembers the last bytes written through it, up to its capacity.
+type tailWriter struct {
+	w    io.Writer
+	n    int64
+	size int
+	tail []byte
+}
+
+func (l *tailWriter) Write(p []byte) (int, error) {
+	n, err := l.w.Write(p)
+	if n > 0 {
+		l.n += int64(n)
+		t := append(l.tail, p[n-min(n, l.size):n]...)
+		l.tail = append(l.tail[:0], t[len(t)-min(len(t), l.size):]...)
+	}
+	return n, err
+}
+
+// appendSpool copies a spool file into the merged output unchanged, whatever its record
+// length, terminating an unterminated last record so the next spool starts a new record.
+// Fixed-length records (no terminator) are copied as they are.
+func appendSpool(w io.Writer, spool io.Reader, term []byte) error {
+	lw := &tailWriter{w: w, size: len(term)}
+	if _, err := io.Copy(lw, spool); err != nil {
+		return err
+	}
+	if lw.n > 0 && !bytes.HasSuffix(lw.tail, term) {
+		_, err := w.Write(term)
+		return err
+	}
+	return nil
+}
+
+// quarantineStaleSpools moves spool files of proc, or of its view with the spool suffix,
+// that were left behind by earlier runs out of the spool directory so they can never be
+// merged into this run's output.
+func quarantineStaleSpools(cfg *ExtractionConfig, proc, suffix string, current []string) error {
+	pattern, err := cfg.staleSpoolGlob(proc)
+	if err != nil {
+		return err
+	}
+	pattern += suffix
+	all, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("glob failed for pattern %s: %w", pattern, err)
+	}
+
+	isCurrent := make(map[string]bool, len(current))
+	for _, f := range current {
+		isCurrent[f] = true
+	}
+	if suffix == "" {
+		// The views' spools are left to their own merge.
+		for _, f := range all {
+			if cfg.isViewSpool(proc, f) {
+				isCurrent[f] = true
 			}
-			mergedCount++
 		}
-		writer.Flush()
-		log.Info("📑 Merged files", "count", mergedCount, "output_file", finalFile, "duration", time.Since(start).Round(time.Second))
+	}
+
+	quarantineDir := cfg.quarantineDir()
+	for _, f := range all {
+		if isCurrent[f] || cfg.otherProcedureSpool(proc, f) {
+			continue
+		}
+		if err := os.MkdirAll(quarantineDir, 0755); err != nil {
+			return fmt.Errorf("failed to create quarantine directory %s: %w", quarantineDir, err)
+		}
+		dest, err := quarantineFile(cfg, f)
+		if err != nil {
+			log.Warn("Failed to quarantine stale spool file", "file", f, "error", err)
+			continue
+		}
+		log.Warn("⚠️ Quarantined stale spool file from an earlier run", "procedure", proc, "file", f, "moved_to", dest)
 	}
 	return nil
 }
 
+// otherProcedureSpool reports whether f, matched by proc's spool pattern, is the spool
+// of a procedure with a longer name that the pattern also covers, e.g. GAM_HIST's for
+// GAM. Those are left to that procedure's own merge.
+func (c *ExtractionConfig) otherProcedureSpool(proc, f string) bool {
+	for _, other := range c.Procedures {
+		if len(other) <= len(proc) {
+			continue
+		}
+		pattern, err := c.filePattern(c.SpoolOutputPath, c.spoolDirTmpl, c.spoolTmpl, other, `(?:\..+)?`)
+		if err == nil && pattern.MatchString(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// quarantineFile moves a spool file into the quarantine directory and returns its new path.
+func quarantineFile(cfg *ExtractionConfig, f string) (string, error) {
+	dir := cfg.quarantineDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create quarantine directory %s: %w", dir, err)
+	}
+	dest := filepath.Join(dir, filepath.Base(f))
+	if err := os.Rename(f, dest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+// loadTemplates reads the column template of every configured procedure.
+func loadTemplates(cfg *ExtractionConfig) (map[string][]ColumnConfig, error) {
+	templates := make(map[string][]ColumnConfig)
+	for _, proc := range cfg.Procedures {
+		tmplPath := filepath.Join(cfg.TemplatePath, fmt.Sprintf("%s.csv", proc))
+		cols, err := readColumnsFromCSV(tmplPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template for %s: %w", proc, err)
+		}
+		templates[proc] = cols
+	}
+	if err := cfg.addViewTemplates(templates); err != nil {
+		return nil, err
+	}
+	cfg.recordWidths = make(map[string]int, len(templates))
+	for name, cols := range templates {
+		cfg.recordWidths[name] = recordWidth(cols)
+	}
+	return templates, nil
+}
+
 func readColumnsFromCSV(path string) ([]ColumnConfig, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -180,6 +789,9 @@ func readColumnsFromCSV(path string) ([]ColumnConfig, error) {
 	defer f.Close()
 
 	r := bufio.NewReader(f)
+	if err := skipInputBOM(r, path); err != nil {
+		return nil, err
+	}
 	csvr := csv.NewReader(r)
 	headers, err := csvr.Read()
 	if err != nil {
@@ -208,11 +820,51 @@ func readColumnsFromCSV(path string) ([]ColumnConfig, error) {
 		if i, ok := index["align"]; ok && i < len(row) {
 			col.Align = row[i]
 		}
+		if i, ok := index["length_policy"]; ok && i < len(row) {
+			col.LengthPolicy = strings.ToLower(strings.TrimSpace(row[i]))
+			if !validLengthPolicy(col.LengthPolicy) {
+				return nil, fmt.Errorf("invalid length_policy %q for column %s in csv template: %s", row[i], col.Name, path)
+			}
+		}
+		if i, ok := index["picture"]; ok && i < len(row) && strings.TrimSpace(row[i]) != "" {
+			if err := col.applyPicture(row[i]); err != nil {
+				return nil, fmt.Errorf("column %s in csv template %s: %w", col.Name, path, err)
+			}
+		}
+		if i, ok := index["scale"]; ok && i < len(row) && strings.TrimSpace(row[i]) != "" {
+			if col.Scale, err = strconv.Atoi(strings.TrimSpace(row[i])); err != nil || col.Scale < 0 {
+				return nil, fmt.Errorf("invalid scale %q for column %s in csv template: %s", row[i], col.Name, path)
+			}
+		}
+		if i, ok := index["zero_fill"]; ok && i < len(row) && strings.TrimSpace(row[i]) != "" {
+			if col.ZeroFill, err = strconv.ParseBool(strings.TrimSpace(row[i])); err != nil {
+				return nil, fmt.Errorf("invalid zero_fill %q for column %s in csv template: %s", row[i], col.Name, path)
+			}
+		}
+		if i, ok := index["optional"]; ok && i < len(row) && strings.TrimSpace(row[i]) != "" {
+			if col.Optional, err = strconv.ParseBool(strings.TrimSpace(row[i])); err != nil {
+				return nil, fmt.Errorf("invalid optional %q for column %s in csv template: %s", row[i], col.Name, path)
+			}
+			if col.Optional && !plainIdentifier.MatchString(col.Name) {
+				return nil, fmt.Errorf("column %s in csv template %s: only plain column names can be optional", col.Name, path)
+			}
+		}
+		if i, ok := index["default"]; ok && i < len(row) {
+			col.Default = row[i]
+		}
+		if i, ok := index["sanitize"]; ok && i < len(row) && strings.TrimSpace(row[i]) != "" {
+			col.Sanitize = strings.TrimSpace(row[i])
+			if _, err := parseSanitizeRules(col.Sanitize); err != nil {
+				return nil, fmt.Errorf("column %s in csv template %s: %w", col.Name, path, err)
+			}
+		}
+		if i, ok := index["sign"]; ok && i < len(row) && strings.TrimSpace(row[i]) != "" {
+			col.Sign = strings.ToLower(strings.TrimSpace(row[i]))
+			if !validSign(col.Sign) {
+				return nil, fmt.Errorf("invalid sign %q for column %s in csv template: %s", row[i], col.Name, path)
+			}
+		}
 		cols = append(cols, col)
 	}
 	return cols, nil
 }
-
-func sanitize(s string) string {
-	return strings.ReplaceAll(strings.ReplaceAll(s, "\n", " "), "\r", " ")
-}
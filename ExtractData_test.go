@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatFixedLengthPolicies(t *testing.T) {
+	cols := []ColumnConfig{
+		{Name: "ID", Length: 4, Align: "right"},
+		{Name: "NAME", Length: 3},
+	}
+
+	truncated := make(map[string]int64)
+	line, err := formatFixed(cols, []string{"12", "ABCDE"}, "", truncated)
+	if err != nil {
+		t.Fatalf("formatFixed: %v", err)
+	}
+	if line != "  12ABC" {
+		t.Errorf("line = %q, want %q", line, "  12ABC")
+	}
+	if truncated["NAME"] != 1 {
+		t.Errorf("NAME truncations = %d, want 1", truncated["NAME"])
+	}
+
+	cols[1].LengthPolicy = LengthPolicyFail
+	if _, err := formatFixed(cols, []string{"12345", "ABCDE"}, LengthPolicyTruncate, truncated); err == nil {
+		t.Fatal("expected error for column with fail policy")
+	}
+	if truncated["ID"] != 0 {
+		t.Errorf("rejected row must not be counted, ID truncations = %d", truncated["ID"])
+	}
+}
+
+func TestColumnProfile(t *testing.T) {
+	amount := ColumnProfile{Name: "AMOUNT", Length: 4, kind: kindNumber}
+	for _, v := range []sql.NullString{{String: "9", Valid: true}, {String: "-12.5", Valid: true}, {}, {String: "100", Valid: true}} {
+		amount.observe(v)
+	}
+	other := ColumnProfile{Name: "AMOUNT", Length: 4, kind: kindNumber}
+	other.observe(sql.NullString{String: "20", Valid: true})
+	merged := mergeProfiles(nil, []ColumnProfile{other})
+	merged = mergeProfiles(merged, []ColumnProfile{amount})
+
+	got := merged[0]
+	if got.Values != 5 || got.Nulls != 1 || got.MaxLength != 5 || got.Overlong != 1 {
+		t.Errorf("values, nulls, max length, overlong = %d, %d, %d, %d, want 5, 1, 5, 1", got.Values, got.Nulls, got.MaxLength, got.Overlong)
+	}
+	if got.Min != "-12.5" || got.Max != "100" {
+		t.Errorf("min, max = %s, %s, want -12.5, 100", got.Min, got.Max)
+	}
+}
+
+func TestAppendSpoolKeepsLongRecords(t *testing.T) {
+	long := strings.Repeat("x", 200*1024)
+	var out strings.Builder
+	for _, spool := range []string{long + "\n", "a\nb", ""} {
+		if err := appendSpool(&out, strings.NewReader(spool), []byte("\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := long + "\na\nb\n"; out.String() != want {
+		t.Errorf("merged %d bytes, want %d", out.Len(), len(want))
+	}
+}
+
+func TestFailedMergeKeepsSpools(t *testing.T) {
+	dir := t.TempDir()
+	cfg := ExtractionConfig{SpoolOutputPath: dir, RunID: "R1", sols: []string{"0001", "0002"}}
+	if err := cfg.compileFileNames(); err != nil {
+		t.Fatal(err)
+	}
+	good, _ := cfg.spoolFilePath("GAM", "0001")
+	bad, _ := cfg.spoolFilePath("GAM", "0002")
+	os.WriteFile(good, []byte("a\n"), 0644)
+	os.Mkdir(bad, 0755) // reads fail part-way through the merge
+	final := filepath.Join(dir, "GAM.txt")
+	os.WriteFile(final, []byte("previous\n"), 0644)
+
+	if _, err := mergeProcedure(&cfg, "GAM"); err == nil {
+		t.Fatal("merge of an unreadable spool succeeded")
+	}
+	if _, err := os.Stat(good); err != nil {
+		t.Fatalf("spool merged before the failure was removed: %v", err)
+	}
+	if got, _ := os.ReadFile(final); string(got) != "previous\n" {
+		t.Errorf("failed merge changed the output in place: %q", got)
+	}
+	if _, err := os.Stat(final + ".partial"); !os.IsNotExist(err) {
+		t.Error("failed merge left its partial file behind")
+	}
+
+	os.Remove(bad)
+	os.WriteFile(bad, []byte("b\n"), 0644)
+	if _, err := mergeProcedure(&cfg, "GAM"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(final); string(got) != "a\nb\n" {
+		t.Errorf("merged output = %q, want both spools", got)
+	}
+	if left, _ := filepath.Glob(filepath.Join(dir, "*R1*")); len(left) > 0 {
+		t.Errorf("spools left after a successful merge: %v", left)
+	}
+}
+
+func TestMergeLeavesPrefixedProcedureSpools(t *testing.T) {
+	dir := t.TempDir()
+	cfg := ExtractionConfig{SpoolOutputPath: dir, RunID: "R1", Procedures: []string{"GAM", "GAM_HIST"}, sols: []string{"0001"}}
+	if err := cfg.compileFileNames(); err != nil {
+		t.Fatal(err)
+	}
+	for _, proc := range cfg.Procedures {
+		spool, _ := cfg.spoolFilePath(proc, "0001")
+		os.WriteFile(spool, []byte(proc+"\n"), 0644)
+	}
+	// A spool of an earlier run is still quarantined.
+	stale := filepath.Join(dir, "GAM_0001_R0.spool")
+	os.WriteFile(stale, []byte("old\n"), 0644)
+
+	if _, err := mergeProcedure(&cfg, "GAM"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "GAM.txt")); string(got) != "GAM\n" {
+		t.Errorf("GAM.txt = %q, want only GAM's rows", got)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("stale spool of GAM not quarantined")
+	}
+	if _, err := mergeProcedure(&cfg, "GAM_HIST"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "GAM_HIST.txt")); string(got) != "GAM_HIST\n" {
+		t.Errorf("GAM_HIST.txt = %q, want GAM_HIST's rows kept for its own merge", got)
+	}
+}
+
+func TestSummaryAggregator(t *testing.T) {
+	a := newSummaryAggregator()
+	start := time.Now()
+	a.Add(ProcLog{Procedure: "GAM", SolID: "1", StartTime: start, EndTime: start.Add(time.Second), ExecutionTime: time.Second, Status: "SUCCESS", Rows: 3})
+	a.Add(ProcLog{Procedure: "GAM", SolID: "2", StartTime: start.Add(-time.Second), EndTime: start, ExecutionTime: time.Second, Status: "FAIL", ErrorDetails: "ORA-01555: snapshot too old"})
+	a.Add(ProcLog{Procedure: "HTD", SolID: "1", StartTime: start, EndTime: start, ExecutionTime: time.Millisecond, Status: "SUCCESS", Rows: 5})
+
+	summary, slowest := a.Close()
+	gam := summary["GAM"]
+	if gam.Status != "FAIL" || gam.Rows != 3 || !gam.StartTime.Equal(start.Add(-time.Second)) || !gam.EndTime.Equal(start.Add(time.Second)) {
+		t.Errorf("GAM summary = %+v", gam)
+	}
+	if gam.FailuresByCode["ORA-01555"] != 1 {
+		t.Errorf("GAM failures = %v", gam.FailuresByCode)
+	}
+	if htd := summary["HTD"]; htd.Status != "SUCCESS" || htd.Rows != 5 {
+		t.Errorf("HTD summary = %+v", htd)
+	}
+	if gam.P50 != time.Second || gam.MaxDuration != time.Second {
+		t.Errorf("GAM percentiles p50=%v max=%v, want 1s", gam.P50, gam.MaxDuration)
+	}
+	if len(slowest) != 3 || slowest[0].Duration != time.Second || slowest[2].Procedure != "HTD" {
+		t.Errorf("slowest = %+v", slowest)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var d []time.Duration
+	for i := 1; i <= 20; i++ {
+		d = append(d, time.Duration(i)*time.Minute)
+	}
+	if p50, p95 := percentile(d, 0.50), percentile(d, 0.95); p50 != 10*time.Minute || p95 != 19*time.Minute {
+		t.Errorf("p50=%v p95=%v, want 10m and 19m", p50, p95)
+	}
+	if p := percentile(d[:1], 0.95); p != time.Minute {
+		t.Errorf("single-job p95 = %v", p)
+	}
+}
+
+func TestErrorClasses(t *testing.T) {
+	query := newQueryError("GAM", fmt.Errorf("prepared statement query failed for procedure GAM: %w", errors.New("ORA-00942: table or view does not exist")))
+	cases := []struct {
+		err  error
+		code string
+		exit int
+	}{
+		{query, "ORA-00942", exitQuery},
+		{fmt.Errorf("job: %w", &TemplateError{Proc: "GAM", Err: errors.New("column mismatch")}), "TEMPLATE", exitTemplate},
+		{&WriteError{Proc: "GAM", Err: errors.New("no space left on device")}, "WRITE", exitWrite},
+		{&TimeoutError{After: time.Minute, Err: query}, "TIMEOUT", exitTimeout},
+		{errors.New("boom"), "OTHER", 1},
+	}
+	for _, c := range cases {
+		if got := errorCode(c.err); got != c.code {
+			t.Errorf("errorCode(%v) = %q, want %q", c.err, got, c.code)
+		}
+		if got := exitCode(c.err); got != c.exit {
+			t.Errorf("exitCode(%v) = %d, want %d", c.err, got, c.exit)
+		}
+	}
+	if query.Error() != "prepared statement query failed for procedure GAM: ORA-00942: table or view does not exist" {
+		t.Errorf("QueryError message = %q", query.Error())
+	}
+}
+
+// resultColumns is a database/sql connector whose queries return no rows under the
+// given column names.
+type resultColumns []string
+
+func (c resultColumns) Connect(context.Context) (driver.Conn, error) { return c, nil }
+func (c resultColumns) Driver() driver.Driver                        { return nil }
+func (c resultColumns) Prepare(string) (driver.Stmt, error)          { return c, nil }
+func (c resultColumns) Close() error                                 { return nil }
+func (c resultColumns) Begin() (driver.Tx, error)                    { return nil, errors.ErrUnsupported }
+func (c resultColumns) NumInput() int                                { return -1 }
+func (c resultColumns) Exec([]driver.Value) (driver.Result, error)   { return nil, errors.ErrUnsupported }
+func (c resultColumns) Query([]driver.Value) (driver.Rows, error)    { return c, nil }
+func (c resultColumns) Columns() []string                            { return c }
+func (c resultColumns) Next([]driver.Value) error                    { return io.EOF }
+
+func TestCheckColumns(t *testing.T) {
+	template := []ColumnConfig{{Name: "SOL_ID"}, {Name: "ACCT_NO"}, {Name: "NVL(BAL, 0)"}}
+	for _, tc := range []struct {
+		query []string
+		want  string
+	}{
+		{[]string{"sol_id", "ACCT_NO", "NVL(BAL,0)"}, ""},
+		{[]string{"SOL_ID", "ACCT_NAME", "BAL"}, "position 2: template column ACCT_NO, query column ACCT_NAME"},
+		{[]string{"SOL_ID", "ACCT_NO"}, "template has 3 columns, query returned 2; position 3: template column NVL(BAL, 0) missing from query"},
+		{[]string{"SOL_ID", "ACCT_NO", "BAL", "CCY"}, "template has 3 columns, query returned 4; position 4: unexpected query column CCY"},
+	} {
+		db := sql.OpenDB(resultColumns(tc.query))
+		rows, err := db.Query("SELECT")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = checkColumns(rows, template)
+		rows.Close()
+		db.Close()
+		if tc.want == "" {
+			if err != nil {
+				t.Errorf("columns %v: %v", tc.query, err)
+			}
+		} else if err == nil || err.Error() != "column mismatch: "+tc.want {
+			t.Errorf("columns %v: %v, want %s", tc.query, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+const (
+	// maxSessionLimitRetries is how often a job that could not get a session is retried.
+	maxSessionLimitRetries = 5
+	// rampUpJobsPerWorker successful jobs per allowed worker earn one more worker back.
+	rampUpJobsPerWorker = 5
+	// rampUpQuietPeriod must pass after the last backoff before ramping up again.
+	rampUpQuietPeriod = 30 * time.Second
+)
+
+// sessionLimitCodes are raised when the database has run out of sessions or processes.
+var sessionLimitCodes = map[string]bool{
+	"ORA-00018": true, "ORA-00020": true, "ORA-12516": true, "ORA-12519": true,
+}
+
+// isSessionLimit reports whether err means the database hit its session or process cap.
+func isSessionLimit(err error) bool {
+	return err != nil && sessionLimitCodes[errorCode(err)]
+}
+
+// concurrencyLimiter caps how many workers may run a job at once. It halves the limit,
+// and the connection pools with it, when the database hits its session cap, then ramps
+// back up one worker at a time as jobs succeed. A nil limiter imposes no limit.
+type concurrencyLimiter struct {
+	mu          sync.Mutex
+	cond        *sync.Cond
+	max         int
+	limit       int
+	active      int
+	successes   int
+	lastBackoff time.Time
+	dbs         map[string]*sql.DB
+}
+
+func newConcurrencyLimiter(max int, dbs map[string]*sql.DB) *concurrencyLimiter {
+	l := &concurrencyLimiter{max: max, limit: max, dbs: dbs}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire blocks until the worker may start a job.
+func (l *concurrencyLimiter) acquire() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.active >= l.limit {
+		l.cond.Wait()
+	}
+	l.active++
+}
+
+// release frees the worker's slot; succeeded jobs count towards ramping back up.
+func (l *concurrencyLimiter) release(succeeded bool) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	if succeeded && l.limit < l.max {
+		l.successes++
+		if l.successes >= l.limit*rampUpJobsPerWorker && time.Since(l.lastBackoff) >= rampUpQuietPeriod {
+			l.setLimit(l.limit + 1)
+			log.Info("📈 Raising concurrency after session-limit backoff", "workers", l.limit, "max", l.max)
+		}
+	}
+	l.cond.Broadcast()
+}
+
+// backoff halves the limit. Failures arriving together count as one backoff.
+func (l *concurrencyLimiter) backoff() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if time.Since(l.lastBackoff) < 5*time.Second || l.limit == 1 {
+		return
+	}
+	l.setLimit(max(1, l.limit/2))
+	l.lastBackoff = time.Now()
+	log.Warn("📉 Database session limit reached, reducing concurrency", "workers", l.limit, "max", l.max)
+}
+
+func (l *concurrencyLimiter) setLimit(n int) {
+	l.limit = n
+	l.successes = 0
+	for _, db := range l.dbs {
+		db.SetMaxOpenConns(n)
+		db.SetMaxIdleConns(n)
+	}
+}
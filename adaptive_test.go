@@ -0,0 +1,65 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimiterBacksOffOnSessionLimit(t *testing.T) {
+	for err, want := range map[error]bool{
+		fmt.Errorf("job: %w", errors.New("ORA-00020: maximum number of processes (300) exceeded")): true,
+		errors.New("ORA-12516: TNS:listener could not find available handler"):                     true,
+		errors.New("ORA-00942: table or view does not exist"):                                      false,
+		nil: false,
+	} {
+		if got := isSessionLimit(err); got != want {
+			t.Errorf("isSessionLimit(%v) = %v", err, got)
+		}
+	}
+
+	db := sql.OpenDB(&prepareCounter{})
+	defer db.Close()
+	l := newConcurrencyLimiter(8, map[string]*sql.DB{"": db})
+	l.backoff()
+	l.backoff() // the same burst of failures
+	if l.limit != 4 || db.Stats().MaxOpenConnections != 4 {
+		t.Fatalf("limit = %d, pool = %d after a backoff, want 4", l.limit, db.Stats().MaxOpenConnections)
+	}
+
+	// A fifth job waits for one of the four running to finish.
+	for range 4 {
+		l.acquire()
+	}
+	started := make(chan struct{})
+	go func() {
+		l.acquire()
+		close(started)
+	}()
+	select {
+	case <-started:
+		t.Fatal("job started over the reduced limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+	l.release(true)
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job not started after another finished")
+	}
+	for range 4 {
+		l.release(false)
+	}
+
+	// Once the quiet period has passed, limit × rampUpJobsPerWorker successes earn a worker back.
+	l.lastBackoff = time.Now().Add(-rampUpQuietPeriod)
+	for range 4 * rampUpJobsPerWorker {
+		l.acquire()
+		l.release(true)
+	}
+	if l.limit != 5 || db.Stats().MaxOpenConnections != 5 {
+		t.Errorf("limit = %d, pool = %d after ramping up, want 5", l.limit, db.Stats().MaxOpenConnections)
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Archive formats.
+const (
+	ArchiveZip   = "zip"
+	ArchiveTarGz = "tar.gz"
+)
+
+func validArchiveFormat(f string) bool {
+	return f == "" || f == ArchiveZip || f == ArchiveTarGz
+}
+
+// ArchiveConfig packages a run's final files, trigger files and summaries into one
+// archive, <package>_<business date>.<format>, for hand-off.
+type ArchiveConfig struct {
+	Enabled bool   `json:"enabled"`
+	Format  string `json:"format"` // zip (default) or tar.gz
+	Path    string `json:"path"`   // directory of the archive, default the output directory
+	// PasswordEnv names the environment variable holding the password a zip archive is
+	// AES-256 encrypted with (WinZip AE-2, readable by 7-Zip and WinZip).
+	PasswordEnv string `json:"password_env"`
+}
+
+// archivePath returns where the run's archive is written.
+func (c *ExtractionConfig) archivePath() string {
+	dir := c.Archive.Path
+	if dir == "" {
+		dir = c.outputDir()
+	}
+	format := c.Archive.Format
+	if format == "" {
+		format = ArchiveZip
+	}
+	return filepath.Join(dir, c.PackageName+"_"+c.BusinessDate.Format("20060102")+"."+format)
+}
+
+// writeArchive packages files into the run's archive, written under a temporary name and
+// renamed once complete.
+func writeArchive(cfg *ExtractionConfig, files []string) (string, error) {
+	path := cfg.archivePath()
+	mode := cfg.dirMode
+	if mode == 0 {
+		mode = defaultDirMode
+	}
+	if err := os.MkdirAll(filepath.Dir(path), mode); err != nil {
+		return "", fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	password := ""
+	if cfg.Archive.PasswordEnv != "" {
+		if password = os.Getenv(cfg.Archive.PasswordEnv); password == "" {
+			return "", fmt.Errorf("archive password variable %s is not set", cfg.Archive.PasswordEnv)
+		}
+	}
+
+	tmp := path + ".partial"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return "", err
+	}
+	if cfg.Archive.Format == ArchiveTarGz {
+		err = writeTarGz(f, files)
+	} else {
+		err = writeZip(f, files, password)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to write archive %s: %w", path, err)
+	}
+	return path, nil
+}
+
+func writeTarGz(w io.Writer, files []string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, name := range files {
+		if err := func() error {
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			info, err := f.Stat()
+			if err != nil {
+				return err
+			}
+			hdr, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			_, err = io.Copy(tw, f)
+			return err
+		}(); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeZip(w io.Writer, files []string, password string) error {
+	zw := zip.NewWriter(w)
+	for _, name := range files {
+		var err error
+		if password == "" {
+			err = addZipFile(zw, name)
+		} else {
+			err = addEncryptedZipFile(zw, name, password)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func addZipFile(zw *zip.Writer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	hdr.Method = zip.Deflate
+	out, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, f)
+	return err
+}
+
+// WinZip AES constants: compression method 99 with an 0x9901 extra field, AE-2 (no CRC),
+// AES-256 with a 16-byte salt and PBKDF2-HMAC-SHA1 key derivation.
+const (
+	zipMethodAES   = 99
+	zipAESSaltSize = 16
+	zipAESKeySize  = 32
+	zipAESMACSize  = 10
+)
+
+// addEncryptedZipFile deflates and encrypts name into a temporary file first, because
+// the entry's header must carry its final size. The file is kept in the system's
+// temporary directory, away from the output directory pollers watch.
+func addEncryptedZipFile(zw *zip.Writer, name, password string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp("", ".zipaes-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	salt := make([]byte, zipAESSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	keys, err := pbkdf2.Key(sha1.New, password, salt, 1000, 2*zipAESKeySize+2)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(salt); err != nil {
+		return err
+	}
+	if _, err := tmp.Write(keys[2*zipAESKeySize:]); err != nil { // password verification value
+		return err
+	}
+
+	enc, err := newZipAESWriter(tmp, keys[:zipAESKeySize], keys[zipAESKeySize:2*zipAESKeySize])
+	if err != nil {
+		return err
+	}
+	fw, err := flate.NewWriter(enc, flate.DefaultCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(fw, src); err != nil {
+		return err
+	}
+	if err := fw.Close(); err != nil {
+		return err
+	}
+	if _, err := tmp.Write(enc.mac.Sum(nil)[:zipAESMACSize]); err != nil {
+		return err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	hdr.Method = zipMethodAES
+	hdr.Flags |= 0x1 // encrypted
+	hdr.CRC32 = 0    // AE-2 authenticates with the MAC instead
+	hdr.CompressedSize64 = uint64(size)
+	hdr.UncompressedSize64 = uint64(info.Size())
+	// Extra field 0x9901: size 7, AE-2, vendor "AE", AES-256, actual method deflate.
+	hdr.Extra = []byte{0x01, 0x99, 0x07, 0x00, 0x02, 0x00, 'A', 'E', 0x03, 0x08, 0x00}
+	out, err := zw.CreateRaw(hdr)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.Copy(out, tmp)
+	return err
+}
+
+// zipAESWriter encrypts with AES in WinZip's counter mode (a little-endian counter
+// starting at 1) and MACs the ciphertext with HMAC-SHA1.
+type zipAESWriter struct {
+	w       io.Writer
+	block   interface{ Encrypt(dst, src []byte) }
+	counter [aes.BlockSize]byte
+	stream  [aes.BlockSize]byte
+	used    int
+	mac     hash.Hash
+}
+
+func newZipAESWriter(w io.Writer, key, macKey []byte) (*zipAESWriter, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &zipAESWriter{w: w, block: block, used: aes.BlockSize, mac: hmac.New(sha1.New, macKey)}, nil
+}
+
+func (z *zipAESWriter) Write(p []byte) (int, error) {
+	buf := make([]byte, len(p))
+	for i, b := range p {
+		if z.used == aes.BlockSize {
+			binary.LittleEndian.PutUint64(z.counter[:8], binary.LittleEndian.Uint64(z.counter[:8])+1)
+			z.block.Encrypt(z.stream[:], z.counter[:])
+			z.used = 0
+		}
+		buf[i] = b ^ z.stream[z.used]
+		z.used++
+	}
+	z.mac.Write(buf)
+	return z.w.Write(buf)
+}
+
+// archiveFiles lists what goes into the run's archive: each procedure's final files and
+// trigger files, leaving out files unchanged since the previous run, then the summaries.
+func archiveFiles(cfg *ExtractionConfig, summary map[string]ProcSummary, summaries ...string) []string {
+	var files []string
+	for _, proc := range cfg.Procedures {
+		for _, name := range cfg.outputNames(proc) {
+			for _, out := range summary[name].outputs() {
+				if out.UnchangedSince != "" {
+					continue
+				}
+				files = append(files, out.Path)
+				if trigger := cfg.triggerFilePath(out.Path); cfg.TriggerFile.Enabled && fileExists(trigger) {
+					files = append(files, trigger)
+				}
+			}
+		}
+	}
+	for _, f := range summaries {
+		if fileExists(f) && !strings.HasSuffix(f, ".partial") {
+			files = append(files, f)
+		}
+	}
+	return files
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func archiveFixture(t *testing.T, format, passwordEnv string) (*ExtractionConfig, []string) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := &ExtractionConfig{
+		PackageName:     "PKG",
+		Procedures:      []string{"GAM"},
+		SpoolOutputPath: dir,
+		BusinessDate:    time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+		Archive:         ArchiveConfig{Enabled: true, Format: format, PasswordEnv: passwordEnv},
+	}
+	gam := filepath.Join(dir, "GAM.txt")
+	os.WriteFile(gam, bytes.Repeat([]byte("0001Ann\n"), 100), 0644)
+	summary := filepath.Join(dir, "PKG_E_summary.csv")
+	os.WriteFile(summary, []byte("Procedure,Status\nGAM,SUCCESS\n"), 0644)
+	return cfg, archiveFiles(cfg, map[string]ProcSummary{"GAM": {Output: &OutputFile{Path: gam}}}, summary, filepath.Join(dir, "missing.json"))
+}
+
+func TestWriteArchiveTarGz(t *testing.T) {
+	cfg, files := archiveFixture(t, ArchiveTarGz, "")
+	path, err := writeArchive(cfg, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "PKG_20240331.tar.gz" {
+		t.Errorf("archive = %s, want PKG_20240331.tar.gz", path)
+	}
+	f, _ := os.Open(path)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+	if len(names) != 2 || names[0] != "GAM.txt" || names[1] != "PKG_E_summary.csv" {
+		t.Errorf("entries = %v, want [GAM.txt PKG_E_summary.csv]", names)
+	}
+}
+
+func TestWriteArchiveZipEncrypted(t *testing.T) {
+	t.Setenv("TEST_ARCHIVE_PASSWORD", "s3cret")
+	cfg, files := archiveFixture(t, "", "TEST_ARCHIVE_PASSWORD")
+	path, err := writeArchive(cfg, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "PKG_20240331.zip" {
+		t.Errorf("archive = %s, want PKG_20240331.zip", path)
+	}
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	if len(zr.File) != 2 {
+		t.Fatalf("entries = %d, want 2", len(zr.File))
+	}
+	entry := zr.File[0]
+	if entry.Name != "GAM.txt" || entry.Method != zipMethodAES || entry.Flags&0x1 == 0 {
+		t.Fatalf("entry %s: method %d, flags %#x, want an AES-encrypted entry", entry.Name, entry.Method, entry.Flags)
+	}
+	r, err := entry.OpenRaw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(r)
+	salt, verifier := raw[:zipAESSaltSize], raw[zipAESSaltSize:zipAESSaltSize+2]
+	data, mac := raw[zipAESSaltSize+2:len(raw)-zipAESMACSize], raw[len(raw)-zipAESMACSize:]
+
+	keys, _ := pbkdf2.Key(sha1.New, "s3cret", salt, 1000, 2*zipAESKeySize+2)
+	if !bytes.Equal(verifier, keys[2*zipAESKeySize:]) {
+		t.Fatal("password verifier does not match")
+	}
+	h := hmac.New(sha1.New, keys[zipAESKeySize:2*zipAESKeySize])
+	h.Write(data)
+	if !bytes.Equal(mac, h.Sum(nil)[:zipAESMACSize]) {
+		t.Fatal("authentication code does not match")
+	}
+	// Counter mode is symmetric, so encrypting the ciphertext again decrypts it.
+	var plain bytes.Buffer
+	dec, _ := newZipAESWriter(&plain, keys[:zipAESKeySize], keys[zipAESKeySize:2*zipAESKeySize])
+	dec.Write(data)
+	got, err := io.ReadAll(flate.NewReader(&plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := bytes.Repeat([]byte("0001Ann\n"), 100); !bytes.Equal(got, want) {
+		t.Errorf("decrypted %d bytes, want the %d of GAM.txt", len(got), len(want))
+	}
+}
+
+func TestWriteArchiveMissingPassword(t *testing.T) {
+	cfg, files := archiveFixture(t, ArchiveZip, "TEST_ARCHIVE_PASSWORD_UNSET")
+	if _, err := writeArchive(cfg, files); err == nil {
+		t.Fatal("writeArchive without the password variable succeeded")
+	}
+	if _, err := os.Stat(cfg.archivePath() + ".partial"); !os.IsNotExist(err) {
+		t.Errorf("partial archive left behind: %v", err)
+	}
+}
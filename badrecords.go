@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// badRecordWriter collects rows that failed to scan or format into one .bad file per
+// procedure. It is shared by all workers, so writes are serialised with a mutex.
+type badRecordWriter struct {
+	mu      sync.Mutex
+	dir     string
+	files   map[string]*os.File
+	writers map[string]*csv.Writer
+}
+
+func newBadRecordWriter(dir string) *badRecordWriter {
+	return &badRecordWriter{
+		dir:     dir,
+		files:   make(map[string]*os.File),
+		writers: make(map[string]*csv.Writer),
+	}
+}
+
+// Write records a rejected row together with the SOL ID and the reason it was rejected.
+func (b *badRecordWriter) Write(proc, solID string, values []string, cause error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	w, ok := b.writers[proc]
+	if !ok {
+		path := filepath.Join(b.dir, proc+".bad")
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create bad record file %s: %w", path, err)
+		}
+		w = csv.NewWriter(f)
+		if err := w.Write([]string{"SOL_ID", "ERROR", "VALUES..."}); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write bad record header to %s: %w", path, err)
+		}
+		b.files[proc] = f
+		b.writers[proc] = w
+	}
+
+	record := append([]string{solID, cause.Error()}, values...)
+	if err := w.Write(record); err != nil {
+		return fmt.Errorf("failed to write bad record for procedure %s: %w", proc, err)
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// Close flushes and closes every .bad file that was opened.
+func (b *badRecordWriter) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var firstErr error
+	for proc, f := range b.files {
+		b.writers[proc].Flush()
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// runConfigPaths expands -runCfg into the run configuration files of this invocation:
+// a single path, a comma-separated list, or @manifest naming a file that lists one
+// path per line (blank and # comment lines ignored, relative to the manifest).
+func runConfigPaths() ([]string, error) {
+	value := strings.TrimSpace(*runCfgFile)
+	if value == "" {
+		return nil, fmt.Errorf("both appCfg and runCfg flags must be specified")
+	}
+	if manifest, ok := strings.CutPrefix(value, "@"); ok {
+		f, err := os.Open(manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read run manifest: %w", err)
+		}
+		defer f.Close()
+		var paths []string
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if !filepath.IsAbs(line) {
+				line = filepath.Join(filepath.Dir(manifest), line)
+			}
+			paths = append(paths, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read run manifest: %w", err)
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("run manifest %s lists no run configurations", manifest)
+		}
+		return paths, nil
+	}
+	var paths []string
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+// batchResult is the outcome of one package of a multi-run invocation.
+type batchResult struct {
+	Package string
+	Start   time.Time
+	End     time.Time
+	Err     error
+	Skipped bool
+}
+
+// runBatch runs several run configurations, one after another or all at once, over the
+// shared connection pools. A failed package does not stop the others; every outcome is
+// written to a consolidated summary and the batch fails if any package failed.
+func runBatch(ctx context.Context, appCfg MainConfig, runCfgs []*ExtractionConfig, dbs map[string]*sql.DB, parallel bool) error {
+	log.Info("📚 Running batch", "packages", len(runCfgs), "parallel", parallel)
+	results := make([]batchResult, len(runCfgs))
+	runOne := func(i int) {
+		results[i] = batchResult{Package: runCfgs[i].PackageName, Start: time.Now()}
+		log.Info("📦 Starting package", "package", results[i].Package)
+		results[i].Err = runPackage(ctx, appCfg, runCfgs[i], dbs)
+		results[i].End = time.Now()
+		if results[i].Err != nil {
+			log.Error("Package failed", "package", results[i].Package, "error", results[i].Err)
+		}
+	}
+	if parallel {
+		var wg sync.WaitGroup
+		for i := range runCfgs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				runOne(i)
+			}()
+		}
+		wg.Wait()
+	} else {
+		// An interrupt cancels the running package; the ones after it are not started.
+		interrupted := make(chan os.Signal, 1)
+		signal.Notify(interrupted, os.Interrupt)
+		notifyServiceStop(interrupted)
+		defer signal.Stop(interrupted)
+		defer stopServiceNotify(interrupted)
+		for i := range runCfgs {
+			select {
+			case <-interrupted:
+				for j := i; j < len(runCfgs); j++ {
+					results[j] = batchResult{Package: runCfgs[j].PackageName, Skipped: true}
+				}
+			default:
+			}
+			if results[i].Skipped {
+				break
+			}
+			runOne(i)
+		}
+	}
+
+	var failed []string
+	for _, r := range results {
+		if r.Err != nil || r.Skipped {
+			failed = append(failed, r.Package)
+		}
+	}
+	path := filepath.Join(appCfg.LogFilePath, fmt.Sprintf("batch_%s_summary.csv", map[string]string{"E": "extract", "I": "insert"}[*mode]))
+	if err := writeBatchSummary(path, results); err != nil {
+		log.Warn("Failed to write batch summary", "file", path, "error", err)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d package(s) did not complete: %s", len(failed), len(results), strings.Join(failed, ", "))
+	}
+	log.Info("🎯 Batch complete", "packages", len(results), "summary", path)
+	return nil
+}
+
+func writeBatchSummary(path string, results []batchResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"PACKAGE", "START_TIME", "END_TIME", "EXECUTION_SECONDS", "STATUS", "ERROR_DETAILS"})
+	for _, r := range results {
+		status, details := "SUCCESS", "-"
+		switch {
+		case r.Skipped:
+			status, details = "SKIPPED", "batch interrupted before the package started"
+		case r.Err != nil:
+			status, details = "FAIL", r.Err.Error()
+		}
+		start, end, secs := "-", "-", "-"
+		if !r.Skipped {
+			start, end = timestamps.format(r.Start), timestamps.format(r.End)
+			secs = fmt.Sprintf("%.3f", r.End.Sub(r.Start).Seconds())
+		}
+		w.Write([]string{r.Package, start, end, secs, status, details})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// writesBOM reports whether the named output, or the procedure it is a view of, starts
+// with a UTF-8 byte order mark.
+func (c *ExtractionConfig) writesBOM(name string) bool {
+	return slices.Contains(c.BOMOutputs, name) || slices.Contains(c.BOMOutputs, c.sourceProcedure(name))
+}
+
+// needsBOM reports whether the merge of the named output into path starts it with a BOM:
+// when the output has one, unless the merge appends to a file that already has content.
+func (c *ExtractionConfig) needsBOM(name, path string) bool {
+	if !c.writesBOM(name) {
+		return false
+	}
+	if c.appends(name) {
+		if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// dropBOM discards a leading UTF-8 byte order mark from r, reporting whether there was one.
+func dropBOM(r *bufio.Reader) bool {
+	if head, _ := r.Peek(len(utf8BOM)); bytes.Equal(head, utf8BOM) {
+		r.Discard(len(utf8BOM))
+		return true
+	}
+	return false
+}
+
+// skipInputBOM drops a UTF-8 byte order mark from an input file such as the SOL list or a
+// template, which would otherwise end up in the first SOL ID or column name. A UTF-16
+// mark is an error, since those files are read as UTF-8.
+func skipInputBOM(r *bufio.Reader, path string) error {
+	if dropBOM(r) {
+		return nil
+	}
+	if head, _ := r.Peek(2); bytes.Equal(head, []byte{0xFE, 0xFF}) || bytes.Equal(head, []byte{0xFF, 0xFE}) {
+		return fmt.Errorf("%s is UTF-16 encoded, save it as UTF-8", path)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInputBOM(t *testing.T) {
+	dir := t.TempDir()
+	sols := filepath.Join(dir, "sols.txt")
+	os.WriteFile(sols, []byte("\uFEFF0001\n0002\n"), 0644)
+	if got, err := readSols(sols); err != nil || len(got) != 2 || got[0] != "0001" {
+		t.Errorf("readSols = %q, %v, want [0001 0002]", got, err)
+	}
+	tmpl := filepath.Join(dir, "GAM.csv")
+	os.WriteFile(tmpl, []byte("\uFEFFname,length\nACID,4\n"), 0644)
+	if cols, err := readColumnsFromCSV(tmpl); err != nil || len(cols) != 1 || cols[0].Name != "ACID" {
+		t.Errorf("readColumnsFromCSV = %+v, %v, want ACID", cols, err)
+	}
+	os.WriteFile(sols, []byte{0xFF, 0xFE, '0', 0}, 0644)
+	if _, err := readSols(sols); err == nil {
+		t.Error("UTF-16 SOL list accepted")
+	}
+}
+
+func TestMergeWritesBOMOnce(t *testing.T) {
+	dir := t.TempDir()
+	cfg := ExtractionConfig{
+		SpoolOutputPath: dir,
+		Format:          "delimited",
+		Delimiter:       "|",
+		RunID:           "R1",
+		BOMOutputs:      []string{"GAM"},
+		RollingFeeds:    []string{"GAM"},
+	}
+	if err := cfg.compileFileNames(); err != nil {
+		t.Fatal(err)
+	}
+	final, _ := cfg.outputFilePath("GAM")
+	for _, data := range []string{"A1|0001\n", "A2|0002\n"} {
+		spool := filepath.Join(dir, "spool.spool")
+		os.WriteFile(spool, []byte(data), 0644)
+		if _, err := mergeSpools(&cfg, "GAM", []string{spool}, final); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, err := os.ReadFile(final); err != nil || string(got) != "\uFEFFA1|0001\nA2|0002\n" {
+		t.Errorf("merged = %q (%v), want one leading BOM", got, err)
+	}
+	cols := []ColumnConfig{{Name: "ACID"}, {Name: "SOL_ID"}}
+	if n, err := verifyOutput(cfg.forOutput("GAM"), final, cols); err != nil || n != 2 {
+		t.Errorf("verifyOutput = %d, %v, want 2 records", n, err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// businessDateLayout is the format of -business-date and of the bound value.
+const businessDateLayout = "2006-01-02"
+
+// resolveBusinessDate sets the run's business date from the -business-date value, or
+// from the database's SYSDATE when none was given, so every feed of the run is keyed by
+// the same date.
+func (c *ExtractionConfig) resolveBusinessDate(ctx context.Context, db *sql.DB, value string) error {
+	if value != "" {
+		d, err := parseBusinessDate(value)
+		if err != nil {
+			return err
+		}
+		c.BusinessDate = d
+		return nil
+	}
+	var d time.Time
+	if err := db.QueryRowContext(ctx, "SELECT TRUNC(SYSDATE) FROM dual").Scan(&d); err != nil {
+		return fmt.Errorf("failed to read the database date for the business date: %w", err)
+	}
+	c.BusinessDate = time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Local)
+	return nil
+}
+
+func parseBusinessDate(value string) (time.Time, error) {
+	d, err := time.ParseInLocation(businessDateLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid -business-date %q, expected YYYY-MM-DD", value)
+	}
+	return d, nil
+}
+
+// solFilter is the WHERE condition selecting one SOL's rows, restricted to the business
+// date when a business date column is configured. solArgs returns its bind values.
+func (c *ExtractionConfig) solFilter() string {
+	if c.BusinessDateColumn == "" {
+		return "SOL_ID = :1"
+	}
+	return fmt.Sprintf("SOL_ID = :1 AND %s = TO_DATE(:2, 'YYYY-MM-DD')", c.BusinessDateColumn)
+}
+
+func (c *ExtractionConfig) solArgs(solID string) []any {
+	if c.BusinessDateColumn == "" {
+		return []any{solID}
+	}
+	return []any{solID, c.BusinessDate.Format(businessDateLayout)}
+}
+
+// procedureCall is the PL/SQL block running proc for one SOL, passing the business date
+// as a second DATE argument when configured. procedureArgs returns its bind values.
+func (c *ExtractionConfig) procedureCall(proc string) string {
+	if !c.BusinessDateParam {
+		return fmt.Sprintf("BEGIN %s.%s(:1); END;", c.PackageName, proc)
+	}
+	return fmt.Sprintf("BEGIN %s.%s(:1, TO_DATE(:2, 'YYYY-MM-DD')); END;", c.PackageName, proc)
+}
+
+func (c *ExtractionConfig) procedureArgs(solID string) []any {
+	if !c.BusinessDateParam {
+		return []any{solID}
+	}
+	return []any{solID, c.BusinessDate.Format(businessDateLayout)}
+}
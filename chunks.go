@@ -0,0 +1,59 @@
+package main
+
+import "fmt"
+
+// ChunkConfig splits each SOL of a procedure into Buckets sub-jobs by ORA_HASH of Key
+// (default ROWID), each reading its own cursor into its own spool segment.
+type ChunkConfig struct {
+	Buckets int    `json:"buckets"`
+	Key     string `json:"key"`
+}
+
+// maxChunkBuckets keeps segment numbers at three digits so spools sort in chunk order.
+const maxChunkBuckets = 999
+
+// spoolID identifies the job's spool segment, completion marker and log rows: the SOL ID,
+// suffixed with the chunk number for chunked procedures.
+func (j Job) spoolID() string {
+	if j.Chunk == 0 {
+		return j.SolID
+	}
+	return fmt.Sprintf("%s.%03d", j.SolID, j.Chunk)
+}
+
+// procJobs returns the jobs of proc for one SOL: one per chunk when the procedure is
+// chunked, otherwise a single job.
+func (c *ExtractionConfig) procJobs(proc, solID string) []Job {
+	chunk, ok := c.SolChunks[proc]
+	if !ok {
+		return []Job{{SolID: solID, Proc: proc}}
+	}
+	jobs := make([]Job, chunk.Buckets)
+	for i := range jobs {
+		jobs[i] = Job{SolID: solID, Proc: proc, Chunk: i + 1}
+	}
+	return jobs
+}
+
+// chunkFilter is the extra WHERE condition selecting one chunk of a SOL, bound as
+// parameter number bind, or "" when proc is not chunked.
+func (c *ExtractionConfig) chunkFilter(proc string, bind int) string {
+	chunk, ok := c.SolChunks[proc]
+	if !ok {
+		return ""
+	}
+	key := chunk.Key
+	if key == "" {
+		key = "ROWID"
+	}
+	return fmt.Sprintf(" AND ORA_HASH(%s, %d) = :%d", key, chunk.Buckets-1, bind)
+}
+
+// jobArgs returns the bind values of the extraction query for job.
+func (c *ExtractionConfig) jobArgs(job Job) []any {
+	args := c.solArgs(job.SolID)
+	if job.Chunk > 0 {
+		args = append(args, job.Chunk-1)
+	}
+	return args
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// RetentionConfig controls how old spool leftovers, log CSVs and merged outputs are pruned.
+type RetentionConfig struct {
+	MaxAgeDays     int      `json:"max_age_days"`
+	MaxRuns        int      `json:"max_runs"`
+	CleanOnStart   bool     `json:"clean_on_start"`
+	OutputPatterns []string `json:"output_patterns"`
+}
+
+// runLogKinds are the per-run log files, by what follows the log stem.
+var runLogKinds = []string{".csv", "_summary.csv", "_summary.json", "_timeline.json", "_sessionstats.csv", "_statements.sql"}
+
+// logStem returns the name the per-run logs of pkg in mode start with; with the suffix
+// rerun policy the run ID follows it.
+func logStem(pkg, mode string) string {
+	if mode == "I" {
+		return pkg + "_insert"
+	}
+	return pkg + "_extract"
+}
+
+// cleanupTarget is a set of files the tool named, found by glob and confirmed by
+// pattern. Files whose pattern captures the same values form one run series.
+type cleanupTarget struct {
+	kind      string
+	glob      string
+	pattern   *regexp.Regexp
+	applyRuns bool
+}
+
+// cleanupTargets returns the spool, quarantine, log and output files of the configured
+// procedures. Outputs of longer procedure names come first, so GAM_HIST's files are
+// claimed by GAM_HIST rather than by GAM.
+func cleanupTargets(appCfg *MainConfig, runCfg *ExtractionConfig) ([]cleanupTarget, error) {
+	var targets []cleanupTarget
+	procs := slices.Clone(runCfg.Procedures)
+	sort.SliceStable(procs, func(i, j int) bool { return len(procs[i]) > len(procs[j]) })
+	for _, proc := range procs {
+		// A job's spool and the spools of the procedure's views, named after it.
+		suffix := ""
+		if views := runCfg.Views[proc]; len(views) > 0 {
+			names := make([]string, len(views))
+			for i, v := range views {
+				names[i] = regexp.QuoteMeta(v.Name)
+			}
+			suffix = `(?:\.(?:` + strings.Join(names, "|") + `))?`
+		}
+		glob, err := runCfg.staleSpoolGlob(proc)
+		if err != nil {
+			return nil, err
+		}
+		pattern, err := runCfg.filePattern(runCfg.SpoolOutputPath, runCfg.spoolDirTmpl, runCfg.spoolTmpl, proc, suffix)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, cleanupTarget{kind: "spool", glob: glob + "*", pattern: pattern})
+		quarantined, err := runCfg.filePattern(runCfg.quarantineDir(), nil, runCfg.spoolTmpl, proc, suffix)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, cleanupTarget{kind: "quarantine", glob: filepath.Join(runCfg.quarantineDir(), filepath.Base(glob)+"*"), pattern: quarantined})
+
+		for _, name := range runCfg.outputNames(proc) {
+			glob, err := runCfg.compressedPath(name)(runCfg.renderPath(runCfg.outputDir(), runCfg.outputDirTmpl, runCfg.outputTmpl,
+				fileNameData{Proc: name, SolID: "*", Group: "*", RunID: "*", wildcard: true}))
+			if err != nil {
+				return nil, err
+			}
+			pattern, err := runCfg.filePattern(runCfg.outputDir(), runCfg.outputDirTmpl, runCfg.outputTmpl, name, regexp.QuoteMeta(runCfg.outputExtension(name)))
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, cleanupTarget{kind: "output", glob: glob, pattern: pattern, applyRuns: true})
+		}
+	}
+	for _, mode := range []string{"E", "I"} {
+		stem := logStem(runCfg.PackageName, mode)
+		for _, kind := range runLogKinds {
+			pattern := regexp.MustCompile("^" + regexp.QuoteMeta(filepath.Join(appCfg.LogFilePath, stem)) + "(?:_[0-9]{" + fmt.Sprint(len(runIDLayout)) + "})?" + regexp.QuoteMeta(kind) + "$")
+			targets = append(targets, cleanupTarget{kind: "log", glob: filepath.Join(appCfg.LogFilePath, stem) + "*" + kind, pattern: pattern, applyRuns: true})
+		}
+	}
+	return targets, nil
+}
+
+// cleanup prunes files according to the retention policy. Only files the tool names
+// are touched: the spools, quarantined spools, per-run logs and merged outputs of the
+// configured procedures. Spool and quarantined files are only ever pruned by age since
+// each one belongs to a single SOL; logs and outputs additionally keep only the newest
+// MaxRuns files of each series, the files of one procedure, and one SOL or group, that
+// differ only in their run ID or dates.
+func cleanup(appCfg *MainConfig, runCfg *ExtractionConfig) error {
+	ret := appCfg.Retention
+	if ret.MaxAgeDays <= 0 && ret.MaxRuns <= 0 {
+		log.Info("Retention policy not configured, nothing to clean")
+		return nil
+	}
+	targets, err := cleanupTargets(appCfg, runCfg)
+	if err != nil {
+		return err
+	}
+
+	var cutoff time.Time
+	if ret.MaxAgeDays > 0 {
+		cutoff = time.Now().AddDate(0, 0, -ret.MaxAgeDays)
+	}
+
+	var removed int
+	claimed := make(map[string]bool)
+	for _, t := range targets {
+		files, err := filepath.Glob(t.glob)
+		if err != nil {
+			return fmt.Errorf("glob failed for pattern %s: %w", t.glob, err)
+		}
+
+		// Outputs deduplicated by link keep the file they point to alive.
+		linked := linkedTargets(files)
+		series := make(map[string][]os.FileInfo)
+		paths := make(map[os.FileInfo]string)
+		for _, f := range files {
+			m := t.pattern.FindStringSubmatch(f)
+			if m == nil || claimed[f] {
+				continue
+			}
+			if t.kind == "output" && len(ret.OutputPatterns) > 0 && !matchesAny(ret.OutputPatterns, filepath.Base(f)) {
+				continue
+			}
+			info, err := os.Stat(f)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			claimed[f] = true
+			paths[info] = f
+			key := strings.Join(m[1:], "\x00")
+			series[key] = append(series[key], info)
+		}
+
+		for _, infos := range series {
+			// Newest first, so everything past MaxRuns is surplus.
+			sort.Slice(infos, func(i, j int) bool { return infos[i].ModTime().After(infos[j].ModTime()) })
+			for i, info := range infos {
+				expired := !cutoff.IsZero() && info.ModTime().Before(cutoff)
+				surplus := t.applyRuns && ret.MaxRuns > 0 && i >= ret.MaxRuns
+				if !expired && !surplus {
+					continue
+				}
+				path := paths[info]
+				if abs, err := filepath.Abs(path); err == nil && linked[abs] {
+					log.Debug("Keeping file a newer output links to", "kind", t.kind, "file", path)
+					continue
+				}
+				if err := os.Remove(path); err != nil {
+					log.Warn("Failed to remove file during cleanup", "file", path, "error", err)
+					continue
+				}
+				log.Debug("Removed file", "kind", t.kind, "file", path, "modified", info.ModTime().Format(time.RFC3339))
+				removed++
+			}
+		}
+	}
+	log.Info("🧹 Cleanup finished", "removed_files", removed)
+	return nil
+}
+
+// matchesAny reports whether name matches one of the glob patterns.
+func matchesAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return ok
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// touch creates path with a modification time age ago.
+func touch(t *testing.T, path string, age time.Duration) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(-age)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func remaining(t *testing.T, dir string) map[string]bool {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	left := make(map[string]bool)
+	for _, e := range entries {
+		left[e.Name()] = true
+	}
+	return left
+}
+
+func TestCleanupMaxRunsKeepsEachSeries(t *testing.T) {
+	// Logs, templates, spools and outputs all share one directory.
+	dir := t.TempDir()
+	appCfg := &MainConfig{LogFilePath: dir, Retention: RetentionConfig{MaxRuns: 1}}
+	runCfg := &ExtractionConfig{PackageName: "PKG", Procedures: []string{"GAM", "GAM_HIST"}, TemplatePath: dir, SpoolOutputPath: dir,
+		PerSolOutput: true, OutputFileTemplate: "{{.Proc}}_{{.SolID}}_{{.RunID}}.txt"}
+	if err := runCfg.compileFileNames(); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]time.Duration{
+		"GAM_0001_20260301000000.txt":      2 * time.Hour,
+		"GAM_0001_20260302000000.txt":      time.Hour,
+		"GAM_0002_20260302000000.txt":      time.Hour,
+		"GAM_HIST_0001_20260302000000.txt": time.Hour,
+		"PKG_extract_20260301000000.csv":   2 * time.Hour,
+		"PKG_extract_20260302000000.csv":   time.Hour,
+		"PKG_extract_summary.csv":          time.Hour,
+		"GAM.csv":                          3 * time.Hour, // column template
+		"notes.csv":                        3 * time.Hour,
+		"GL01.txt":                         3 * time.Hour,
+		"GL02.txt":                         2 * time.Hour,
+	}
+	for name, age := range files {
+		touch(t, filepath.Join(dir, name), age)
+	}
+
+	if err := cleanup(appCfg, runCfg); err != nil {
+		t.Fatal(err)
+	}
+	left := remaining(t, dir)
+	for _, gone := range []string{"GAM_0001_20260301000000.txt", "PKG_extract_20260301000000.csv"} {
+		if left[gone] {
+			t.Errorf("%s survived max_runs 1", gone)
+		}
+		delete(files, gone)
+	}
+	for name := range files {
+		if !left[name] {
+			t.Errorf("%s removed, but it is the newest of its series or not the tool's", name)
+		}
+	}
+}
+
+func TestCleanupMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	spools := filepath.Join(dir, "spool")
+	appCfg := &MainConfig{LogFilePath: dir, Retention: RetentionConfig{MaxAgeDays: 7}}
+	runCfg := &ExtractionConfig{PackageName: "PKG", Procedures: []string{"GAM"}, TemplatePath: dir, SpoolOutputPath: spools, PerSolOutput: true,
+		SpoolFileTemplate: "{{.RunID}}-{{.Proc}}-{{.SolID}}.dat", SpoolDirTemplate: `{{.Date "2006-01-02"}}`}
+	if err := runCfg.compileFileNames(); err != nil {
+		t.Fatal(err)
+	}
+	old, recent := 10*24*time.Hour, time.Hour
+	os.MkdirAll(filepath.Join(spools, "2026-03-01"), 0755)
+	touch(t, filepath.Join(spools, "2026-03-01", "20260301000000-GAM-0001.dat"), old)
+	touch(t, filepath.Join(spools, "2026-03-01", "20260301000000-GAM-0002.dat"), recent)
+	touch(t, filepath.Join(spools, "2026-03-01", "unrelated.dat"), old)
+	touch(t, filepath.Join(spools, "GAM_0001.txt"), old)
+	touch(t, filepath.Join(spools, "GAM_0002.txt"), recent)
+	touch(t, filepath.Join(dir, "PKG_extract.csv"), old)
+	touch(t, filepath.Join(dir, "GAM.csv"), old)
+
+	if err := cleanup(appCfg, runCfg); err != nil {
+		t.Fatal(err)
+	}
+	layout, top, logs := remaining(t, filepath.Join(spools, "2026-03-01")), remaining(t, spools), remaining(t, dir)
+	if layout["20260301000000-GAM-0001.dat"] || top["GAM_0001.txt"] || logs["PKG_extract.csv"] {
+		t.Errorf("expired files survived: layout %v, outputs %v, logs %v", layout, top, logs)
+	}
+	if !layout["20260301000000-GAM-0002.dat"] || !top["GAM_0002.txt"] {
+		t.Errorf("recent files removed: layout %v, outputs %v", layout, top)
+	}
+	if !layout["unrelated.dat"] || !logs["GAM.csv"] {
+		t.Errorf("files the tool did not name removed: layout %v, logs %v", layout, logs)
+	}
+}
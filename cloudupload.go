@@ -0,0 +1,669 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// Upload providers.
+const (
+	UploadAzure = "azure"
+	UploadGCS   = "gcs"
+)
+
+const (
+	// azureBlockSize is the size of the blocks a file is uploaded to Azure in.
+	azureBlockSize = 64 << 20
+	// gcsChunkSize is the size of the chunks sent to a GCS upload session; it must be a
+	// multiple of 256 KiB.
+	gcsChunkSize = 32 << 20
+)
+
+// UploadConfig is a cloud storage or SFTP destination that final outputs, and their
+// trigger files, are copied to. Credentials come from the provider's standard sources.
+type UploadConfig struct {
+	Provider  string `json:"provider"`  // azure, gcs or sftp
+	Account   string `json:"account"`   // Azure storage account
+	Container string `json:"container"` // Azure container
+	Bucket    string `json:"bucket"`    // GCS bucket
+	Prefix    string `json:"prefix"`    // object name prefix, e.g. extracts/, or SFTP directory
+	// Host (host or host:port), User, KeyFile and KnownHosts reach an SFTP server.
+	Host       string `json:"host"`
+	User       string `json:"user"`
+	KeyFile    string `json:"key_file"`
+	KnownHosts string `json:"known_hosts"`
+	// Endpoint overrides the service URL, e.g. for the Azurite or fake-gcs-server emulators.
+	Endpoint string `json:"endpoint"`
+	// MaxMbps caps the upload bandwidth to this destination, in megabits per second,
+	// ParallelParts is how many Azure blocks are sent at once, and Retries how often a
+	// failed block or chunk is resent.
+	MaxMbps       float64 `json:"max_mbps"`
+	ParallelParts int     `json:"parallel_parts"`
+	Retries       int     `json:"retries"`
+}
+
+// location returns the URI of the object name at the destination.
+func (u UploadConfig) location(name string) string {
+	switch u.Provider {
+	case UploadAzure:
+		return "azure://" + u.Account + "/" + u.Container + "/" + name
+	case UploadSFTP:
+		return "sftp://" + u.User + "@" + u.Host + "/" + strings.TrimPrefix(name, "/")
+	}
+	return "gs://" + u.Bucket + "/" + name
+}
+
+var uploadClient = &http.Client{Timeout: 30 * time.Minute}
+
+// uploadFile copies the local file at src to the destination, named prefix + base name.
+// Uploads that can be resumed keep their state in stateDir.
+func uploadFile(ctx context.Context, dest UploadConfig, src, stateDir string) (string, error) {
+	name := dest.Prefix + filepath.Base(src)
+	limit := newRateLimiter(dest.MaxMbps)
+	var err error
+	switch dest.Provider {
+	case UploadAzure:
+		err = uploadAzure(ctx, dest, name, src, limit)
+	case UploadGCS:
+		err = uploadGCS(ctx, dest, name, src, stateDir, limit)
+	case UploadSFTP:
+		err = uploadSFTP(ctx, dest, name, src, stateDir, limit)
+	default:
+		err = fmt.Errorf("unknown upload provider %q", dest.Provider)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s to %s: %w", src, dest.location(name), err)
+	}
+	return dest.location(name), nil
+}
+
+// uploadOutputs copies files, an output followed by its trigger file when one was
+// written, to every configured destination and returns where the output went. The
+// trigger follows its file so pollers see it last.
+func uploadOutputs(ctx context.Context, cfg *ExtractionConfig, files ...string) (uris []string, err error) {
+	for _, dest := range cfg.Uploads {
+		for i, f := range files {
+			start := time.Now()
+			uri, err := uploadFile(ctx, dest, f, cfg.uploadStateDir())
+			if err != nil {
+				return uris, err
+			}
+			log.Info("☁️ Uploaded", "file", f, "to", uri, "duration", time.Since(start).Round(time.Millisecond))
+			if i == 0 {
+				uris = append(uris, uri)
+			}
+		}
+	}
+	return uris, nil
+}
+
+// --- Azure Blob Storage ---
+
+// azureAuth returns the query string or Authorization header for a storage account,
+// from AZURE_STORAGE_SAS_TOKEN, a service principal (AZURE_TENANT_ID, AZURE_CLIENT_ID,
+// AZURE_CLIENT_SECRET) or the managed identity, in that order.
+func azureAuth(ctx context.Context) (sas, bearer string, err error) {
+	if sas := os.Getenv("AZURE_STORAGE_SAS_TOKEN"); sas != "" {
+		return strings.TrimPrefix(sas, "?"), "", nil
+	}
+	const scope = "https://storage.azure.com/.default"
+	if tenant, id, secret := os.Getenv("AZURE_TENANT_ID"), os.Getenv("AZURE_CLIENT_ID"), os.Getenv("AZURE_CLIENT_SECRET"); tenant != "" && id != "" && secret != "" {
+		form := url.Values{"grant_type": {"client_credentials"}, "client_id": {id}, "client_secret": {secret}, "scope": {scope}}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://login.microsoftonline.com/"+url.PathEscape(tenant)+"/oauth2/v2.0/token", strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		token, err := fetchToken(req)
+		return "", token, err
+	}
+	q := url.Values{"api-version": {"2018-02-01"}, "resource": {"https://storage.azure.com/"}}
+	if id := os.Getenv("AZURE_CLIENT_ID"); id != "" {
+		q.Set("client_id", id)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://169.254.169.254/metadata/identity/oauth2/token?"+q.Encode(), nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Metadata", "true")
+	token, err := fetchToken(req)
+	if err != nil {
+		return "", "", fmt.Errorf("no Azure credentials (SAS token, service principal or managed identity): %w", err)
+	}
+	return "", token, nil
+}
+
+// uploadAzure uploads src as a block blob, block by block so files of any size fit and
+// up to ParallelParts blocks are in flight. Block IDs are derived from the file's size and
+// modification time, so a retry after an interruption skips the blocks Azure already
+// holds uncommitted.
+func uploadAzure(ctx context.Context, dest UploadConfig, name, src string, limit *rateLimiter) error {
+	sas, bearer, err := azureAuth(ctx)
+	if err != nil {
+		return err
+	}
+	base := dest.Endpoint
+	if base == "" {
+		base = "https://" + dest.Account + ".blob.core.windows.net"
+	}
+	blobURL := strings.TrimRight(base, "/") + "/" + url.PathEscape(dest.Container) + "/" + escapeObjectPath(name)
+	newRequest := func(method, query string, body io.Reader, size int64) (*http.Request, error) {
+		u := blobURL + "?" + query
+		if sas != "" {
+			u += "&" + sas
+		}
+		req, err := http.NewRequestWithContext(ctx, method, u, body)
+		if err != nil {
+			return nil, err
+		}
+		req.ContentLength = size
+		req.Header.Set("x-ms-version", "2021-08-06")
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		return req, nil
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	tag := sha256.Sum256(fmt.Appendf(nil, "%s|%d|%d", name, info.Size(), info.ModTime().UnixNano()))
+	blocks := int(max(1, (info.Size()+azureBlockSize-1)/azureBlockSize))
+	ids := make([]string, blocks)
+	for n := range ids {
+		ids[n] = base64.StdEncoding.EncodeToString(fmt.Appendf(nil, "%x-%08d", tag[:8], n))
+	}
+
+	// Blocks of an interrupted upload stay uncommitted on the blob for a week.
+	held := make(map[string]int64)
+	if req, err := newRequest(http.MethodGet, "comp=blocklist&blocklisttype=uncommitted", nil, 0); err == nil {
+		if resp, err := uploadClient.Do(req); err == nil {
+			var list struct {
+				Blocks []struct {
+					Name string `xml:"Name"`
+					Size int64  `xml:"Size"`
+				} `xml:"UncommittedBlocks>Block"`
+			}
+			if resp.StatusCode == http.StatusOK {
+				xml.NewDecoder(resp.Body).Decode(&list)
+			}
+			resp.Body.Close()
+			for _, b := range list.Blocks {
+				held[b.Name] = b.Size
+			}
+		}
+	}
+
+	// The first block that fails cancels the others, in flight or not yet started.
+	blockCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range max(1, dest.ParallelParts) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range work {
+				off := int64(n) * azureBlockSize
+				size := min(azureBlockSize, info.Size()-off)
+				if held[ids[n]] == size && size > 0 {
+					continue
+				}
+				err := withRetries(blockCtx, dest.Retries, func() error {
+					req, err := newRequest(http.MethodPut, "comp=block&blockid="+url.QueryEscape(ids[n]), limit.reader(blockCtx, io.NewSectionReader(f, off, size)), size)
+					if err != nil {
+						return err
+					}
+					return checkResponse(uploadClient.Do(req.WithContext(blockCtx)))
+				})
+				if err != nil {
+					cancel(err)
+					return
+				}
+			}
+		}()
+	}
+feed:
+	for n := range ids {
+		select {
+		case work <- n:
+		case <-blockCtx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+	if err := context.Cause(blockCtx); err != nil {
+		return err
+	}
+
+	var list strings.Builder
+	list.WriteString(`<?xml version="1.0" encoding="utf-8"?><BlockList>`)
+	for _, id := range ids {
+		list.WriteString("<Latest>" + id + "</Latest>")
+	}
+	list.WriteString("</BlockList>")
+	return withRetries(ctx, dest.Retries, func() error {
+		req, err := newRequest(http.MethodPut, "comp=blocklist", strings.NewReader(list.String()), int64(list.Len()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/xml")
+		return checkResponse(uploadClient.Do(req))
+	})
+}
+
+// --- Google Cloud Storage ---
+
+// gcsToken returns an OAuth access token from GOOGLE_OAUTH_ACCESS_TOKEN, the
+// GOOGLE_APPLICATION_CREDENTIALS file, gcloud's application default credentials or the
+// metadata server, in that order.
+func gcsToken(ctx context.Context) (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	credFile := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if credFile == "" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			if f := filepath.Join(dir, "gcloud", "application_default_credentials.json"); fileExists(f) {
+				credFile = f
+			}
+		}
+	}
+	if credFile != "" {
+		return gcsFileToken(ctx, credFile)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	token, err := fetchToken(req)
+	if err != nil {
+		return "", fmt.Errorf("no Google credentials (access token, credentials file or metadata server): %w", err)
+	}
+	return token, nil
+}
+
+// gcsFileToken exchanges a service account key or gcloud user credentials for a token.
+func gcsFileToken(ctx context.Context, credFile string) (string, error) {
+	data, err := os.ReadFile(credFile)
+	if err != nil {
+		return "", err
+	}
+	var cred struct {
+		Type         string `json:"type"`
+		ClientEmail  string `json:"client_email"`
+		PrivateKey   string `json:"private_key"`
+		TokenURI     string `json:"token_uri"`
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(data, &cred); err != nil {
+		return "", fmt.Errorf("invalid credentials file %s: %w", credFile, err)
+	}
+	tokenURI := cred.TokenURI
+	if tokenURI == "" {
+		tokenURI = "https://oauth2.googleapis.com/token"
+	}
+	var form url.Values
+	switch cred.Type {
+	case "service_account":
+		assertion, err := signJWT(cred.PrivateKey, map[string]any{
+			"iss": cred.ClientEmail, "scope": "https://www.googleapis.com/auth/devstorage.read_write",
+			"aud": tokenURI, "iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			return "", fmt.Errorf("credentials file %s: %w", credFile, err)
+		}
+		form = url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}}
+	case "authorized_user":
+		form = url.Values{"grant_type": {"refresh_token"}, "client_id": {cred.ClientID}, "client_secret": {cred.ClientSecret}, "refresh_token": {cred.RefreshToken}}
+	default:
+		return "", fmt.Errorf("credentials file %s: unsupported type %q", credFile, cred.Type)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return fetchToken(req)
+}
+
+// signJWT signs claims with an RS256 PEM private key.
+func signJWT(privateKey string, claims map[string]any) (string, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return "", errors.New("private key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return "", fmt.Errorf("invalid private key: %w", err)
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("private key is not an RSA key")
+	}
+	body, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + enc.EncodeToString(body)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// uploadGCS uploads src through a resumable upload session in chunks. The session is
+// kept in stateDir, the run's uploadStateDir, until the upload completes, so a retry,
+// in this run or the next, continues from the last chunk Google acknowledged.
+func uploadGCS(ctx context.Context, dest UploadConfig, name, src, stateDir string, limit *rateLimiter) error {
+	token, err := gcsToken(ctx)
+	if err != nil {
+		return err
+	}
+	base := dest.Endpoint
+	if base == "" {
+		base = "https://storage.googleapis.com"
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+
+	statePath, err := gcsStatePath(stateDir, src, dest.Bucket+"/"+name)
+	if err != nil {
+		return err
+	}
+	put := func(session, contentRange string, body io.Reader, n int64) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, session, body)
+		if err != nil {
+			return nil, err
+		}
+		req.ContentLength = n
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Range", contentRange)
+		return uploadClient.Do(req)
+	}
+	// persisted asks the session how many bytes it holds; -1 means it is finished.
+	persisted := func(session string) (int64, error) {
+		resp, err := put(session, fmt.Sprintf("bytes */%d", size), nil, 0)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated:
+			return -1, nil
+		case resp.StatusCode == http.StatusPermanentRedirect:
+			return sessionOffset(resp.Header.Get("Range"))
+		default:
+			return 0, fmt.Errorf("upload session: %s", resp.Status)
+		}
+	}
+
+	var session string
+	var offset int64
+	if data, err := os.ReadFile(statePath); err == nil {
+		session = strings.TrimSpace(string(data))
+		if offset, err = persisted(session); err != nil {
+			log.Warn("Upload session expired, starting over", "file", src, "error", err)
+			session, offset = "", 0
+		} else if offset < 0 {
+			return os.Remove(statePath)
+		} else {
+			log.Info("⏯️ Resuming upload", "file", src, "bytes_done", offset, "bytes", size)
+		}
+	}
+	if session == "" {
+		if err := os.MkdirAll(stateDir, 0755); err != nil {
+			log.Warn("Failed to create upload state directory, the upload cannot be resumed", "dir", stateDir, "error", err)
+		}
+		start := strings.TrimRight(base, "/") + "/upload/storage/v1/b/" + url.PathEscape(dest.Bucket) + "/o?uploadType=resumable&name=" + url.QueryEscape(name)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, start, bytes.NewReader([]byte("{}")))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := uploadClient.Do(req)
+		if err := checkResponse(resp, err); err != nil {
+			return err
+		}
+		if session = resp.Header.Get("Location"); session == "" {
+			return errors.New("no upload session returned")
+		}
+		if err := os.WriteFile(statePath, []byte(session+"\n"), 0600); err != nil {
+			log.Warn("Failed to record upload session, the upload cannot be resumed", "file", src, "error", err)
+		}
+	}
+
+	for failures := 0; offset >= 0; {
+		n := min(gcsChunkSize, size-offset)
+		contentRange := fmt.Sprintf("bytes %d-%d/%d", offset, offset+n-1, size)
+		if n == 0 {
+			contentRange = fmt.Sprintf("bytes */%d", size)
+		}
+		resp, err := put(session, contentRange, limit.reader(ctx, io.NewSectionReader(f, offset, n)), n)
+		switch {
+		case err == nil && resp.StatusCode == http.StatusPermanentRedirect:
+			// The session reports how much it kept, which may be less than was sent.
+			resp.Body.Close()
+			if offset, err = sessionOffset(resp.Header.Get("Range")); err != nil {
+				return err
+			}
+			failures = 0
+			continue
+		case err == nil:
+			if err = checkResponse(resp, nil); err == nil {
+				offset = -1
+				continue
+			}
+		}
+		if failures++; failures > dest.Retries || ctx.Err() != nil {
+			return err
+		}
+		log.Warn("Upload chunk failed, resuming", "file", src, "attempt", failures, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(failures) * 2 * time.Second):
+		}
+		if offset, err = persisted(session); err != nil {
+			return err
+		}
+	}
+	return os.Remove(statePath)
+}
+
+// gcsStatePath returns the file that records the resumable session of the upload of src
+// to object, keyed by both so outputs of the same name in different directories, or
+// uploads to different buckets, never share a session.
+func gcsStatePath(stateDir, src, object string) (string, error) {
+	abs, err := filepath.Abs(src)
+	if err != nil {
+		return "", err
+	}
+	tag := sha256.Sum256([]byte(abs + "|" + object))
+	return filepath.Join(stateDir, fmt.Sprintf("%s.gcs-%x", filepath.Base(src), tag[:8])), nil
+}
+
+// sessionOffset returns how many bytes a resumable session holds from the Range header,
+// "bytes=0-N", of its 308 response; a session without one holds none.
+func sessionOffset(r string) (int64, error) {
+	if r == "" {
+		return 0, nil
+	}
+	var last int64
+	if n, err := fmt.Sscanf(r, "bytes=0-%d", &last); err != nil || n != 1 || last < 0 {
+		return 0, fmt.Errorf("upload session returned an unexpected Range %q", r)
+	}
+	return last + 1, nil
+}
+
+// --- helpers ---
+
+// rateLimiter paces the bytes read through it to a bandwidth shared by all of a
+// transfer's parts. A nil limiter does not limit.
+type rateLimiter struct {
+	mu          sync.Mutex
+	bytesPerSec float64
+	next        time.Time
+}
+
+func newRateLimiter(mbps float64) *rateLimiter {
+	if mbps <= 0 {
+		return nil
+	}
+	return &rateLimiter{bytesPerSec: mbps * 1e6 / 8}
+}
+
+// wait blocks until n more bytes fit the bandwidth.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(float64(n) / l.bytesPerSec * float64(time.Second)))
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+// reader wraps r so reading from it respects the bandwidth.
+func (l *rateLimiter) reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &throttledReader{ctx: ctx, r: r, limit: l}
+}
+
+type throttledReader struct {
+	ctx   context.Context
+	r     io.Reader
+	limit *rateLimiter
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > 32<<10 {
+		p = p[:32<<10]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if werr := t.limit.wait(t.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// withRetries runs fn, running it again up to retries times with a growing delay.
+func withRetries(ctx context.Context, retries int, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= retries || ctx.Err() != nil {
+			return err
+		}
+		log.Warn("Upload part failed, retrying", "attempt", attempt+1, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * 2 * time.Second):
+		}
+	}
+}
+
+// fetchToken runs an OAuth token request and returns the access token.
+func fetchToken(req *http.Request) (string, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var body struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode/100 != 2 || body.AccessToken == "" {
+		return "", fmt.Errorf("token request to %s failed: %s %s %s", req.URL.Host, resp.Status, body.Error, body.Description)
+	}
+	return body.AccessToken, nil
+}
+
+// checkResponse closes resp and turns a non-2xx status into an error with the body.
+func checkResponse(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
+
+// escapeObjectPath escapes each segment of a slash-separated object name.
+func escapeObjectPath(name string) string {
+	parts := strings.Split(name, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUploadAzureBlocks(t *testing.T) {
+	t.Setenv("AZURE_STORAGE_SAS_TOKEN", "?sig=abc")
+	var blocks, committed string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path != "/landing/finacle/GAM.txt" || r.URL.Query().Get("sig") != "abc" {
+			t.Errorf("request to %s", r.URL)
+		}
+		switch r.URL.Query().Get("comp") {
+		case "block":
+			blocks += string(body)
+		case "blocklist":
+			committed = string(body)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	src := filepath.Join(t.TempDir(), "GAM.txt")
+	os.WriteFile(src, []byte("a\nb\n"), 0644)
+	dest := UploadConfig{Provider: UploadAzure, Container: "landing", Prefix: "finacle/", Endpoint: srv.URL}
+	if _, err := uploadFile(context.Background(), dest, src, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if blocks != "a\nb\n" || !strings.Contains(committed, "<Latest>") {
+		t.Errorf("blocks %q, block list %q", blocks, committed)
+	}
+}
+
+func TestUploadAzureStopsOnFirstFailedBlock(t *testing.T) {
+	t.Setenv("AZURE_STORAGE_SAS_TOKEN", "?sig=abc")
+	var puts atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if puts.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		<-release // the other block hangs until its upload is cancelled
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	// A sparse file of four blocks, uploaded two at a time.
+	src := filepath.Join(t.TempDir(), "GAM.txt")
+	f, _ := os.Create(src)
+	f.Truncate(4 * azureBlockSize)
+	f.Close()
+	dest := UploadConfig{Provider: UploadAzure, Container: "landing", Endpoint: srv.URL, ParallelParts: 2}
+	if _, err := uploadFile(context.Background(), dest, src, t.TempDir()); err == nil {
+		t.Fatal("upload with a failed block succeeded")
+	}
+	// Returning at all shows the hanging block was cancelled.
+	if n := puts.Load(); n > 2 {
+		t.Errorf("%d blocks started, want none after the failed one and the one beside it", n)
+	}
+}
+
+func TestUploadGCSResumable(t *testing.T) {
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "tok")
+	var uploaded string
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			t.Errorf("authorization %q", r.Header.Get("Authorization"))
+		}
+		switch r.Method {
+		case http.MethodPost:
+			if r.URL.Query().Get("name") != "finacle/GAM.txt" {
+				t.Errorf("object name %q", r.URL.Query().Get("name"))
+			}
+			w.Header().Set("Location", srv.URL+"/session/1")
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			uploaded = string(body)
+		}
+	}))
+	defer srv.Close()
+
+	src := filepath.Join(t.TempDir(), "GAM.txt")
+	os.WriteFile(src, []byte("a\nb\n"), 0644)
+	uri, err := uploadFile(context.Background(), UploadConfig{Provider: UploadGCS, Bucket: "landing", Prefix: "finacle/", Endpoint: srv.URL}, src, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uploaded != "a\nb\n" || uri != "gs://landing/finacle/GAM.txt" {
+		t.Errorf("uploaded %q to %s", uploaded, uri)
+	}
+}
+
+func TestUploadAzureResumesHeldBlocks(t *testing.T) {
+	t.Setenv("AZURE_STORAGE_SAS_TOKEN", "sig=abc")
+	src := filepath.Join(t.TempDir(), "GAM.txt")
+	os.WriteFile(src, []byte("a\nb\n"), 0644)
+
+	var puts int
+	var held string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case r.Method == http.MethodGet:
+			if held == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			io.WriteString(w, `<?xml version="1.0"?><BlockList><UncommittedBlocks><Block><Name>`+held+`</Name><Size>4</Size></Block></UncommittedBlocks></BlockList>`)
+		case q.Get("comp") == "block":
+			puts++
+			held = q.Get("blockid")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	dest := UploadConfig{Provider: UploadAzure, Container: "landing", Endpoint: srv.URL}
+	for range 2 {
+		if _, err := uploadFile(context.Background(), dest, src, t.TempDir()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if puts != 1 {
+		t.Errorf("uploaded the block %d times, want once with the retry reusing it", puts)
+	}
+}
+
+func TestUploadGCSResumesSession(t *testing.T) {
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "tok")
+	dir := t.TempDir()
+	src := filepath.Join(dir, "GAM.txt")
+	os.WriteFile(src, []byte("a\nb\n"), 0644)
+
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			t.Error("started a new session instead of resuming")
+		}
+		cr := r.Header.Get("Content-Range")
+		ranges = append(ranges, cr)
+		if cr == "bytes */4" {
+			w.Header().Set("Range", "bytes=0-1")
+			w.WriteHeader(http.StatusPermanentRedirect)
+		}
+	}))
+	defer srv.Close()
+
+	// An earlier, interrupted upload left its session behind with two bytes stored.
+	dest := UploadConfig{Provider: UploadGCS, Bucket: "landing", Endpoint: srv.URL}
+	stateDir := filepath.Join(t.TempDir(), "uploads")
+	state, err := gcsStatePath(stateDir, src, "landing/GAM.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(stateDir, 0755)
+	os.WriteFile(state, []byte(srv.URL+"/session/1\n"), 0600)
+
+	if _, err := uploadFile(context.Background(), dest, src, stateDir); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(ranges, ";") != "bytes */4;bytes 2-3/4" {
+		t.Errorf("content ranges = %v", ranges)
+	}
+	if _, err := os.Stat(state); !os.IsNotExist(err) {
+		t.Error("upload session file left behind")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("upload wrote into the output directory: %v", entries)
+	}
+}
+
+func TestSessionOffset(t *testing.T) {
+	for r, want := range map[string]int64{"": 0, "bytes=0-1": 2, "bytes=0-1023": 1024} {
+		if got, err := sessionOffset(r); err != nil || got != want {
+			t.Errorf("sessionOffset(%q) = %d, %v, want %d", r, got, err, want)
+		}
+	}
+	for _, r := range []string{"bytes=5-9", "bytes=0-", "items=0-4"} {
+		if _, err := sessionOffset(r); err == nil {
+			t.Errorf("sessionOffset(%q) accepted a range that does not start at 0", r)
+		}
+	}
+}
+
+func TestRateLimiterPaces(t *testing.T) {
+	l := newRateLimiter(8) // 1 MB/s
+	start := time.Now()
+	for range 3 {
+		l.wait(context.Background(), 100_000)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("300 kB at 1 MB/s took %s", elapsed)
+	}
+}
+
+func TestUploadGCSRetryStopsOnCancel(t *testing.T) {
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "tok")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.Header().Set("Location", srv.URL+"/session/1")
+			return
+		}
+		// Fail the chunk and cancel the run while the upload waits to resume it.
+		time.AfterFunc(50*time.Millisecond, cancel)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	src := filepath.Join(t.TempDir(), "GAM.txt")
+	os.WriteFile(src, []byte("a\nb\n"), 0644)
+	start := time.Now()
+	_, err := uploadFile(ctx, UploadConfig{Provider: UploadGCS, Bucket: "landing", Endpoint: srv.URL, Retries: 5}, src, t.TempDir())
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("upload error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancelled upload took %v, waiting out its retry delay", elapsed)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"compress/bzip2"
+	"io"
+
+	dsbzip2 "github.com/dsnet/compress/bzip2"
+)
+
+func init() {
+	// The standard library only decompresses bzip2.
+	registerCodec(&codec{
+		Name:      "bzip2",
+		Extension: ".bz2",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) { return dsbzip2.NewWriter(w, nil) },
+		NewReader: func(r io.Reader) (io.ReadCloser, error) { return io.NopCloser(bzip2.NewReader(r)), nil },
+		// The reader continues into concatenated streams.
+		Appendable: true,
+	})
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"io"
+
+	"github.com/pierrec/lz4/v4"
+)
+
+func init() {
+	registerCodec(&codec{
+		Name:      "lz4",
+		Extension: ".lz4",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) { return lz4.NewWriter(w), nil },
+		NewReader: func(r io.Reader) (io.ReadCloser, error) { return io.NopCloser(lz4.NewReader(r)), nil },
+	})
+}
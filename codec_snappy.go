@@ -0,0 +1,19 @@
+package main
+
+import (
+	"io"
+
+	"github.com/golang/snappy"
+)
+
+func init() {
+	// The framed format, which unlike raw snappy blocks can be streamed.
+	registerCodec(&codec{
+		Name:      "snappy",
+		Extension: ".sz",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) { return snappy.NewBufferedWriter(w), nil },
+		NewReader: func(r io.Reader) (io.ReadCloser, error) { return io.NopCloser(snappy.NewReader(r)), nil },
+		// A repeated stream identifier is allowed anywhere in the stream.
+		Appendable: true,
+	})
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func init() {
+	registerCodec(&codec{
+		Name:      "zstd",
+		Extension: ".zst",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) },
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			d, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return d.IOReadCloser(), nil
+		},
+		// The decoder reads concatenated frames as one stream.
+		Appendable: true,
+	})
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// codec compresses final output files as they are merged. Codecs register themselves in
+// the registry by name; a codec needing a third-party library (zstd, lz4, snappy, bzip2)
+// is added the same way from a file of its own.
+type codec struct {
+	Name      string
+	Extension string // appended to the output file name, e.g. ".gz"
+	NewWriter func(w io.Writer) (io.WriteCloser, error)
+	NewReader func(r io.Reader) (io.ReadCloser, error)
+	// Appendable codecs read concatenated streams back as one, so a compressed output
+	// can be appended to by later merges.
+	Appendable bool
+}
+
+var codecs = make(map[string]*codec)
+
+func registerCodec(c *codec) {
+	codecs[c.Name] = c
+}
+
+func init() {
+	registerCodec(&codec{
+		Name:       "gzip",
+		Extension:  ".gz",
+		NewWriter:  func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil },
+		NewReader:  func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+		Appendable: true,
+	})
+	registerCodec(&codec{
+		Name:      "zlib",
+		Extension: ".zz",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) { return zlib.NewWriter(w), nil },
+		NewReader: func(r io.Reader) (io.ReadCloser, error) { return zlib.NewReader(r) },
+	})
+	registerCodec(&codec{
+		Name:      "deflate",
+		Extension: ".deflate",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) { return flate.NewWriter(w, flate.DefaultCompression) },
+		NewReader: func(r io.Reader) (io.ReadCloser, error) { return flate.NewReader(r), nil },
+	})
+}
+
+// codecNames lists the registered codecs, for messages.
+func codecNames() string {
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func validCodec(name string) bool {
+	return name == "" || name == "none" || codecs[name] != nil
+}
+
+// codecFor returns the codec the named output (a procedure or view) is compressed with,
+// or nil: its own setting, its procedure's, then the run default.
+func (c *ExtractionConfig) codecFor(name string) *codec {
+	choice, ok := c.ProcedureCompression[name]
+	if !ok {
+		choice, ok = c.ProcedureCompression[c.sourceProcedure(name)]
+	}
+	if !ok {
+		choice = c.Compression
+	}
+	return codecs[choice]
+}
+
+// outputExtension returns the extension the named output's codec adds to its file name.
+func (c *ExtractionConfig) outputExtension(name string) string {
+	if cd := c.codecFor(name); cd != nil {
+		return cd.Extension
+	}
+	return ""
+}
+
+// codecForPath returns the codec of a file by its extension, or nil.
+func codecForPath(path string) *codec {
+	for _, cd := range codecs {
+		if strings.HasSuffix(path, cd.Extension) {
+			return cd
+		}
+	}
+	return nil
+}
+
+// decompressingReader reads a file decompressed by its codec, if any.
+type decompressingReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (d *decompressingReader) Close() error {
+	var first error
+	for _, c := range d.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// openOutput opens a final output file for reading its records, decompressing it when
+// its extension names a codec.
+func openOutput(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return decompress(path, f)
+}
+
+// openRecords opens a final output file of the output c is resolved for, decompressed
+// and with its records ending in '\n'.
+func (c *ExtractionConfig) openRecords(path string) (io.ReadCloser, error) {
+	f, err := openOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{c.recordReader(f), f}, nil
+}
+
+// decompress wraps r, the content of path, in the decompressor of path's codec.
+func decompress(path string, r io.ReadCloser) (io.ReadCloser, error) {
+	cd := codecForPath(path)
+	if cd == nil {
+		return r, nil
+	}
+	dr, err := cd.NewReader(r)
+	if err != nil {
+		r.Close()
+		return nil, fmt.Errorf("failed to open %s as %s: %w", path, cd.Name, err)
+	}
+	return &decompressingReader{Reader: dr, closers: []io.Closer{dr, r}}, nil
+}
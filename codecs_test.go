@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompressedMergeAppends(t *testing.T) {
+	dir := t.TempDir()
+	cfg := ExtractionConfig{
+		SpoolOutputPath: dir,
+		Format:          "delimited",
+		Delimiter:       "|",
+		RunID:           "R1",
+		Compression:     "gzip",
+		RollingFeeds:    []string{"GAM"},
+	}
+	if err := cfg.compileFileNames(); err != nil {
+		t.Fatal(err)
+	}
+	final, _ := cfg.outputFilePath("GAM")
+	if filepath.Base(final) != "GAM.txt.gz" {
+		t.Fatalf("output file = %s, want GAM.txt.gz", final)
+	}
+
+	for _, data := range []string{"A1|0001\nA2|0001", "A3|0002\n"} {
+		spool := filepath.Join(dir, "spool.spool")
+		os.WriteFile(spool, []byte(data), 0644)
+		if _, err := mergeSpools(&cfg, "GAM", []string{spool}, final); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := openOutput(final)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if want := "A1|0001\nA2|0001\nA3|0002\n"; err != nil || string(got) != want {
+		t.Errorf("decompressed output = %q (%v), want %q", got, err, want)
+	}
+	out, err := describeOutput(&ExtractionConfig{}, final)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(final); out.Records != 3 || out.Bytes != info.Size() {
+		t.Errorf("records, bytes = %d, %d, want 3, %d", out.Records, out.Bytes, info.Size())
+	}
+
+	cfg.ProcedureCompression = map[string]string{"GAM": "zlib"}
+	if _, err := mergeSpools(&cfg, "GAM", nil, final); err == nil {
+		t.Error("append to zlib output accepted")
+	}
+}
+
+func TestEmitEmptyCompressedOutput(t *testing.T) {
+	cfg := &ExtractionConfig{Compression: "gzip"}
+	path := filepath.Join(t.TempDir(), "GAM.txt.gz")
+	if err := emitEmptyOutput(cfg, "GAM", path); err != nil {
+		t.Fatal(err)
+	}
+	out, err := describeOutput(&ExtractionConfig{}, path)
+	if err != nil || out.Records != 0 || out.Bytes == 0 {
+		t.Errorf("describeOutput = %+v, %v, want a valid empty gzip stream", out, err)
+	}
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	for name, cd := range codecs {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			streams := []string{"A1|0001\n"}
+			if cd.Appendable {
+				streams = append(streams, "A2|0002\n")
+			}
+			for _, s := range streams {
+				w, err := cd.NewWriter(&buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				io.WriteString(w, s)
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			r, err := cd.NewReader(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(r)
+			r.Close()
+			if want := strings.Join(streams, ""); err != nil || string(got) != want {
+				t.Errorf("decompressed = %q (%v), want %q", got, err, want)
+			}
+			if codecForPath("GAM.txt"+cd.Extension) != cd {
+				t.Errorf("extension %s not resolved to %s", cd.Extension, name)
+			}
+		})
+	}
+}
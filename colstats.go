@@ -0,0 +1,205 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/charmbracelet/log"
+)
+
+// ColumnProfile holds the statistics of one column gathered while rows are written, to
+// catch layout-breaking values before the target system rejects the file.
+type ColumnProfile struct {
+	Name      string
+	Type      string
+	Length    int // template length, 0 when unset
+	Values    int64
+	Nulls     int64
+	MaxLength int
+	Overlong  int64 // values longer than the template length
+	Min, Max  string
+
+	kind           columnKind
+	minNum, maxNum float64
+	hasRange       bool
+}
+
+type columnKind int
+
+const (
+	kindText columnKind = iota
+	kindNumber
+	kindDate
+)
+
+// newColumnProfiles prepares a profile per template column, typed from the result set.
+func newColumnProfiles(cols []ColumnConfig, types []*sql.ColumnType) []ColumnProfile {
+	profiles := make([]ColumnProfile, len(cols))
+	for i, col := range cols {
+		p := ColumnProfile{Name: col.Name, Length: col.Length}
+		if i < len(types) {
+			p.Type = types[i].DatabaseTypeName()
+		}
+		switch t := strings.ToUpper(p.Type); {
+		case t == "NUMBER" || t == "FLOAT" || strings.HasPrefix(t, "BINARY_"):
+			p.kind = kindNumber
+		case t == "DATE" || strings.HasPrefix(t, "TIMESTAMP"):
+			p.kind = kindDate
+		}
+		profiles[i] = p
+	}
+	return profiles
+}
+
+// observe accounts for one value of the column.
+func (p *ColumnProfile) observe(v sql.NullString) {
+	p.Values++
+	if !v.Valid {
+		p.Nulls++
+		return
+	}
+	if n := len(v.String); n > p.MaxLength {
+		p.MaxLength = n
+	}
+	if p.Length > 0 && len(v.String) > p.Length {
+		p.Overlong++
+	}
+	switch p.kind {
+	case kindNumber:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v.String), 64)
+		if err != nil {
+			return
+		}
+		if !p.hasRange || f < p.minNum {
+			p.minNum, p.Min = f, v.String
+		}
+		if !p.hasRange || f > p.maxNum {
+			p.maxNum, p.Max = f, v.String
+		}
+		p.hasRange = true
+	case kindDate:
+		// Dates and timestamps are returned in a fixed-width, most significant first
+		// layout, so they order as strings.
+		if !p.hasRange || v.String < p.Min {
+			p.Min = v.String
+		}
+		if !p.hasRange || v.String > p.Max {
+			p.Max = v.String
+		}
+		p.hasRange = true
+	}
+}
+
+// merge folds the profile of another job for the same column into p.
+func (p *ColumnProfile) merge(o ColumnProfile) {
+	p.Values += o.Values
+	p.Nulls += o.Nulls
+	p.Overlong += o.Overlong
+	if o.MaxLength > p.MaxLength {
+		p.MaxLength = o.MaxLength
+	}
+	if p.Type == "" {
+		p.Type, p.kind = o.Type, o.kind
+	}
+	if !o.hasRange {
+		return
+	}
+	if !p.hasRange {
+		p.Min, p.Max, p.minNum, p.maxNum, p.hasRange = o.Min, o.Max, o.minNum, o.maxNum, true
+		return
+	}
+	if p.kind == kindNumber {
+		if o.minNum < p.minNum {
+			p.minNum, p.Min = o.minNum, o.Min
+		}
+		if o.maxNum > p.maxNum {
+			p.maxNum, p.Max = o.maxNum, o.Max
+		}
+		return
+	}
+	if o.Min < p.Min {
+		p.Min = o.Min
+	}
+	if o.Max > p.Max {
+		p.Max = o.Max
+	}
+}
+
+// mergeProfiles folds the column profiles of a finished job into a procedure's totals.
+func mergeProfiles(total, job []ColumnProfile) []ColumnProfile {
+	if total == nil {
+		return append([]ColumnProfile(nil), job...)
+	}
+	for i := range job {
+		if i < len(total) {
+			total[i].merge(job[i])
+		}
+	}
+	return total
+}
+
+// writeProfiles writes one data-profile CSV per procedure into dir and warns about
+// columns with values longer than their template length.
+func writeProfiles(dir, packageName string, summary map[string]ProcSummary) {
+	var procs []string
+	for p, s := range summary {
+		if len(s.Profile) > 0 {
+			procs = append(procs, p)
+		}
+	}
+	sort.Strings(procs)
+
+	for _, proc := range procs {
+		path := filepath.Join(dir, fmt.Sprintf("%s_%s_profile.csv", packageName, proc))
+		if err := writeProfile(path, summary[proc].Profile); err != nil {
+			log.Warn("Failed to write data profile", "procedure", proc, "file", path, "error", err)
+			continue
+		}
+		for _, p := range summary[proc].Profile {
+			if p.Overlong > 0 {
+				log.Warn("📏 Values longer than template length", "procedure", proc, "column", p.Name, "length", p.Length, "max_length", p.MaxLength, "count", p.Overlong)
+			}
+		}
+		log.Info("Data profile written", "procedure", proc, "file", path)
+	}
+}
+
+func writeProfile(path string, profiles []ColumnProfile) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"COLUMN", "TYPE", "VALUES", "NULLS", "NULL_PERCENT", "MAX_LENGTH", "TEMPLATE_LENGTH", "OVERLONG", "MIN", "MAX"})
+	for _, p := range profiles {
+		var nullPct float64
+		if p.Values > 0 {
+			nullPct = float64(p.Nulls) * 100 / float64(p.Values)
+		}
+		w.Write([]string{
+			p.Name,
+			p.Type,
+			strconv.FormatInt(p.Values, 10),
+			strconv.FormatInt(p.Nulls, 10),
+			fmt.Sprintf("%.2f", nullPct),
+			strconv.Itoa(p.MaxLength),
+			strconv.Itoa(p.Length),
+			strconv.FormatInt(p.Overlong, 10),
+			p.Min,
+			p.Max,
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
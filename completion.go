@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// completionStore records which procedure/SOL pairs have completed successfully so
+// that --skip-existing runs only dispatch the remaining jobs. Entries are kept as
+// "PROC,SOL_ID" lines in a plain text file next to the run logs.
+//
+// In extract mode a job is only really done once its spool has been merged, so
+// completions are staged in memory and written by Commit after a successful merge.
+type completionStore struct {
+	mu       sync.Mutex
+	file     *os.File
+	done     map[string]bool
+	deferred bool
+	pending  []Job
+
+	// journal, when set, also receives every dispatch and completion as it happens.
+	journal *jobJournal
+}
+
+func completionKey(proc, solID string) string {
+	return proc + "," + solID
+}
+
+// openCompletionStore opens the store at path. Unless keep is set, previous entries are
+// discarded, because a full run redoes every job.
+func openCompletionStore(path string, keep, deferred bool) (*completionStore, error) {
+	c := &completionStore{done: make(map[string]bool), deferred: deferred}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if keep {
+		if f, err := os.Open(path); err == nil {
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				if line := strings.TrimSpace(scanner.Text()); line != "" {
+					c.done[line] = true
+				}
+			}
+			f.Close()
+			if err := scanner.Err(); err != nil {
+				return nil, fmt.Errorf("failed to read completion markers %s: %w", path, err)
+			}
+		}
+	} else {
+		flags |= os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open completion markers %s: %w", path, err)
+	}
+	c.file = f
+	return c, nil
+}
+
+// IsDone reports whether the job completed in an earlier run.
+func (c *completionStore) IsDone(job Job) bool {
+	return c.done[completionKey(job.Proc, job.spoolID())]
+}
+
+// Dispatched journals that a worker picked up job.
+func (c *completionStore) Dispatched(job Job) error {
+	return c.journal.Dispatched(job)
+}
+
+// MarkDone records a successfully completed job, or stages it until Commit in deferred mode.
+// The journal records it straight away either way.
+func (c *completionStore) MarkDone(job Job) error {
+	if err := c.journal.Done(job); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.deferred {
+		c.pending = append(c.pending, job)
+		return nil
+	}
+	return c.write(job)
+}
+
+// Commit writes every staged completion to the store.
+func (c *completionStore) Commit() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, job := range c.pending {
+		if err := c.write(job); err != nil {
+			return err
+		}
+	}
+	c.pending = nil
+	return c.file.Sync()
+}
+
+// CommitProcedure writes the staged completions of one procedure, e.g. once its output
+// has been merged ahead of the rest of the run.
+func (c *completionStore) CommitProcedure(proc string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// A failed write leaves every staged completion in place for a later commit; writing
+	// one twice is harmless, losing one is not.
+	var kept []Job
+	for _, job := range c.pending {
+		if job.Proc != proc {
+			kept = append(kept, job)
+			continue
+		}
+		if err := c.write(job); err != nil {
+			return err
+		}
+	}
+	c.pending = kept
+	return c.file.Sync()
+}
+
+func (c *completionStore) write(job Job) error {
+	if _, err := fmt.Fprintln(c.file, completionKey(job.Proc, job.spoolID())); err != nil {
+		return fmt.Errorf("failed to record completion of %s for SOL %s: %w", job.Proc, job.spoolID(), err)
+	}
+	return nil
+}
+
+func (c *completionStore) Close() error {
+	return c.file.Close()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCommitProcedure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "PKG_completed.txt")
+	gam1, gam2, tst := Job{SolID: "0001", Proc: "GAM"}, Job{SolID: "0002", Proc: "GAM"}, Job{SolID: "0001", Proc: "TEST"}
+
+	c, err := openCompletionStore(path, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range []Job{gam1, tst, gam2} {
+		c.MarkDone(job)
+	}
+	if err := c.CommitProcedure("GAM"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(c.pending, []Job{tst}) {
+		t.Errorf("pending after committing GAM = %v", c.pending)
+	}
+	c.Close()
+
+	c, err = openCompletionStore(path, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.IsDone(gam1) || !c.IsDone(gam2) || c.IsDone(tst) {
+		t.Errorf("done = %v, want only GAM", c.done)
+	}
+
+	// A failed write keeps every staged completion, the ones already written included.
+	c.MarkDone(gam1)
+	c.MarkDone(tst)
+	c.file.Close()
+	if err := c.CommitProcedure("GAM"); err == nil {
+		t.Fatal("commit to a closed store succeeded")
+	}
+	if !slices.Equal(c.pending, []Job{gam1, tst}) {
+		t.Errorf("pending after a failed commit = %v", c.pending)
+	}
+}
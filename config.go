@@ -3,44 +3,299 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	log "github.com/charmbracelet/log"
 )
 
 type MainConfig struct {
-	DBUser      string `json:"db_user"`
-	DBPassword  string `json:"db_password"`
-	DBHost      string `json:"db_host"`
-	DBPort      int    `json:"db_port"`
-	DBSid       string `json:"db_sid"`
+	DBUser     string `json:"db_user"`
+	DBPassword string `json:"db_password"`
+	DBHost     string `json:"db_host"`
+	DBPort     int    `json:"db_port"`
+	DBSid      string `json:"db_sid"`
+	ProxyUser  string `json:"proxy_user"`
+
+	// ConsumerGroup is the Resource Manager consumer group the tool's sessions switch to,
+	// letting DBAs cap its resource usage.
+	ConsumerGroup string `json:"consumer_group"`
+
+	// ConnectString is a TNS alias or full connect descriptor used instead of host/port/SID.
+	ConnectString string `json:"connect_string"`
+	TNSAdmin      string `json:"tns_admin"`
+
+	// FailoverConnectStrings are tried in order when the primary endpoint is unreachable,
+	// and JobRetries is how often a job that lost its connection is run again.
+	FailoverConnectStrings []string `json:"failover_connect_strings"`
+	JobRetries             int      `json:"job_retries"`
+
+	// Environment names the environment (PROD, UAT, ...) for {{environment}} in templates.
+	Environment string `json:"environment"`
+
+	// PasswordKeyFile holds the AES key for "enc:" passwords (see encrypt-password).
+	PasswordKeyFile string `json:"password_key_file"`
+
 	Concurrency int    `json:"concurrency"`
 	LogFilePath string `json:"log_path"`
 	SolFilePath string `json:"sol_list_path"`
+	DirMode     string `json:"dir_mode"`
+
+	ProgressIntervalSeconds  int  `json:"progress_interval_seconds"`
+	HeartbeatIntervalSeconds int  `json:"heartbeat_interval_seconds"`
+	StallThresholdSeconds    int  `json:"stall_threshold_seconds"`
+	CancelStalledJobs        bool `json:"cancel_stalled_jobs"`
+	ExportTimeline           bool `json:"export_timeline"`
+	RecentFailures           int  `json:"recent_failures"`
+
+	// ConnectionWaitWarnSeconds is the average wait for a pooled connection, per progress
+	// interval, above which the progress report warns that jobs are queueing.
+	ConnectionWaitWarnSeconds int `json:"connection_wait_warn_seconds"`
+
+	// StatementCacheSize is the number of statements each session keeps open in its
+	// client-side statement cache (0 keeps the driver default of 40, -1 disables it).
+	// MonitorOpenCursors samples the sessions' open cursors with each progress report,
+	// which needs SELECT on v$session and v$sesstat; OpenCursorsWarnPercent is the share
+	// of the database's open_cursors that a session may hold before it warns.
+	StatementCacheSize     int  `json:"statement_cache_size"`
+	MonitorOpenCursors     bool `json:"monitor_open_cursors"`
+	OpenCursorsWarnPercent int  `json:"open_cursors_warn_percent"`
+
+	// DriverParams are further godror connection parameters (poolSessionTimeout,
+	// standaloneConnection, timezone, enableEvents, libDir, ...) added to the connect
+	// string as they are.
+	DriverParams map[string]string `json:"driver_params"`
+
+	// HealthListen serves /healthz and /readyz on this address for container probes, and
+	// ShutdownGraceSeconds bounds how long a cancelled run waits for in-flight jobs.
+	HealthListen         string `json:"health_listen"`
+	ShutdownGraceSeconds int    `json:"shutdown_grace_seconds"`
+	// ControlToken enables the control API's cancel, pause and resume commands for
+	// callers sending it as a bearer token.
+	ControlToken string `json:"control_token"`
+
+	// Profiles holds named overrides (e.g. dev/uat/prod) of any of the fields above,
+	// applied on top of the base configuration by applyProfile.
+	Profiles map[string]json.RawMessage `json:"profiles"`
+
+	// Connections holds named databases that procedures can be routed to; each overrides
+	// the connection fields above, inheriting the credentials.
+	Connections map[string]json.RawMessage `json:"connections"`
+
+	OTLPEndpoint string            `json:"otlp_endpoint"`
+	OTLPHeaders  map[string]string `json:"otlp_headers"`
+
+	Retention   RetentionConfig   `json:"retention"`
+	LogRotation LogRotationConfig `json:"log_rotation"`
+	JobLog      JobLogConfig      `json:"job_log"`
+	Timestamps  TimestampConfig   `json:"timestamps"`
+	Syslog      SyslogConfig      `json:"syslog"`
+	EventLog    EventLogConfig    `json:"event_log"`
+	Vault       VaultConfig       `json:"vault"`
+	CyberArk    CyberArkConfig    `json:"cyberark"`
+	Memory      MemoryConfig      `json:"memory"`
 }
 
 type ExtractionConfig struct {
 	PackageName           string   `json:"package_name"`
 	Procedures            []string `json:"procedures"`
 	SpoolOutputPath       string   `json:"spool_output_path"`
+	FinalOutputPath       string   `json:"final_output_path"`
+	FinalOutputCopy       bool     `json:"final_output_copy"`
 	RunInsertionParallel  bool     `json:"run_insertion_parallel"`
 	RunExtractionParallel bool     `json:"run_extraction_parallel"`
 	TemplatePath          string   `json:"template_path"`
 	Format                string   `json:"format"`
 	Delimiter             string   `json:"delimiter"`
+	SpoolFileTemplate     string   `json:"spool_file_template"`
+	OutputFileTemplate    string   `json:"output_file_template"`
+	SpoolDirTemplate      string   `json:"spool_dir_template"`
+	OutputDirTemplate     string   `json:"output_dir_template"`
+	QuarantinePath        string   `json:"quarantine_path"`
+	TolerateBadRecords    bool     `json:"tolerate_bad_records"`
+	LengthPolicy          string   `json:"length_policy"`
+	DelimiterPolicy       string   `json:"delimiter_policy"`
+	DelimiterReplacement  string   `json:"delimiter_replacement"`
+	Sanitize              string   `json:"sanitize"` // default sanitisation rules, see parseSanitizeRules
+
+	// ProcedureConnections routes procedures to named connections from the main config.
+	ProcedureConnections map[string]string `json:"procedure_connections"`
+	// EarlyMerge merges each procedure's output as soon as all of its SOLs are done.
+	EarlyMerge bool `json:"early_merge"`
+	// ProcedurePriorities tags procedures critical, normal or low for dispatch order.
+	ProcedurePriorities map[string]string `json:"procedure_priorities"`
+	// EmptyOutputPolicy is warn, fail or emit for procedures that produced no rows.
+	EmptyOutputPolicy string `json:"empty_output_policy"`
+	// ColumnStats writes a per-procedure data profile (nulls, lengths, ranges) of the output.
+	ColumnStats bool `json:"column_stats"`
+	// SchemaSnapshot saves the DDL of every extracted object next to the outputs.
+	SchemaSnapshot bool `json:"schema_snapshot"`
+	// BusinessDateColumn restricts extraction queries to rows of the run's business date,
+	// and BusinessDateParam passes it as a second argument to insertion procedures.
+	BusinessDateColumn string `json:"business_date_column"`
+	BusinessDateParam  bool   `json:"business_date_param"`
+	// Phases orders procedures into groups that each start once the previous group has
+	// finished for all SOLs; unlisted procedures run last.
+	Phases []PhaseConfig `json:"phases"`
+	// LoadTables maps procedures to the target tables the load command inserts their
+	// files into (default the procedure name), on LoadConnection in LoadBatchSize batches.
+	LoadTables     map[string]string `json:"load_tables"`
+	LoadConnection string            `json:"load_connection"`
+	LoadBatchSize  int               `json:"load_batch_size"`
+	// CommitInterval batches insertion calls into transactions committed every N calls
+	// per worker (0 commits each call), and RollbackPolicy decides what a failed call
+	// undoes: just itself (call) or the whole uncommitted batch (batch).
+	CommitInterval int    `json:"commit_interval"`
+	RollbackPolicy string `json:"rollback_policy"`
+	// DebugStatements records the text of every prepared statement and logs the binds
+	// of failed jobs, with the binds named in DebugMaskBinds (e.g. SOL_ID) masked.
+	DebugStatements bool     `json:"debug_statements"`
+	DebugMaskBinds  []string `json:"debug_mask_binds"`
+	// LazyPrepare prepares each procedure's statement when its first job runs instead of
+	// all of them at startup, so procedures that get no jobs hold no cursors.
+	LazyPrepare bool `json:"lazy_prepare"`
+	// StatementStrategy decides how workers share prepared statements: shared (default),
+	// worker (each worker prepares its own) or cached (each job prepares its own and
+	// relies on the driver's statement cache).
+	StatementStrategy string `json:"statement_strategy"`
+	// TraceJobs lists procedure/SOL pairs run with Oracle SQL trace enabled.
+	TraceJobs []TraceJob `json:"trace_jobs"`
+	// VerifyOutput re-reads every final file after the merge, checking each record's shape
+	// and the record count against the extracted rows.
+	VerifyOutput bool `json:"verify_output"`
+	// VolumeAlertPercent warns, or fails the run under VolumeAlertPolicy fail, when a
+	// procedure's row count differs from the previous run by more than this percentage.
+	VolumeAlertPercent float64 `json:"volume_alert_percent"`
+	VolumeAlertPolicy  string  `json:"volume_alert_policy"`
+	// FsyncPolicy controls when spool and merged files are synced to disk: never, on
+	// close, or every FsyncRows rows.
+	FsyncPolicy string `json:"fsync_policy"`
+	FsyncRows   int    `json:"fsync_rows"`
+	// SolChunks splits each SOL of the listed procedures into ORA_HASH buckets extracted
+	// as separate jobs.
+	SolChunks map[string]ChunkConfig `json:"sol_chunks"`
+	// SessionStats samples each job's session statistics into an extended log; jobs then
+	// run on a session of their own.
+	SessionStats bool `json:"session_stats"`
+	// Webhooks receive a JSON POST on run start, procedure completion, merged file
+	// availability and run end.
+	Webhooks []WebhookConfig `json:"webhooks"`
+	// TriggerFile writes a .done marker with the count and checksum of each final file.
+	TriggerFile TriggerFileConfig `json:"trigger_file"`
+	// Uploads copies each final file and its trigger file to Azure Blob Storage or GCS.
+	Uploads []UploadConfig `json:"uploads"`
+	// Archive packages the run's final files, trigger files and summaries into one
+	// archive for hand-off, optionally encrypted.
+	Archive ArchiveConfig `json:"archive"`
+	// PerSolOutput keeps one final file per SOL, named by the output template's
+	// {{.SolID}}, instead of merging every SOL into one file.
+	PerSolOutput bool `json:"per_sol_output"`
+	// OutputGroups merges the SOLs of each group (region, zone) into a file per group.
+	OutputGroups OutputGroupConfig `json:"output_groups"`
+	// Views derives further outputs from a procedure's extraction, each a projection of
+	// its template's columns written to a file named <PROC>_<VIEW>, in the same query pass.
+	Views map[string][]ViewConfig `json:"views"`
+	// RollingFeeds lists procedures whose final file is kept across runs, each run's
+	// extraction being appended to it instead of replacing it.
+	RollingFeeds []string `json:"rolling_feeds"`
+	// RFC4180 lists procedures, or views, whose delimited output is strict RFC 4180 for
+	// standards-strict parsers.
+	RFC4180 []string `json:"rfc4180"`
+	// Compression names the codec final files are compressed with as they are merged, and
+	// ProcedureCompression overrides it per procedure or view ("none" for plain text).
+	Compression          string            `json:"compression"`
+	ProcedureCompression map[string]string `json:"procedure_compression"`
+	// RecordTerminator ends every record: lf (default), crlf, none for fixed-length
+	// records, or hex bytes such as 0x15; ProcedureRecordTerminators overrides it per
+	// procedure or view.
+	RecordTerminator           string            `json:"record_terminator"`
+	ProcedureRecordTerminators map[string]string `json:"procedure_record_terminators"`
+	// BOMOutputs lists procedures, or views, whose final files start with a UTF-8 BOM.
+	BOMOutputs []string `json:"bom_outputs"`
+	// UnchangedOutputPolicy decides what happens to a final file identical to the previous
+	// run's: ship it anyway (default), skip shipping it, or link it to the previous file.
+	UnchangedOutputPolicy string `json:"unchanged_output_policy"`
+	// DiffKeys names the key columns per procedure used by the diff command.
+	DiffKeys map[string][]string `json:"diff_keys"`
+	// Variables are run parameters templates and configured SQL reference as {{name}},
+	// besides the built-in business_date, month_start, month_end, run_id, package and
+	// environment.
+	Variables map[string]string `json:"variables"`
+	// SourceObjects names the view or synonym (schema.view) a procedure extracts from,
+	// when it differs from the procedure and template name.
+	SourceObjects map[string]string `json:"source_objects"`
+
+	// Per-run values, set at startup rather than loaded from JSON.
+	RunID          string    `json:"-"`
+	RunStart       time.Time `json:"-"`
+	BusinessDate   time.Time `json:"-"`
+	spoolTmpl      *template.Template
+	outputTmpl     *template.Template
+	spoolDirTmpl   *template.Template
+	outputDirTmpl  *template.Template
+	triggerTmpl    *template.Template
+	usesSeq        bool              // a filename template uses {{.Seq}}
+	previous       *runRecord        // previous complete run, when unchanged outputs are deduplicated
+	sols           []string          // SOLs of the run, for per-SOL and grouped outputs
+	solGroups      map[string]string // output group of each SOL
+	environment    string            // environment name from the main config
+	recordWidths   map[string]int    // fixed record length of each output, from its template
+	output         string            // the output a copy from forOutput is resolved for
+	dirMode        os.FileMode
+	appendOutput   bool
+	forceMerge     bool
+	journal        *jobJournal // records merge progress, so -resume knows which spools are still needed
+	limitRows      int
+	samplePercent  float64
+	fetchArraySize int
+	statementText  map[string]string
+}
+
+// applyProfile overlays the named profile onto the base configuration. When profiles are
+// defined one must be chosen explicitly, so a run never silently targets the base settings.
+func (c *MainConfig) applyProfile(name string) error {
+	if len(c.Profiles) == 0 {
+		if name != "" {
+			return fmt.Errorf("profile %q requested but the main config defines no profiles", name)
+		}
+		return nil
+	}
+
+	available := make([]string, 0, len(c.Profiles))
+	for p := range c.Profiles {
+		available = append(available, p)
+	}
+	sort.Strings(available)
+	if name == "" {
+		return fmt.Errorf("the main config defines profiles, select one with -profile (available: %s)", strings.Join(available, ", "))
+	}
+	raw, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(available, ", "))
+	}
+	if err := json.Unmarshal(raw, c); err != nil {
+		return fmt.Errorf("invalid profile %q: %w", name, err)
+	}
+	return nil
 }
 
+// loadConfig decodes a JSON configuration file, allowing // and /* */ comments.
 func loadConfig[T any](path string) (T, error) {
 	var cfg T
-	file, err := os.Open(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return cfg, err
 	}
-	defer file.Close()
-
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&cfg)
+	err = json.Unmarshal(stripJSONComments(data), &cfg)
 	return cfg, err
 }
 
+// readSols reads the SOL list, trimming whitespace and a leading UTF-8 BOM, skipping
+// blank and # comment lines and dropping duplicate IDs so no extraction runs twice.
 func readSols(path string) ([]string, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -48,13 +303,43 @@ func readSols(path string) ([]string, error) {
 	}
 	defer f.Close()
 
-	var sols []string
-	scanner := bufio.NewScanner(f)
+	r := bufio.NewReader(f)
+	if err := skipInputBOM(r, path); err != nil {
+		return nil, err
+	}
+	var sols, duplicates []string
+	seen := make(map[string]bool)
+	var comments, trimmed int
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
-		line := scanner.Text()
-		if line != "" {
-			sols = append(sols, line)
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line != raw {
+			trimmed++
+		}
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			comments++
+			continue
 		}
+		if seen[line] {
+			duplicates = append(duplicates, line)
+			continue
+		}
+		seen[line] = true
+		sols = append(sols, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(duplicates) > 0 {
+		log.Warn("Duplicate SOL IDs removed from SOL list", "file", path, "count", len(duplicates), "sol_ids", strings.Join(duplicates, ","))
+	}
+	if comments > 0 || trimmed > 0 {
+		log.Info("Cleaned SOL list", "file", path, "comment_lines", comments, "trimmed_lines", trimmed)
 	}
-	return sols, scanner.Err()
+	return sols, nil
 }
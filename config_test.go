@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadSolsNormalises(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sols.txt")
+	content := "\uFEFF0001\n  0002 \n# branch closed\n0003\n\n0001\n0002\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sols, err := readSols(path)
+	if err != nil {
+		t.Fatalf("readSols: %v", err)
+	}
+	if want := []string{"0001", "0002", "0003"}; !reflect.DeepEqual(sols, want) {
+		t.Errorf("sols = %v, want %v", sols, want)
+	}
+}
+
+func TestApplyProfileOverridesBase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	content := `{
+		"db_host": "base", "db_port": 1521, "concurrency": 4,
+		"profiles": {
+			"uat": {"db_host": "uat-scan", "concurrency": 8},
+			"prod": {"db_host": "prod-scan"}
+		}
+	}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig[MainConfig](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.applyProfile(""); err == nil {
+		t.Fatal("expected error when profiles exist but none is selected")
+	}
+	if err := cfg.applyProfile("dev"); err == nil {
+		t.Fatal("expected error for unknown profile")
+	}
+	if err := cfg.applyProfile("uat"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DBHost != "uat-scan" || cfg.Concurrency != 8 || cfg.DBPort != 1521 {
+		t.Errorf("got host=%s concurrency=%d port=%d, want uat-scan/8/1521", cfg.DBHost, cfg.Concurrency, cfg.DBPort)
+	}
+}
+
+func TestConnectionConfigInheritsCredentials(t *testing.T) {
+	cfg := MainConfig{
+		DBUser: "extract", DBPassword: "pw", DBHost: "primary", DBPort: 1521, DBSid: "ORCL",
+		Connections: map[string]json.RawMessage{
+			"replica": json.RawMessage(`{"connect_string": "REPLICA_TNS"}`),
+		},
+	}
+	cc, err := cfg.connectionConfig("replica")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cc.DBUser != "extract" || cc.DBPassword != "pw" {
+		t.Errorf("credentials not inherited: user=%q password=%q", cc.DBUser, cc.DBPassword)
+	}
+	if got := cc.connectString(); got != "REPLICA_TNS" {
+		t.Errorf("connectString() = %q, want REPLICA_TNS", got)
+	}
+	if cfg.connectString() != "primary:1521/ORCL" {
+		t.Errorf("main connection modified: %q", cfg.connectString())
+	}
+	if _, err := cfg.connectionConfig("missing"); err == nil {
+		t.Error("expected error for unknown connection")
+	}
+}
+
+func TestRunConfigPaths(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "nightly.txt")
+	os.WriteFile(manifest, []byte("# nightly packages\ngam.json\n\n/etc/htd.json\n"), 0644)
+
+	defer func(v string) { *runCfgFile = v }(*runCfgFile)
+	for value, want := range map[string][]string{
+		"a.json":          {"a.json"},
+		"a.json, b.json,": {"a.json", "b.json"},
+		"@" + manifest:    {filepath.Join(dir, "gam.json"), "/etc/htd.json"},
+	} {
+		*runCfgFile = value
+		got, err := runConfigPaths()
+		if err != nil {
+			t.Fatalf("runConfigPaths(%q): %v", value, err)
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("runConfigPaths(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestSplitPhases(t *testing.T) {
+	pending := []Job{{SolID: "1", Proc: "BAL"}, {SolID: "1", Proc: "GAM"}, {SolID: "1", Proc: "HTD"}, {SolID: "2", Proc: "GAM"}, {SolID: "1", Proc: "OTHER"}}
+	phases := splitPhases(pending, []PhaseConfig{
+		{Name: "masters", Procedures: []string{"GAM"}},
+		{Name: "transactions", Procedures: []string{"HTD"}},
+		{Name: "balances", Procedures: []string{"BAL"}},
+	}, []string{"GAM", "HTD", "BAL", "OTHER"})
+
+	var got []string
+	for _, ph := range phases {
+		got = append(got, fmt.Sprintf("%s:%d", ph.name, len(ph.jobs)))
+	}
+	if want := "masters:2 transactions:1 balances:1 default:1"; strings.Join(got, " ") != want {
+		t.Errorf("phases = %s, want %s", strings.Join(got, " "), want)
+	}
+}
+
+func TestDriverParamsPassedThrough(t *testing.T) {
+	cfg := MainConfig{
+		DBUser: "extract", DBPassword: "pw", ConnectString: "PROD",
+		DriverParams: map[string]string{"timezone": "UTC", "poolSessionTimeout": "5m"},
+		Connections: map[string]json.RawMessage{
+			"replica": json.RawMessage(`{"connect_string": "REPLICA", "driver_params": {"standaloneConnection": "1"}}`),
+		},
+	}
+	dsn := cfg.dataSourceName(cfg.connectString())
+	if !strings.HasSuffix(dsn, ` poolSessionTimeout="5m" timezone="UTC"`) {
+		t.Errorf("driver params not appended in order: %s", dsn)
+	}
+	cc, err := cfg.connectionConfig("replica")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cc.DriverParams) != 3 || len(cfg.DriverParams) != 2 {
+		t.Errorf("connection params %v should extend main params %v without changing them", cc.DriverParams, cfg.DriverParams)
+	}
+
+	cfg.DriverParams = map[string]string{"password": "x", "timezone": `U"TC`}
+	err = validateConfigs(&cfg, &ExtractionConfig{}, "E")
+	for _, want := range []string{"password is set from the other settings", "value of timezone"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("validation error %v does not mention %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// configDocs describes configuration fields by their JSON path. It feeds both the
+// commented example files and the JSON Schema written by the init command.
+var configDocs = map[string]string{
+	"db_user":                      "Database user name, or a vault:/cyberark: secret reference.",
+	"db_password":                  "Database password, an enc: value from encrypt-password, or a vault:/cyberark: secret reference.",
+	"proxy_user":                   "Schema user to open sessions as through Oracle proxy authentication, connecting as db_user.",
+	"consumer_group":               "Resource Manager consumer group for the tool's sessions; a dedicated service in db_sid/connect_string works too.",
+	"connect_string":               "TNS alias or full connect descriptor; replaces db_host/db_port/db_sid.",
+	"tns_admin":                    "Directory containing tnsnames.ora (default $TNS_ADMIN).",
+	"failover_connect_strings":     "Further endpoints (RAC nodes, Data Guard standby) tried when the primary is unreachable.",
+	"job_retries":                  "How often a job that lost its database connection is retried (default 0). Insert procedures must be safe to re-run.",
+	"environment":                  "Name of the environment (PROD, UAT, ...), available to templates and configured SQL as {{environment}}.",
+	"password_key_file":            "File holding the base64 AES-256 key for enc: passwords (default $GEMINI_EXTRACT_KEY).",
+	"db_host":                      "Database host name or SCAN address (unless connect_string is set).",
+	"db_port":                      "Database listener port (1-65535).",
+	"db_sid":                       "Database SID or service name.",
+	"concurrency":                  "Number of parallel workers and pooled connections.",
+	"log_path":                     "Directory for job logs, summaries and run markers.",
+	"sol_list_path":                "File listing one SOL ID per line; # starts a comment.",
+	"dir_mode":                     "Octal permissions for directories created at startup.",
+	"progress_interval_seconds":    "Seconds between progress/ETA reports (default 30).",
+	"heartbeat_interval_seconds":   "Seconds between heartbeats listing stalled jobs (default 60).",
+	"stall_threshold_seconds":      "Jobs running longer than this are reported as stalled; 0 disables.",
+	"cancel_stalled_jobs":          "Cancel jobs that exceed the stall threshold.",
+	"export_timeline":              "Write a Chrome trace JSON timeline of all jobs next to the logs.",
+	"recent_failures":              "How many recent failures the progress report repeats (default 10).",
+	"connection_wait_warn_seconds": "Average connection-pool wait per progress interval that triggers a queueing warning (default 5).",
+	"statement_cache_size":         "Statements cached per session by the driver (0 for the default of 40, -1 to disable); lower it when sessions hit ORA-01000.",
+	"driver_params":                "Further godror connection parameters added to the connect string, e.g. {\"poolSessionTimeout\": \"5m\", \"timezone\": \"UTC\"}.",
+	"monitor_open_cursors":         "Sample the open cursors of the tool's sessions with each progress report and warn before one reaches open_cursors (default off). Needs SELECT on v$session, v$sesstat and v$parameter.",
+	"open_cursors_warn_percent":    "Share of open_cursors a session may hold before the progress report warns (default 80), with monitor_open_cursors.",
+	"health_listen":                "Address such as :8080 serving the monitoring dashboard at /, /healthz (alive) and /readyz (config loaded, databases reachable, run not draining); empty disables.",
+	"control_token":                "Bearer token required by POST /cancel, /pause and /resume on health_listen; empty disables the commands. GET /status and /events (JSON Lines job records) are always served. The serve command's gRPC control API (controlapi.proto) needs the same token for StartRun and CancelRun.",
+	"shutdown_grace_seconds":       "After SIGTERM or a service stop, cancel jobs still running after this many seconds so the run finishes inside the orchestrator's grace period; 0 waits for them.",
+	"profiles":                     "Named overrides of any field above, selected with -profile.",
+	"connections":                  "Named databases (e.g. replica, standby) overriding the connection fields, for procedure_connections.",
+	"otlp_endpoint":                "OTLP/HTTP collector base URL for tracing, e.g. http://tempo:4318.",
+	"otlp_headers":                 "Extra HTTP headers sent to the OTLP collector.",
+	"retention":                    "Pruning of old spool files, logs and outputs.",
+	"retention.max_age_days":       "Remove files older than this many days; 0 disables.",
+	"retention.max_runs":           "Keep only the newest N files of each log/output series; 0 disables.",
+	"retention.clean_on_start":     "Apply the retention policy at the start of every run.",
+	"retention.output_patterns":    "Glob patterns limiting which merged outputs of the configured procedures are pruned, matched against the file name; empty prunes all of them. Only files named by the output templates are ever considered.",
+	"log_rotation":                 "Size caps for the detail CSV log.",
+	"log_rotation.max_bytes":       "Start a new log part after this many bytes; 0 disables.",
+	"log_rotation.max_records":     "Start a new log part after this many records; 0 disables.",
+	"log_rotation.gzip":            "Gzip each log part once it is complete.",
+	"job_log":                      "Where per-job records are written.",
+	"job_log.sinks":                "Any of csv (the detail CSV log, default), jsonl (<package>_<mode>.jsonl next to it) and db (rows in job_log.table).",
+	"job_log.table":                "Table for the db sink, with RUN_ID, PACKAGE_NAME, SOL_ID, PROCEDURE_NAME, START_TIME, END_TIME, EXECUTION_SECONDS, STATUS, ROWS_WRITTEN and ERROR_DETAILS columns.",
+	"job_log.rerun":                "overwrite (default) replaces the previous run's job log and summaries; append adds to the job log with a RUN_ID column; suffix names logs and summaries <package>_<mode>_<run id>.",
+	"timestamps":                   "Format of times in logs and summaries, and timezone of run IDs and dated file names.",
+	"timestamps.format":            "rfc3339 (default), rfc3339nano, legacy (DD-MM-YYYY hh:mm:ss) or a Go time layout.",
+	"timestamps.timezone":          "local (default), UTC or an IANA zone such as Asia/Kolkata.",
+	"syslog":                       "Forward application log entries to syslog (Linux/Unix).",
+	"syslog.enabled":               "Enable the syslog sink.",
+	"syslog.network":               "Empty for the local daemon, or udp/tcp for a remote one.",
+	"syslog.address":               "Remote syslog address as host:port.",
+	"syslog.facility":              "Syslog facility, e.g. local0 or daemon.",
+	"syslog.tag":                   "Syslog tag (default gemini_extract).",
+	"event_log":                    "Forward application log entries to the Windows Event Log.",
+	"event_log.enabled":            "Enable the event log sink.",
+	"event_log.source":             "Event source name (default gemini_extract).",
+	"memory":                       "Memory budget for shared servers.",
+	"memory.max_mb":                "Soft memory limit in MiB; dispatch slows while the heap is above it. 0 disables.",
+	"memory.max_large_row_jobs":    "Large-row jobs allowed to run at once under the budget (default concurrency/4).",
+	"memory.large_row_bytes":       "Template row width from which a procedure counts as large-row (default 4096).",
+	"memory.fetch_array_size":      "Rows fetched per round trip; lower it to reduce memory for wide rows (default driver setting).",
+	"vault":                        "HashiCorp Vault used to resolve vault:<path>#<key> references in db_user/db_password.",
+	"vault.address":                "Vault address (default $VAULT_ADDR).",
+	"vault.namespace":              "Vault Enterprise namespace.",
+	"vault.token_env":              "Environment variable holding the Vault token (default VAULT_TOKEN).",
+	"vault.token_file":             "File holding the Vault token, e.g. a Vault Agent sink.",
+	"vault.ca_file":                "PEM CA bundle used to verify Vault's certificate.",
+	"cyberark":                     "CyberArk CCP used to resolve cyberark:Safe=..;Object=..[#field] references.",
+	"cyberark.url":                 "CCP base URL, e.g. https://ccp.example.com.",
+	"cyberark.app_id":              "Application ID registered in CyberArk.",
+	"cyberark.cert_file":           "Client certificate for CCP authentication.",
+	"cyberark.key_file":            "Private key for the client certificate.",
+	"cyberark.ca_file":             "PEM CA bundle used to verify the CCP certificate.",
+
+	"package_name":                 "PL/SQL package name; also prefixes log file names.",
+	"procedures":                   "Procedures (insert mode) or tables/views (extract mode) to run.",
+	"spool_output_path":            "Directory for spool files, ideally fast local disk; also merged outputs unless final_output_path is set.",
+	"final_output_path":            "Directory for merged outputs, e.g. a network share; defaults to spool_output_path.",
+	"final_output_copy":            "Merge on the spool disk, then copy to final_output_path and verify the copy's SHA-256.",
+	"run_insertion_parallel":       "Run insert-mode jobs in parallel.",
+	"run_extraction_parallel":      "Run extract-mode jobs in parallel.",
+	"template_path":                "Directory holding one <procedure>.csv column template per procedure.",
+	"format":                       "Output format: delimited or fixed.",
+	"delimiter":                    "Single-character field delimiter for delimited output.",
+	"early_merge":                  "Merge each procedure's output as soon as all of its SOLs are done rather than at the end of the run.",
+	"procedure_priorities":         "Priority class per procedure (critical, normal, low); critical jobs are dispatched first across all SOLs.",
+	"source_objects":               "Extract mode: database object (view or synonym, optionally schema-qualified or @dblink) per procedure, when it differs from the procedure and template name. Every source object is checked at startup; missing or inaccessible ones are listed in <package>_missing_objects.csv and stop the run before any job starts.",
+	"variables":                    "Run parameters referenced as {{name}} by template columns, chunk keys, business_date_column and output_groups.query, resolved when statements are prepared. Built in: business_date, month_start and month_end (YYYY-MM-DD), run_id, package and environment; entries here add to or override them. An unknown name fails the prepare.",
+	"diff_keys":                    "Key columns per procedure for the diff command; without a key whole records are compared.",
+	"procedure_connections":        "Maps procedures to named connections from the main config; others use the main connection.",
+	"spool_file_template":          "Spool file name template, e.g. {{.Proc}}_{{.SolID}}_{{.RunID}}.spool.",
+	"output_file_template":         "Merged file name template, e.g. {{.Proc}}_{{.BusinessDate \"20060102\"}}.txt; {{.Date}} is the run start. {{.Seq 3}} is the part number padded to 3 digits: the SOL's position in the SOL file for per_sol_output, the group's among the sorted output_groups, otherwise 1.",
+	"spool_dir_template":           "Subdirectory layout for spool files under spool_output_path, e.g. {{.Proc}}/{{.Date \"20060102\"}}.",
+	"output_dir_template":          "Subdirectory layout for merged outputs under final_output_path (or spool_output_path), e.g. {{.Proc}}.",
+	"quarantine_path":              "Where stale spool files from earlier runs are moved.",
+	"tolerate_bad_records":         "Write rows that fail to scan or format to <procedure>.bad and continue.",
+	"length_policy":                "Oversize fixed-width values: truncate, warn or fail.",
+	"sanitize":                     "Default clean-up of extracted values, rules joined with +: newlines (line breaks to spaces, the default), control (remove control characters), ascii (remove non-ASCII), whitespace (collapse and trim), or none to leave values untouched. Every rule set but none replaces line breaks. A template's sanitize column overrides it per column; changed values are counted per column in the JSON summary.",
+	"delimiter_policy":             "Values containing the delimiter: escape, replace or fail; empty quotes them.",
+	"delimiter_replacement":        "Replacement for the delimiter under the replace policy (default space).",
+	"empty_output_policy":          "Procedures with no rows for any SOL: warn (default), fail the run, or emit an empty file.",
+	"column_stats":                 "Gather per-column null counts, max lengths and min/max values into a data profile per procedure.",
+	"schema_snapshot":              "Save the DBMS_METADATA definition of every extracted object next to the outputs at run start.",
+	"business_date_column":         "DATE column that extraction queries restrict to the -business-date.",
+	"business_date_param":          "Pass the -business-date to insertion procedures as a second DATE argument.",
+	"debug_statements":             "Write the prepared SQL/PL/SQL of every procedure next to the logs and log the binds of failed jobs.",
+	"lazy_prepare":                 "Prepare each procedure's statement when its first job runs instead of all at startup.",
+	"statement_strategy":           "How workers share prepared statements: shared (default), worker (one set per worker) or cached (per job, via the driver's statement cache).",
+	"debug_mask_binds":             "Bind names (SOL_ID, BUSINESS_DATE) shown masked in debug_statements output.",
+	"trace_jobs":                   "Procedure/SOL pairs ({\"procedure\", \"sol_id\"}; empty sol_id for all) run with DBMS_MONITOR SQL trace.",
+	"verify_output":                "Extract mode: after merging, re-read each final file, check record length (fixed) or field count (delimited) and the record count against the extracted rows; mismatches fail the run.",
+	"volume_alert_percent":         "Extract mode: flag procedures whose row count differs from the previous complete run (run history) by more than this percentage; 0 disables.",
+	"volume_alert_policy":          "warn (default) or fail the run when volume_alert_percent is exceeded.",
+	"fsync_policy":                 "Extract mode: never (default, OS flushes), close (sync each spool and merged file when complete) or rows (also sync spools every fsync_rows rows).",
+	"fsync_rows":                   "Rows between spool syncs with fsync_policy rows (default 100000).",
+	"sol_chunks":                   "Extract mode: procedure -> {\"buckets\", \"key\"} splitting each SOL into ORA_HASH(key, buckets-1) sub-jobs (key default ROWID) with their own spool segments, logged as SOL.NNN.",
+	"session_stats":                "Sample v$sesstat/v$sql metrics around each job into <package>_<mode>_sessionstats.csv; disables commit_interval batching.",
+	"commit_interval":              "Insert mode: commit every N procedure calls per worker session; 0 commits each call.",
+	"rollback_policy":              "Insert mode with commit_interval: a failed call rolls back itself (call, default) or the whole uncommitted batch (batch).",
+	"load_tables":                  "Target table per procedure for the load command (default the procedure name).",
+	"load_connection":              "Named connection the load command inserts into (default the main connection).",
+	"load_batch_size":              "Records per array insert for the load command (default 1000).",
+	"per_sol_output":               "Extract mode: keep one final file per SOL instead of merging all SOLs into one; output_file_template (default {{.Proc}}_{{.SolID}}.txt) or output_dir_template must use {{.SolID}}. Each file gets its own trigger file, checksum and upload.",
+	"views":                        "Extract mode: per procedure, named projections ({\"name\", \"columns\", \"format\", \"delimiter\"}) of its template written from the same query pass to a final file of their own, named as procedure <PROC>_<NAME>. columns default to all, widths and alignment come from the template; format (delimited, fixed or jsonl) and delimiter default to the procedure's, so one scan can feed several formats.",
+	"output_groups":                "Extract mode: merges the SOLs of each group (region, zone) into a final file per group and procedure instead of one file; output_file_template (default {{.Proc}}_{{.Group}}.txt) or output_dir_template must use {{.Group}}.",
+	"output_groups.file":           "CSV file of SOL_ID,GROUP lines (# comments and a SOL_ID header allowed).",
+	"output_groups.query":          "Query returning SOL ID and group pairs, run on the default connection; alternative to file.",
+	"output_groups.default":        "Group of SOLs missing from the mapping; unset, such SOLs fail the run.",
+	"rolling_feeds":                "Extract mode: procedures whose final file is appended to by every run instead of replaced; its trigger file, checksum and verification then cover the whole file. The output file name must not contain .RunID.",
+	"rfc4180":                      "Delimited output: procedures or views (<PROC>_<VIEW>) written as strict RFC 4180, with CRLF record ends and values containing the delimiter, quotes or line breaks quoted as they are instead of sanitised; delimiter_policy does not apply to them. Views of a listed procedure are included.",
+	"record_terminator":            "Bytes ending every output record: lf (default), crlf, none for fixed-length records (fixed-width format only, split by the template's record length on reading), or hex bytes such as 0x15. Strict RFC 4180 outputs always use crlf.",
+	"procedure_record_terminators": "Record terminator per procedure or view (<PROC>_<VIEW>), overriding record_terminator.",
+	"bom_outputs":                  "Extract mode: procedures or views (<PROC>_<VIEW>) whose final files start with a UTF-8 byte order mark, for consumers that require one. Appending to an existing file does not repeat it; verification and counts skip it. A BOM in the SOL list, templates or output group file is always dropped, and a UTF-16 one rejected.",
+	"compression":                  "Extract mode: codec final files are compressed with while they are merged (gzip, zlib, deflate, zstd, lz4, snappy or bzip2; empty or none for plain text). The codec's extension (.gz, .zz, .deflate, .zst, .lz4, .sz, .bz2) is added to the output file name; size and checksum are of the compressed file, record counts of its content.",
+	"procedure_compression":        "Extract mode: codec per procedure or view (<PROC>_<VIEW>), overriding compression; none leaves it uncompressed. Rolling feeds need an appendable codec (gzip, zstd, snappy or bzip2).",
+	"unchanged_output_policy":      "Extract mode: ship (default), skip or link. Compares each final file's SHA-256 with the previous complete run's; under skip an unchanged file gets no trigger file, upload, webhook or archive entry, under link it is replaced by a symlink to the previous file and only the uploads and archive entry are skipped.",
+	"archive":                      "Extract mode: packages the final files, trigger files and summaries of a complete run into <package>_<YYYYMMDD>.zip|tar.gz for hand-off.",
+	"archive.enabled":              "Write the archive.",
+	"archive.format":               "zip (default) or tar.gz.",
+	"archive.path":                 "Directory of the archive (default the final output directory).",
+	"archive.password_env":         "Environment variable holding the password the zip is AES-256 encrypted with (WinZip AE-2, opened by 7-Zip and WinZip).",
+	"trigger_file":                 "Extract mode: companion marker written next to each final output after merge and verification.",
+	"trigger_file.enabled":         "Write trigger files.",
+	"trigger_file.suffix":          "Appended to the output file name (default .done).",
+	"trigger_file.format":          "text (file/records/bytes/sha256/run_id/business_date lines, default) or json.",
+	"trigger_file.template":        "Go template overriding format, over .File, .Path, .Records, .Bytes, .SHA256, .RunID, .Business and .Time.",
+	"uploads":                      "Extract mode: cloud or SFTP destinations ({\"provider\": azure|gcs|sftp, \"account\", \"container\", \"bucket\", \"prefix\", \"endpoint\", \"host\", \"user\", \"key_file\", \"known_hosts\", \"max_mbps\", \"parallel_parts\", \"retries\"}) each final file and trigger file is uploaded to. Azure credentials: AZURE_STORAGE_SAS_TOKEN, a service principal (AZURE_TENANT_ID/CLIENT_ID/CLIENT_SECRET) or managed identity; GCS: GOOGLE_OAUTH_ACCESS_TOKEN, GOOGLE_APPLICATION_CREDENTIALS, gcloud application default credentials or the metadata server; SFTP: key_file, the SSH agent or SFTP_PASSWORD, with the host key checked against known_hosts (default ~/.ssh/known_hosts), writing to prefix + name via a .partial file. max_mbps caps the bandwidth, parallel_parts sends Azure blocks or SFTP parts concurrently and retries resends failed parts; interrupted uploads resume from the parts already stored.",
+	"webhooks":                     "HTTP endpoints ({\"url\", \"events\", \"headers\", \"timeout_seconds\", \"retries\"}) POSTed a JSON payload on run_start, procedure_complete, file_ready (path, bytes, records, sha256 of a merged file) and run_end; events default to all.",
+	"phases":                       "Ordered groups of procedures ({\"name\", \"procedures\"}); each starts after the previous finished for all SOLs.",
+}
+
+// configExamples supplies realistic example values for the generated example files.
+var configExamples = map[string]any{
+	"db_user":                   "extract_user",
+	"db_password":               "change-me",
+	"db_host":                   "db.example.com",
+	"db_port":                   1521,
+	"db_sid":                    "ORCL",
+	"concurrency":               8,
+	"log_path":                  "./logs",
+	"health_listen":             ":8080",
+	"shutdown_grace_seconds":    25,
+	"job_log.sinks":             []string{"csv", "jsonl"},
+	"job_log.table":             "EXTRACT_JOB_LOG",
+	"job_log.rerun":             "append",
+	"timestamps.format":         "rfc3339",
+	"timestamps.timezone":       "UTC",
+	"sol_list_path":             "./sols.txt",
+	"dir_mode":                  "0755",
+	"progress_interval_seconds": 30,
+	"stall_threshold_seconds":   1800,
+	"profiles":                  map[string]any{"uat": map[string]any{"db_host": "uat-db.example.com"}},
+	"connections":               map[string]any{"replica": map[string]any{"db_host": "replica.example.com", "db_port": 1521, "db_sid": "ORCLRO"}},
+	"retention.max_age_days":    14,
+	"retention.output_patterns": []string{"*.txt"},
+
+	"package_name":                 "MIG_PKG",
+	"procedures":                   []string{"GAM", "HTD"},
+	"spool_output_path":            "./spool",
+	"run_insertion_parallel":       true,
+	"run_extraction_parallel":      true,
+	"template_path":                "./templates",
+	"format":                       "delimited",
+	"delimiter":                    "|",
+	"procedure_connections":        map[string]string{"GAM": "replica"},
+	"source_objects":               map[string]string{"GAM": "CUSTOM.GAM_V"},
+	"variables":                    map[string]string{"ledger": "GL01"},
+	"diff_keys":                    map[string][]string{"GAM": {"FORACID"}},
+	"procedure_priorities":         map[string]string{"GAM": PriorityCritical, "HTD": PriorityLow},
+	"spool_file_template":          defaultSpoolFileTemplate,
+	"output_file_template":         defaultOutputFileTemplate,
+	"length_policy":                LengthPolicyTruncate,
+	"sanitize":                     "control+whitespace",
+	"empty_output_policy":          EmptyOutputWarn,
+	"trace_jobs":                   []map[string]any{{"procedure": "GAM", "sol_id": "0001"}},
+	"verify_output":                true,
+	"volume_alert_percent":         25,
+	"volume_alert_policy":          "warn",
+	"fsync_policy":                 "close",
+	"fsync_rows":                   100000,
+	"sol_chunks":                   map[string]any{"GAM": map[string]any{"buckets": 8, "key": "ACID"}},
+	"session_stats":                false,
+	"per_sol_output":               false,
+	"views":                        map[string][]map[string]any{"GAM": {{"name": "BRANCH", "columns": []string{"SOL_ID", "ACID", "CLR_BAL_AMT"}}, {"name": "JSON", "format": "jsonl"}}},
+	"output_groups.file":           "",
+	"output_groups.query":          "SELECT SOL_ID, REGION_CODE FROM SERVICE_OUTLET_TABLE",
+	"output_groups.default":        "UNASSIGNED",
+	"rolling_feeds":                []string{"TRANSACTION_FEED"},
+	"rfc4180":                      []string{"GAM"},
+	"record_terminator":            "lf",
+	"procedure_record_terminators": map[string]string{"GL_FEED": "0x15", "VENDOR_FEED": "crlf"},
+	"bom_outputs":                  []string{"GAM_BRANCH"},
+	"compression":                  "gzip",
+	"procedure_compression":        map[string]string{"GAM": "zlib", "GAM_BRANCH": "none"},
+	"unchanged_output_policy":      "link",
+	"archive.enabled":              true,
+	"archive.format":               "zip",
+	"archive.path":                 "/data/handoff",
+	"archive.password_env":         "EXTRACT_ARCHIVE_PASSWORD",
+	"trigger_file.enabled":         true,
+	"trigger_file.suffix":          ".done",
+	"trigger_file.format":          "text",
+	"trigger_file.template":        "{{.File}}|{{.Records}}|{{.SHA256}}\n",
+	"uploads":                      []map[string]any{{"provider": "azure", "account": "extractstore", "container": "landing", "prefix": "finacle/", "max_mbps": 200, "parallel_parts": 4, "retries": 3}, {"provider": "gcs", "bucket": "extract-landing", "prefix": "finacle/"}},
+	"webhooks":                     []map[string]any{{"url": "https://loader.example.com/hooks/extract", "events": []string{"file_ready", "run_end"}, "retries": 3}},
+	"phases":                       []map[string]any{{"name": "masters", "procedures": []string{"GAM"}}, {"name": "transactions", "procedures": []string{"HTD"}}},
+}
+
+// configFields returns the JSON-visible fields of a struct type with their JSON names.
+func configFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if name := jsonName(f); name == "" || name == "-" {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
+
+// exampleConfig renders a commented example (JSON with // comments) of a config type.
+func exampleConfig(t reflect.Type) []byte {
+	var b bytes.Buffer
+	writeExampleObject(&b, t, "", 0)
+	b.WriteString("\n")
+	return b.Bytes()
+}
+
+func writeExampleObject(b *bytes.Buffer, t reflect.Type, prefix string, depth int) {
+	indent := strings.Repeat("  ", depth+1)
+	b.WriteString("{\n")
+	fields := configFields(t)
+	for i, f := range fields {
+		path := prefix + jsonName(f)
+		if doc, ok := configDocs[path]; ok {
+			fmt.Fprintf(b, "%s// %s\n", indent, doc)
+		}
+		fmt.Fprintf(b, "%s%q: ", indent, jsonName(f))
+		if example, ok := configExamples[path]; ok {
+			v, _ := json.Marshal(example)
+			b.Write(v)
+		} else if f.Type.Kind() == reflect.Struct {
+			writeExampleObject(b, f.Type, path+".", depth+1)
+		} else {
+			v, _ := json.Marshal(zeroExample(f.Type))
+			b.Write(v)
+		}
+		if i < len(fields)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(strings.Repeat("  ", depth) + "}")
+}
+
+func zeroExample(t reflect.Type) any {
+	switch t.Kind() {
+	case reflect.Slice:
+		return []any{}
+	case reflect.Map:
+		return map[string]any{}
+	default:
+		return reflect.Zero(t).Interface()
+	}
+}
+
+// configSchema builds a JSON Schema (draft 2020-12) for a config type.
+func configSchema(t reflect.Type, title string) map[string]any {
+	s := schemaFor(t, "")
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["title"] = title
+	return s
+}
+
+func schemaFor(t reflect.Type, path string) map[string]any {
+	s := map[string]any{}
+	if doc, ok := configDocs[path]; ok {
+		s["description"] = doc
+	}
+	if t == reflect.TypeOf(json.RawMessage{}) {
+		return s // any JSON value
+	}
+	switch t.Kind() {
+	case reflect.String:
+		s["type"] = "string"
+	case reflect.Bool:
+		s["type"] = "boolean"
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		s["type"] = "integer"
+	case reflect.Float32, reflect.Float64:
+		s["type"] = "number"
+	case reflect.Slice:
+		s["type"] = "array"
+		s["items"] = schemaFor(t.Elem(), "")
+	case reflect.Map:
+		s["type"] = "object"
+		s["additionalProperties"] = schemaFor(t.Elem(), "")
+	case reflect.Struct:
+		s["type"] = "object"
+		props := map[string]any{}
+		for _, f := range configFields(t) {
+			name := jsonName(f)
+			p := name
+			if path != "" {
+				p = path + "." + name
+			}
+			props[name] = schemaFor(f.Type, p)
+		}
+		s["properties"] = props
+	case reflect.Pointer:
+		return schemaFor(t.Elem(), path)
+	}
+	return s
+}
+
+// stripJSONComments removes // line and /* block */ comments outside of strings, so
+// configuration files may carry the comments written by the init command.
+func stripJSONComments(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString, escaped := false, false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			out = append(out, c)
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch {
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i+1 < len(data) && !(data[i] == '*' && data[i+1] == '/') {
+				i++
+			}
+			i++
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
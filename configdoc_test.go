@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExampleConfigsLoad(t *testing.T) {
+	dir := t.TempDir()
+	if err := runInit([]string{"-dir", dir, "-schema"}); err != nil {
+		t.Fatal(err)
+	}
+	app, err := loadConfig[MainConfig](filepath.Join(dir, "app_config.example.json"))
+	if err != nil {
+		t.Fatalf("example app config does not load: %v", err)
+	}
+	if app.DBPort != 1521 || app.Retention.MaxAgeDays != 14 {
+		t.Errorf("unexpected example values: port=%d max_age_days=%d", app.DBPort, app.Retention.MaxAgeDays)
+	}
+	run, err := loadConfig[ExtractionConfig](filepath.Join(dir, "extraction_config.example.json"))
+	if err != nil {
+		t.Fatalf("example extraction config does not load: %v", err)
+	}
+	if run.Delimiter != "|" || len(run.Procedures) != 2 {
+		t.Errorf("unexpected example values: delimiter=%q procedures=%v", run.Delimiter, run.Procedures)
+	}
+	if err := runInit([]string{"-dir", dir}); err == nil {
+		t.Error("expected init to refuse overwriting without -force")
+	}
+}
+
+func TestConfigFieldsDocumented(t *testing.T) {
+	var check func(t reflect.Type, prefix string)
+	check = func(typ reflect.Type, prefix string) {
+		for _, f := range configFields(typ) {
+			path := prefix + jsonName(f)
+			if _, ok := configDocs[path]; !ok {
+				t.Errorf("config field %s has no entry in configDocs", path)
+			}
+			if f.Type.Kind() == reflect.Struct {
+				check(f.Type, path+".")
+			}
+		}
+	}
+	check(reflect.TypeOf(MainConfig{}), "")
+	check(reflect.TypeOf(ExtractionConfig{}), "")
+}
+
+func TestStripJSONComments(t *testing.T) {
+	in := "{\n  // comment\n  \"url\": \"http://x/*y*/\", /* block */ \"q\": \"a\\\"//b\"\n}"
+	got := string(stripJSONComments([]byte(in)))
+	for _, want := range []string{`"http://x/*y*/"`, `"a\"//b"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("stripped output %q lost string %s", got, want)
+		}
+	}
+	if strings.Contains(got, "comment") || strings.Contains(got, "block") {
+		t.Errorf("comments not removed: %q", got)
+	}
+}
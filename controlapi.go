@@ -0,0 +1,196 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// eventBuffer is how many job events a slow /events client may fall behind before
+// events are dropped for it.
+const eventBuffer = 256
+
+// runControl is the run currently served by the control API.
+type runControl struct {
+	runID    string
+	pkg      string
+	progress *progressTracker
+	control  *dispatchControl
+}
+
+// eventStream fans job records out to /events subscribers.
+type eventStream struct {
+	mu     sync.Mutex
+	subs   map[chan jobEvent]bool
+	closed bool
+}
+
+// subscribe returns a channel of the events published from now on, closed at once when
+// the stream has ended.
+func (s *eventStream) subscribe() chan jobEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch := make(chan jobEvent, eventBuffer)
+	if s.closed {
+		close(ch)
+		return ch
+	}
+	if s.subs == nil {
+		s.subs = make(map[chan jobEvent]bool)
+	}
+	s.subs[ch] = true
+	return ch
+}
+
+func (s *eventStream) unsubscribe(ch chan jobEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subs[ch] {
+		delete(s.subs, ch)
+		close(ch)
+	}
+}
+
+// publish delivers ev to every subscriber that has room for it.
+func (s *eventStream) publish(ev jobEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// closeAll ends every subscription, so streaming clients see the end of the stream.
+func (s *eventStream) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for ch := range s.subs {
+		delete(s.subs, ch)
+		close(ch)
+	}
+}
+
+// eventSink is the LogSink that streams a run's job records to /events.
+type eventSink struct {
+	stream     *eventStream
+	runID, pkg string
+}
+
+func (s eventSink) Write(plog ProcLog) error {
+	s.stream.publish(newJobEvent(s.runID, s.pkg, plog))
+	return nil
+}
+
+// Close does nothing; the stream outlives the run and is closed with the server.
+func (s eventSink) Close() error { return nil }
+
+// controlRoutes adds the control API: GET /status and /events for everyone, and POST
+// /cancel, /pause and /resume for callers presenting the control token.
+// The API serves the run of this process; the serve command's gRPC API of
+// controlgrpc.go also starts runs.
+func (h *healthServer) controlRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/status", h.status)
+	mux.HandleFunc("/events", h.events)
+	mux.HandleFunc("/cancel", h.command(func(d *dispatchControl) { d.Cancel() }))
+	mux.HandleFunc("/pause", h.command(func(d *dispatchControl) { d.Pause() }))
+	mux.HandleFunc("/resume", h.command(func(d *dispatchControl) { d.Resume() }))
+}
+
+// setRun makes rc the run served by the control API.
+func (h *healthServer) setRun(rc *runControl) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.run = rc
+}
+
+// jobSink returns the sink streaming the run's job records, or nil without a server.
+func (h *healthServer) jobSink(runCfg *ExtractionConfig) LogSink {
+	if h == nil {
+		return nil
+	}
+	return eventSink{stream: &h.stream, runID: runCfg.RunID, pkg: runCfg.PackageName}
+}
+
+func (h *healthServer) current() (*runControl, string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.run, h.state
+}
+
+func (h *healthServer) status(w http.ResponseWriter, _ *http.Request) {
+	rc, state := h.current()
+	body := struct {
+		State     string `json:"state"`
+		RunID     string `json:"run_id,omitempty"`
+		Package   string `json:"package,omitempty"`
+		Paused    bool   `json:"paused"`
+		Cancelled bool   `json:"cancelled"`
+		*runStatus
+	}{State: state}
+	if rc != nil {
+		s := rc.progress.Status()
+		body.RunID, body.Package, body.runStatus = rc.runID, rc.pkg, &s
+		body.Paused, body.Cancelled = rc.control.Paused(), rc.control.Cancelled()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// events streams job records as JSON Lines until the client goes away or the server
+// shuts down.
+func (h *healthServer) events(w http.ResponseWriter, r *http.Request) {
+	ch := h.stream.subscribe()
+	defer h.stream.unsubscribe(ch)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := enc.Encode(ev); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// command wraps a dispatch control action as a POST endpoint guarded by the token.
+func (h *healthServer) command(action func(*dispatchControl)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "use POST", http.StatusMethodNotAllowed)
+			return
+		}
+		want := "Bearer " + h.token
+		if h.token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(want)) != 1 {
+			http.Error(w, "control token required", http.StatusForbidden)
+			return
+		}
+		rc, _ := h.current()
+		if rc == nil {
+			http.Error(w, "no run in progress", http.StatusConflict)
+			return
+		}
+		action(rc.control)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
@@ -0,0 +1,731 @@
+// gRPC control API served by "gemini_extract serve", for orchestrators that start runs
+// and follow their jobs programmatically. Regenerate the Go code with go generate.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: controlapi.proto
+
+package main
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StartRunRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Path of the run configuration on the server.
+	RunConfig string `protobuf:"bytes,1,opt,name=run_config,json=runConfig,proto3" json:"run_config,omitempty"`
+	// E to extract, I to insert.
+	Mode string `protobuf:"bytes,2,opt,name=mode,proto3" json:"mode,omitempty"`
+	// YYYY-MM-DD; empty for the database's SYSDATE.
+	BusinessDate  string `protobuf:"bytes,3,opt,name=business_date,json=businessDate,proto3" json:"business_date,omitempty"`
+	Resume        bool   `protobuf:"varint,4,opt,name=resume,proto3" json:"resume,omitempty"`
+	SkipExisting  bool   `protobuf:"varint,5,opt,name=skip_existing,json=skipExisting,proto3" json:"skip_existing,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartRunRequest) Reset() {
+	*x = StartRunRequest{}
+	mi := &file_controlapi_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartRunRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartRunRequest) ProtoMessage() {}
+
+func (x *StartRunRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_controlapi_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartRunRequest.ProtoReflect.Descriptor instead.
+func (*StartRunRequest) Descriptor() ([]byte, []int) {
+	return file_controlapi_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StartRunRequest) GetRunConfig() string {
+	if x != nil {
+		return x.RunConfig
+	}
+	return ""
+}
+
+func (x *StartRunRequest) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *StartRunRequest) GetBusinessDate() string {
+	if x != nil {
+		return x.BusinessDate
+	}
+	return ""
+}
+
+func (x *StartRunRequest) GetResume() bool {
+	if x != nil {
+		return x.Resume
+	}
+	return false
+}
+
+func (x *StartRunRequest) GetSkipExisting() bool {
+	if x != nil {
+		return x.SkipExisting
+	}
+	return false
+}
+
+type StartRunResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Identifies the run in the other calls.
+	Id            string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartRunResponse) Reset() {
+	*x = StartRunResponse{}
+	mi := &file_controlapi_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartRunResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartRunResponse) ProtoMessage() {}
+
+func (x *StartRunResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_controlapi_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartRunResponse.ProtoReflect.Descriptor instead.
+func (*StartRunResponse) Descriptor() ([]byte, []int) {
+	return file_controlapi_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *StartRunResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type GetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_controlapi_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_controlapi_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_controlapi_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetStatusRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type RunStatus struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// starting, running, succeeded, failed or cancelled.
+	State string `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	// The run ID the run's logs and outputs are named with, once it is known.
+	RunId          string  `protobuf:"bytes,3,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	Package        string  `protobuf:"bytes,4,opt,name=package,proto3" json:"package,omitempty"`
+	TotalJobs      int32   `protobuf:"varint,5,opt,name=total_jobs,json=totalJobs,proto3" json:"total_jobs,omitempty"`
+	CompletedJobs  int32   `protobuf:"varint,6,opt,name=completed_jobs,json=completedJobs,proto3" json:"completed_jobs,omitempty"`
+	FailedJobs     int32   `protobuf:"varint,7,opt,name=failed_jobs,json=failedJobs,proto3" json:"failed_jobs,omitempty"`
+	RunningJobs    int32   `protobuf:"varint,8,opt,name=running_jobs,json=runningJobs,proto3" json:"running_jobs,omitempty"`
+	Rows           int64   `protobuf:"varint,9,opt,name=rows,proto3" json:"rows,omitempty"`
+	RowsPerSec     float64 `protobuf:"fixed64,10,opt,name=rows_per_sec,json=rowsPerSec,proto3" json:"rows_per_sec,omitempty"`
+	ElapsedSeconds float64 `protobuf:"fixed64,11,opt,name=elapsed_seconds,json=elapsedSeconds,proto3" json:"elapsed_seconds,omitempty"`
+	EtaSeconds     float64 `protobuf:"fixed64,12,opt,name=eta_seconds,json=etaSeconds,proto3" json:"eta_seconds,omitempty"`
+	// Process exit code and error of a run that has ended.
+	ExitCode      int32  `protobuf:"varint,13,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	Error         string `protobuf:"bytes,14,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunStatus) Reset() {
+	*x = RunStatus{}
+	mi := &file_controlapi_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunStatus) ProtoMessage() {}
+
+func (x *RunStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_controlapi_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunStatus.ProtoReflect.Descriptor instead.
+func (*RunStatus) Descriptor() ([]byte, []int) {
+	return file_controlapi_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RunStatus) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RunStatus) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *RunStatus) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *RunStatus) GetPackage() string {
+	if x != nil {
+		return x.Package
+	}
+	return ""
+}
+
+func (x *RunStatus) GetTotalJobs() int32 {
+	if x != nil {
+		return x.TotalJobs
+	}
+	return 0
+}
+
+func (x *RunStatus) GetCompletedJobs() int32 {
+	if x != nil {
+		return x.CompletedJobs
+	}
+	return 0
+}
+
+func (x *RunStatus) GetFailedJobs() int32 {
+	if x != nil {
+		return x.FailedJobs
+	}
+	return 0
+}
+
+func (x *RunStatus) GetRunningJobs() int32 {
+	if x != nil {
+		return x.RunningJobs
+	}
+	return 0
+}
+
+func (x *RunStatus) GetRows() int64 {
+	if x != nil {
+		return x.Rows
+	}
+	return 0
+}
+
+func (x *RunStatus) GetRowsPerSec() float64 {
+	if x != nil {
+		return x.RowsPerSec
+	}
+	return 0
+}
+
+func (x *RunStatus) GetElapsedSeconds() float64 {
+	if x != nil {
+		return x.ElapsedSeconds
+	}
+	return 0
+}
+
+func (x *RunStatus) GetEtaSeconds() float64 {
+	if x != nil {
+		return x.EtaSeconds
+	}
+	return 0
+}
+
+func (x *RunStatus) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *RunStatus) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type CancelRunRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelRunRequest) Reset() {
+	*x = CancelRunRequest{}
+	mi := &file_controlapi_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelRunRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelRunRequest) ProtoMessage() {}
+
+func (x *CancelRunRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_controlapi_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelRunRequest.ProtoReflect.Descriptor instead.
+func (*CancelRunRequest) Descriptor() ([]byte, []int) {
+	return file_controlapi_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CancelRunRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type CancelRunResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelRunResponse) Reset() {
+	*x = CancelRunResponse{}
+	mi := &file_controlapi_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelRunResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelRunResponse) ProtoMessage() {}
+
+func (x *CancelRunResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_controlapi_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelRunResponse.ProtoReflect.Descriptor instead.
+func (*CancelRunResponse) Descriptor() ([]byte, []int) {
+	return file_controlapi_proto_rawDescGZIP(), []int{5}
+}
+
+type StreamJobEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamJobEventsRequest) Reset() {
+	*x = StreamJobEventsRequest{}
+	mi := &file_controlapi_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamJobEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamJobEventsRequest) ProtoMessage() {}
+
+func (x *StreamJobEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_controlapi_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamJobEventsRequest.ProtoReflect.Descriptor instead.
+func (*StreamJobEventsRequest) Descriptor() ([]byte, []int) {
+	return file_controlapi_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *StreamJobEventsRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type JobEvent struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	RunId            string                 `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	Package          string                 `protobuf:"bytes,2,opt,name=package,proto3" json:"package,omitempty"`
+	SolId            string                 `protobuf:"bytes,3,opt,name=sol_id,json=solId,proto3" json:"sol_id,omitempty"`
+	Procedure        string                 `protobuf:"bytes,4,opt,name=procedure,proto3" json:"procedure,omitempty"`
+	StartTime        *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime          *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	ExecutionSeconds float64                `protobuf:"fixed64,7,opt,name=execution_seconds,json=executionSeconds,proto3" json:"execution_seconds,omitempty"`
+	Status           string                 `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"`
+	Rows             int64                  `protobuf:"varint,9,opt,name=rows,proto3" json:"rows,omitempty"`
+	BadRecords       int64                  `protobuf:"varint,10,opt,name=bad_records,json=badRecords,proto3" json:"bad_records,omitempty"`
+	ErrorCode        string                 `protobuf:"bytes,11,opt,name=error_code,json=errorCode,proto3" json:"error_code,omitempty"`
+	ErrorDetails     string                 `protobuf:"bytes,12,opt,name=error_details,json=errorDetails,proto3" json:"error_details,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *JobEvent) Reset() {
+	*x = JobEvent{}
+	mi := &file_controlapi_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobEvent) ProtoMessage() {}
+
+func (x *JobEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_controlapi_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobEvent.ProtoReflect.Descriptor instead.
+func (*JobEvent) Descriptor() ([]byte, []int) {
+	return file_controlapi_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *JobEvent) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *JobEvent) GetPackage() string {
+	if x != nil {
+		return x.Package
+	}
+	return ""
+}
+
+func (x *JobEvent) GetSolId() string {
+	if x != nil {
+		return x.SolId
+	}
+	return ""
+}
+
+func (x *JobEvent) GetProcedure() string {
+	if x != nil {
+		return x.Procedure
+	}
+	return ""
+}
+
+func (x *JobEvent) GetStartTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartTime
+	}
+	return nil
+}
+
+func (x *JobEvent) GetEndTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndTime
+	}
+	return nil
+}
+
+func (x *JobEvent) GetExecutionSeconds() float64 {
+	if x != nil {
+		return x.ExecutionSeconds
+	}
+	return 0
+}
+
+func (x *JobEvent) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *JobEvent) GetRows() int64 {
+	if x != nil {
+		return x.Rows
+	}
+	return 0
+}
+
+func (x *JobEvent) GetBadRecords() int64 {
+	if x != nil {
+		return x.BadRecords
+	}
+	return 0
+}
+
+func (x *JobEvent) GetErrorCode() string {
+	if x != nil {
+		return x.ErrorCode
+	}
+	return ""
+}
+
+func (x *JobEvent) GetErrorDetails() string {
+	if x != nil {
+		return x.ErrorDetails
+	}
+	return ""
+}
+
+var File_controlapi_proto protoreflect.FileDescriptor
+
+const file_controlapi_proto_rawDesc = "" +
+	"\n" +
+	"\x10controlapi.proto\x12\x19gemini_extract.control.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xa6\x01\n" +
+	"\x0fStartRunRequest\x12\x1d\n" +
+	"\n" +
+	"run_config\x18\x01 \x01(\tR\trunConfig\x12\x12\n" +
+	"\x04mode\x18\x02 \x01(\tR\x04mode\x12#\n" +
+	"\rbusiness_date\x18\x03 \x01(\tR\fbusinessDate\x12\x16\n" +
+	"\x06resume\x18\x04 \x01(\bR\x06resume\x12#\n" +
+	"\rskip_existing\x18\x05 \x01(\bR\fskipExisting\"\"\n" +
+	"\x10StartRunResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\"\n" +
+	"\x10GetStatusRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x9f\x03\n" +
+	"\tRunStatus\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05state\x18\x02 \x01(\tR\x05state\x12\x15\n" +
+	"\x06run_id\x18\x03 \x01(\tR\x05runId\x12\x18\n" +
+	"\apackage\x18\x04 \x01(\tR\apackage\x12\x1d\n" +
+	"\n" +
+	"total_jobs\x18\x05 \x01(\x05R\ttotalJobs\x12%\n" +
+	"\x0ecompleted_jobs\x18\x06 \x01(\x05R\rcompletedJobs\x12\x1f\n" +
+	"\vfailed_jobs\x18\a \x01(\x05R\n" +
+	"failedJobs\x12!\n" +
+	"\frunning_jobs\x18\b \x01(\x05R\vrunningJobs\x12\x12\n" +
+	"\x04rows\x18\t \x01(\x03R\x04rows\x12 \n" +
+	"\frows_per_sec\x18\n" +
+	" \x01(\x01R\n" +
+	"rowsPerSec\x12'\n" +
+	"\x0felapsed_seconds\x18\v \x01(\x01R\x0eelapsedSeconds\x12\x1f\n" +
+	"\veta_seconds\x18\f \x01(\x01R\n" +
+	"etaSeconds\x12\x1b\n" +
+	"\texit_code\x18\r \x01(\x05R\bexitCode\x12\x14\n" +
+	"\x05error\x18\x0e \x01(\tR\x05error\"\"\n" +
+	"\x10CancelRunRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x13\n" +
+	"\x11CancelRunResponse\"(\n" +
+	"\x16StreamJobEventsRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\xa0\x03\n" +
+	"\bJobEvent\x12\x15\n" +
+	"\x06run_id\x18\x01 \x01(\tR\x05runId\x12\x18\n" +
+	"\apackage\x18\x02 \x01(\tR\apackage\x12\x15\n" +
+	"\x06sol_id\x18\x03 \x01(\tR\x05solId\x12\x1c\n" +
+	"\tprocedure\x18\x04 \x01(\tR\tprocedure\x129\n" +
+	"\n" +
+	"start_time\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tstartTime\x125\n" +
+	"\bend_time\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\aendTime\x12+\n" +
+	"\x11execution_seconds\x18\a \x01(\x01R\x10executionSeconds\x12\x16\n" +
+	"\x06status\x18\b \x01(\tR\x06status\x12\x12\n" +
+	"\x04rows\x18\t \x01(\x03R\x04rows\x12\x1f\n" +
+	"\vbad_records\x18\n" +
+	" \x01(\x03R\n" +
+	"badRecords\x12\x1d\n" +
+	"\n" +
+	"error_code\x18\v \x01(\tR\terrorCode\x12#\n" +
+	"\rerror_details\x18\f \x01(\tR\ferrorDetails2\xaa\x03\n" +
+	"\x0eControlService\x12c\n" +
+	"\bStartRun\x12*.gemini_extract.control.v1.StartRunRequest\x1a+.gemini_extract.control.v1.StartRunResponse\x12^\n" +
+	"\tGetStatus\x12+.gemini_extract.control.v1.GetStatusRequest\x1a$.gemini_extract.control.v1.RunStatus\x12f\n" +
+	"\tCancelRun\x12+.gemini_extract.control.v1.CancelRunRequest\x1a,.gemini_extract.control.v1.CancelRunResponse\x12k\n" +
+	"\x0fStreamJobEvents\x121.gemini_extract.control.v1.StreamJobEventsRequest\x1a#.gemini_extract.control.v1.JobEvent0\x01B\tZ\a./;mainb\x06proto3"
+
+var (
+	file_controlapi_proto_rawDescOnce sync.Once
+	file_controlapi_proto_rawDescData []byte
+)
+
+func file_controlapi_proto_rawDescGZIP() []byte {
+	file_controlapi_proto_rawDescOnce.Do(func() {
+		file_controlapi_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_controlapi_proto_rawDesc), len(file_controlapi_proto_rawDesc)))
+	})
+	return file_controlapi_proto_rawDescData
+}
+
+var file_controlapi_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_controlapi_proto_goTypes = []any{
+	(*StartRunRequest)(nil),        // 0: gemini_extract.control.v1.StartRunRequest
+	(*StartRunResponse)(nil),       // 1: gemini_extract.control.v1.StartRunResponse
+	(*GetStatusRequest)(nil),       // 2: gemini_extract.control.v1.GetStatusRequest
+	(*RunStatus)(nil),              // 3: gemini_extract.control.v1.RunStatus
+	(*CancelRunRequest)(nil),       // 4: gemini_extract.control.v1.CancelRunRequest
+	(*CancelRunResponse)(nil),      // 5: gemini_extract.control.v1.CancelRunResponse
+	(*StreamJobEventsRequest)(nil), // 6: gemini_extract.control.v1.StreamJobEventsRequest
+	(*JobEvent)(nil),               // 7: gemini_extract.control.v1.JobEvent
+	(*timestamppb.Timestamp)(nil),  // 8: google.protobuf.Timestamp
+}
+var file_controlapi_proto_depIdxs = []int32{
+	8, // 0: gemini_extract.control.v1.JobEvent.start_time:type_name -> google.protobuf.Timestamp
+	8, // 1: gemini_extract.control.v1.JobEvent.end_time:type_name -> google.protobuf.Timestamp
+	0, // 2: gemini_extract.control.v1.ControlService.StartRun:input_type -> gemini_extract.control.v1.StartRunRequest
+	2, // 3: gemini_extract.control.v1.ControlService.GetStatus:input_type -> gemini_extract.control.v1.GetStatusRequest
+	4, // 4: gemini_extract.control.v1.ControlService.CancelRun:input_type -> gemini_extract.control.v1.CancelRunRequest
+	6, // 5: gemini_extract.control.v1.ControlService.StreamJobEvents:input_type -> gemini_extract.control.v1.StreamJobEventsRequest
+	1, // 6: gemini_extract.control.v1.ControlService.StartRun:output_type -> gemini_extract.control.v1.StartRunResponse
+	3, // 7: gemini_extract.control.v1.ControlService.GetStatus:output_type -> gemini_extract.control.v1.RunStatus
+	5, // 8: gemini_extract.control.v1.ControlService.CancelRun:output_type -> gemini_extract.control.v1.CancelRunResponse
+	7, // 9: gemini_extract.control.v1.ControlService.StreamJobEvents:output_type -> gemini_extract.control.v1.JobEvent
+	6, // [6:10] is the sub-list for method output_type
+	2, // [2:6] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_controlapi_proto_init() }
+func file_controlapi_proto_init() {
+	if File_controlapi_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_controlapi_proto_rawDesc), len(file_controlapi_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_controlapi_proto_goTypes,
+		DependencyIndexes: file_controlapi_proto_depIdxs,
+		MessageInfos:      file_controlapi_proto_msgTypes,
+	}.Build()
+	File_controlapi_proto = out.File
+	file_controlapi_proto_goTypes = nil
+	file_controlapi_proto_depIdxs = nil
+}
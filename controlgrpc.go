@@ -0,0 +1,443 @@
+package main
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative controlapi.proto
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/charmbracelet/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// controlPipeEnv tells a run it was started by the serve command, which reads its job
+// events and progress as JSON Lines on its stdout; runs write nothing else there.
+const controlPipeEnv = "GEMINI_EXTRACT_CONTROL_PIPE"
+
+// States of runs started by the serve command, besides starting and running.
+const (
+	runSucceeded = "succeeded"
+	runFailed    = "failed"
+	runCancelled = "cancelled"
+)
+
+// pipeStatusInterval is how often a run reports its progress to the serve command.
+const pipeStatusInterval = time.Second
+
+// pipeMessage is one JSON line a run sends the serve command that started it.
+type pipeMessage struct {
+	RunID   string     `json:"run_id,omitempty"`
+	Package string     `json:"package,omitempty"`
+	Status  *runStatus `json:"status,omitempty"`
+	Event   *jobEvent  `json:"event,omitempty"`
+}
+
+// pipeReporter sends a run's job events and progress to the serve command.
+type pipeReporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// controlPipe is the process's reporter, nil unless it was started by the serve command.
+var controlPipe = openControlPipe()
+
+func openControlPipe() *pipeReporter {
+	if os.Getenv(controlPipeEnv) == "" {
+		return nil
+	}
+	return &pipeReporter{enc: json.NewEncoder(os.Stdout)}
+}
+
+func (p *pipeReporter) send(m pipeMessage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.enc.Encode(m); err != nil {
+		log.Debug("Failed to report to the serve command", "error", err)
+	}
+}
+
+// jobSink returns the sink reporting the run's job records, or nil without a pipe.
+func (p *pipeReporter) jobSink(runCfg *ExtractionConfig) LogSink {
+	if p == nil {
+		return nil
+	}
+	return pipeSink{p: p, runID: runCfg.RunID, pkg: runCfg.PackageName}
+}
+
+// reportRun sends rc's progress every pipeStatusInterval until the returned function,
+// which sends it a last time, is called.
+func (p *pipeReporter) reportRun(rc *runControl) (stop func()) {
+	if p == nil {
+		return func() {}
+	}
+	report := func() {
+		s := rc.progress.Status()
+		p.send(pipeMessage{RunID: rc.runID, Package: rc.pkg, Status: &s})
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(pipeStatusInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				report()
+			case <-done:
+				return
+			}
+		}
+	}()
+	report()
+	return func() {
+		close(done)
+		wg.Wait()
+		report()
+	}
+}
+
+type pipeSink struct {
+	p          *pipeReporter
+	runID, pkg string
+}
+
+func (s pipeSink) Write(plog ProcLog) error {
+	ev := newJobEvent(s.runID, s.pkg, plog)
+	s.p.send(pipeMessage{Event: &ev})
+	return nil
+}
+
+func (s pipeSink) Close() error { return nil }
+
+// childRun is a run the serve command started, as a process of its own.
+type childRun struct {
+	id     string
+	proc   *os.Process
+	stream eventStream
+	done   chan struct{}
+
+	mu        sync.Mutex
+	state     string
+	runID     string
+	pkg       string
+	status    runStatus
+	exitCode  int
+	err       string
+	cancelled bool
+}
+
+// follow reads the run's reports from r until the process exits, then records how it
+// ended and ends the event streams.
+func (c *childRun) follow(r io.Reader, wait func() error) {
+	dec := json.NewDecoder(r)
+	for {
+		var m pipeMessage
+		if err := dec.Decode(&m); err != nil {
+			// Keep the pipe drained so the run never blocks on a full pipe.
+			io.Copy(io.Discard, r)
+			break
+		}
+		c.mu.Lock()
+		if m.RunID != "" {
+			c.runID, c.pkg = m.RunID, m.Package
+		}
+		if m.Status != nil {
+			c.state, c.status = runRunning, *m.Status
+		}
+		c.mu.Unlock()
+		if m.Event != nil {
+			c.stream.publish(*m.Event)
+		}
+	}
+	err := wait()
+
+	c.mu.Lock()
+	var exit *exec.ExitError
+	switch {
+	case err == nil:
+		c.state = runSucceeded
+	case c.cancelled:
+		c.state = runCancelled
+	default:
+		c.state = runFailed
+	}
+	if errors.As(err, &exit) {
+		c.exitCode = exit.ExitCode()
+	}
+	if err != nil {
+		c.err = err.Error()
+	}
+	c.mu.Unlock()
+	c.stream.closeAll()
+	close(c.done)
+	log.Info("Run started over gRPC ended", "id", c.id, "run_id", c.runID, "state", c.state)
+}
+
+// controlServer implements the gRPC control API of controlapi.proto.
+type controlServer struct {
+	UnimplementedControlServiceServer
+	token string
+	// command builds the process of a run from the tool's arguments.
+	command func(args ...string) *exec.Cmd
+
+	mu   sync.Mutex
+	runs map[string]*childRun
+	next int
+}
+
+func newControlServer(token string) *controlServer {
+	exe, err := os.Executable()
+	if err != nil {
+		exe = os.Args[0]
+	}
+	return &controlServer{token: token, runs: make(map[string]*childRun), command: func(args ...string) *exec.Cmd {
+		return exec.Command(exe, args...)
+	}}
+}
+
+// authorize checks the control token, which StartRun and CancelRun need.
+func (s *controlServer) authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if s.token != "" {
+		for _, v := range md.Get("authorization") {
+			if subtle.ConstantTimeCompare([]byte(v), []byte("Bearer "+s.token)) == 1 {
+				return nil
+			}
+		}
+	}
+	return status.Error(codes.PermissionDenied, "control token required")
+}
+
+func (s *controlServer) run(id string) (*childRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.runs[id]; ok {
+		return c, nil
+	}
+	return nil, status.Errorf(codes.NotFound, "no run %q", id)
+}
+
+func (s *controlServer) StartRun(ctx context.Context, req *StartRunRequest) (*StartRunResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if req.RunConfig == "" {
+		return nil, status.Error(codes.InvalidArgument, "run_config is required")
+	}
+	if req.Mode != "E" && req.Mode != "I" {
+		return nil, status.Error(codes.InvalidArgument, "mode must be E or I")
+	}
+	args := []string{"-appCfg", *appCfgFile, "-runCfg", req.RunConfig, "-mode", req.Mode}
+	if req.BusinessDate != "" {
+		if _, err := parseBusinessDate(req.BusinessDate); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		args = append(args, "-business-date", req.BusinessDate)
+	}
+	if *profile != "" {
+		args = append(args, "-profile", *profile)
+	}
+	if *useKeyring {
+		args = append(args, "-keyring")
+	}
+	if req.Resume {
+		args = append(args, "-resume")
+	}
+	if req.SkipExisting {
+		args = append(args, "-skip-existing")
+	}
+
+	cmd := s.command(args...)
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), controlPipeEnv+"=1")
+	detachRun(cmd)
+	r, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to start run: %v", err)
+	}
+
+	s.mu.Lock()
+	s.next++
+	c := &childRun{id: strconv.Itoa(s.next), proc: cmd.Process, state: runStarting, done: make(chan struct{})}
+	s.runs[c.id] = c
+	s.mu.Unlock()
+	go c.follow(r, cmd.Wait)
+	log.Info("Started run over gRPC", "id", c.id, "run_config", req.RunConfig, "mode", req.Mode, "pid", cmd.Process.Pid)
+	return &StartRunResponse{Id: c.id}, nil
+}
+
+func (s *controlServer) GetStatus(_ context.Context, req *GetStatusRequest) (*RunStatus, error) {
+	c, err := s.run(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := c.status
+	return &RunStatus{
+		Id: c.id, State: c.state, RunId: c.runID, Package: c.pkg,
+		TotalJobs: int32(st.Total), CompletedJobs: int32(st.Completed), FailedJobs: int32(st.Failed), RunningJobs: int32(st.Running),
+		Rows: st.Rows, RowsPerSec: st.RowsPerSec, ElapsedSeconds: st.ElapsedSeconds, EtaSeconds: st.ETASeconds,
+		ExitCode: int32(c.exitCode), Error: c.err,
+	}, nil
+}
+
+func (s *controlServer) CancelRun(ctx context.Context, req *CancelRunRequest) (*CancelRunResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	c, err := s.run(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-c.done:
+		return nil, status.Errorf(codes.FailedPrecondition, "run %s has ended", c.id)
+	default:
+	}
+	c.mu.Lock()
+	c.cancelled = true
+	c.mu.Unlock()
+	if err := interruptRun(c.proc); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to cancel run %s: %v", c.id, err)
+	}
+	return &CancelRunResponse{}, nil
+}
+
+// interruptRun drains a run like Ctrl+C does. Windows cannot interrupt another
+// process, so there the run is killed.
+func interruptRun(p *os.Process) error {
+	if err := p.Signal(os.Interrupt); err != nil {
+		return p.Kill()
+	}
+	return nil
+}
+
+func (s *controlServer) StreamJobEvents(req *StreamJobEventsRequest, stream grpc.ServerStreamingServer[JobEvent]) error {
+	c, err := s.run(req.Id)
+	if err != nil {
+		return err
+	}
+	ch := c.stream.subscribe()
+	defer c.stream.unsubscribe(ch)
+	// The headers tell the client it is subscribed and sees every later event.
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(protoJobEvent(ev)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+func protoJobEvent(ev jobEvent) *JobEvent {
+	return &JobEvent{
+		RunId: ev.RunID, Package: ev.Package, SolId: ev.SolID, Procedure: ev.Procedure,
+		StartTime: timestamppb.New(ev.StartTime), EndTime: timestamppb.New(ev.EndTime), ExecutionSeconds: ev.ExecutionSeconds,
+		Status: ev.Status, Rows: ev.Rows, BadRecords: ev.BadRecords, ErrorCode: ev.ErrorCode, ErrorDetails: ev.ErrorDetails,
+	}
+}
+
+// shutdown interrupts the runs still going and waits for them to end.
+func (s *controlServer) shutdown() {
+	s.mu.Lock()
+	runs := make([]*childRun, 0, len(s.runs))
+	for _, c := range s.runs {
+		runs = append(runs, c)
+	}
+	s.mu.Unlock()
+	for _, c := range runs {
+		select {
+		case <-c.done:
+			continue
+		default:
+		}
+		c.mu.Lock()
+		c.cancelled = true
+		c.mu.Unlock()
+		interruptRun(c.proc)
+		<-c.done
+	}
+}
+
+// runServe implements the "serve" subcommand: a gRPC control API starting runs of the
+// main configuration as processes of their own, for external orchestrators.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	listen := fs.String("listen", ":50051", "Address to serve the gRPC control API on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *appCfgFile == "" {
+		return fmt.Errorf("the appCfg flag must be specified")
+	}
+	appCfg, err := loadConfig[MainConfig](*appCfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load main config: %w", err)
+	}
+	if err := appCfg.applyProfile(*profile); err != nil {
+		return err
+	}
+	if err := appCfg.resolveSecrets(); err != nil {
+		return err
+	}
+	if appCfg.ControlToken == "" {
+		return fmt.Errorf("serve needs control_token in the main configuration")
+	}
+
+	lis, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	srv := grpc.NewServer()
+	control := newControlServer(appCfg.ControlToken)
+	RegisterControlServiceServer(srv, control)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	notifyServiceStop(sigs)
+	defer signal.Stop(sigs)
+	defer stopServiceNotify(sigs)
+	go func() {
+		<-sigs
+		log.Info("Stopping the control API; interrupting runs in progress")
+		control.shutdown()
+		srv.GracefulStop()
+	}()
+
+	log.Info("🛰️ Serving the gRPC control API", "address", lis.Addr().String())
+	notifyService("READY=1")
+	defer notifyService("STOPPING=1")
+	return srv.Serve(lis)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TestControlHelperRun is the run process the control server starts in the tests below.
+func TestControlHelperRun(t *testing.T) {
+	behaviour := os.Getenv("CONTROL_HELPER")
+	if behaviour == "" {
+		t.Skip("only run as the control server's run process")
+	}
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	p := openControlPipe()
+	rc := &runControl{runID: "20260301000000", pkg: "PKG", progress: newProgressTracker([]Job{{SolID: "0001", Proc: "GAM"}}, 1), control: newDispatchControl()}
+	stop := p.reportRun(rc)
+	if behaviour == "wait" {
+		<-sigs
+	}
+	p.jobSink(&ExtractionConfig{RunID: rc.runID, PackageName: rc.pkg}).Write(ProcLog{SolID: "0001", Procedure: "GAM", Status: "SUCCESS", Rows: 42})
+	stop()
+	if behaviour == "wait" {
+		os.Exit(130)
+	}
+	os.Exit(0)
+}
+
+func startControlServer(t *testing.T, behaviour string) ControlServiceClient {
+	t.Helper()
+	t.Setenv("CONTROL_HELPER", behaviour)
+	s := newControlServer("s3cret")
+	s.command = func(args ...string) *exec.Cmd {
+		return exec.Command(os.Args[0], append([]string{"-test.run=^TestControlHelperRun$", "--"}, args...)...)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	RegisterControlServiceServer(srv, s)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewControlServiceClient(conn)
+}
+
+func authorized() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer s3cret")
+}
+
+// waitForState polls GetStatus until the run reaches state.
+func waitForState(t *testing.T, client ControlServiceClient, id, state string) *RunStatus {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		st, err := client.GetStatus(context.Background(), &GetStatusRequest{Id: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.State == state {
+			return st
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("run state = %s, want %s", st.State, state)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestControlServerRunsAndStreams(t *testing.T) {
+	client := startControlServer(t, "wait")
+	req := &StartRunRequest{RunConfig: "run.json", Mode: "E"}
+	if _, err := client.StartRun(context.Background(), req); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("StartRun without token: %v", err)
+	}
+	if _, err := client.StartRun(authorized(), &StartRunRequest{RunConfig: "run.json", Mode: "X"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("StartRun with mode X: %v", err)
+	}
+	started, err := client.StartRun(authorized(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := client.StreamJobEvents(context.Background(), &StreamJobEventsRequest{Id: started.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := events.Header(); err != nil {
+		t.Fatal(err)
+	}
+	st := waitForState(t, client, started.Id, runRunning)
+	if st.RunId != "20260301000000" || st.Package != "PKG" || st.TotalJobs != 1 {
+		t.Errorf("status of the running run = %+v", st)
+	}
+
+	if _, err := client.CancelRun(context.Background(), &CancelRunRequest{Id: started.Id}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("CancelRun without token: %v", err)
+	}
+	if _, err := client.CancelRun(authorized(), &CancelRunRequest{Id: started.Id}); err != nil {
+		t.Fatal(err)
+	}
+	if st := waitForState(t, client, started.Id, runCancelled); st.ExitCode != 130 {
+		t.Errorf("exit code of the cancelled run = %d, want 130", st.ExitCode)
+	}
+	ev, err := events.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.SolId != "0001" || ev.Procedure != "GAM" || ev.Rows != 42 || ev.RunId != "20260301000000" {
+		t.Errorf("streamed event = %v", ev)
+	}
+	if _, err := events.Recv(); err != io.EOF {
+		t.Errorf("stream did not end with the run: %v", err)
+	}
+	if _, err := client.GetStatus(context.Background(), &GetStatusRequest{Id: "nope"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetStatus of an unknown run: %v", err)
+	}
+}
+
+func TestControlServerRunSucceeds(t *testing.T) {
+	client := startControlServer(t, "exit")
+	started, err := client.StartRun(authorized(), &StartRunRequest{RunConfig: "run.json", Mode: "I"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := waitForState(t, client, started.Id, runSucceeded)
+	if st.ExitCode != 0 || st.Error != "" || st.RunId != "20260301000000" {
+		t.Errorf("status of the finished run = %+v", st)
+	}
+	if _, err := client.CancelRun(authorized(), &CancelRunRequest{Id: started.Id}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("CancelRun of an ended run: %v", err)
+	}
+}
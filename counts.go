@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// rowCounts holds database row counts per procedure and SOL ID.
+type rowCounts map[string]map[string]int64
+
+func (c rowCounts) total(proc string) int64 {
+	var n int64
+	for _, v := range c[proc] {
+		n += v
+	}
+	return n
+}
+
+// countRows runs SELECT COUNT(*) with the extraction's SOL filter for every procedure/SOL
+// pair, using up to concurrency parallel queries.
+func countRows(ctx context.Context, dbs map[string]*sql.DB, runCfg *ExtractionConfig, sols []string, concurrency int) (rowCounts, error) {
+	stmts := make(map[string]*sql.Stmt)
+	defer func() {
+		for _, s := range stmts {
+			s.Close()
+		}
+	}()
+	for _, proc := range runCfg.Procedures {
+		query, err := runCfg.expandVariables(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", runCfg.sourceObject(proc), runCfg.solFilter()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare count for %s: %w", proc, err)
+		}
+		stmt, err := dbs[runCfg.ProcedureConnections[proc]].PrepareContext(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare count for %s: %w", proc, err)
+		}
+		stmts[proc] = stmt
+	}
+
+	counts := make(rowCounts)
+	for _, proc := range runCfg.Procedures {
+		counts[proc] = make(map[string]int64)
+	}
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	jobs := make(chan Job)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				var n int64
+				err := stmts[job.Proc].QueryRowContext(ctx, runCfg.solArgs(job.SolID)...).Scan(&n)
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = fmt.Errorf("count failed for %s/%s: %w", job.Proc, job.SolID, err)
+				}
+				counts[job.Proc][job.SolID] = n
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, sol := range sols {
+		for _, proc := range runCfg.Procedures {
+			jobs <- Job{SolID: sol, Proc: proc}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return counts, firstErr
+}
+
+// estimatedBytes returns the expected output size for rows records of a fixed-width
+// template, or -1 when the size cannot be known before extraction.
+func estimatedBytes(cfg *ExtractionConfig, cols []ColumnConfig, rows int64) int64 {
+	if cfg.Format != "fixed" {
+		return -1
+	}
+	width := int64(1) // record terminator
+	for _, c := range cols {
+		width += int64(c.Length)
+	}
+	return width * rows
+}
+
+// runCount implements the "count" subcommand, reporting the row volumes a full
+// extraction would produce without extracting anything.
+func runCount(args []string) error {
+	fs := flag.NewFlagSet("count", flag.ContinueOnError)
+	perSol := fs.Bool("per-sol", false, "Report counts for every SOL, not only per procedure")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	appCfg, runCfg, err := loadConfigs()
+	if err != nil {
+		return err
+	}
+	if err := validateConfigs(&appCfg, &runCfg, "E"); err != nil {
+		return err
+	}
+	dirMode, err := parseDirMode(appCfg.DirMode)
+	if err != nil {
+		return err
+	}
+	if err := ensureWritableDir(appCfg.LogFilePath, dirMode); err != nil {
+		return err
+	}
+	templates, err := loadTemplates(&runCfg)
+	if err != nil {
+		return err
+	}
+	sols, err := readSols(appCfg.SolFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read SOL IDs: %w", err)
+	}
+	dbs, err := openDatabases(&appCfg, &runCfg)
+	if err != nil {
+		return err
+	}
+	defer closeDatabases(dbs)
+	if err := runCfg.resolveBusinessDate(context.Background(), dbs[""], *busDate); err != nil {
+		return err
+	}
+	runCfg.environment = appCfg.Environment
+
+	start := time.Now()
+	log.Info("🔢 Counting rows", "procedures", len(runCfg.Procedures), "sols", len(sols))
+	counts, err := countRows(context.Background(), dbs, &runCfg, sols, appCfg.Concurrency)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(appCfg.LogFilePath, runCfg.PackageName+"_count.csv")
+	if err := writeCountReport(path, &runCfg, templates, sols, counts, *perSol); err != nil {
+		return err
+	}
+	var total int64
+	for _, proc := range runCfg.Procedures {
+		n := counts.total(proc)
+		total += n
+		log.Info("Expected rows", "procedure", proc, "rows", n)
+	}
+	log.Info("✅ Count complete", "total_rows", total, "report", path, "duration", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func writeCountReport(path string, runCfg *ExtractionConfig, templates map[string][]ColumnConfig, sols []string, counts rowCounts, perSol bool) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create count report: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"PROCEDURE", "SOL_ID", "ROWS", "EST_BYTES"})
+	record := func(proc, sol string, n int64) {
+		est := "-"
+		if b := estimatedBytes(runCfg, templates[proc], n); b >= 0 {
+			est = strconv.FormatInt(b, 10)
+		}
+		writer.Write([]string{proc, sol, strconv.FormatInt(n, 10), est})
+	}
+
+	procs := append([]string(nil), runCfg.Procedures...)
+	sort.Strings(procs)
+	for _, proc := range procs {
+		if perSol {
+			for _, sol := range sols {
+				record(proc, sol, counts[proc][sol])
+			}
+		}
+		record(proc, "*", counts.total(proc))
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// defaultOpenCursorsWarnPercent is the share of open_cursors warned about when not configured.
+const defaultOpenCursorsWarnPercent = 80
+
+// openCursorsQuery finds the most cursors held by one of the tool's sessions: those of
+// the same user, client machine and program as the monitoring session.
+const openCursorsQuery = `SELECT NVL(MAX(st.value), 0), COUNT(*)
+FROM v$session s
+JOIN v$sesstat st ON st.sid = s.sid
+JOIN v$statname n ON n.statistic# = st.statistic#
+JOIN v$session me ON me.sid = SYS_CONTEXT('USERENV', 'SID')
+WHERE n.name = 'opened cursors current'
+AND s.username = me.username AND s.machine = me.machine AND s.program = me.program`
+
+// cursorMonitor samples the open cursors of each pool's sessions and warns before one of
+// them reaches the database's open_cursors limit and jobs start failing with ORA-01000.
+type cursorMonitor struct {
+	dbs         map[string]*sql.DB
+	warnPercent int
+	limits      map[string]int64
+	disabled    map[string]bool
+}
+
+func newCursorMonitor(dbs map[string]*sql.DB, warnPercent int) *cursorMonitor {
+	if warnPercent <= 0 {
+		warnPercent = defaultOpenCursorsWarnPercent
+	}
+	return &cursorMonitor{dbs: dbs, warnPercent: warnPercent, limits: make(map[string]int64), disabled: make(map[string]bool)}
+}
+
+// nearLimit reports whether open cursors are at or above warnPercent of limit.
+func nearLimit(open, limit int64, warnPercent int) bool {
+	return limit > 0 && open*100 >= limit*int64(warnPercent)
+}
+
+// Check samples every pool once. A pool whose dictionary views cannot be read (no
+// SELECT on v$session/v$sesstat) is reported once and not sampled again.
+func (m *cursorMonitor) Check(ctx context.Context) {
+	names := make([]string, 0, len(m.dbs))
+	for name := range m.dbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if m.disabled[name] {
+			continue
+		}
+		label := name
+		if label == "" {
+			label = "main"
+		}
+		db := m.dbs[name]
+		qctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		if _, ok := m.limits[name]; !ok {
+			var limit int64
+			if err := db.QueryRowContext(qctx, `SELECT TO_NUMBER(value) FROM v$parameter WHERE name = 'open_cursors'`).Scan(&limit); err != nil {
+				cancel()
+				m.disabled[name] = true
+				log.Warn("Cannot read open_cursors, open cursor monitoring disabled", "connection", label, "error", err)
+				continue
+			}
+			m.limits[name] = limit
+		}
+		var open, sessions int64
+		err := db.QueryRowContext(qctx, openCursorsQuery).Scan(&open, &sessions)
+		cancel()
+		if err != nil {
+			m.disabled[name] = true
+			log.Warn("Cannot read session cursors, open cursor monitoring disabled", "connection", label, "error", err)
+			continue
+		}
+		limit := m.limits[name]
+		log.Debug("Open cursors", "connection", label, "max_per_session", open, "sessions", sessions, "open_cursors", limit)
+		if nearLimit(open, limit, m.warnPercent) {
+			log.Warn("⚠️ A session is close to the open_cursors limit", "connection", label, "open", open, "open_cursors", limit,
+				"sessions", sessions)
+			log.Warn("   Lower statement_cache_size or concurrency, or enable lazy_prepare, to avoid ORA-01000", "connection", label)
+		}
+	}
+}
+
+// Run checks the pools every interval until stop is closed.
+func (m *cursorMonitor) Run(ctx context.Context, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.Check(ctx)
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestNearOpenCursorsLimit(t *testing.T) {
+	cases := []struct {
+		open, limit int64
+		pct         int
+		want        bool
+	}{
+		{240, 300, 80, true},
+		{239, 300, 80, false},
+		{300, 300, 100, true},
+		{50, 0, 80, false},
+	}
+	for _, c := range cases {
+		if got := nearLimit(c.open, c.limit, c.pct); got != c.want {
+			t.Errorf("nearLimit(%d, %d, %d) = %v, want %v", c.open, c.limit, c.pct, got, c.want)
+		}
+	}
+}
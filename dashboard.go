@@ -0,0 +1,68 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// dashboardRoutes serves the monitoring page at / and the run summaries in the log
+// directory under /summaries/, so the run can be watched without shell access.
+func (h *healthServer) dashboardRoutes(mux *http.ServeMux, logDir string) {
+	assets, _ := fs.Sub(dashboardFiles, "dashboard")
+	mux.Handle("/", http.FileServer(http.FS(assets)))
+	mux.HandleFunc("/summaries/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/summaries/")
+		if name == "" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(summaryFiles(logDir))
+			return
+		}
+		// Only summary files directly in the log directory are served.
+		if name != filepath.Base(name) || !isSummaryFile(name) {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join(logDir, name))
+	})
+}
+
+// isSummaryFile reports whether name is a per-run or batch summary written to the logs.
+func isSummaryFile(name string) bool {
+	return strings.HasSuffix(name, "_summary.csv") || strings.HasSuffix(name, "_summary.json")
+}
+
+// summaryFiles lists the summary files in dir, newest first.
+func summaryFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return []string{}
+	}
+	type file struct {
+		name string
+		mod  int64
+	}
+	var found []file
+	for _, e := range entries {
+		if e.IsDir() || !isSummaryFile(e.Name()) {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			found = append(found, file{e.Name(), info.ModTime().UnixNano()})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].mod > found[j].mod })
+	names := make([]string, len(found))
+	for i, f := range found {
+		names[i] = f.name
+	}
+	return names
+}
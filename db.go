@@ -0,0 +1,151 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// connectString returns the Oracle connect string: the configured TNS alias or connect
+// descriptor when set, otherwise an Easy Connect string built from host, port and SID.
+func (c *MainConfig) connectString() string {
+	if c.ConnectString != "" {
+		return c.ConnectString
+	}
+	return fmt.Sprintf("%s:%d/%s", c.DBHost, c.DBPort, c.DBSid)
+}
+
+// dataSourceName builds the godror connection parameters for c against connectString.
+func (c *MainConfig) dataSourceName(connectString string) string {
+	user := c.DBUser
+	if c.ProxyUser != "" {
+		// Oracle proxy authentication: authenticate as db_user, open the session as proxy_user.
+		user = fmt.Sprintf("%s[%s]", c.DBUser, c.ProxyUser)
+	}
+	dsn := fmt.Sprintf(`user="%s" password="%s" connectString="%s"`,
+		user, c.DBPassword, connectString)
+	if c.TNSAdmin != "" {
+		dsn += fmt.Sprintf(` configDir="%s"`, c.TNSAdmin)
+	}
+	if c.StatementCacheSize != 0 {
+		dsn += fmt.Sprintf(` stmtCacheSize=%d`, c.StatementCacheSize)
+	}
+	if c.ConsumerGroup != "" {
+		// Sessions join the Resource Manager consumer group once, when they are created.
+		dsn += fmt.Sprintf(` initOnNewConnection=1 onInit="DECLARE old_group VARCHAR2(128); BEGIN DBMS_SESSION.SWITCH_CURRENT_CONSUMER_GROUP('%s', old_group, FALSE); END;"`,
+			strings.ToUpper(c.ConsumerGroup))
+	}
+	names := make([]string, 0, len(c.DriverParams))
+	for name := range c.DriverParams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		dsn += fmt.Sprintf(` %s="%s"`, name, c.DriverParams[name])
+	}
+	return dsn
+}
+
+// openDatabase opens the connection pool sized for the configured concurrency, failing
+// over between endpoints when failover_connect_strings are configured.
+func openDatabase(c *MainConfig) (*sql.DB, error) {
+	var db *sql.DB
+	if len(c.FailoverConnectStrings) > 0 {
+		fc, err := newFailoverConnector(c)
+		if err != nil {
+			return nil, err
+		}
+		db = sql.OpenDB(fc)
+	} else {
+		var err error
+		if db, err = sql.Open("godror", c.dataSourceName(c.connectString())); err != nil {
+			return nil, fmt.Errorf("failed to connect to DB: %w", err)
+		}
+	}
+	db.SetMaxOpenConns(c.Concurrency)
+	db.SetMaxIdleConns(c.Concurrency)
+	db.SetConnMaxLifetime(30 * time.Minute)
+	return db, nil
+}
+
+// connectionConfig returns the settings for the named connection: the main config's
+// credentials with the connection's overrides applied. Endpoint fields are not inherited,
+// so a connection always names its own database. The empty name is the main connection.
+func (c *MainConfig) connectionConfig(name string) (*MainConfig, error) {
+	if name == "" {
+		return c, nil
+	}
+	raw, ok := c.Connections[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown connection %q", name)
+	}
+	cc := *c
+	cc.DBHost, cc.DBPort, cc.DBSid = "", 0, ""
+	cc.ConnectString, cc.FailoverConnectStrings = "", nil
+	// Parameters the connection sets are added to a copy of the main ones.
+	cc.DriverParams = maps.Clone(c.DriverParams)
+	if err := json.Unmarshal(raw, &cc); err != nil {
+		return nil, fmt.Errorf("invalid connection %q: %w", name, err)
+	}
+	return &cc, nil
+}
+
+// openDatabases opens the main connection pool plus one per named connection that the
+// runs' procedures are routed to, keyed by connection name ("" for the main one).
+func openDatabases(appCfg *MainConfig, runCfgs ...*ExtractionConfig) (map[string]*sql.DB, error) {
+	names := map[string]bool{"": true}
+	for _, runCfg := range runCfgs {
+		for _, proc := range runCfg.Procedures {
+			names[runCfg.ProcedureConnections[proc]] = true
+		}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	dbs := make(map[string]*sql.DB)
+	for _, name := range sorted {
+		db, err := openConnection(appCfg, name)
+		if err != nil {
+			closeDatabases(dbs)
+			return nil, err
+		}
+		dbs[name] = db
+	}
+	return dbs, nil
+}
+
+// openConnection opens the pool of a named connection, or the main one for "".
+func openConnection(appCfg *MainConfig, name string) (*sql.DB, error) {
+	cfg, err := appCfg.connectionConfig(name)
+	if err == nil && name != "" {
+		if err = cfg.resolveSecrets(); err != nil {
+			err = fmt.Errorf("connection %q: %w", name, err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if name != "" {
+		log.Info("Opened routed connection", "connection", name, "connect_string", cfg.connectString())
+	}
+	return db, nil
+}
+
+func closeDatabases(dbs map[string]*sql.DB) {
+	for _, db := range dbs {
+		db.Close()
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// bindNames names the bind values of the job statements, in order.
+func (c *ExtractionConfig) bindNames(mode string) []string {
+	names := []string{"SOL_ID"}
+	if mode == "E" && c.BusinessDateColumn != "" || mode == "I" && c.BusinessDateParam {
+		names = append(names, "BUSINESS_DATE")
+	}
+	return names
+}
+
+// describeBinds renders bind values as ":1 SOL_ID='001'", masking the configured ones.
+func (c *ExtractionConfig) describeBinds(mode string, args []any) string {
+	masked := make(map[string]bool, len(c.DebugMaskBinds))
+	for _, name := range c.DebugMaskBinds {
+		masked[strings.ToUpper(name)] = true
+	}
+	names := c.bindNames(mode)
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+		value := fmt.Sprintf("'%v'", arg)
+		if masked[name] {
+			value = "****"
+		}
+		parts[i] = strings.TrimSpace(fmt.Sprintf(":%d %s=%s", i+1, name, value))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writeStatements records the exact text prepared for every statement key.
+func writeStatements(path string, texts map[string]string) error {
+	keys := make([]string, 0, len(texts))
+	for k := range texts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "-- %s\n%s\n/\n\n", k, texts[k])
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
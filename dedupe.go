@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/charmbracelet/log"
+)
+
+// Policies for final files whose content is identical to the previous run's.
+const (
+	UnchangedShip = "ship"
+	UnchangedSkip = "skip"
+	UnchangedLink = "link"
+)
+
+func validUnchangedPolicy(p string) bool {
+	return p == "" || p == UnchangedShip || p == UnchangedSkip || p == UnchangedLink
+}
+
+// dedupes reports whether unchanged outputs are held back from shipping.
+func (c *ExtractionConfig) dedupes() bool {
+	return c.UnchangedOutputPolicy == UnchangedSkip || c.UnchangedOutputPolicy == UnchangedLink
+}
+
+// dedupeOutput compares proc's final file with the previous complete run's by checksum.
+// An unchanged file is marked so it is not shipped again, and under the link policy it
+// is replaced by a symlink to the previous file.
+func (c *ExtractionConfig) dedupeOutput(proc string, out *OutputFile) {
+	if !c.dedupes() || c.previous == nil {
+		return
+	}
+	var prev *procedureRecord
+	for i := range c.previous.Procedures {
+		if c.previous.Procedures[i].Procedure == proc {
+			prev = &c.previous.Procedures[i]
+		}
+	}
+	if prev == nil || prev.Output == "" || prev.SHA256 != out.SHA256 {
+		return
+	}
+	// The previous file may have been removed by retention, or be a link itself.
+	target, err := filepath.EvalSymlinks(prev.Output)
+	if err != nil {
+		return
+	}
+	if target, err = filepath.Abs(target); err != nil {
+		return
+	}
+	out.UnchangedSince = c.previous.RunID
+	if c.UnchangedOutputPolicy != UnchangedLink {
+		log.Info("♻️ Output unchanged since previous run, not shipping it", "procedure", proc, "file", out.Path, "previous_run", out.UnchangedSince)
+		return
+	}
+	if current, err := filepath.EvalSymlinks(out.Path); err == nil {
+		if current, err = filepath.Abs(current); err == nil && current == target {
+			// Same path as last run, or already linked.
+			out.LinkedTo = target
+			return
+		}
+	}
+	if err := linkOutput(out.Path, target); err != nil {
+		log.Warn("Failed to link unchanged output to the previous run's file", "procedure", proc, "file", out.Path, "error", err)
+		return
+	}
+	out.LinkedTo = target
+	log.Info("♻️ Output unchanged since previous run, linked to it", "procedure", proc, "file", out.Path, "target", target, "previous_run", out.UnchangedSince)
+}
+
+// linkOutput replaces the file at path with a symlink to target. The link is created
+// under a temporary name and renamed over the file, so path never goes missing.
+func linkOutput(path, target string) error {
+	tmp := path + ".link"
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace %s with a link: %w", path, err)
+	}
+	return nil
+}
+
+// linkedTargets returns the absolute targets of the symlinks among files, which retention
+// must keep while a link to them survives.
+func linkedTargets(files []string) map[string]bool {
+	targets := make(map[string]bool)
+	for _, f := range files {
+		info, err := os.Lstat(f)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		if target, err := filepath.EvalSymlinks(f); err == nil {
+			if target, err = filepath.Abs(target); err == nil {
+				targets[target] = true
+			}
+		}
+	}
+	return targets
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDedupeOutput(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "GAM_R1.txt")
+	os.WriteFile(old, []byte("a\nb\n"), 0644)
+	prevOut, err := describeOutput(&ExtractionConfig{}, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := &runRecord{RunID: "R1", Procedures: []procedureRecord{{Procedure: "GAM", Output: old, SHA256: prevOut.SHA256}}}
+
+	write := func(name, content string) *OutputFile {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0644)
+		out, err := describeOutput(&ExtractionConfig{}, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	cfg := &ExtractionConfig{UnchangedOutputPolicy: UnchangedSkip, previous: prev}
+	changed := write("GAM_R2.txt", "a\nc\n")
+	cfg.dedupeOutput("GAM", changed)
+	if changed.UnchangedSince != "" {
+		t.Errorf("changed output marked unchanged since %s", changed.UnchangedSince)
+	}
+	skipped := write("GAM_R3.txt", "a\nb\n")
+	cfg.dedupeOutput("GAM", skipped)
+	if skipped.UnchangedSince != "R1" || skipped.LinkedTo != "" {
+		t.Errorf("skip: unchanged since %q, linked to %q, want R1 and no link", skipped.UnchangedSince, skipped.LinkedTo)
+	}
+
+	cfg.UnchangedOutputPolicy = UnchangedLink
+	linked := write("GAM_R4.txt", "a\nb\n")
+	cfg.dedupeOutput("GAM", linked)
+	if info, err := os.Lstat(linked.Path); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("link: %s is not a symlink (%v)", linked.Path, err)
+	}
+	if target, _ := os.Readlink(linked.Path); target != old || linked.LinkedTo != old {
+		t.Errorf("link: target %s, LinkedTo %s, want %s", target, linked.LinkedTo, old)
+	}
+	if got := linkedTargets([]string{old, linked.Path}); !got[old] || len(got) != 1 {
+		t.Errorf("linkedTargets = %v, want only %s", got, old)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Delimiter collision policies for delimited output.
+const (
+	DelimiterPolicyEscape  = "escape"
+	DelimiterPolicyReplace = "replace"
+	DelimiterPolicyFail    = "fail"
+)
+
+func validDelimiterPolicy(p string) bool {
+	return p == "" || p == DelimiterPolicyEscape || p == DelimiterPolicyReplace || p == DelimiterPolicyFail
+}
+
+// strictCSV reports whether the named output, or the procedure it is a view of, is
+// written as strict RFC 4180: CRLF record ends and values quoted as they are, without
+// sanitising line breaks or applying the delimiter policy.
+func (c *ExtractionConfig) strictCSV(name string) bool {
+	return slices.Contains(c.RFC4180, name) || slices.Contains(c.RFC4180, c.sourceProcedure(name))
+}
+
+// delimiterRune returns the field separator for delimited output, defaulting to a comma
+// when the configured delimiter is not a single character.
+func (c *ExtractionConfig) delimiterRune() rune {
+	if len(c.Delimiter) == 1 {
+		return []rune(c.Delimiter)[0]
+	}
+	return ','
+}
+
+// resolveDelimiterCollision applies the configured policy to a value containing the
+// delimiter or a line terminator. It reports whether a collision was found; with the
+// "fail" policy a collision is returned as an error instead.
+func (c *ExtractionConfig) resolveDelimiterCollision(val string) (string, bool, error) {
+	delim := string(c.delimiterRune())
+	if !strings.Contains(val, delim) && !strings.ContainsAny(val, "\r\n") {
+		return val, false, nil
+	}
+
+	switch c.DelimiterPolicy {
+	case DelimiterPolicyEscape:
+		r := strings.NewReplacer(`\`, `\\`, delim, `\`+delim, "\n", `\n`, "\r", `\r`)
+		return r.Replace(val), true, nil
+	case DelimiterPolicyReplace:
+		repl := c.DelimiterReplacement
+		if repl == "" {
+			repl = " "
+		}
+		r := strings.NewReplacer(delim, repl, "\n", repl, "\r", repl)
+		return r.Replace(val), true, nil
+	case DelimiterPolicyFail:
+		return val, true, fmt.Errorf("value contains the delimiter %q or a line terminator", delim)
+	}
+	return val, false, nil
+}
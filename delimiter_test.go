@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDelimiterCollisionPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		policy, repl, in, want string
+		collided, fails        bool
+	}{
+		{DelimiterPolicyEscape, "", "plain", "plain", false, false},
+		{DelimiterPolicyEscape, "", `a|b\c`, `a\|b\\c`, true, false},
+		{DelimiterPolicyEscape, "", "a\r\nb", `a\r\nb`, true, false},
+		{DelimiterPolicyReplace, "", "a|b\nc", "a b c", true, false},
+		{DelimiterPolicyReplace, "_", "a|b", "a_b", true, false},
+		{DelimiterPolicyFail, "", "a,b", "a,b", false, false},
+		{DelimiterPolicyFail, "", "a|b", "a|b", true, true},
+	} {
+		cfg := &ExtractionConfig{Delimiter: "|", DelimiterPolicy: tc.policy, DelimiterReplacement: tc.repl}
+		got, collided, err := cfg.resolveDelimiterCollision(tc.in)
+		if got != tc.want || collided != tc.collided || (err != nil) != tc.fails {
+			t.Errorf("%s(%q) = %q, %v, %v; want %q, %v, error %v", tc.policy, tc.in, got, collided, err, tc.want, tc.collided, tc.fails)
+		}
+		if err != nil && !strings.Contains(err.Error(), `"|"`) {
+			t.Errorf("fail policy error %q does not name the delimiter", err)
+		}
+	}
+
+	// A delimiter that is not a single character falls back to a comma.
+	cfg := &ExtractionConfig{Delimiter: "||", DelimiterPolicy: DelimiterPolicyFail}
+	if _, collided, _ := cfg.resolveDelimiterCollision("a,b"); !collided {
+		t.Error("comma not treated as the delimiter")
+	}
+}
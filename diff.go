@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/charmbracelet/log"
+)
+
+// diffStats summarises the differences between two runs' outputs of one procedure.
+type diffStats struct {
+	OldRows, NewRows                   int64
+	Added, Removed, Changed, Unchanged int64
+}
+
+// outputRecord is one parsed output record: its fields and its text as written.
+type outputRecord struct {
+	fields []string
+	text   string
+}
+
+// readOutputRecords parses a merged output file into records using the procedure template.
+func readOutputRecords(cfg *ExtractionConfig, path string, cols []ColumnConfig, fn func(outputRecord) error) error {
+	f, err := cfg.openRecords(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if cfg.Format == "fixed" {
+		reader := bufio.NewReader(f)
+		for {
+			line, err := reader.ReadString('\n')
+			if line = strings.TrimRight(line, "\r\n"); line != "" {
+				rec := outputRecord{text: line}
+				pos := 0
+				for _, c := range cols {
+					end := min(pos+c.Length, len(line))
+					rec.fields = append(rec.fields, strings.TrimSpace(line[min(pos, end):end]))
+					pos += c.Length
+				}
+				if err := fn(rec); err != nil {
+					return err
+				}
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	reader := csv.NewReader(bufio.NewReader(f))
+	reader.Comma = cfg.delimiterRune()
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if err := fn(outputRecord{fields: fields, text: strings.Join(fields, string(reader.Comma))}); err != nil {
+			return err
+		}
+	}
+}
+
+// recordKey builds the comparison key of rec from the key column indexes, or uses the
+// whole record when no key is configured.
+func recordKey(rec outputRecord, keyIdx []int) string {
+	if len(keyIdx) == 0 {
+		return rec.text
+	}
+	parts := make([]string, len(keyIdx))
+	for i, idx := range keyIdx {
+		if idx < len(rec.fields) {
+			parts[i] = rec.fields[idx]
+		}
+	}
+	return strings.Join(parts, "\x1f")
+}
+
+// diffOutputs compares two output files of a procedure and writes every added, removed
+// and changed record to w.
+func diffOutputs(cfg *ExtractionConfig, proc, oldPath, newPath string, cols []ColumnConfig, keyCols []string, w *csv.Writer) (diffStats, error) {
+	var stats diffStats
+	var keyIdx []int
+	for _, k := range keyCols {
+		idx := -1
+		for i, c := range cols {
+			if strings.EqualFold(c.Name, k) {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			return stats, fmt.Errorf("key column %s is not in the template of %s", k, proc)
+		}
+		keyIdx = append(keyIdx, idx)
+	}
+
+	old := make(map[string][]string)
+	cfg = cfg.forOutput(proc)
+	if err := readOutputRecords(cfg, oldPath, cols, func(rec outputRecord) error {
+		k := recordKey(rec, keyIdx)
+		old[k] = append(old[k], rec.text)
+		stats.OldRows++
+		return nil
+	}); err != nil {
+		return stats, err
+	}
+
+	if err := readOutputRecords(cfg, newPath, cols, func(rec outputRecord) error {
+		stats.NewRows++
+		k := recordKey(rec, keyIdx)
+		prev := old[k]
+		if len(prev) == 0 {
+			stats.Added++
+			return w.Write([]string{proc, "ADDED", k, "", rec.text})
+		}
+		old[k] = prev[1:]
+		if prev[0] == rec.text {
+			stats.Unchanged++
+			return nil
+		}
+		stats.Changed++
+		return w.Write([]string{proc, "CHANGED", k, prev[0], rec.text})
+	}); err != nil {
+		return stats, err
+	}
+
+	keys := make([]string, 0, len(old))
+	for k := range old {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, text := range old[k] {
+			stats.Removed++
+			if err := w.Write([]string{proc, "REMOVED", k, text, ""}); err != nil {
+				return stats, err
+			}
+		}
+	}
+	return stats, nil
+}
+
+// resolveRunOutput finds a procedure's merged output for a run given as either an output
+// directory or a run ID whose outputs are in the spool directory.
+func resolveRunOutput(cfg *ExtractionConfig, run, proc string) (string, error) {
+	if info, err := os.Stat(run); err == nil && info.IsDir() {
+		pattern, err := cfg.outputFileGlob(proc)
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(cfg.outputDir(), pattern)
+		if err != nil {
+			return "", err
+		}
+		matches, err := filepath.Glob(filepath.Join(run, rel))
+		if err != nil {
+			return "", err
+		}
+		if len(matches) == 0 {
+			return "", fmt.Errorf("no output for %s in %s", proc, run)
+		}
+		sort.Strings(matches)
+		return matches[len(matches)-1], nil
+	}
+
+	start, err := timestamps.parseRunID(run)
+	if err != nil {
+		return "", fmt.Errorf("%q is neither a directory nor a run ID", run)
+	}
+	runCfg := *cfg
+	runCfg.RunID, runCfg.RunStart = run, start
+	return runCfg.outputFilePath(proc)
+}
+
+// runDiff implements the "diff" subcommand, comparing the outputs of two extraction runs.
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	oldRun := fs.String("old", "", "Earlier run: output directory or run ID")
+	newRun := fs.String("new", "", "Later run: output directory or run ID")
+	keyFlag := fs.String("key", "", "Comma-separated key columns for all procedures (default diff_keys, else the whole record)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *oldRun == "" || *newRun == "" {
+		return fmt.Errorf("diff requires both -old and -new")
+	}
+
+	appCfg, runCfg, err := loadConfigs()
+	if err != nil {
+		return err
+	}
+	if runCfg.partitioned() {
+		return fmt.Errorf("diff compares merged outputs, but per_sol_output or output_groups is set")
+	}
+	if err := runCfg.compileFileNames(); err != nil {
+		return err
+	}
+	templates, err := loadTemplates(&runCfg)
+	if err != nil {
+		return err
+	}
+
+	diffPath := filepath.Join(appCfg.LogFilePath, runCfg.PackageName+"_diff.csv")
+	file, err := os.Create(diffPath)
+	if err != nil {
+		return fmt.Errorf("failed to create diff report: %w", err)
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"PROCEDURE", "CHANGE", "KEY", "OLD_RECORD", "NEW_RECORD"})
+
+	summary := make(map[string]diffStats)
+	for _, proc := range runCfg.Procedures {
+		keys := runCfg.DiffKeys[proc]
+		if *keyFlag != "" {
+			keys = strings.Split(*keyFlag, ",")
+		}
+		oldPath, err := resolveRunOutput(&runCfg, *oldRun, proc)
+		if err != nil {
+			return err
+		}
+		newPath, err := resolveRunOutput(&runCfg, *newRun, proc)
+		if err != nil {
+			return err
+		}
+		stats, err := diffOutputs(&runCfg, proc, oldPath, newPath, templates[proc], keys, writer)
+		if err != nil {
+			return fmt.Errorf("failed to diff %s: %w", proc, err)
+		}
+		summary[proc] = stats
+		log.Info("Diffed outputs", "procedure", proc, "added", stats.Added, "removed", stats.Removed, "changed", stats.Changed, "unchanged", stats.Unchanged)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+
+	summaryPath := filepath.Join(appCfg.LogFilePath, runCfg.PackageName+"_diff_summary.csv")
+	if err := writeDiffSummary(summaryPath, summary); err != nil {
+		return err
+	}
+	log.Info("✅ Diff complete", "report", diffPath, "summary", summaryPath)
+	return nil
+}
+
+func writeDiffSummary(path string, summary map[string]diffStats) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create diff summary: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"PROCEDURE", "OLD_ROWS", "NEW_ROWS", "ADDED", "REMOVED", "CHANGED", "UNCHANGED"})
+	procs := make([]string, 0, len(summary))
+	for p := range summary {
+		procs = append(procs, p)
+	}
+	sort.Strings(procs)
+	for _, p := range procs {
+		s := summary[p]
+		writer.Write([]string{p,
+			strconv.FormatInt(s.OldRows, 10), strconv.FormatInt(s.NewRows, 10),
+			strconv.FormatInt(s.Added, 10), strconv.FormatInt(s.Removed, 10),
+			strconv.FormatInt(s.Changed, 10), strconv.FormatInt(s.Unchanged, 10)})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiffOutputsByKey(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old.txt")
+	newPath := filepath.Join(dir, "new.txt")
+	os.WriteFile(oldPath, []byte("1|a\n2|b\n3|c\n"), 0644)
+	os.WriteFile(newPath, []byte("1|a\n2|B\n4|d\n"), 0644)
+
+	cfg := &ExtractionConfig{Format: "delimited", Delimiter: "|"}
+	cols := []ColumnConfig{{Name: "ID"}, {Name: "VAL"}}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	stats, err := diffOutputs(cfg, "GAM", oldPath, newPath, cols, []string{"ID"}, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+	want := diffStats{OldRows: 3, NewRows: 3, Added: 1, Removed: 1, Changed: 1, Unchanged: 1}
+	if stats != want {
+		t.Errorf("stats = %+v, want %+v\n%s", stats, want, buf.String())
+	}
+
+	stats, err = diffOutputs(cfg, "GAM", oldPath, newPath, cols, nil, csv.NewWriter(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Added != 2 || stats.Removed != 2 || stats.Changed != 0 {
+		t.Errorf("whole-record diff = %+v, want 2 added, 2 removed", stats)
+	}
+}
+
+func TestInsertStatement(t *testing.T) {
+	got, err := insertStatement("TGT.GAM", []ColumnConfig{{Name: "SOL_ID"}, {Name: "ACID"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "INSERT INTO TGT.GAM (SOL_ID, ACID) VALUES (:1, :2)"; got != want {
+		t.Errorf("insertStatement() = %q, want %q", got, want)
+	}
+	if _, err := insertStatement("GAM", []ColumnConfig{{Name: "NVL(ACID, '-')"}}); err == nil {
+		t.Error("insertStatement accepted an expression column")
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// dispatchControl lets operators pause, resume and cancel the dispatching of new jobs
+// while a run is in progress. Jobs already handed to workers always run to completion.
+type dispatchControl struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	paused    bool
+	cancelled bool
+	memory    *memoryGate
+	// grace, when set, is how long in-flight jobs may run after Cancel before abort
+	// cancels them.
+	grace time.Duration
+	abort context.CancelCauseFunc
+}
+
+func newDispatchControl() *dispatchControl {
+	d := &dispatchControl{}
+	d.cond = sync.NewCond(&d.mu)
+	return d
+}
+
+// Pause stops new jobs from being dispatched until Resume is called.
+func (d *dispatchControl) Pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.paused && !d.cancelled {
+		d.paused = true
+		log.Warn("⏸️ Dispatching paused, in-flight jobs will finish")
+	}
+}
+
+// Resume continues dispatching after Pause.
+func (d *dispatchControl) Resume() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.paused {
+		d.paused = false
+		log.Info("▶️ Dispatching resumed")
+		d.cond.Broadcast()
+	}
+}
+
+// Cancel stops dispatching for good; the run finishes once in-flight jobs complete.
+func (d *dispatchControl) Cancel() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.cancelled {
+		d.cancelled = true
+		log.Warn("🛑 Run cancelled, waiting for in-flight jobs to finish")
+		health.setState(runDraining)
+		d.cond.Broadcast()
+		if d.grace > 0 && d.abort != nil {
+			grace, abort := d.grace, d.abort
+			time.AfterFunc(grace, func() {
+				log.Error("Shutdown grace period expired, cancelling in-flight jobs", "grace", grace)
+				abort(fmt.Errorf("shutdown grace period of %s expired", grace))
+			})
+		}
+	}
+}
+
+// Cancelled reports whether the run was cancelled.
+func (d *dispatchControl) Cancelled() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cancelled
+}
+
+// Paused reports whether dispatching is paused.
+func (d *dispatchControl) Paused() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.paused
+}
+
+// wait blocks while dispatching is paused and reports whether the next job may be dispatched.
+func (d *dispatchControl) wait() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for d.paused && !d.cancelled {
+		d.cond.Wait()
+	}
+	return !d.cancelled
+}
+
+// send feeds jobs to the workers, honouring pause and cancel, and leaves the channel
+// open for the next phase. It returns the number of jobs that were never dispatched.
+func (d *dispatchControl) send(pending []Job, jobs chan<- Job) int {
+	for i, job := range pending {
+		if !d.wait() || !d.memory.waitForHeap(d) {
+			return len(pending) - i
+		}
+		jobs <- job
+	}
+	return 0
+}
+
+// handleSignals maps operator signals onto the control: the platform pause/resume
+// signals, and interrupt/terminate for a graceful cancel. A second interrupt exits at once.
+func (d *dispatchControl) handleSignals(sigs <-chan os.Signal) {
+	for sig := range sigs {
+		switch {
+		case isPauseSignal(sig):
+			d.Pause()
+		case isResumeSignal(sig):
+			d.Resume()
+		default:
+			if d.Cancelled() {
+				log.Error("Received second interrupt, exiting immediately")
+				os.Exit(130)
+			}
+			d.Cancel()
+		}
+	}
+}
+
+// Priority classes for procedures; critical jobs are dispatched first across all SOLs.
+const (
+	PriorityCritical = "critical"
+	PriorityNormal   = "normal"
+	PriorityLow      = "low"
+)
+
+var priorityRank = map[string]int{PriorityCritical: 0, "": 1, PriorityNormal: 1, PriorityLow: 2}
+
+func validPriority(p string) bool {
+	_, ok := priorityRank[p]
+	return ok
+}
+
+// sortByPriority orders jobs by their procedure's priority class, keeping the existing
+// order within a class.
+func sortByPriority(jobs []Job, priorities map[string]string) {
+	if len(priorities) == 0 {
+		return
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return priorityRank[priorities[jobs[i].Proc]] < priorityRank[priorities[jobs[j].Proc]]
+	})
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSortByPriority(t *testing.T) {
+	var jobs []Job
+	for _, sol := range []string{"0001", "0002"} {
+		for _, proc := range []string{"LOW", "GAM", "CRIT", "HTD"} {
+			jobs = append(jobs, Job{SolID: sol, Proc: proc})
+		}
+	}
+	sortByPriority(jobs, map[string]string{"CRIT": PriorityCritical, "LOW": PriorityLow, "HTD": PriorityNormal})
+
+	// Critical jobs of every SOL come first and low ones last; unclassed procedures are
+	// normal, and each class keeps the order the jobs were listed in.
+	var got []string
+	for _, job := range jobs {
+		got = append(got, job.Proc+"/"+job.SolID)
+	}
+	want := []string{"CRIT/0001", "CRIT/0002", "GAM/0001", "HTD/0001", "GAM/0002", "HTD/0002", "LOW/0001", "LOW/0002"}
+	if !slices.Equal(got, want) {
+		t.Errorf("dispatch order = %v, want %v", got, want)
+	}
+}
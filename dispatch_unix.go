@@ -0,0 +1,25 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// notifyControlSignals subscribes to SIGUSR1 (pause), SIGUSR2 (resume) and
+// SIGINT/SIGTERM (graceful cancel).
+func notifyControlSignals(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGUSR1, syscall.SIGUSR2, os.Interrupt, syscall.SIGTERM)
+}
+
+func isPauseSignal(sig os.Signal) bool  { return sig == syscall.SIGUSR1 }
+func isResumeSignal(sig os.Signal) bool { return sig == syscall.SIGUSR2 }
+
+// detachRun puts a run started by the serve command in a process group of its own, so
+// a Ctrl+C of the serve command reaches it once, through the serve command's shutdown.
+func detachRun(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+)
+
+// notifyControlSignals subscribes to Ctrl+C for a graceful cancel. Windows has no
+// user signals, so pause and resume are not available from the console.
+func notifyControlSignals(c chan<- os.Signal) {
+	signal.Notify(c, os.Interrupt)
+}
+
+func isPauseSignal(os.Signal) bool  { return false }
+func isResumeSignal(os.Signal) bool { return false }
+
+// detachRun does nothing on Windows, where the serve command kills its runs on shutdown.
+func detachRun(*exec.Cmd) {}
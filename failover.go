@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+
+	log "github.com/charmbracelet/log"
+	"github.com/godror/godror"
+)
+
+// failoverConnector opens connections against the first reachable of several endpoints
+// (e.g. RAC nodes or a Data Guard standby), sticking with the last one that worked.
+// database/sql discards connections that go bad and asks for new ones, and prepared
+// statements are re-prepared transparently on the new connections, so a node loss only
+// costs the jobs that were in flight, which the workers retry.
+type failoverConnector struct {
+	mu         sync.Mutex
+	endpoints  []string
+	connectors []driver.Connector
+	current    int
+}
+
+func newFailoverConnector(c *MainConfig) (*failoverConnector, error) {
+	fc := &failoverConnector{endpoints: append([]string{c.connectString()}, c.FailoverConnectStrings...)}
+	for _, ep := range fc.endpoints {
+		params, err := godror.ParseDSN(c.dataSourceName(ep))
+		if err != nil {
+			return nil, fmt.Errorf("invalid connection parameters for %s: %w", ep, err)
+		}
+		fc.connectors = append(fc.connectors, godror.NewConnector(params))
+	}
+	return fc, nil
+}
+
+func (fc *failoverConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	fc.mu.Lock()
+	start := fc.current
+	fc.mu.Unlock()
+
+	var errs []error
+	for n := 0; n < len(fc.connectors); n++ {
+		i := (start + n) % len(fc.connectors)
+		conn, err := fc.connectors[i].Connect(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", fc.endpoints[i], err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		fc.mu.Lock()
+		if fc.current != i {
+			log.Warn("🔀 Failed over to database endpoint", "from", fc.endpoints[fc.current], "to", fc.endpoints[i])
+			fc.current = i
+		}
+		fc.mu.Unlock()
+		return conn, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (fc *failoverConnector) Driver() driver.Driver {
+	return fc.connectors[0].Driver()
+}
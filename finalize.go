@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// copyVerified copies src to dst, or appends it when appendTo is set, and re-reads what
+// was written to make sure it matches src byte for byte. A fresh copy is written next to
+// dst and renamed into place, so readers never see a partial file.
+func copyVerified(src, dst string, appendTo bool) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	target, flags := tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC
+	if appendTo {
+		target, flags = dst, os.O_CREATE|os.O_WRONLY|os.O_APPEND
+	}
+	out, err := os.OpenFile(target, flags, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := out.Stat()
+	if err != nil {
+		out.Close()
+		return err
+	}
+	offset := info.Size()
+
+	srcHash := sha256.New()
+	if _, err := io.Copy(out, io.TeeReader(in, srcHash)); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	dstHash, err := hashFrom(target, offset)
+	if err != nil {
+		return fmt.Errorf("failed to verify copy: %w", err)
+	}
+	if !bytes.Equal(srcHash.Sum(nil), dstHash) {
+		return fmt.Errorf("verification of %s failed: checksum mismatch", target)
+	}
+	if !appendTo {
+		return os.Rename(tmp, dst)
+	}
+	return nil
+}
+
+// hashFrom returns the SHA-256 of path from offset to the end.
+func hashFrom(path string, offset int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// appends reports whether the merge of proc, or of a view, adds to its existing final
+// file: always for rolling feeds and their views, and for every procedure when jobs of earlier runs were skipped.
+func (c *ExtractionConfig) appends(proc string) bool {
+	return c.appendOutput || slices.Contains(c.RollingFeeds, c.sourceProcedure(proc))
+}
+
+// prepareAppend readies an existing final file for appending. A file deduplicated into a
+// link to an earlier run's output is first replaced by a copy, so appending never changes
+// the earlier file, and an unterminated last record is terminated with term.
+func prepareAppend(path string, term []byte) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve linked output %s: %w", path, err)
+		}
+		tmp := path + ".unlink"
+		if err := copyVerified(target, tmp, false); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("failed to copy linked output %s: %w", path, err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("failed to replace linked output %s: %w", path, err)
+		}
+	}
+
+	// A compressed output gains a new stream per merge, whose records are terminated.
+	if codecForPath(path) != nil {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil || size == 0 {
+		return err
+	}
+	last := make([]byte, min(int64(len(term)), size))
+	if _, err := f.ReadAt(last, size-int64(len(last))); err != nil {
+		return err
+	}
+	if !bytes.HasSuffix(last, term) {
+		if _, err := f.Write(term); err != nil {
+			return fmt.Errorf("failed to terminate last record of %s: %w", path, err)
+		}
+	}
+	return nil
+}
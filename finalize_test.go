@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCopyVerified(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "merged")
+	dst := filepath.Join(dir, "share", "GAM.txt")
+	os.MkdirAll(filepath.Dir(dst), 0755)
+	os.WriteFile(src, []byte("b\n"), 0644)
+
+	if err := copyVerified(src, dst, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyVerified(src, dst, true); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "b\nb\n" {
+		t.Errorf("final content = %q, want %q", got, "b\nb\n")
+	}
+	if _, err := os.Stat(dst + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary copy left behind: %v", err)
+	}
+}
+
+func TestDescribeOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "GAM.txt")
+	os.WriteFile(path, []byte("a\nb\nc"), 0644)
+
+	out, err := describeOutput(&ExtractionConfig{}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Bytes != 5 || out.Records != 3 {
+		t.Errorf("bytes, records = %d, %d, want 5, 3", out.Bytes, out.Records)
+	}
+	if want := "ea7fb08b7a2dc4619ffb7c7bb38d95a2047935fa165d71b12efd3852a2e6d0cc"; out.SHA256 != want {
+		t.Errorf("sha256 = %s, want %s", out.SHA256, want)
+	}
+}
+
+func TestVerifyOutput(t *testing.T) {
+	dir := t.TempDir()
+	cols := []ColumnConfig{{Name: "ACID", Length: 4}, {Name: "NAME", Length: 3}}
+	cfg := &ExtractionConfig{Format: "fixed"}
+
+	path := filepath.Join(dir, "GAM.txt")
+	os.WriteFile(path, []byte("0001Ann\n0002Zoë\n"), 0644)
+	if n, err := verifyOutput(cfg, path, cols); err != nil || n != 2 {
+		t.Errorf("verifyOutput(fixed) = %d, %v, want 2 records", n, err)
+	}
+	os.WriteFile(path, []byte("0001Ann\n0002Zo\n"), 0644)
+	if _, err := verifyOutput(cfg, path, cols); err == nil {
+		t.Error("verifyOutput accepted a short fixed-width record")
+	}
+
+	cfg = &ExtractionConfig{Format: "delimited", Delimiter: "|"}
+	os.WriteFile(path, []byte("0001|\"A|n\"\n0002\n"), 0644)
+	if n, err := verifyOutput(cfg, path, cols); err == nil || n != 2 {
+		t.Errorf("verifyOutput(delimited) = %d, %v, want a field count error on record 2", n, err)
+	}
+}
+
+func TestWriteTriggerFile(t *testing.T) {
+	dir := t.TempDir()
+	out := &OutputFile{Path: filepath.Join(dir, "GAM.txt"), Records: 42, Bytes: 4200, SHA256: "abc123"}
+	cfg := &ExtractionConfig{RunID: "20260301000000", TriggerFile: TriggerFileConfig{Enabled: true}}
+
+	path, err := writeTriggerFile(cfg, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if path != out.Path+".done" || !strings.Contains(string(data), "records=42\n") || !strings.Contains(string(data), "sha256=abc123\n") {
+		t.Errorf("%s = %q", path, data)
+	}
+
+	cfg.TriggerFile = TriggerFileConfig{Enabled: true, Suffix: ".ok", Template: "{{.File}}|{{.Records}}|{{.SHA256}}\n"}
+	if err := cfg.compileTrigger(); err != nil {
+		t.Fatal(err)
+	}
+	if path, err = writeTriggerFile(cfg, out); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "GAM.txt|42|abc123\n" {
+		t.Errorf("templated trigger = %q", data)
+	}
+}
+
+func TestPrepareAppend(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "FEED_R1.txt")
+	os.WriteFile(old, []byte("a\nb"), 0644)
+	path := filepath.Join(dir, "FEED.txt")
+	if err := os.Symlink(old, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := prepareAppend(path, []byte("\n")); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(path); err != nil || info.Mode()&os.ModeSymlink != 0 {
+		t.Fatalf("%s is still a link (%v)", path, err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "a\nb\n" {
+		t.Errorf("prepared file = %q, want %q", got, "a\nb\n")
+	}
+	if got, _ := os.ReadFile(old); string(got) != "a\nb" {
+		t.Errorf("linked file changed to %q", got)
+	}
+	if err := prepareAppend(filepath.Join(dir, "NEW.txt"), []byte("\n")); err != nil {
+		t.Errorf("prepareAppend of a missing file: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Fsync policies for spool and merged output files: never leaves flushing to the OS,
+// close syncs each file once it is complete, and rows additionally syncs spools every
+// fsync_rows rows so at most that many rows are lost in a crash.
+const (
+	FsyncNever = "never"
+	FsyncClose = "close"
+	FsyncRows  = "rows"
+)
+
+// defaultFsyncRows is the sync interval of the rows policy when fsync_rows is unset.
+const defaultFsyncRows = 100000
+
+func validFsyncPolicy(p string) bool {
+	return p == "" || p == FsyncNever || p == FsyncClose || p == FsyncRows
+}
+
+// fsyncInterval returns the number of rows between spool syncs, or 0 when spools are
+// only synced, if at all, on close.
+func (c *ExtractionConfig) fsyncInterval() int64 {
+	if c.FsyncPolicy != FsyncRows {
+		return 0
+	}
+	if c.FsyncRows > 0 {
+		return int64(c.FsyncRows)
+	}
+	return defaultFsyncRows
+}
+
+// closeOutput closes a spool or merged output file, syncing it first unless the policy
+// is never, so a write error surfaces as a failed job rather than a short file.
+func (c *ExtractionConfig) closeOutput(f *os.File) error {
+	if c.FsyncPolicy != "" && c.FsyncPolicy != FsyncNever {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to sync %s: %w", f.Name(), err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", f.Name(), err)
+	}
+	return nil
+}
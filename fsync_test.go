@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// resultSet is a database/sql connector whose queries return the same rows of one
+// SOL_ID column.
+type resultSet []string
+
+func (r resultSet) Connect(context.Context) (driver.Conn, error) { return r, nil }
+func (r resultSet) Driver() driver.Driver                        { return nil }
+func (r resultSet) Prepare(string) (driver.Stmt, error)          { return r, nil }
+func (r resultSet) Close() error                                 { return nil }
+func (r resultSet) Begin() (driver.Tx, error)                    { return nil, errors.ErrUnsupported }
+func (r resultSet) NumInput() int                                { return -1 }
+func (r resultSet) Exec([]driver.Value) (driver.Result, error)   { return nil, errors.ErrUnsupported }
+func (r resultSet) Query([]driver.Value) (driver.Rows, error)    { return &resultRows{rest: r}, nil }
+
+type resultRows struct{ rest []string }
+
+func (r *resultRows) Columns() []string { return []string{"SOL_ID"} }
+func (r *resultRows) Close() error      { return nil }
+func (r *resultRows) Next(dest []driver.Value) error {
+	if len(r.rest) == 0 {
+		return io.EOF
+	}
+	dest[0], r.rest = r.rest[0], r.rest[1:]
+	return nil
+}
+
+func TestFsyncRowsSyncsFlushedRows(t *testing.T) {
+	db := sql.OpenDB(resultSet{"0001", "0002", "0003", "0004", "0005"})
+	defer db.Close()
+	pool := &sync.Pool{New: func() any { return make([]any, 1) }}
+	cols := []ColumnConfig{{Name: "SOL_ID"}}
+
+	for _, tc := range []struct {
+		policy string
+		rows   int
+		synced []string
+	}{
+		{FsyncRows, 2, []string{"0001\n0002\n", "0001\n0002\n0003\n0004\n"}},
+		{FsyncClose, 2, nil},
+		{FsyncNever, 0, nil},
+	} {
+		rows, err := db.Query("SELECT")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Each sync must see every row written so far, not rows still buffered.
+		var out bytes.Buffer
+		var synced []string
+		syncFile := func() error {
+			synced = append(synced, out.String())
+			return nil
+		}
+		cfg := &ExtractionConfig{Format: "delimited", Delimiter: ",", FsyncPolicy: tc.policy, FsyncRows: tc.rows}
+		stats, err := writeRows(&out, rows, pool, "GAM", "0001", cols, cfg, nil, syncFile, nil)
+		rows.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Rows != 5 || strings.Count(out.String(), "\n") != 5 {
+			t.Errorf("%s: wrote %d rows: %q", tc.policy, stats.Rows, out.String())
+		}
+		if !slices.Equal(synced, tc.synced) {
+			t.Errorf("%s: synced %q, want %q", tc.policy, synced, tc.synced)
+		}
+	}
+
+	if got := (&ExtractionConfig{FsyncPolicy: FsyncRows}).fsyncInterval(); got != defaultFsyncRows {
+		t.Errorf("default fsync interval = %d", got)
+	}
+}
+
+func TestCloseOutputReportsWriteErrors(t *testing.T) {
+	for _, policy := range []string{FsyncNever, FsyncClose} {
+		f, err := os.Create(filepath.Join(t.TempDir(), "GAM.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := &ExtractionConfig{FsyncPolicy: policy}
+		if err := cfg.closeOutput(f); err != nil {
+			t.Errorf("%s: %v", policy, err)
+		}
+		// A file that can no longer be written to, synced or closed fails the job.
+		if err := cfg.closeOutput(f); err == nil {
+			t.Errorf("%s: closing a closed file succeeded", policy)
+		}
+	}
+}
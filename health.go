@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// Run states reported by /readyz.
+const (
+	runStarting = "starting"
+	runRunning  = "running"
+	runDraining = "draining"
+)
+
+// healthServer serves /healthz (the process is alive) and /readyz (the configuration is
+// loaded, the databases answer and the run is not draining) for container orchestrators,
+// next to the control API of controlapi.go and the dashboard of dashboard.go.
+type healthServer struct {
+	srv    *http.Server
+	token  string
+	stream eventStream
+	mu     sync.Mutex
+	dbs    map[string]*sql.DB
+	state  string
+	run    *runControl
+}
+
+// health is the process's health server, nil unless health_listen is set.
+var health *healthServer
+
+// startHealthServer listens on addr in the background; token enables the control API's
+// commands and the dashboard offers the summaries in logDir. The configuration has been
+// loaded by the time it is called.
+func startHealthServer(addr, token, logDir string) *healthServer {
+	h := &healthServer{state: runStarting, token: token}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("/readyz", h.ready)
+	h.controlRoutes(mux)
+	h.dashboardRoutes(mux, logDir)
+	h.srv = &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := h.srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("Health endpoint failed", "address", addr, "error", err)
+		}
+	}()
+	log.Info("🩺 Serving health endpoints", "address", addr)
+	return h
+}
+
+// setDatabases makes /readyz check the connection pools.
+func (h *healthServer) setDatabases(dbs map[string]*sql.DB) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dbs = dbs
+}
+
+// setState records the run state reported by /readyz.
+func (h *healthServer) setState(state string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.state = state
+}
+
+func (h *healthServer) ready(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	dbs, state := h.dbs, h.state
+	h.mu.Unlock()
+
+	checks := map[string]string{"config": "ok", "run": state}
+	ok := state == runRunning
+	if dbs == nil {
+		checks["database"] = "not connected"
+		ok = false
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	for name, db := range dbs {
+		key := "database"
+		if name != "" {
+			key += ":" + name
+		}
+		checks[key] = "ok"
+		if err := db.PingContext(ctx); err != nil {
+			checks[key] = err.Error()
+			ok = false
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]any{"ready": ok, "checks": checks})
+}
+
+// Close stops serving.
+func (h *healthServer) Close() {
+	if h == nil {
+		return
+	}
+	h.stream.closeAll()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	h.srv.Shutdown(ctx)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadinessFollowsRunState(t *testing.T) {
+	h := &healthServer{state: runStarting}
+	probe := func() (int, string) {
+		rec := httptest.NewRecorder()
+		h.ready(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	if code, body := probe(); code != http.StatusServiceUnavailable || !strings.Contains(body, `"database":"not connected"`) {
+		t.Errorf("before connecting: %d %s", code, body)
+	}
+	h.setDatabases(map[string]*sql.DB{})
+	h.setState(runRunning)
+	if code, body := probe(); code != http.StatusOK || !strings.Contains(body, `"ready":true`) {
+		t.Errorf("running: %d %s", code, body)
+	}
+	h.setState(runDraining)
+	if code, body := probe(); code != http.StatusServiceUnavailable || !strings.Contains(body, `"run":"draining"`) {
+		t.Errorf("draining: %d %s", code, body)
+	}
+}
+
+func TestControlAPI(t *testing.T) {
+	h := &healthServer{state: runRunning, token: "s3cret"}
+	mux := http.NewServeMux()
+	h.controlRoutes(mux)
+	control := newDispatchControl()
+	progress := newProgressTracker([]Job{{SolID: "0001", Proc: "GAM"}}, 1)
+	h.setRun(&runControl{runID: "20260301000000", pkg: "PKG", progress: progress, control: control})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if body := rec.Body.String(); !strings.Contains(body, `"run_id":"20260301000000"`) || !strings.Contains(body, `"total_jobs":1`) {
+		t.Errorf("status = %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/cancel", nil))
+	if rec.Code != http.StatusForbidden || control.Cancelled() {
+		t.Fatalf("cancel without token: %d, cancelled %v", rec.Code, control.Cancelled())
+	}
+	req := httptest.NewRequest(http.MethodPost, "/cancel", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted || !control.Cancelled() {
+		t.Errorf("cancel with token: %d, cancelled %v", rec.Code, control.Cancelled())
+	}
+
+	ch := h.stream.subscribe()
+	h.jobSink(&ExtractionConfig{RunID: "20260301000000", PackageName: "PKG"}).Write(ProcLog{SolID: "0001", Procedure: "GAM", Status: "SUCCESS"})
+	if ev := <-ch; ev.SolID != "0001" || ev.Package != "PKG" {
+		t.Errorf("streamed event = %+v", ev)
+	}
+	h.stream.closeAll()
+}
+
+func TestDashboardServesSummariesOnly(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "PKG_extract_summary.csv"), []byte("PROCEDURE\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "PKG_extract.csv"), []byte("SOL_ID\n"), 0644)
+	h := &healthServer{}
+	mux := http.NewServeMux()
+	h.dashboardRoutes(mux, dir)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	if rec := get("/"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<title>gemini_extract</title>") {
+		t.Errorf("dashboard: %d", rec.Code)
+	}
+	if rec := get("/summaries/"); strings.TrimSpace(rec.Body.String()) != `["PKG_extract_summary.csv"]` {
+		t.Errorf("summary list = %s", rec.Body.String())
+	}
+	if rec := get("/summaries/PKG_extract_summary.csv"); rec.Code != http.StatusOK {
+		t.Errorf("summary download: %d", rec.Code)
+	}
+	if rec := get("/summaries/PKG_extract.csv"); rec.Code != http.StatusNotFound {
+		t.Errorf("job log served: %d", rec.Code)
+	}
+}
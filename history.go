@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// historyFile is the run history kept in the log directory: one JSON line per run of a
+// package, appended when the run finishes.
+const historyFile = "run_history.jsonl"
+
+// runRecord is one run of a package in the history.
+type runRecord struct {
+	RunID        string            `json:"run_id"`
+	Package      string            `json:"package"`
+	Mode         string            `json:"mode"`
+	BusinessDate string            `json:"business_date"`
+	Start        time.Time         `json:"start"`
+	End          time.Time         `json:"end"`
+	Procedures   []procedureRecord `json:"procedures"`
+}
+
+// procedureRecord is one procedure's totals within a run.
+type procedureRecord struct {
+	Procedure string  `json:"procedure"`
+	Status    string  `json:"status"`
+	Seconds   float64 `json:"seconds"`
+	Rows      int64   `json:"rows"`
+	Failures  int64   `json:"failures"`
+	Output    string  `json:"output,omitempty"`
+	SHA256    string  `json:"sha256,omitempty"`
+}
+
+func newRunRecord(cfg *ExtractionConfig, mode string, end time.Time, summary map[string]ProcSummary) runRecord {
+	rec := runRecord{RunID: cfg.RunID, Package: cfg.PackageName, Mode: mode, BusinessDate: cfg.BusinessDate.Format(businessDateLayout), Start: cfg.RunStart, End: end}
+	for proc, s := range summary {
+		var failures int64
+		for _, n := range s.FailuresByCode {
+			failures += n
+		}
+		p := procedureRecord{Procedure: proc, Status: s.Status, Seconds: s.EndTime.Sub(s.StartTime).Seconds(), Rows: s.Rows, Failures: failures}
+		if s.Output != nil {
+			p.Output, p.SHA256 = s.Output.Path, s.Output.SHA256
+		}
+		rec.Procedures = append(rec.Procedures, p)
+	}
+	sort.Slice(rec.Procedures, func(i, j int) bool { return rec.Procedures[i].Procedure < rec.Procedures[j].Procedure })
+	return rec
+}
+
+// appendRunHistory adds rec to the history file at path.
+func appendRunHistory(path string, rec runRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open run history %s: %w", path, err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write run history %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// readRunHistory returns the recorded runs of pkg in mode, oldest first. A missing
+// history file is an empty history.
+func readRunHistory(path, pkg, mode string) ([]runRecord, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var runs []runRecord
+	reader := bufio.NewReader(f)
+	for lineNo := 1; ; lineNo++ {
+		line, readErr := reader.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var rec runRecord
+			if err := json.Unmarshal(line, &rec); err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", path, lineNo, err)
+			}
+			if rec.Package == pkg && rec.Mode == mode {
+				runs = append(runs, rec)
+			}
+		}
+		if readErr != nil {
+			break
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Start.Before(runs[j].Start) })
+	return runs, nil
+}
+
+// procedureTrend compares a procedure's latest run against the average of the runs
+// before it.
+type procedureTrend struct {
+	Procedure  string
+	Seconds    float64
+	AvgSeconds float64
+	Rows       int64
+	AvgRows    float64
+	Baseline   int // earlier runs averaged
+}
+
+// deviation returns the relative change of value against avg in percent, or 0 without
+// a baseline.
+func deviation(value, avg float64) float64 {
+	if avg == 0 {
+		if value == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (value - avg) / avg * 100
+}
+
+func (t procedureTrend) durationDeviation() float64 { return deviation(t.Seconds, t.AvgSeconds) }
+func (t procedureTrend) volumeDeviation() float64   { return deviation(float64(t.Rows), t.AvgRows) }
+
+// trends compares every procedure of the last run with its rolling average over up to
+// window earlier runs in which it succeeded.
+func trends(runs []runRecord, window int) []procedureTrend {
+	if len(runs) == 0 {
+		return nil
+	}
+	last := runs[len(runs)-1]
+	var out []procedureTrend
+	for _, p := range last.Procedures {
+		t := procedureTrend{Procedure: p.Procedure, Seconds: p.Seconds, Rows: p.Rows}
+		for i := len(runs) - 2; i >= 0 && t.Baseline < window; i-- {
+			for _, q := range runs[i].Procedures {
+				if q.Procedure == p.Procedure && q.Status == "SUCCESS" {
+					t.AvgSeconds += q.Seconds
+					t.AvgRows += float64(q.Rows)
+					t.Baseline++
+				}
+			}
+		}
+		if t.Baseline > 0 {
+			t.AvgSeconds /= float64(t.Baseline)
+			t.AvgRows /= float64(t.Baseline)
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// runHistory implements the history command, showing how the last run of the package
+// compares with its earlier runs and flagging procedures whose duration or volume
+// deviated by more than -threshold percent.
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	mode := fs.String("mode", "E", "Mode of the runs to compare: E (extract) or I (insert)")
+	window := fs.Int("runs", 10, "Number of earlier runs the rolling average covers")
+	threshold := fs.Float64("threshold", 50, "Deviation from the rolling average, in percent, that flags a procedure")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *window < 1 || *threshold <= 0 {
+		return fmt.Errorf("-runs and -threshold must be positive")
+	}
+
+	appCfg, runCfg, err := loadConfigs()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(appCfg.LogFilePath, historyFile)
+	runs, err := readRunHistory(path, runCfg.PackageName, *mode)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		return fmt.Errorf("no %s runs of package %s recorded in %s", *mode, runCfg.PackageName, path)
+	}
+	last := runs[len(runs)-1]
+	fmt.Printf("Package %s, run %s (business date %s), %d earlier run(s) on record\n\n", last.Package, last.RunID, last.BusinessDate, len(runs)-1)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROCEDURE\tSECONDS\tAVG_SECONDS\tDURATION_%\tROWS\tAVG_ROWS\tVOLUME_%\tFLAG")
+	var flagged int
+	for _, t := range trends(runs, *window) {
+		if t.Baseline == 0 {
+			fmt.Fprintf(w, "%s\t%.1f\t-\t-\t%d\t-\t-\tno baseline\n", t.Procedure, t.Seconds, t.Rows)
+			continue
+		}
+		var flags []string
+		if math.Abs(t.durationDeviation()) > *threshold {
+			flags = append(flags, "duration")
+		}
+		if math.Abs(t.volumeDeviation()) > *threshold {
+			flags = append(flags, "volume")
+		}
+		if len(flags) > 0 {
+			flagged++
+		}
+		fmt.Fprintf(w, "%s\t%.1f\t%.1f\t%+.0f\t%d\t%.0f\t%+.0f\t%s\n", t.Procedure, t.Seconds, t.AvgSeconds, t.durationDeviation(), t.Rows, t.AvgRows, t.volumeDeviation(), strings.Join(flags, ","))
+	}
+	w.Flush()
+	if flagged > 0 {
+		fmt.Printf("\n%d procedure(s) deviated more than %g%% from the average of up to %d earlier runs\n", flagged, *threshold, *window)
+	}
+	return nil
+}
+
+// Volume alert policies decide what a procedure whose row count moved too far from the
+// previous run does to the run.
+const (
+	VolumeAlertWarn = "warn"
+	VolumeAlertFail = "fail"
+)
+
+func validVolumeAlertPolicy(p string) bool {
+	return p == "" || p == VolumeAlertWarn || p == VolumeAlertFail
+}
+
+// checkVolumeAnomalies compares every successful procedure's row count with the previous
+// complete run of the package and warns, or fails under the fail policy, when it differs
+// by more than volume_alert_percent, catching upstream truncation or missing partitions
+// before the files are picked up.
+func checkVolumeAnomalies(cfg *ExtractionConfig, previous *runRecord, summary map[string]ProcSummary) error {
+	if cfg.VolumeAlertPercent <= 0 || previous == nil {
+		return nil
+	}
+	before := make(map[string]procedureRecord, len(previous.Procedures))
+	for _, p := range previous.Procedures {
+		before[p.Procedure] = p
+	}
+
+	var anomalies []string
+	procs := make([]string, 0, len(summary))
+	for proc := range summary {
+		procs = append(procs, proc)
+	}
+	sort.Strings(procs)
+	for _, proc := range procs {
+		s := summary[proc]
+		prev, ok := before[proc]
+		if !ok || s.Status != "SUCCESS" || prev.Status != "SUCCESS" {
+			continue
+		}
+		d := deviation(float64(s.Rows), float64(prev.Rows))
+		if math.Abs(d) <= cfg.VolumeAlertPercent {
+			continue
+		}
+		log.Warn("📉 Row count deviates from previous run", "procedure", proc, "rows", s.Rows, "previous_rows", prev.Rows, "previous_run", previous.RunID, "change_percent", fmt.Sprintf("%+.1f", d))
+		anomalies = append(anomalies, fmt.Sprintf("%s (%d -> %d rows)", proc, prev.Rows, s.Rows))
+	}
+	if len(anomalies) > 0 && cfg.VolumeAlertPolicy == VolumeAlertFail {
+		return fmt.Errorf("row counts deviate more than %g%% from run %s: %s", cfg.VolumeAlertPercent, previous.RunID, strings.Join(anomalies, ", "))
+	}
+	return nil
+}
+
+// previousRun returns the last recorded complete run of the package in mode, or nil.
+func previousRun(logPath, pkg, mode string) *runRecord {
+	runs, err := readRunHistory(filepath.Join(logPath, historyFile), pkg, mode)
+	if err != nil {
+		log.Warn("Failed to read run history", "error", err)
+		return nil
+	}
+	if len(runs) == 0 {
+		return nil
+	}
+	return &runs[len(runs)-1]
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	log "github.com/charmbracelet/log"
+)
+
+// runInit implements the "init" subcommand, which writes commented example configuration
+// files (and optionally JSON Schemas for editor validation) for new adopters.
+func runInit(args []string) error {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	dir := fs.String("dir", ".", "Directory to write the example files to")
+	schema := fs.Bool("schema", false, "Also write JSON Schema files for both configurations")
+	force := fs.Bool("force", false, "Overwrite existing files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files := map[string][]byte{
+		"app_config.example.json":        exampleConfig(reflect.TypeOf(MainConfig{})),
+		"extraction_config.example.json": exampleConfig(reflect.TypeOf(ExtractionConfig{})),
+	}
+	if *schema {
+		for name, s := range map[string]map[string]any{
+			"app_config.schema.json":        configSchema(reflect.TypeOf(MainConfig{}), "gemini_extract main configuration"),
+			"extraction_config.schema.json": configSchema(reflect.TypeOf(ExtractionConfig{}), "gemini_extract extraction configuration"),
+		} {
+			data, err := json.MarshalIndent(s, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode schema %s: %w", name, err)
+			}
+			files[name] = append(data, '\n')
+		}
+	}
+
+	if err := os.MkdirAll(*dir, defaultDirMode); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", *dir, err)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(*dir, name)
+		if _, err := os.Stat(path); err == nil && !*force {
+			return fmt.Errorf("%s already exists, use -force to overwrite", path)
+		}
+	}
+	for _, name := range names {
+		path := filepath.Join(*dir, name)
+		if err := os.WriteFile(path, files[name], 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		log.Info("📝 Wrote file", "path", path)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// solChunkSize is how many SOL IDs are grouped into one selectable chunk.
+const solChunkSize = 50
+
+// errSelectionCancelled is returned when the operator leaves the selection screen.
+var errSelectionCancelled = errors.New("selection cancelled")
+
+// stdinIsTerminal reports whether stdin is attached to a terminal.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// checkItem is one toggleable line of the selection screen.
+type checkItem struct {
+	label   string
+	chunk   bool // a SOL chunk rather than a procedure
+	index   int  // into the procedures or SOL chunks
+	checked bool
+}
+
+// selectionModel is the bubbletea checklist of procedures and SOL chunks shown before
+// dispatch. Everything starts selected, as a plain run would.
+type selectionModel struct {
+	items     []checkItem
+	cursor    int
+	message   string
+	done      bool
+	cancelled bool
+}
+
+func newSelectionModel(procs []string, chunks [][]string) selectionModel {
+	var m selectionModel
+	for i, p := range procs {
+		m.items = append(m.items, checkItem{label: p, index: i, checked: true})
+	}
+	for i, c := range chunks {
+		m.items = append(m.items, checkItem{label: fmt.Sprintf("%s .. %s (%d SOLs)", c[0], c[len(c)-1], len(c)), chunk: true, index: i, checked: true})
+	}
+	return m
+}
+
+func (m selectionModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m selectionModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	m.message = ""
+	switch key.String() {
+	case "up", "k":
+		m.cursor = max(m.cursor-1, 0)
+	case "down", "j":
+		m.cursor = min(m.cursor+1, len(m.items)-1)
+	case " ", "x":
+		m.items[m.cursor].checked = !m.items[m.cursor].checked
+	case "a":
+		// Toggle the whole section under the cursor: all on, or all off when all are on.
+		section := m.items[m.cursor].chunk
+		all := true
+		for _, it := range m.items {
+			if it.chunk == section && !it.checked {
+				all = false
+			}
+		}
+		for i := range m.items {
+			if m.items[i].chunk == section {
+				m.items[i].checked = !all
+			}
+		}
+	case "enter":
+		procs, chunks := m.counts()
+		if procs == 0 || chunks == 0 {
+			m.message = "select at least one procedure and one SOL chunk"
+			return m, nil
+		}
+		m.done = true
+		return m, tea.Quit
+	case "q", "esc", "ctrl+c":
+		m.cancelled = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// counts returns how many procedures and SOL chunks are selected.
+func (m selectionModel) counts() (procs, chunks int) {
+	for _, it := range m.items {
+		switch {
+		case it.checked && it.chunk:
+			chunks++
+		case it.checked:
+			procs++
+		}
+	}
+	return procs, chunks
+}
+
+func (m selectionModel) View() string {
+	if m.done || m.cancelled {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintln(&b, titleStyle.Render("Choose what to run"))
+	for i, it := range m.items {
+		if i == 0 || it.chunk != m.items[i-1].chunk {
+			heading := "Procedures"
+			if it.chunk {
+				heading = "SOL chunks"
+			}
+			fmt.Fprintln(&b, "\n"+titleStyle.Render(heading))
+		}
+		cursor, box := "  ", "[ ]"
+		if i == m.cursor {
+			cursor = "> "
+		}
+		if it.checked {
+			box = "[x]"
+		}
+		fmt.Fprintf(&b, "%s%s %s\n", cursor, box, it.label)
+	}
+	procs, chunks := m.counts()
+	fmt.Fprintf(&b, "\n%d procedures and %d SOL chunks selected\n", procs, chunks)
+	if m.message != "" {
+		fmt.Fprintln(&b, failStyle.Render(m.message))
+	}
+	fmt.Fprint(&b, dimStyle.Render("↑/↓ move • space toggle • a toggle section • enter run • q quit"))
+	return b.String()
+}
+
+// selection returns the chosen procedures and the SOLs of the chosen chunks.
+func (m selectionModel) selection(procs []string, chunks [][]string) (selProcs, selSols []string) {
+	for _, it := range m.items {
+		switch {
+		case it.checked && it.chunk:
+			selSols = append(selSols, chunks[it.index]...)
+		case it.checked:
+			selProcs = append(selProcs, procs[it.index])
+		}
+	}
+	return selProcs, selSols
+}
+
+// selectInteractively lets the operator toggle which procedures and SOL chunks to run on
+// a checklist screen before dispatch begins. The screen is drawn on out so stdout stays
+// free for data.
+func selectInteractively(procs, sols []string, in io.Reader, out io.Writer) ([]string, []string, error) {
+	if len(procs) == 0 || len(sols) == 0 {
+		return nil, nil, fmt.Errorf("nothing to select: %d procedures and %d SOLs configured", len(procs), len(sols))
+	}
+	var chunks [][]string
+	for start := 0; start < len(sols); start += solChunkSize {
+		end := min(start+solChunkSize, len(sols))
+		chunks = append(chunks, sols[start:end])
+	}
+
+	final, err := tea.NewProgram(newSelectionModel(procs, chunks), tea.WithInput(in), tea.WithOutput(out)).Run()
+	if err != nil {
+		return nil, nil, fmt.Errorf("selection screen failed: %w", err)
+	}
+	m := final.(selectionModel)
+	if !m.done {
+		return nil, nil, errSelectionCancelled
+	}
+	selProcs, selSols := m.selection(procs, chunks)
+	fmt.Fprintf(out, "Selected %d procedures and %d SOLs (%d jobs).\n", len(selProcs), len(selSols), len(selProcs)*len(selSols))
+	return selProcs, selSols, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSelectionChecklist(t *testing.T) {
+	procs := []string{"GAM", "HTD", "EAB"}
+	chunks := [][]string{{"0001", "0002"}, {"0003"}}
+	var m tea.Model = newSelectionModel(procs, chunks)
+	press := func(keys ...tea.KeyMsg) {
+		for _, k := range keys {
+			m, _ = m.Update(k)
+		}
+	}
+	down := tea.KeyMsg{Type: tea.KeyDown}
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+
+	// Untick HTD and the first SOL chunk.
+	press(down, space, down, down, space, enter)
+	sel := m.(selectionModel)
+	if !sel.done {
+		t.Fatal("enter should confirm the selection")
+	}
+	gotProcs, gotSols := sel.selection(procs, chunks)
+	if !reflect.DeepEqual(gotProcs, []string{"GAM", "EAB"}) || !reflect.DeepEqual(gotSols, []string{"0003"}) {
+		t.Errorf("selection = %v %v, want [GAM EAB] [0003]", gotProcs, gotSols)
+	}
+}
+
+func TestSelectionNeedsProcedureAndChunk(t *testing.T) {
+	var m tea.Model = newSelectionModel([]string{"GAM"}, [][]string{{"0001"}})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.(selectionModel).done || cmd != nil || m.(selectionModel).message == "" {
+		t.Error("enter with no procedure selected should be refused")
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if !m.(selectionModel).cancelled {
+		t.Error("esc should leave the selection screen")
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godror/godror"
+)
+
+// Job failures are classified by type so retries, summaries and the exit code can branch
+// on the kind of failure rather than on its message.
+
+// TemplateError means a procedure's column template is missing or does not match its
+// result set. Like the other job errors it reads as the message it wraps.
+type TemplateError struct {
+	Proc string
+	Err  error
+}
+
+func (e *TemplateError) Error() string { return e.Err.Error() }
+
+func (e *TemplateError) Unwrap() error { return e.Err }
+
+// QueryError is a failure reported by the database while running or fetching a job's
+// statement. ORACode is the ORA- code when there is one.
+type QueryError struct {
+	Proc    string
+	ORACode string
+	Err     error
+}
+
+func newQueryError(proc string, err error) *QueryError {
+	code := oraCode(err.Error())
+	if oerr, ok := godror.AsOraErr(err); ok && oerr.Code() != 0 {
+		code = fmt.Sprintf("ORA-%05d", oerr.Code())
+	}
+	if code == "OTHER" {
+		code = ""
+	}
+	return &QueryError{Proc: proc, ORACode: code, Err: err}
+}
+
+func (e *QueryError) Error() string { return e.Err.Error() }
+
+func (e *QueryError) Unwrap() error { return e.Err }
+
+// WriteError is a failure writing, syncing or closing a procedure's spool or output file.
+type WriteError struct {
+	Proc string
+	Err  error
+}
+
+func (e *WriteError) Error() string { return e.Err.Error() }
+
+func (e *WriteError) Unwrap() error { return e.Err }
+
+// TimeoutError means a job was cancelled for running longer than the stall threshold.
+type TimeoutError struct {
+	After time.Duration
+	Err   error
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("cancelled after exceeding stall threshold of %s", e.After)
+	}
+	return fmt.Sprintf("%v (cancelled after exceeding stall threshold of %s)", e.Err, e.After)
+}
+
+func (e *TimeoutError) Unwrap() error { return e.Err }
+
+// errorCode is the key a failure is counted under in summaries: TIMEOUT for stalled jobs,
+// the ORA- code of database errors, otherwise the failure's class.
+func errorCode(err error) string {
+	var to *TimeoutError
+	if errors.As(err, &to) {
+		return "TIMEOUT"
+	}
+	var qe *QueryError
+	if errors.As(err, &qe) && qe.ORACode != "" {
+		return qe.ORACode
+	}
+	if code := oraCode(err.Error()); code != "OTHER" {
+		return code
+	}
+	var te *TemplateError
+	var we *WriteError
+	switch {
+	case errors.As(err, &te):
+		return "TEMPLATE"
+	case errors.As(err, &we):
+		return "WRITE"
+	}
+	return "OTHER"
+}
+
+// Exit codes by failure class; any other error exits with 1.
+const (
+	exitTemplate = 3
+	exitQuery    = 4
+	exitWrite    = 5
+	exitTimeout  = 6
+)
+
+func exitCode(err error) int {
+	var te *TemplateError
+	var qe *QueryError
+	var we *WriteError
+	var to *TimeoutError
+	switch {
+	case errors.As(err, &to):
+		return exitTimeout
+	case errors.As(err, &te):
+		return exitTemplate
+	case errors.As(err, &we):
+		return exitWrite
+	case errors.As(err, &qe):
+		return exitQuery
+	}
+	return 1
+}
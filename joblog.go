@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// JobLogConfig selects where the per-job records of a run are written: the CSV log
+// (default), a JSON Lines file next to it, and/or a database table. Rerun decides what
+// a rerun does to the previous run's log files.
+type JobLogConfig struct {
+	Sinks []string `json:"sinks"`
+	Table string   `json:"table"`
+	Rerun string   `json:"rerun"`
+}
+
+// Rerun policies for log files: overwrite replaces the previous run's job log and
+// summaries, append adds to the job logs with a RUN_ID column, and suffix names every
+// log and summary after its run ID.
+const (
+	RerunOverwrite = "overwrite"
+	RerunAppend    = "append"
+	RerunSuffix    = "suffix"
+)
+
+func validRerunPolicy(p string) bool {
+	return p == "" || p == RerunOverwrite || p == RerunAppend || p == RerunSuffix
+}
+
+// Job log sink names.
+const (
+	JobLogCSV   = "csv"
+	JobLogJSONL = "jsonl"
+	JobLogDB    = "db"
+)
+
+var qualifiedIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$#]*(\.[A-Za-z_][A-Za-z0-9_$#]*)?$`)
+
+func (c JobLogConfig) sinks() []string {
+	if len(c.Sinks) == 0 {
+		return []string{JobLogCSV}
+	}
+	return c.Sinks
+}
+
+// LogSink receives the record of every finished job.
+type LogSink interface {
+	Write(plog ProcLog) error
+	Close() error
+}
+
+// openLogSinks opens the configured sinks for a run whose CSV log is csvPath. A sink
+// that cannot be opened is reported and left out rather than failing the run.
+func openLogSinks(appCfg *MainConfig, runCfg *ExtractionConfig, db *sql.DB, csvPath string) []LogSink {
+	var sinks []LogSink
+	for _, name := range appCfg.JobLog.sinks() {
+		var sink LogSink
+		var err error
+		switch name {
+		case JobLogCSV:
+			sink, err = newCSVLogSink(csvPath, appCfg.LogRotation, runCfg.RunID, appCfg.JobLog.Rerun == RerunAppend)
+		case JobLogJSONL:
+			sink, err = newJSONLogSink(strings.TrimSuffix(csvPath, ".csv")+".jsonl", runCfg, appCfg.JobLog.Rerun == RerunAppend)
+		case JobLogDB:
+			sink, err = newDBLogSink(db, appCfg.JobLog.Table, runCfg)
+		}
+		if err != nil {
+			log.Errorf("Failed to open %s job log, it will not be written: %v", name, err)
+			continue
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks
+}
+
+// csvLogSink writes the rotating CSV job log, or in append mode a log shared by every
+// run with the run ID in its first column.
+type csvLogSink struct {
+	w     *rotatingLog
+	runID string
+}
+
+func newCSVLogSink(path string, rot LogRotationConfig, runID string, appendRuns bool) (*csvLogSink, error) {
+	header := []string{"SOL_ID", "PROCEDURE", "START_TIME", "END_TIME", "EXECUTION_SECONDS", "STATUS", "ERROR_DETAILS"}
+	if appendRuns {
+		w, err := newAppendingLog(path, append([]string{"RUN_ID"}, header...))
+		if err != nil {
+			return nil, err
+		}
+		return &csvLogSink{w: w, runID: runID}, nil
+	}
+	w, err := newRotatingLog(path, header, rot)
+	if err != nil {
+		return nil, err
+	}
+	return &csvLogSink{w: w}, nil
+}
+
+func (s *csvLogSink) Write(plog ProcLog) error {
+	errDetails := plog.ErrorDetails
+	if errDetails == "" {
+		errDetails = "-"
+	}
+	record := []string{
+		plog.SolID,
+		plog.Procedure,
+		timestamps.format(plog.StartTime),
+		timestamps.format(plog.EndTime),
+		fmt.Sprintf("%.3f", plog.ExecutionTime.Seconds()),
+		plog.Status,
+		errDetails,
+	}
+	if s.runID != "" {
+		record = append([]string{s.runID}, record...)
+	}
+	return s.w.Write(record, plog.StartTime, plog.EndTime)
+}
+
+func (s *csvLogSink) Close() error { return s.w.Close() }
+
+// jobEvent is the JSON form of a job record, written by the jsonl sink and streamed by
+// the control API.
+type jobEvent struct {
+	RunID            string    `json:"run_id"`
+	Package          string    `json:"package"`
+	SolID            string    `json:"sol_id"`
+	Procedure        string    `json:"procedure"`
+	StartTime        time.Time `json:"start_time"`
+	EndTime          time.Time `json:"end_time"`
+	ExecutionSeconds float64   `json:"execution_seconds"`
+	Status           string    `json:"status"`
+	Rows             int64     `json:"rows"`
+	BadRecords       int64     `json:"bad_records"`
+	ErrorCode        string    `json:"error_code,omitempty"`
+	ErrorDetails     string    `json:"error_details,omitempty"`
+}
+
+func newJobEvent(runID, pkg string, plog ProcLog) jobEvent {
+	return jobEvent{runID, pkg, plog.SolID, plog.Procedure, plog.StartTime, plog.EndTime, plog.ExecutionTime.Seconds(), plog.Status, plog.Rows, plog.BadRecords, plog.ErrorCode, plog.ErrorDetails}
+}
+
+// jsonLogSink writes one JSON object per job.
+type jsonLogSink struct {
+	f     *os.File
+	enc   *json.Encoder
+	runID string
+	pkg   string
+}
+
+func newJSONLogSink(path string, runCfg *ExtractionConfig, appendRuns bool) (*jsonLogSink, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendRuns {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonLogSink{f: f, enc: json.NewEncoder(f), runID: runCfg.RunID, pkg: runCfg.PackageName}, nil
+}
+
+func (s *jsonLogSink) Write(plog ProcLog) error {
+	return s.enc.Encode(newJobEvent(s.runID, s.pkg, plog))
+}
+
+func (s *jsonLogSink) Close() error { return s.f.Close() }
+
+// dbLogSink inserts one row per job into a table with the columns RUN_ID, PACKAGE_NAME,
+// SOL_ID, PROCEDURE_NAME, START_TIME, END_TIME, EXECUTION_SECONDS, STATUS, ROWS_WRITTEN
+// and ERROR_DETAILS, committing each row.
+type dbLogSink struct {
+	stmt  *sql.Stmt
+	runID string
+	pkg   string
+}
+
+// maxErrorDetails fits error details into a VARCHAR2(4000) column.
+const maxErrorDetails = 4000
+
+func newDBLogSink(db *sql.DB, table string, runCfg *ExtractionConfig) (*dbLogSink, error) {
+	if !qualifiedIdentifier.MatchString(table) {
+		return nil, fmt.Errorf("invalid job_log.table %q", table)
+	}
+	stmt, err := db.PrepareContext(context.Background(), fmt.Sprintf(
+		"INSERT INTO %s (RUN_ID, PACKAGE_NAME, SOL_ID, PROCEDURE_NAME, START_TIME, END_TIME, EXECUTION_SECONDS, STATUS, ROWS_WRITTEN, ERROR_DETAILS) VALUES (:1, :2, :3, :4, :5, :6, :7, :8, :9, :10)", table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare insert into %s: %w", table, err)
+	}
+	return &dbLogSink{stmt: stmt, runID: runCfg.RunID, pkg: runCfg.PackageName}, nil
+}
+
+func (s *dbLogSink) Write(plog ProcLog) error {
+	details := plog.ErrorDetails
+	if len(details) > maxErrorDetails {
+		details = details[:maxErrorDetails]
+	}
+	_, err := s.stmt.Exec(s.runID, s.pkg, plog.SolID, plog.Procedure, plog.StartTime, plog.EndTime, plog.ExecutionTime.Seconds(), plog.Status, plog.Rows, details)
+	return err
+}
+
+func (s *dbLogSink) Close() error { return s.stmt.Close() }
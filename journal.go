@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Journal record states.
+const (
+	journalStarted    = "started"
+	journalDispatched = "dispatched"
+	journalDone       = "done"
+	journalMerging    = "merging"
+	journalMerged     = "merged"
+	journalFinished   = "finished"
+)
+
+// journalRecord is one line of the job journal.
+type journalRecord struct {
+	State        string    `json:"state"`
+	Time         time.Time `json:"time"`
+	RunID        string    `json:"run_id,omitempty"`
+	RunStart     time.Time `json:"run_start,omitzero"`
+	BusinessDate string    `json:"business_date,omitempty"`
+	Job          string    `json:"job,omitempty"` // completion key, PROC,SOL_ID
+	Procedure    string    `json:"procedure,omitempty"`
+}
+
+// jobJournal is the crash-safe record of a run's jobs: which were dispatched and which
+// finished, and which procedures' merges started and completed, written as JSON Lines
+// next to the logs. The process holds an exclusive lock
+// on it for the whole run, so a second process cannot work the same package and mode.
+type jobJournal struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// resumeState is what an interrupted run left in the journal.
+type resumeState struct {
+	RunID        string
+	RunStart     time.Time
+	BusinessDate string
+	done         map[string]bool
+	merging      map[string]bool // procedures whose merge started
+	merged       map[string]bool // procedures whose merge completed and whose spools are gone
+}
+
+// IsDone reports whether the interrupted run finished the job.
+func (r *resumeState) IsDone(job Job) bool {
+	return r != nil && r.done[completionKey(job.Proc, job.spoolID())]
+}
+
+// Keeps reports whether resuming can skip job: the interrupted run finished it and, when
+// its rows are spooled, either its procedure was merged or its spools are still there to
+// merge. A finished job whose spool was lost, e.g. quarantined as unreadable, runs again.
+func (r *resumeState) Keeps(cfg *ExtractionConfig, job Job, spooled bool) bool {
+	if !r.IsDone(job) {
+		return false
+	}
+	return !spooled || r.merged[job.Proc] || cfg.jobSpoolsPresent(job)
+}
+
+// checkMerges refuses to resume a run that stopped part-way through appending a
+// procedure to its output: merging it again would append the same rows twice.
+func (r *resumeState) checkMerges(cfg *ExtractionConfig) error {
+	if r == nil {
+		return nil
+	}
+	for _, proc := range cfg.Procedures {
+		if r.merging[proc] && !r.merged[proc] && cfg.appends(proc) {
+			return fmt.Errorf("run %s stopped while appending %s to its output, which resuming would append again: restore the output from before the run and rerun without -resume", r.RunID, proc)
+		}
+	}
+	return nil
+}
+
+func journalFile(pkg, mode string) string {
+	if mode == "I" {
+		return pkg + "_insert_journal.jsonl"
+	}
+	return pkg + "_extract_journal.jsonl"
+}
+
+// openJobJournal locks the journal at path. With resume it returns the state of the last
+// run recorded in it, which must not have finished; otherwise the journal starts afresh.
+func openJobJournal(path string, resume bool) (*jobJournal, *resumeState, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open job journal %s: %w", path, err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("job journal %s is locked, another process is running this package: %w", path, err)
+	}
+	j := &jobJournal{f: f}
+
+	var state *resumeState
+	if resume {
+		if state, err = readJournal(f); err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("failed to read job journal %s: %w", path, err)
+		}
+	} else if err := f.Truncate(0); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	// End a record cut short by a crash, so the next one starts on a line of its own.
+	last := make([]byte, 1)
+	if end > 0 {
+		if _, err := f.ReadAt(last, end-1); err == nil && last[0] != '\n' {
+			if _, err := f.Write([]byte{'\n'}); err != nil {
+				f.Close()
+				return nil, nil, err
+			}
+		}
+	}
+	return j, state, nil
+}
+
+// readJournal returns the state of the last run in the journal. A truncated last line,
+// as a crash can leave, is ignored.
+func readJournal(r io.Reader) (*resumeState, error) {
+	var state *resumeState
+	finished := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var rec journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		switch rec.State {
+		case journalStarted:
+			if state == nil || state.RunID != rec.RunID {
+				state = &resumeState{RunID: rec.RunID, RunStart: rec.RunStart, BusinessDate: rec.BusinessDate, done: make(map[string]bool),
+					merging: make(map[string]bool), merged: make(map[string]bool)}
+			}
+			finished = false
+		case journalDone:
+			if state != nil {
+				state.done[rec.Job] = true
+			}
+		case journalMerging:
+			if state != nil {
+				state.merging[rec.Procedure] = true
+			}
+		case journalMerged:
+			if state != nil {
+				state.merged[rec.Procedure] = true
+			}
+		case journalFinished:
+			finished = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, errors.New("no run to resume")
+	}
+	if finished {
+		return nil, fmt.Errorf("run %s finished, there is nothing to resume", state.RunID)
+	}
+	return state, nil
+}
+
+func (j *jobJournal) write(rec journalRecord, sync bool) error {
+	if j == nil {
+		return nil
+	}
+	rec.Time = time.Now()
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write job journal: %w", err)
+	}
+	if sync {
+		return j.f.Sync()
+	}
+	return nil
+}
+
+// Start records the start, or resumption, of a run.
+func (j *jobJournal) Start(runCfg *ExtractionConfig) error {
+	return j.write(journalRecord{State: journalStarted, RunID: runCfg.RunID, RunStart: runCfg.RunStart, BusinessDate: runCfg.BusinessDate.Format(businessDateLayout)}, true)
+}
+
+// Dispatched records that a worker picked up job.
+func (j *jobJournal) Dispatched(job Job) error {
+	return j.write(journalRecord{State: journalDispatched, Job: completionKey(job.Proc, job.spoolID())}, false)
+}
+
+// Done records that job finished successfully; it is synced so a crash cannot lose it.
+func (j *jobJournal) Done(job Job) error {
+	return j.write(journalRecord{State: journalDone, Job: completionKey(job.Proc, job.spoolID())}, true)
+}
+
+// Merging records that the merge of proc is about to write its output.
+func (j *jobJournal) Merging(proc string) error {
+	return j.write(journalRecord{State: journalMerging, Procedure: proc}, true)
+}
+
+// Merged records that proc was merged in full, before its spools are removed.
+func (j *jobJournal) Merged(proc string) error {
+	return j.write(journalRecord{State: journalMerged, Procedure: proc}, true)
+}
+
+// Finish records that the run completed, after which it can no longer be resumed.
+func (j *jobJournal) Finish() error {
+	return j.write(journalRecord{State: journalFinished}, true)
+}
+
+// Close releases the journal and its lock.
+func (j *jobJournal) Close() error {
+	if j == nil {
+		return nil
+	}
+	return j.f.Close()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJobJournalResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "PKG_extract_journal.jsonl")
+	runCfg := &ExtractionConfig{RunID: "20260301000000", RunStart: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), BusinessDate: time.Date(2026, 2, 28, 0, 0, 0, 0, time.Local)}
+	done, pending := Job{SolID: "0001", Proc: "GAM"}, Job{SolID: "0002", Proc: "GAM"}
+
+	j, _, err := openJobJournal(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := openJobJournal(path, false); err == nil || !strings.Contains(err.Error(), "locked") {
+		t.Errorf("second process opened a locked journal: %v", err)
+	}
+	j.Start(runCfg)
+	j.Dispatched(done)
+	j.Dispatched(pending)
+	j.Done(done)
+	j.f.WriteString(`{"state":"do`) // the crash cuts the last record short
+	j.Close()
+
+	j, state, err := openJobJournal(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.RunID != runCfg.RunID || !state.RunStart.Equal(runCfg.RunStart) || state.BusinessDate != "2026-02-28" {
+		t.Errorf("resumed run = %+v", state)
+	}
+	if !state.IsDone(done) || state.IsDone(pending) {
+		t.Errorf("done jobs = %v", state.done)
+	}
+	j.Finish()
+	j.Close()
+
+	if _, _, err := openJobJournal(path, true); err == nil {
+		t.Error("resumed a finished run")
+	}
+}
+
+func TestResumeAfterCrashMidMerge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "PKG_extract_journal.jsonl")
+	cfg := &ExtractionConfig{SpoolOutputPath: dir, RunID: "R1", Procedures: []string{"GAM"}, sols: []string{"0001", "0002", "0003"}}
+	if err := cfg.compileFileNames(); err != nil {
+		t.Fatal(err)
+	}
+	jobs := []Job{{SolID: "0001", Proc: "GAM"}, {SolID: "0002", Proc: "GAM"}, {SolID: "0003", Proc: "GAM"}}
+	spools := make([]string, len(jobs))
+	for i, job := range jobs {
+		spools[i], _ = cfg.spoolFilePath(job.Proc, job.SolID)
+	}
+
+	j, _, err := openJobJournal(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.journal = j
+	j.Start(cfg)
+	for i, job := range jobs {
+		os.WriteFile(spools[i], []byte(job.SolID+"\n"), 0644)
+		j.Done(job)
+	}
+	// The run dies part-way through the merge: the first spool is already in the output
+	// and the last one is lost, as an unreadable spool is to quarantine.
+	os.Remove(spools[1])
+	os.Mkdir(spools[1], 0755)
+	os.Remove(spools[2])
+	if _, err := mergeProcedure(cfg, "GAM"); err == nil {
+		t.Fatal("merge of an unreadable spool succeeded")
+	}
+	j.Close()
+
+	j, state, err := openJobJournal(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	if !state.merging["GAM"] || state.merged["GAM"] {
+		t.Errorf("merge state = merging %v, merged %v", state.merging, state.merged)
+	}
+	os.Remove(spools[1])
+	os.WriteFile(spools[1], []byte("0002\n"), 0644)
+	if !state.Keeps(cfg, jobs[0], true) || !state.Keeps(cfg, jobs[1], true) {
+		t.Error("finished jobs with their spools in place are run again")
+	}
+	if state.Keeps(cfg, jobs[2], true) {
+		t.Error("finished job whose spool is gone is not run again")
+	}
+	if err := state.checkMerges(cfg); err != nil {
+		t.Errorf("replacing merge refused: %v", err)
+	}
+	rolling := *cfg
+	rolling.RollingFeeds = []string{"GAM"}
+	if err := state.checkMerges(&rolling); err == nil {
+		t.Error("resumed a run that stopped part-way through appending")
+	}
+
+	// The resumed run extracts the lost job again and merges every spool.
+	cfg.journal = j
+	os.WriteFile(spools[2], []byte("0003\n"), 0644)
+	if _, err := mergeProcedure(cfg, "GAM"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "GAM.txt")); string(got) != "0001\n0002\n0003\n" {
+		t.Errorf("output after resume = %q", got)
+	}
+	j.Close()
+	j, state, err = openJobJournal(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.merged["GAM"] || !state.Keeps(cfg, jobs[2], true) {
+		t.Errorf("merged procedure not recorded: %v", state.merged)
+	}
+	j.Close()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	log "github.com/charmbracelet/log"
+)
+
+// keyringService is the service name under which passwords are kept in the OS keyring.
+const keyringService = "gemini_extract"
+
+var errKeyringNotFound = errors.New("password not found in keyring")
+
+// keyringAccount identifies the stored password by database user and connect string.
+func keyringAccount(c *MainConfig) string {
+	return c.DBUser + "@" + c.connectString()
+}
+
+// obtainPassword fills DBPassword from the OS keyring and/or an interactive prompt. With
+// useKeyring a prompted password is stored for the next run.
+func obtainPassword(c *MainConfig, prompt, useKeyring bool) error {
+	account := keyringAccount(c)
+	if useKeyring {
+		pw, err := keyringGet(keyringService, account)
+		if err == nil {
+			log.Info("🔑 Using database password from OS keyring", "account", account)
+			c.DBPassword = pw
+			return nil
+		}
+		if !errors.Is(err, errKeyringNotFound) {
+			return fmt.Errorf("keyring lookup failed: %w", err)
+		}
+		if !prompt {
+			return fmt.Errorf("no password stored in OS keyring for %s, run once with -prompt-password to store it", account)
+		}
+	}
+	if !prompt {
+		return nil
+	}
+	if !stdinIsTerminal() {
+		return fmt.Errorf("-prompt-password needs an interactive terminal")
+	}
+	pw, err := readPassword(fmt.Sprintf("Password for %s: ", account))
+	if err != nil {
+		return err
+	}
+	c.DBPassword = pw
+	if useKeyring {
+		if err := keyringSet(keyringService, account, pw); err != nil {
+			log.Warn("Failed to store password in OS keyring", "account", account, "error", err)
+		} else {
+			log.Info("🔑 Stored database password in OS keyring", "account", account)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// keyringGet reads a password from the macOS Keychain (security) or the freedesktop
+// Secret Service (secret-tool from libsecret).
+func keyringGet(service, account string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w")
+	} else {
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "account", account)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", errKeyringNotFound
+		}
+		return "", err
+	}
+	pw := strings.TrimRight(string(out), "\n")
+	if pw == "" {
+		return "", errKeyringNotFound
+	}
+	return pw, nil
+}
+
+func keyringSet(service, account, password string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "add-generic-password", "-U", "-s", service, "-a", account, "-w", password)
+	} else {
+		cmd = exec.Command("secret-tool", "store", "--label", service+" "+account, "service", service, "account", account)
+		cmd.Stdin = strings.NewReader(password)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// defaultLoadBatchSize is how many records each array insert binds when not configured.
+const defaultLoadBatchSize = 1000
+
+// loadResult is the outcome of loading one procedure's file.
+type loadResult struct {
+	Proc  string
+	File  string
+	Table string
+	Rows  int64
+	Time  time.Duration
+	Err   error
+}
+
+// insertStatement builds the array insert of a template's columns into table.
+func insertStatement(table string, cols []ColumnConfig) (string, error) {
+	names := make([]string, len(cols))
+	binds := make([]string, len(cols))
+	for i, c := range cols {
+		if !plainIdentifier.MatchString(c.Name) {
+			return "", fmt.Errorf("template column %q is not a plain column name", c.Name)
+		}
+		names[i] = c.Name
+		binds[i] = ":" + strconv.Itoa(i+1)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(names, ", "), strings.Join(binds, ", ")), nil
+}
+
+// loadFile inserts every record of an extracted file into table with array binds of
+// batchSize records, in one transaction so a failed load leaves the table untouched.
+// Values are bound as text and converted by the database to the column types.
+func loadFile(ctx context.Context, db *sql.DB, cfg *ExtractionConfig, path, table string, cols []ColumnConfig, batchSize int) (rows int64, err error) {
+	query, err := insertStatement(table, cols)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare insert into %s: %w", table, err)
+	}
+	defer stmt.Close()
+
+	batch := make([][]string, len(cols))
+	flush := func() error {
+		if len(batch[0]) == 0 {
+			return nil
+		}
+		args := make([]any, len(batch))
+		for i := range batch {
+			args[i] = batch[i]
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return fmt.Errorf("array insert into %s failed after %d rows: %w", table, rows, err)
+		}
+		rows += int64(len(batch[0]))
+		for i := range batch {
+			batch[i] = batch[i][:0]
+		}
+		return nil
+	}
+
+	var recNo int64
+	err = readOutputRecords(cfg, path, cols, func(rec outputRecord) error {
+		recNo++
+		if len(rec.fields) != len(cols) {
+			return fmt.Errorf("record %d has %d fields, template has %d", recNo, len(rec.fields), len(cols))
+		}
+		for i, v := range rec.fields {
+			batch[i] = append(batch[i], v)
+		}
+		if len(batch[0]) >= batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit load into %s: %w", table, err)
+	}
+	return rows, nil
+}
+
+// runLoad implements the "load" subcommand: it reads the files of an extraction with
+// the same templates and inserts them into target tables, for round-trip migrations.
+func runLoad(args []string) error {
+	fs := flag.NewFlagSet("load", flag.ContinueOnError)
+	from := fs.String("from", "", "Extraction to load: output directory or run ID (default the current output files)")
+	batchSize := fs.Int("batch-size", 0, "Records per array insert (default load_batch_size, else 1000)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	appCfg, runCfg, err := loadConfigs()
+	if err != nil {
+		return err
+	}
+	if err := validateConfigs(&appCfg, &runCfg, "E"); err != nil {
+		return err
+	}
+	if runCfg.partitioned() {
+		return fmt.Errorf("load reads merged outputs, but per_sol_output or output_groups is set")
+	}
+	runCfg.RunStart = time.Now()
+	runCfg.RunID = timestamps.runID(runCfg.RunStart)
+	if err := runCfg.compileFileNames(); err != nil {
+		return err
+	}
+	templates, err := loadTemplates(&runCfg)
+	if err != nil {
+		return err
+	}
+	if *batchSize <= 0 {
+		*batchSize = runCfg.LoadBatchSize
+	}
+	if *batchSize <= 0 {
+		*batchSize = defaultLoadBatchSize
+	}
+
+	db, err := openConnection(&appCfg, runCfg.LoadConnection)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	results := make([]loadResult, len(runCfg.Procedures))
+	sem := make(chan struct{}, max(1, appCfg.Concurrency))
+	var wg sync.WaitGroup
+	for i, proc := range runCfg.Procedures {
+		r := &results[i]
+		r.Proc, r.Table = proc, proc
+		if t := runCfg.LoadTables[proc]; t != "" {
+			r.Table = t
+		}
+		if *from != "" {
+			r.File, r.Err = resolveRunOutput(&runCfg, *from, proc)
+		} else {
+			r.File, r.Err = runCfg.outputFilePath(proc)
+		}
+		if r.Err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			start := time.Now()
+			log.Info("📥 Loading", "procedure", r.Proc, "file", r.File, "table", r.Table)
+			r.Rows, r.Err = loadFile(ctx, db, runCfg.forOutput(r.Proc), r.File, r.Table, templates[r.Proc], *batchSize)
+			r.Time = time.Since(start)
+			if r.Err != nil {
+				log.Error("Load failed", "procedure", r.Proc, "table", r.Table, "error", r.Err)
+			} else {
+				log.Info("Loaded", "procedure", r.Proc, "table", r.Table, "rows", r.Rows, "duration", r.Time.Round(time.Millisecond))
+			}
+		}()
+	}
+	wg.Wait()
+
+	path := filepath.Join(appCfg.LogFilePath, runCfg.PackageName+"_load_summary.csv")
+	if err := writeLoadSummary(path, results); err != nil {
+		log.Warn("Failed to write load summary", "file", path, "error", err)
+	}
+	var failed []string
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r.Proc)
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("load failed for %d procedure(s): %s", len(failed), strings.Join(failed, ", "))
+	}
+	log.Info("✅ Load complete", "procedures", len(results), "summary", path)
+	return nil
+}
+
+func writeLoadSummary(path string, results []loadResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"PROCEDURE", "FILE", "TABLE", "ROWS", "EXECUTION_SECONDS", "STATUS", "ERROR_DETAILS"})
+	for _, r := range results {
+		status, details := "SUCCESS", "-"
+		if r.Err != nil {
+			status, details = "FAIL", r.Err.Error()
+		}
+		w.Write([]string{r.Proc, r.File, r.Table, strconv.FormatInt(r.Rows, 10), fmt.Sprintf("%.3f", r.Time.Seconds()), status, details})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// LogRotationConfig caps the size of the detail CSV log. When MaxBytes or MaxRecords is
+// set the log is split into numbered parts listed in an index file; Gzip compresses
+// each part once it is complete.
+type LogRotationConfig struct {
+	MaxBytes   int64 `json:"max_bytes"`
+	MaxRecords int   `json:"max_records"`
+	Gzip       bool  `json:"gzip"`
+}
+
+func (r LogRotationConfig) enabled() bool {
+	return r.MaxBytes > 0 || r.MaxRecords > 0
+}
+
+// countingWriter tracks how many bytes have been written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// rotatingLog writes CSV records to path, rolling over to a new part whenever the
+// configured size or record cap is reached.
+type rotatingLog struct {
+	path    string
+	header  []string
+	cfg     LogRotationConfig
+	part    int
+	file    *os.File
+	counter *countingWriter
+	writer  *csv.Writer
+	records int
+	first   time.Time
+	last    time.Time
+	index   *csv.Writer
+	indexF  *os.File
+	append  bool
+}
+
+func newRotatingLog(path string, header []string, cfg LogRotationConfig) (*rotatingLog, error) {
+	r := &rotatingLog{path: path, header: header, cfg: cfg}
+	if cfg.enabled() {
+		indexPath := strings.TrimSuffix(path, ".csv") + "_index.csv"
+		f, err := os.Create(indexPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create log index %s: %w", indexPath, err)
+		}
+		r.indexF = f
+		r.index = csv.NewWriter(f)
+		r.index.Write([]string{"PART", "RECORDS", "FIRST_START_TIME", "LAST_END_TIME"})
+	}
+	if err := r.open(); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// newAppendingLog opens a log that adds to path across runs, writing the header only
+// when the file is new. It does not rotate.
+func newAppendingLog(path string, header []string) (*rotatingLog, error) {
+	r := &rotatingLog{path: path, header: header, append: true}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingLog) partPath() string {
+	if !r.cfg.enabled() {
+		return r.path
+	}
+	return fmt.Sprintf("%s_%03d.csv", strings.TrimSuffix(r.path, ".csv"), r.part)
+}
+
+func (r *rotatingLog) open() error {
+	r.part++
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if r.append {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(r.partPath(), flags, 0644)
+	if err != nil {
+		return err
+	}
+	r.file = f
+	r.counter = &countingWriter{w: f}
+	r.writer = csv.NewWriter(r.counter)
+	r.records = 0
+	r.first, r.last = time.Time{}, time.Time{}
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		return nil
+	}
+	return r.writer.Write(r.header)
+}
+
+// Write appends a record covering the job that ran between start and end.
+func (r *rotatingLog) Write(record []string, start, end time.Time) error {
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return err
+		}
+	}
+	if err := r.writer.Write(record); err != nil {
+		return err
+	}
+	r.writer.Flush()
+	r.records++
+	if r.first.IsZero() {
+		r.first = start
+	}
+	r.last = end
+
+	full := (r.cfg.MaxRecords > 0 && r.records >= r.cfg.MaxRecords) ||
+		(r.cfg.MaxBytes > 0 && r.counter.n >= r.cfg.MaxBytes)
+	if !full {
+		return r.writer.Error()
+	}
+	// The next part is opened lazily so a run ending on a cap leaves no empty part.
+	return r.closePart()
+}
+
+// closePart finishes the current part, records it in the index and compresses it.
+func (r *rotatingLog) closePart() error {
+	r.writer.Flush()
+	err := r.file.Close()
+	r.file = nil
+	if err != nil {
+		return err
+	}
+	name := r.partPath()
+	if r.cfg.Gzip {
+		gz, err := gzipFile(name)
+		if err != nil {
+			log.Warn("Failed to compress procedure log part", "file", name, "error", err)
+		} else {
+			name = gz
+		}
+	}
+	if r.index != nil {
+		r.index.Write([]string{name, strconv.Itoa(r.records), timestamps.format(r.first), timestamps.format(r.last)})
+		r.index.Flush()
+	}
+	return nil
+}
+
+// Close finishes the last part and the index.
+func (r *rotatingLog) Close() error {
+	var err error
+	if r.file != nil {
+		err = r.closePart()
+	}
+	if r.indexF != nil {
+		r.index.Flush()
+		if cerr := r.indexF.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// gzipFile compresses path to path.gz and removes the original.
+func gzipFile(path string) (string, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	gzPath := path + ".gz"
+	out, err := os.Create(gzPath)
+	if err != nil {
+		return "", err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		os.Remove(gzPath)
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		os.Remove(gzPath)
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(gzPath)
+		return "", err
+	}
+	in.Close()
+	return gzPath, os.Remove(path)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingLogSplitsByRecordCount(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "PKG_extract.csv")
+	r, err := newRotatingLog(path, []string{"A"}, LogRotationConfig{MaxRecords: 2, Gzip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := r.Write([]string{"x"}, now, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"PKG_extract_001.csv.gz", "PKG_extract_002.csv.gz", "PKG_extract_index.csv"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "PKG_extract_003.csv")); err == nil {
+		t.Error("unexpected empty trailing part")
+	}
+}
+
+func TestJobLogSinks(t *testing.T) {
+	dir := t.TempDir()
+	appCfg := &MainConfig{JobLog: JobLogConfig{Sinks: []string{JobLogCSV, JobLogJSONL}}}
+	runCfg := &ExtractionConfig{RunID: "20260301000000", PackageName: "PKG"}
+	sinks := openLogSinks(appCfg, runCfg, nil, filepath.Join(dir, "PKG_extract.csv"))
+	if len(sinks) != 2 {
+		t.Fatalf("opened %d sinks, want 2", len(sinks))
+	}
+
+	logCh := make(chan ProcLog, 1)
+	logCh <- ProcLog{SolID: "0001", Procedure: "GAM", Status: "FAIL", ErrorDetails: "ORA-00942", ErrorCode: "ORA-00942"}
+	close(logCh)
+	writeLog(sinks, logCh)
+
+	csvData, err := os.ReadFile(filepath.Join(dir, "PKG_extract.csv"))
+	if err != nil || !strings.Contains(string(csvData), "0001,GAM,") {
+		t.Errorf("CSV log = %q, %v", csvData, err)
+	}
+	jsonData, err := os.ReadFile(filepath.Join(dir, "PKG_extract.jsonl"))
+	if err != nil || !strings.Contains(string(jsonData), `"run_id":"20260301000000","package":"PKG","sol_id":"0001"`) || !strings.Contains(string(jsonData), `"error_code":"ORA-00942"`) {
+		t.Errorf("JSON log = %q, %v", jsonData, err)
+	}
+}
+
+func TestJobLogAppendKeepsPreviousRuns(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "PKG_extract.csv")
+	appCfg := &MainConfig{JobLog: JobLogConfig{Rerun: RerunAppend}}
+	for _, runID := range []string{"20260301000000", "20260302000000"} {
+		logCh := make(chan ProcLog, 1)
+		logCh <- ProcLog{SolID: "0001", Procedure: "GAM", Status: "SUCCESS"}
+		close(logCh)
+		writeLog(openLogSinks(appCfg, &ExtractionConfig{RunID: runID, PackageName: "PKG"}, nil, path), logCh)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "RUN_ID,SOL_ID,") ||
+		!strings.HasPrefix(lines[1], "20260301000000,0001,GAM,") || !strings.HasPrefix(lines[2], "20260302000000,0001,GAM,") {
+		t.Errorf("appended log = %q", data)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	log "github.com/charmbracelet/log"
+)
+
+// SyslogConfig configures forwarding of application log entries to syslog.
+type SyslogConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Network  string `json:"network"` // "", "udp" or "tcp"; empty uses the local syslog daemon
+	Address  string `json:"address"`
+	Facility string `json:"facility"` // e.g. "local0", "daemon", "user"
+	Tag      string `json:"tag"`
+}
+
+// EventLogConfig configures forwarding of application log entries to the Windows Event Log.
+type EventLogConfig struct {
+	Enabled bool   `json:"enabled"`
+	Source  string `json:"source"`
+}
+
+// levelSink is a log destination that understands severities. *syslog.Writer satisfies it.
+type levelSink interface {
+	Debug(m string) error
+	Info(m string) error
+	Warning(m string) error
+	Err(m string) error
+	Close() error
+}
+
+var logLevelToken = regexp.MustCompile(`\b(DEBU|INFO|WARN|ERRO|FATA)\b`)
+
+// sinkWriter receives formatted entries from the application logger and forwards them
+// to a levelSink at the severity shown in the entry.
+type sinkWriter struct {
+	sink levelSink
+}
+
+func (s sinkWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	var err error
+	switch logLevelToken.FindString(msg) {
+	case "DEBU":
+		err = s.sink.Debug(msg)
+	case "WARN":
+		err = s.sink.Warning(msg)
+	case "ERRO", "FATA":
+		err = s.sink.Err(msg)
+	default:
+		err = s.sink.Info(msg)
+	}
+	if err != nil {
+		// Never fail the application because the host log is unavailable.
+		fmt.Fprintf(os.Stderr, "log sink write failed: %v\n", err)
+	}
+	return len(p), nil
+}
+
+// setupLogSinks adds the configured host log sinks alongside stderr. The returned
+// function closes them and should be deferred by the caller.
+func setupLogSinks(appCfg *MainConfig) (func(), error) {
+	var sinks []levelSink
+	closeAll := func() {
+		for _, s := range sinks {
+			s.Close()
+		}
+	}
+
+	if appCfg.Syslog.Enabled {
+		s, err := openSyslogSink(appCfg.Syslog)
+		if err != nil {
+			return closeAll, fmt.Errorf("failed to open syslog sink: %w", err)
+		}
+		sinks = append(sinks, s)
+	}
+	if appCfg.EventLog.Enabled {
+		s, err := openEventLogSink(appCfg.EventLog)
+		if err != nil {
+			return closeAll, fmt.Errorf("failed to open event log sink: %w", err)
+		}
+		sinks = append(sinks, s)
+	}
+	if len(sinks) == 0 {
+		return closeAll, nil
+	}
+
+	for _, s := range sinks {
+		hostSinks = append(hostSinks, sinkWriter{sink: s})
+	}
+	setConsole(os.Stderr)
+	return func() {
+		hostSinks = nil
+		setConsole(os.Stderr)
+		closeAll()
+	}, nil
+}
+
+// hostSinks are the writers of the open host log sinks, fed alongside the console.
+var hostSinks []io.Writer
+
+// setConsole sends the log to w, the operator's terminal or the dashboard's log pane,
+// and to the open host log sinks.
+func setConsole(w io.Writer) {
+	if len(hostSinks) == 0 {
+		log.SetOutput(w)
+		return
+	}
+	log.SetOutput(io.MultiWriter(append([]io.Writer{w}, hostSinks...)...))
+}
@@ -0,0 +1,42 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// eventLogEventID is the event ID used for every entry written by the tool.
+const eventLogEventID = 1
+
+// eventLogSink adapts *eventlog.Log to levelSink. The event log has no debug level, so
+// debug entries are written as informational events.
+type eventLogSink struct {
+	l *eventlog.Log
+}
+
+func (e eventLogSink) Debug(m string) error   { return e.l.Info(eventLogEventID, m) }
+func (e eventLogSink) Info(m string) error    { return e.l.Info(eventLogEventID, m) }
+func (e eventLogSink) Warning(m string) error { return e.l.Warning(eventLogEventID, m) }
+func (e eventLogSink) Err(m string) error     { return e.l.Error(eventLogEventID, m) }
+func (e eventLogSink) Close() error           { return e.l.Close() }
+
+func openSyslogSink(SyslogConfig) (levelSink, error) {
+	return nil, errors.New("syslog is not available on Windows, use the event log sink instead")
+}
+
+func openEventLogSink(cfg EventLogConfig) (levelSink, error) {
+	source := cfg.Source
+	if source == "" {
+		source = "gemini_extract"
+	}
+	// Registering the source needs administrator rights; it is fine if it already exists.
+	_ = eventlog.InstallAsEventCreate(source, eventlog.Error|eventlog.Warning|eventlog.Info)
+	l, err := eventlog.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	return eventLogSink{l: l}, nil
+}
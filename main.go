@@ -3,10 +3,14 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,111 +19,453 @@ import (
 )
 
 var (
-	appCfgFile = flag.String("appCfg", "", "Path to the main application configuration file")
-	runCfgFile = flag.String("runCfg", "", "Path to the extraction configuration file")
-	mode       = flag.String("mode", "", "Mode of operation: E - Extract, I - Insert")
+	appCfgFile   = flag.String("appCfg", "", "Path to the main application configuration file")
+	runCfgFile   = flag.String("runCfg", "", "Path to the extraction configuration file; several comma-separated, or @file listing one per line")
+	mode         = flag.String("mode", "", "Mode of operation: E - Extract, I - Insert")
+	toStdout     = flag.Bool("stdout", false, "Stream a single procedure/SOL extraction to stdout instead of spool files (mode E only)")
+	singleProc   = flag.String("proc", "", "Procedure to extract when streaming to stdout")
+	singleSol    = flag.String("sol", "", "SOL ID to extract when streaming to stdout")
+	skipExist    = flag.Bool("skip-existing", false, "Skip procedure/SOL pairs already completed by an earlier run")
+	profile      = flag.String("profile", "", "Named profile from the main configuration to apply (e.g. dev, uat, prod)")
+	interact     = flag.Bool("interactive", false, "Choose procedures and SOL chunks to run on a checklist screen before dispatch")
+	promptPw     = flag.Bool("prompt-password", false, "Read the database password from the terminal without echo")
+	limitRows    = flag.Int("limit-rows", 0, "Extract at most this many rows per procedure/SOL, for rehearsal runs (mode E only)")
+	samplePct    = flag.Float64("sample-percent", 0, "Extract a random sample of this percentage of rows, for rehearsal runs (mode E only)")
+	forceMerge   = flag.Bool("force-merge", false, "Finish merges that had to quarantine unreadable spool files instead of failing them")
+	compare      = flag.Bool("compare", false, "Reconcile merged output record counts against the database after an extraction")
+	busDate      = flag.String("business-date", "", "Business date of the run (YYYY-MM-DD), default the database's SYSDATE")
+	parallelRuns = flag.Bool("parallel-runs", false, "Run several runCfg files at the same time instead of one after another")
+	useKeyring   = flag.Bool("keyring", false, "Look up the database password in the OS keyring by user/host, storing a prompted one")
+	resume       = flag.Bool("resume", false, "Continue the last interrupted run from its job journal, skipping the jobs it finished")
+	showTUI      = flag.Bool("tui", false, "Show a live terminal dashboard of procedure progress and in-flight jobs while the run dispatches")
 )
 
 func main() {
 	flag.Parse()
 
+	var err error
+	switch cmd := flag.Arg(0); cmd {
+	case "":
+		err = runAsService(run)
+	case "clean":
+		err = runClean()
+	case "init":
+		err = runInit(flag.Args()[1:])
+	case "compare":
+		err = runCompare(flag.Args()[1:])
+	case "diff":
+		err = runDiff(flag.Args()[1:])
+	case "count":
+		err = runCount(flag.Args()[1:])
+	case "load":
+		err = runLoad(flag.Args()[1:])
+	case "history":
+		err = runHistory(flag.Args()[1:])
+	case "serve":
+		err = runServe(flag.Args()[1:])
+	case "encrypt-password":
+		err = runEncryptPassword(flag.Args()[1:])
+	default:
+		err = fmt.Errorf("unknown command: %s", cmd)
+	}
+
 	// Centralized error handling
-	if err := run(); err != nil {
-		log.Fatalf("❌ Application failed: %v", err)
+	if err != nil {
+		log.Errorf("❌ Application failed: %v", err)
+		os.Exit(exitCode(err))
+	}
+}
+
+// loadConfigs validates the configuration flags and loads both configuration files,
+// for commands that work on a single run configuration.
+func loadConfigs() (MainConfig, ExtractionConfig, error) {
+	var runCfg ExtractionConfig
+	paths, err := runConfigPaths()
+	if err != nil {
+		return MainConfig{}, runCfg, err
+	}
+	if len(paths) != 1 {
+		return MainConfig{}, runCfg, fmt.Errorf("this command takes a single runCfg, got %d", len(paths))
+	}
+	appCfg, err := loadMainConfig()
+	if err != nil {
+		return appCfg, runCfg, err
+	}
+	runCfg, err = loadRunConfig(paths[0])
+	return appCfg, runCfg, err
+}
+
+// loadMainConfig loads the main configuration, applying the profile and resolving the
+// database credentials.
+func loadMainConfig() (MainConfig, error) {
+	if *appCfgFile == "" || *runCfgFile == "" {
+		return MainConfig{}, fmt.Errorf("both appCfg and runCfg flags must be specified")
+	}
+	if _, err := os.Stat(*appCfgFile); os.IsNotExist(err) {
+		return MainConfig{}, fmt.Errorf("configuration file does not exist: %s", *appCfgFile)
+	}
+
+	appCfg, err := loadConfig[MainConfig](*appCfgFile)
+	if err != nil {
+		return appCfg, fmt.Errorf("failed to load main config: %w", err)
+	}
+	if err := appCfg.applyProfile(*profile); err != nil {
+		return appCfg, err
+	}
+	if timestamps, err = newTimestampFormat(appCfg.Timestamps); err != nil {
+		return appCfg, err
+	}
+	if *profile != "" {
+		log.Info("Using configuration profile", "profile", *profile, "db_host", appCfg.DBHost, "db_sid", appCfg.DBSid)
+	}
+	if err := appCfg.resolveSecrets(); err != nil {
+		return appCfg, err
+	}
+	if err := obtainPassword(&appCfg, *promptPw, *useKeyring); err != nil {
+		return appCfg, err
+	}
+	return appCfg, nil
+}
+
+func loadRunConfig(path string) (ExtractionConfig, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return ExtractionConfig{}, fmt.Errorf("configuration file does not exist: %s", path)
+	}
+	runCfg, err := loadConfig[ExtractionConfig](path)
+	if err != nil {
+		return runCfg, fmt.Errorf("failed to load extraction config %s: %w", path, err)
 	}
+	return runCfg, nil
+}
+
+// runClean applies the retention policy on demand via the "clean" subcommand.
+func runClean() error {
+	appCfg, runCfg, err := loadConfigs()
+	if err != nil {
+		return err
+	}
+	if err := runCfg.compileFileNames(); err != nil {
+		return err
+	}
+	return cleanup(&appCfg, &runCfg)
 }
 
 // run is the main application logic, designed to return errors for graceful handling.
-func run() error {
+func run() (err error) {
 	// --- Configuration and Validation ---
+	paths, err := runConfigPaths()
+	if err != nil {
+		return err
+	}
+	if len(paths) > 1 && (*toStdout || *interact) {
+		return fmt.Errorf("-stdout and -interactive take a single runCfg")
+	}
+	if *showTUI {
+		if *toStdout || *parallelRuns {
+			return fmt.Errorf("-tui cannot be combined with -stdout or -parallel-runs")
+		}
+		if !stdinIsTerminal() || !stderrIsTerminal() {
+			return fmt.Errorf("-tui requires a terminal on stdin and stderr")
+		}
+	}
 	if *mode != "E" && *mode != "I" {
 		return fmt.Errorf("invalid mode: must be 'E' for Extract or 'I' for Insert")
 	}
-	if *appCfgFile == "" || *runCfgFile == "" {
-		return fmt.Errorf("both appCfg and runCfg flags must be specified")
+	if (*limitRows != 0 || *samplePct != 0) && *mode != "E" {
+		return fmt.Errorf("-limit-rows and -sample-percent are only supported in extract mode")
+	}
+	if *limitRows < 0 {
+		return fmt.Errorf("-limit-rows must not be negative")
+	}
+	if *samplePct < 0 || *samplePct >= 100 {
+		return fmt.Errorf("-sample-percent must be between 0 and 100 (exclusive)")
+	}
+	if *busDate != "" {
+		if _, err := parseBusinessDate(*busDate); err != nil {
+			return err
+		}
 	}
-	for _, path := range []string{*appCfgFile, *runCfgFile} {
-		if _, err := os.Stat(path); os.IsNotExist(err) {
-			return fmt.Errorf("configuration file does not exist: %s", path)
+	if *toStdout {
+		if *mode != "E" {
+			return fmt.Errorf("-stdout is only supported in extract mode")
+		}
+		if *singleProc == "" || *singleSol == "" {
+			return fmt.Errorf("-stdout requires both -proc and -sol")
+		}
+		if *resume {
+			return fmt.Errorf("-stdout cannot be combined with -resume")
 		}
+		// Keep stdout clean for the extracted data; all logging goes to stderr.
+		log.SetOutput(os.Stderr)
 	}
 
 	log.Info("🚀 Starting application...")
 
-	appCfg, err := loadConfig[MainConfig](*appCfgFile)
+	appCfg, err := loadMainConfig()
+	if err != nil {
+		return err
+	}
+	closeSinks, err := setupLogSinks(&appCfg)
+	defer closeSinks()
 	if err != nil {
-		return fmt.Errorf("failed to load main config: %w", err)
+		return err
 	}
-	runCfg, err := loadConfig[ExtractionConfig](*runCfgFile)
+	if appCfg.HealthListen != "" {
+		health = startHealthServer(appCfg.HealthListen, appCfg.ControlToken, appCfg.LogFilePath)
+		defer health.Close()
+	}
+	runCfgs := make([]*ExtractionConfig, len(paths))
+	packages := make(map[string]string)
+	for i, path := range paths {
+		runCfg, err := loadRunConfig(path)
+		if err != nil {
+			return err
+		}
+		if *toStdout {
+			runCfg.Procedures = []string{*singleProc}
+			runCfg.Phases = nil
+			runCfg.SolChunks = nil
+		}
+		if *mode == "I" {
+			// Insertion procedures take whole SOLs.
+			runCfg.SolChunks = nil
+		}
+		if err := validateConfigs(&appCfg, &runCfg, *mode); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		// Logs, completion markers and summaries are named after the package.
+		if other, ok := packages[runCfg.PackageName]; ok {
+			return fmt.Errorf("%s and %s both run package %s", other, path, runCfg.PackageName)
+		}
+		packages[runCfg.PackageName] = path
+		runCfgs[i] = &runCfg
+	}
+
+	// All packages of an invocation share the connection pools and business date.
+	dbs, err := openDatabases(&appCfg, runCfgs...)
 	if err != nil {
-		return fmt.Errorf("failed to load extraction config: %w", err)
+		return err
+	}
+	defer closeDatabases(dbs)
+	health.setDatabases(dbs)
+	if err := runCfgs[0].resolveBusinessDate(context.Background(), dbs[""], *busDate); err != nil {
+		return err
+	}
+	for _, runCfg := range runCfgs[1:] {
+		runCfg.BusinessDate = runCfgs[0].BusinessDate
+	}
+	log.Info("📅 Business date", "date", runCfgs[0].BusinessDate.Format(businessDateLayout))
+
+	// Under systemd the unit counts as started once the databases are reachable.
+	notifyService("READY=1")
+	defer notifyService("STOPPING=1")
+	stopWatchdog := startServiceWatchdog()
+	defer stopWatchdog()
+
+	tr := newTracer(appCfg.OTLPEndpoint, appCfg.OTLPHeaders)
+	ctx := withTracer(context.Background(), tr)
+	defer func() {
+		if ferr := tr.Flush(context.Background()); ferr != nil {
+			log.Warn("Failed to export trace spans", "error", ferr)
+		}
+	}()
+
+	if len(runCfgs) == 1 {
+		return runPackage(ctx, appCfg, runCfgs[0], dbs)
+	}
+	return runBatch(ctx, appCfg, runCfgs, dbs, *parallelRuns)
+}
+
+// runPackage runs one run configuration against the already open databases.
+func runPackage(ctx context.Context, appCfg MainConfig, runCfg *ExtractionConfig, dbs map[string]*sql.DB) (err error) {
+	runCfg.RunStart = time.Now()
+	runCfg.RunID = timestamps.runID(runCfg.RunStart)
+	if err := runCfg.compileFileNames(); err != nil {
+		return err
+	}
+	runCfg.limitRows, runCfg.samplePercent = *limitRows, *samplePct
+	runCfg.forceMerge = *forceMerge
+	runCfg.fetchArraySize = appCfg.Memory.FetchArraySize
+	runCfg.environment = appCfg.Environment
+	if *limitRows > 0 || *samplePct > 0 {
+		log.Warn("🧪 Rehearsal run: output is limited and not a full extraction", "limit_rows", *limitRows, "sample_percent", *samplePct)
+	}
+	if !*toStdout {
+		dirMode, err := parseDirMode(appCfg.DirMode)
+		if err != nil {
+			return err
+		}
+		runCfg.dirMode = dirMode
+		dirs := []string{appCfg.LogFilePath}
+		if *mode == "E" {
+			dirs = append(dirs, runCfg.SpoolOutputPath, runCfg.outputDir())
+		}
+		for _, dir := range dirs {
+			if err := ensureWritableDir(dir, dirMode); err != nil {
+				return err
+			}
+		}
+	}
+	var journal *jobJournal
+	var resumed *resumeState
+	if !*toStdout {
+		if journal, resumed, err = openJobJournal(filepath.Join(appCfg.LogFilePath, journalFile(runCfg.PackageName, *mode)), *resume); err != nil {
+			return err
+		}
+		defer journal.Close()
+	}
+	if resumed != nil {
+		// The interrupted run's spool files are named after its run ID and business date.
+		runCfg.RunID, runCfg.RunStart = resumed.RunID, resumed.RunStart
+		if d, err := parseBusinessDate(resumed.BusinessDate); err == nil {
+			runCfg.BusinessDate = d
+		}
+		log.Info("⏯️ Resuming interrupted run", "run_id", runCfg.RunID, "finished_jobs", len(resumed.done))
+	}
+	if err := journal.Start(runCfg); err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			if ferr := journal.Finish(); ferr != nil {
+				log.Warn("Failed to record run completion in the job journal", "error", ferr)
+			}
+		}
+	}()
+	// Retention is not applied while resuming, so the interrupted run's spools are kept.
+	if appCfg.Retention.CleanOnStart && !*toStdout && resumed == nil {
+		if err := cleanup(&appCfg, runCfg); err != nil {
+			return fmt.Errorf("cleanup failed: %w", err)
+		}
 	}
 
-	// --- Database and Template Setup ---
+	// --- Template Setup ---
 	templates := make(map[string][]ColumnConfig)
 	if *mode == "E" {
 		log.Info("Loading extraction templates...")
-		for _, proc := range runCfg.Procedures {
-			tmplPath := filepath.Join(runCfg.TemplatePath, fmt.Sprintf("%s.csv", proc))
-			cols, err := readColumnsFromCSV(tmplPath)
-			if err != nil {
-				return fmt.Errorf("failed to read template for %s: %w", proc, err)
-			}
-			templates[proc] = cols
+		if templates, err = loadTemplates(runCfg); err != nil {
+			return err
 		}
 	}
 
-	connString := fmt.Sprintf(`user="%s" password="%s" connectString="%s:%d/%s"`,
-		appCfg.DBUser, appCfg.DBPassword, appCfg.DBHost, appCfg.DBPort, appCfg.DBSid)
+	ctx, runSpan := startSpan(ctx, "run", "mode", *mode, "package", runCfg.PackageName, "run_id", runCfg.RunID, "business_date", runCfg.BusinessDate.Format(businessDateLayout))
+	defer func() { runSpan.End(err) }()
 
-	db, err := sql.Open("godror", connString)
-	if err != nil {
-		return fmt.Errorf("failed to connect to DB: %w", err)
+	if *toStdout {
+		return streamToStdout(ctx, dbs, runCfg, templates, *singleProc, *singleSol)
 	}
-	defer db.Close()
-
-	db.SetMaxOpenConns(appCfg.Concurrency)
-	db.SetMaxIdleConns(appCfg.Concurrency)
-	db.SetConnMaxLifetime(30 * time.Minute)
 
 	sols, err := readSols(appCfg.SolFilePath)
 	if err != nil {
 		return fmt.Errorf("failed to read SOL IDs: %w", err)
 	}
+	if *interact {
+		if !stdinIsTerminal() {
+			return fmt.Errorf("-interactive requires a terminal on stdin")
+		}
+		runCfg.Procedures, sols, err = selectInteractively(runCfg.Procedures, sols, os.Stdin, os.Stderr)
+		if err != nil {
+			return err
+		}
+	}
+	runCfg.sols = sols
+	if *mode == "E" {
+		if err := runCfg.loadSolGroups(ctx, dbs[""]); err != nil {
+			return err
+		}
+		if err := checkSourceObjects(ctx, dbs, runCfg, filepath.Join(appCfg.LogFilePath, runCfg.PackageName+"_missing_objects.csv")); err != nil {
+			return err
+		}
+	}
 
 	// --- Logging and Concurrency Setup ---
-	procLogCh := make(chan ProcLog, 1000)
-	var summaryMu sync.Mutex
-	procSummary := make(map[string]ProcSummary)
-
 	if (*mode == "I" && !runCfg.RunInsertionParallel) || (*mode == "E" && !runCfg.RunExtractionParallel) {
 		log.Info("Parallel execution disabled, setting concurrency to 1.")
 		appCfg.Concurrency = 1
 	}
 
-	var logFile, logFileSummary string
-	if *mode == "I" {
-		logFile = runCfg.PackageName + "_insert.csv"
-		logFileSummary = runCfg.PackageName + "_insert_summary.csv"
-	} else {
-		logFile = runCfg.PackageName + "_extract.csv"
-		logFileSummary = runCfg.PackageName + "_extract_summary.csv"
+	stem := logStem(runCfg.PackageName, *mode)
+	// The completion file is shared by reruns; logs are per run when suffixed.
+	completedFile := stem + "_completed.txt"
+	if appCfg.JobLog.Rerun == RerunSuffix {
+		stem += "_" + runCfg.RunID
 	}
-	go writeLog(filepath.Join(appCfg.LogFilePath, logFile), procLogCh)
+	logFile := stem + ".csv"
+	logFileSummary := stem + "_summary.csv"
+	timelineFile := stem + "_timeline.json"
 
-	ctx := context.Background()
+	completed, err := openCompletionStore(filepath.Join(appCfg.LogFilePath, completedFile), *skipExist, *mode == "E")
+	if err != nil {
+		return err
+	}
+	defer completed.Close()
+	completed.journal = journal
+	if *mode == "E" {
+		runCfg.journal = journal
+	}
+
+	var pendingJobs []Job
+	var allJobs, resumedJobs int
+	for _, sol := range sols {
+		for _, proc := range runCfg.Procedures {
+			for _, job := range runCfg.procJobs(proc, sol) {
+				allJobs++
+				if *skipExist && completed.IsDone(job) {
+					continue
+				}
+				if resumed.Keeps(runCfg, job, *mode == "E") {
+					resumedJobs++
+					continue
+				}
+				pendingJobs = append(pendingJobs, job)
+			}
+		}
+	}
+	if skipped := allJobs - len(pendingJobs) - resumedJobs; skipped > 0 {
+		log.Info("⏭️ Skipping jobs completed by earlier runs", "skipped_jobs", skipped)
+		// Earlier SOLs are already in the merged output, so add to it rather than replace it.
+		runCfg.appendOutput = true
+	}
+	if resumed != nil {
+		for _, job := range pendingJobs {
+			// The interrupted run merged this procedure and removed its spools, so the
+			// jobs left over add to its output.
+			if resumed.merged[job.Proc] {
+				runCfg.appendOutput = true
+				break
+			}
+		}
+		if err := resumed.checkMerges(runCfg); err != nil {
+			return err
+		}
+	}
+
+	sortByPriority(pendingJobs, runCfg.ProcedurePriorities)
 
 	// --- Prepare Statements ---
 	log.Info("Preparing database statements...")
-	stmts, err := prepareStatements(ctx, db, &runCfg, templates, *mode)
+	stmts, err := prepareStatements(ctx, dbs, runCfg, templates, *mode)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statements: %w", err)
 	}
-	defer func() {
-		for _, stmt := range stmts {
-			stmt.Close()
+	statements := newStatementSet(stmts, dbs, runCfg)
+	defer statements.close()
+	if runCfg.DebugStatements {
+		path := filepath.Join(appCfg.LogFilePath, strings.TrimSuffix(logFile, ".csv")+"_statements.sql")
+		if err := writeStatements(path, runCfg.statementText); err != nil {
+			log.Warn("Failed to record prepared statements", "error", err)
+		} else {
+			log.Info("🐞 Prepared statements recorded", "file", path)
 		}
-	}()
+	}
+
+	if *mode == "E" && runCfg.SchemaSnapshot {
+		if path, err := snapshotSchema(ctx, dbs, runCfg); err != nil {
+			log.Warn("Failed to take schema snapshot", "error", err)
+		} else {
+			log.Info("🗂️ Schema snapshot written", "file", path)
+		}
+	}
 
 	// --- Setup Worker Pool ---
 	var wg sync.WaitGroup
@@ -139,38 +485,322 @@ func run() error {
 		},
 	}
 
+	var bad *badRecordWriter
+	if runCfg.TolerateBadRecords {
+		bad = newBadRecordWriter(runCfg.SpoolOutputPath)
+		defer bad.Close()
+	}
+
+	mem := newMemoryGate(appCfg.Memory, appCfg.Concurrency, templates)
+	limiter := newConcurrencyLimiter(appCfg.Concurrency, dbs)
+
+	progress := newProgressTracker(pendingJobs, appCfg.Concurrency)
+	progressInterval := 30 * time.Second
+	if appCfg.ProgressIntervalSeconds > 0 {
+		progressInterval = time.Duration(appCfg.ProgressIntervalSeconds) * time.Second
+	}
+	heartbeatInterval := time.Minute
+	if appCfg.HeartbeatIntervalSeconds > 0 {
+		heartbeatInterval = time.Duration(appCfg.HeartbeatIntervalSeconds) * time.Second
+	}
+	progress.stallThreshold = time.Duration(appCfg.StallThresholdSeconds) * time.Second
+	progress.cancelStalled = appCfg.CancelStalledJobs
+	progress.keepTimeline = appCfg.ExportTimeline
+	progress.pools = newPoolMonitor(dbs, time.Duration(appCfg.ConnectionWaitWarnSeconds)*time.Second)
+	if appCfg.RecentFailures > 0 {
+		progress.maxRecentFailures = appCfg.RecentFailures
+	}
+	stopProgress := make(chan struct{})
+	go progress.Run(progressInterval, heartbeatInterval, stopProgress)
+	if appCfg.MonitorOpenCursors {
+		go newCursorMonitor(dbs, appCfg.OpenCursorsWarnPercent).Run(ctx, progressInterval, stopProgress)
+	}
+
+	hooks := newWebhookNotifier(runCfg, *mode)
+	progress.webhooks = hooks
+	var procSummary map[string]ProcSummary
+	defer func() {
+		hooks.RunEnd(procSummary, err)
+		hooks.Close()
+	}()
+
+	// With early merge each procedure is merged as soon as all of its jobs have finished,
+	// so downstream loading need not wait for the slowest procedure of the run.
+	rehearsal := runCfg.limitRows > 0 || runCfg.samplePercent > 0
+	if *mode == "E" && !rehearsal && runCfg.dedupes() {
+		runCfg.previous = previousRun(appCfg.LogFilePath, runCfg.PackageName, *mode)
+	}
+	earlyMerged := make(map[string]bool)
+	mergeGaps := make(map[string][]string)
+	var earlyMergeErr error
+	mergerDone := make(chan struct{})
+	var procDone chan string
+	if *mode == "E" && runCfg.EarlyMerge {
+		procDone = make(chan string, len(runCfg.Procedures))
+		progress.procDone = procDone
+		go func() {
+			defer close(mergerDone)
+			for proc := range procDone {
+				_, mergeSpan := startSpan(ctx, "mergeProcedure", "procedure", proc)
+				gaps, err := mergeProcedure(runCfg, proc)
+				mergeSpan.End(err)
+				earlyMerged[proc] = true
+				if len(gaps) > 0 {
+					mergeGaps[proc] = gaps
+				}
+				if err == nil && !rehearsal {
+					err = completed.CommitProcedure(proc)
+				}
+				if err != nil {
+					log.Error("Early merge failed", "procedure", proc, "error", err)
+					earlyMergeErr = errors.Join(earlyMergeErr, fmt.Errorf("%s: %w", proc, err))
+					continue
+				}
+				log.Info("🚚 Procedure complete and merged", "procedure", proc)
+				if !rehearsal && runCfg.publishes(hooks) {
+					if err := publishMerged(ctx, runCfg, hooks, proc); err != nil {
+						log.Error("Failed to publish merged file", "procedure", proc, "error", err)
+						earlyMergeErr = errors.Join(earlyMergeErr, fmt.Errorf("%s: %w", proc, err))
+					}
+				}
+			}
+		}()
+	} else {
+		close(mergerDone)
+	}
+
+	var statsLog *sessionStatsLog
+	if runCfg.SessionStats {
+		if statsLog, err = openSessionStatsLog(filepath.Join(appCfg.LogFilePath, strings.TrimSuffix(logFile, ".csv")+"_sessionstats.csv")); err != nil {
+			return err
+		}
+		defer statsLog.Close()
+	}
+	sessions := newSessionRunner(dbs, runCfg, statsLog)
+	summaries := newSummaryAggregator()
+	// The procedure log is started with the workers, the only senders, and is closed and
+	// flushed before finalisation reads or reports on the run.
+	procLogCh := make(chan ProcLog, 1000)
+	logDone := make(chan struct{})
+	go func() {
+		defer close(logDone)
+		sinks := openLogSinks(&appCfg, runCfg, dbs[""], filepath.Join(appCfg.LogFilePath, logFile))
+		if health != nil {
+			sinks = append(sinks, health.jobSink(runCfg))
+		}
+		if controlPipe != nil {
+			sinks = append(sinks, controlPipe.jobSink(runCfg))
+		}
+		writeLog(sinks, procLogCh)
+	}()
+	// Jobs run on a context of their own so a cancelled run can cut them short once the
+	// shutdown grace period is over.
+	jobsCtx, abortJobs := context.WithCancelCause(ctx)
+	defer abortJobs(nil)
+	health.setState(runRunning)
 	log.Info("Starting worker pool", "concurrency", appCfg.Concurrency)
+	deps := workerDeps{
+		runCfg: runCfg, templates: templates, mode: *mode, retries: appCfg.JobRetries, slicePool: slicePool, procLogCh: procLogCh, summaries: summaries,
+		stmts: statements, bad: bad, completed: completed, progress: progress, mem: mem, limiter: limiter, sessions: sessions,
+	}
 	for i := 0; i < appCfg.Concurrency; i++ {
+		d := deps
+		if *mode == "I" {
+			d.session = newInsertSession(dbs, runCfg)
+		}
 		wg.Add(1)
-		go worker(i+1, ctx, &wg, &runCfg, jobs, procLogCh, &summaryMu, procSummary, stmts, slicePool, templates, *mode)
+		go worker(jobsCtx, i+1, &wg, jobs, d)
 	}
 
 	// --- Dispatch Jobs ---
-	totalJobs := len(sols) * len(runCfg.Procedures)
-	log.Info("Dispatching jobs...", "sols", len(sols), "procedures", len(runCfg.Procedures), "total_jobs", totalJobs)
+	totalJobs := len(pendingJobs)
+	log.Info("Dispatching jobs...", "run_id", runCfg.RunID, "business_date", runCfg.BusinessDate.Format(businessDateLayout), "sols", len(sols), "procedures", len(runCfg.Procedures), "total_jobs", totalJobs)
 	overallStart := time.Now()
+	hooks.RunStart(totalJobs)
+
+	control := newDispatchControl()
+	control.memory = mem
+	control.grace = time.Duration(appCfg.ShutdownGraceSeconds) * time.Second
+	control.abort = abortJobs
+	rc := &runControl{runID: runCfg.RunID, pkg: runCfg.PackageName, progress: progress, control: control}
+	health.setRun(rc)
+	defer controlPipe.reportRun(rc)()
+	sigs := make(chan os.Signal, 1)
+	notifyControlSignals(sigs)
+	notifyServiceStop(sigs)
+	defer signal.Stop(sigs)
+	defer stopServiceNotify(sigs)
+	go control.handleSignals(sigs)
+	var ui *dashboardUI
+	if *showTUI {
+		ui = startDashboard(progress, control)
+	}
 
+	undispatched := make(chan int, 1)
 	go func() {
-		for _, sol := range sols {
-			for _, proc := range runCfg.Procedures {
-				jobs <- Job{SolID: sol, Proc: proc}
-			}
-		}
-		close(jobs)
+		undispatched <- control.dispatchPhases(splitPhases(pendingJobs, runCfg.Phases, runCfg.Procedures), jobs, progress)
 	}()
 
 	wg.Wait()
+	ui.Stop()
+	var slowest []slowJob
 	close(procLogCh)
+	<-logDone
+	procSummary, slowest = summaries.Close()
+	runCfg.addViewSummaries(procSummary)
+	logSlowestJobs(slowest)
+	close(stopProgress)
+	if procDone != nil {
+		close(procDone)
+	}
+	<-mergerDone
+	progress.Report()
 
-	log.Info("All jobs completed.")
+	skippedJobs := <-undispatched
+	if skippedJobs > 0 {
+		log.Warn("Run cancelled before all jobs were dispatched", "undispatched_jobs", skippedJobs)
+	} else {
+		log.Info("All jobs completed.")
+	}
 
 	// --- Finalization ---
+	var mergeErr, emptyErr, verifyErr, volumeErr, publishErr error
+	if *mode == "E" {
+		if earlyMergeErr != nil {
+			mergeErr = earlyMergeErr
+		} else {
+			var remaining []string
+			for _, proc := range runCfg.Procedures {
+				if !earlyMerged[proc] {
+					remaining = append(remaining, proc)
+				}
+			}
+			_, mergeSpan := startSpan(ctx, "mergeFiles", "procedures", strconv.Itoa(len(remaining)))
+			var gaps map[string][]string
+			gaps, mergeErr = mergeFiles(runCfg, remaining)
+			mergeSpan.End(mergeErr)
+			for proc, files := range gaps {
+				mergeGaps[proc] = files
+			}
+		}
+		recordMergeGaps(procSummary, mergeGaps, runCfg.forceMerge)
+		if mergeErr == nil {
+			describeOutputs(runCfg, procSummary)
+			emptyErr = checkEmptyOutputs(runCfg, procSummary)
+			if runCfg.VerifyOutput {
+				verifyErr = verifyOutputs(runCfg, templates, procSummary)
+			}
+			if !rehearsal && skippedJobs == 0 && !runCfg.appendOutput {
+				volumeErr = checkVolumeAnomalies(runCfg, previousRun(appCfg.LogFilePath, runCfg.PackageName, *mode), procSummary)
+			}
+			if verifyErr == nil && !rehearsal {
+				for _, proc := range runCfg.Procedures {
+					if earlyMerged[proc] {
+						continue
+					}
+					for _, name := range runCfg.outputNames(proc) {
+						for _, out := range procSummary[name].outputs() {
+							if err := publishOutput(ctx, runCfg, hooks, name, out); err != nil {
+								publishErr = errors.Join(publishErr, err)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
 	writeSummary(filepath.Join(appCfg.LogFilePath, logFileSummary), procSummary)
+	writeJSONSummary(filepath.Join(appCfg.LogFilePath, strings.TrimSuffix(logFileSummary, ".csv")+".json"), runCfg, procSummary, slowest)
+	var archiveErr error
+	if *mode == "E" && runCfg.Archive.Enabled && !rehearsal && skippedJobs == 0 && mergeErr == nil && verifyErr == nil {
+		files := archiveFiles(runCfg, procSummary, filepath.Join(appCfg.LogFilePath, logFileSummary), filepath.Join(appCfg.LogFilePath, strings.TrimSuffix(logFileSummary, ".csv")+".json"))
+		var path string
+		if path, archiveErr = writeArchive(runCfg, files); archiveErr == nil {
+			log.Info("📦 Run archived", "archive", path, "files", len(files))
+		}
+	}
+	// Only complete runs go into the history, so trends compare like with like.
+	if !rehearsal && skippedJobs == 0 && !runCfg.appendOutput {
+		if err := appendRunHistory(filepath.Join(appCfg.LogFilePath, historyFile), newRunRecord(runCfg, *mode, time.Now(), procSummary)); err != nil {
+			log.Warn("Failed to record run history", "error", err)
+		}
+	}
+	if runCfg.ColumnStats && *mode == "E" {
+		writeProfiles(appCfg.LogFilePath, runCfg.PackageName, procSummary)
+	}
+	if appCfg.ExportTimeline {
+		if err := writeTimeline(filepath.Join(appCfg.LogFilePath, timelineFile), overallStart, progress.Timeline()); err != nil {
+			log.Warn("Failed to export execution timeline", "error", err)
+		}
+	}
 	if *mode == "E" {
-		if err := mergeFiles(&runCfg); err != nil {
-			return fmt.Errorf("failed to merge files: %w", err)
+		if mergeErr != nil {
+			return fmt.Errorf("failed to merge files: %w", mergeErr)
+		}
+		if emptyErr != nil {
+			return emptyErr
+		}
+		if verifyErr != nil {
+			return verifyErr
 		}
+		if volumeErr != nil {
+			return volumeErr
+		}
+		if publishErr != nil {
+			return publishErr
+		}
+		if archiveErr != nil {
+			return archiveErr
+		}
+		// Rehearsal output is partial, so it must not count as done for -skip-existing.
+		if !rehearsal {
+			if err := completed.Commit(); err != nil {
+				return err
+			}
+		}
+		if *compare && skippedJobs == 0 {
+			mismatches, err := reconcile(ctx, dbs, &appCfg, runCfg, templates, sols)
+			if err != nil {
+				return fmt.Errorf("reconciliation failed: %w", err)
+			}
+			if mismatches > 0 {
+				return fmt.Errorf("reconciliation failed: %d mismatch(es)", mismatches)
+			}
+		}
+	}
+	if skippedJobs > 0 {
+		return fmt.Errorf("run cancelled: %d of %d jobs were not dispatched", skippedJobs, totalJobs)
 	}
 	log.Infof("🎯 All done! Processed %d jobs in %s", totalJobs, time.Since(overallStart).Round(time.Second))
 	return nil
 }
+
+// streamToStdout extracts a single procedure/SOL pair straight to stdout so the tool
+// can be used as the head of a Unix pipeline. No spool, log or summary files are written.
+func streamToStdout(ctx context.Context, dbs map[string]*sql.DB, runCfg *ExtractionConfig, templates map[string][]ColumnConfig, proc, sol string) error {
+	stmts, err := prepareStatements(ctx, dbs, runCfg, templates, "E")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statements: %w", err)
+	}
+	statements := newStatementSet(stmts, dbs, runCfg)
+	defer statements.close()
+	stmt, err := statements.get(ctx, proc, proc)
+	if err != nil {
+		return err
+	}
+	defer statements.release(stmt)
+
+	cols := templates[proc]
+	slicePool := &sync.Pool{
+		New: func() interface{} {
+			return make([]interface{}, len(cols))
+		},
+	}
+
+	start := time.Now()
+	if err := extractToWriter(ctx, os.Stdout, stmt, slicePool, proc, sol, runCfg, templates); err != nil {
+		return fmt.Errorf("extraction to stdout failed: %w", err)
+	}
+	log.Info("✅ Streamed extraction to stdout", "procedure", proc, "sol_id", sol, "duration", time.Since(start).Round(time.Millisecond))
+	return nil
+}
@@ -32,6 +32,10 @@ func BenchmarkExtractData(b *testing.B) {
 		Delimiter:       "|",
 	}
 
+	if err := extractCfg.compileFileNames(); err != nil {
+		b.Fatalf("Invalid filename templates: %v", err)
+	}
+
 	// Create spool directory if it doesn't exist
 	if err := os.MkdirAll(extractCfg.SpoolOutputPath, 0755); err != nil {
 		b.Fatalf("Failed to create spool directory: %v", err)
@@ -81,9 +85,87 @@ func BenchmarkExtractData(b *testing.B) {
 	// --- Run Benchmark ---
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		err := extractData(context.Background(), stmt, slicePool, procName, solID, &extractCfg, templates)
+		_, err := extractData(context.Background(), stmt, slicePool, Job{SolID: solID, Proc: procName}, &extractCfg, templates, nil)
 		if err != nil {
 			b.Fatalf("extractData failed: %v", err)
 		}
 	}
 }
+
+// BenchmarkStatementStrategies compares the statement strategies with the workers of a
+// pool running the same extraction concurrently. Like BenchmarkExtractData it needs a
+// database; set the connection details and procedure below.
+// go test -bench=StatementStrategies -run=^#
+func BenchmarkStatementStrategies(b *testing.B) {
+	procName := "YOUR_PROCEDURE_NAME"
+	solID := "YOUR_SOL_ID"
+	templates := map[string][]ColumnConfig{
+		procName: {
+			{Name: "COLUMN1"},
+			{Name: "COLUMN2"},
+		},
+	}
+	appCfg := &MainConfig{
+		DBUser:      "user",
+		DBPassword:  "password",
+		DBHost:      "localhost",
+		DBPort:      1521,
+		DBSid:       "orcl",
+		Concurrency: 8,
+	}
+	db, err := openDatabase(appCfg)
+	if err != nil {
+		b.Fatalf("Failed to connect to DB: %v", err)
+	}
+	defer db.Close()
+	dbs := map[string]*sql.DB{"": db}
+
+	for _, strategy := range []string{StatementsShared, StatementsWorker, StatementsCached} {
+		b.Run(strategy, func(b *testing.B) {
+			runCfg := &ExtractionConfig{
+				SpoolOutputPath:   b.TempDir(),
+				Format:            "delimited",
+				Delimiter:         "|",
+				Procedures:        []string{procName},
+				StatementStrategy: strategy,
+			}
+			if err := runCfg.compileFileNames(); err != nil {
+				b.Fatalf("Invalid filename templates: %v", err)
+			}
+			stmts, err := prepareStatements(context.Background(), dbs, runCfg, templates, "E")
+			if err != nil {
+				b.Fatalf("Failed to prepare statements: %v", err)
+			}
+			shared := newStatementSet(stmts, dbs, runCfg)
+			defer shared.close()
+			slicePool := &sync.Pool{
+				New: func() interface{} {
+					return make([]interface{}, len(templates[procName]))
+				},
+			}
+
+			b.SetParallelism(appCfg.Concurrency)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				own := shared.forWorker()
+				if own != shared {
+					defer own.close()
+				}
+				job := Job{SolID: solID, Proc: procName}
+				for pb.Next() {
+					stmt, err := own.get(context.Background(), procName, procName)
+					if err != nil {
+						b.Errorf("prepare failed: %v", err)
+						return
+					}
+					_, err = extractData(context.Background(), stmt, slicePool, job, runCfg, templates, nil)
+					own.release(stmt)
+					if err != nil {
+						b.Errorf("extractData failed: %v", err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
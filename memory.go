@@ -0,0 +1,117 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// defaultLargeRowBytes is the template row width from which a procedure counts as
+// large-row when not configured.
+const defaultLargeRowBytes = 4096
+
+// MemoryConfig bounds the tool's memory use on shared extraction servers.
+type MemoryConfig struct {
+	MaxMB           int `json:"max_mb"`
+	MaxLargeRowJobs int `json:"max_large_row_jobs"`
+	LargeRowBytes   int `json:"large_row_bytes"`
+	FetchArraySize  int `json:"fetch_array_size"`
+}
+
+// memoryGate applies the memory budget: it limits how many large-row jobs run at once
+// and holds back dispatch while the heap is over budget. A nil gate imposes no limits.
+type memoryGate struct {
+	budget    uint64
+	large     chan struct{}
+	largeRows map[string]bool
+}
+
+// newMemoryGate sets the runtime soft memory limit and prepares the gate, or returns nil
+// when no budget is configured.
+func newMemoryGate(cfg MemoryConfig, concurrency int, templates map[string][]ColumnConfig) *memoryGate {
+	if cfg.MaxMB <= 0 {
+		return nil
+	}
+	budget := uint64(cfg.MaxMB) << 20
+	debug.SetMemoryLimit(int64(budget))
+
+	threshold := cfg.LargeRowBytes
+	if threshold <= 0 {
+		threshold = defaultLargeRowBytes
+	}
+	slots := cfg.MaxLargeRowJobs
+	if slots <= 0 {
+		slots = max(1, concurrency/4)
+	}
+	g := &memoryGate{budget: budget, large: make(chan struct{}, slots), largeRows: make(map[string]bool)}
+	for proc, cols := range templates {
+		if rowWidth(cols) >= threshold {
+			g.largeRows[proc] = true
+		}
+	}
+	log.Info("Memory budget enabled", "max_mb", cfg.MaxMB, "large_row_procedures", len(g.largeRows), "max_large_row_jobs", slots)
+	return g
+}
+
+// rowWidth estimates the size of one output record of a template.
+func rowWidth(cols []ColumnConfig) int {
+	width := 0
+	for _, c := range cols {
+		if c.Length > 0 {
+			width += c.Length
+		} else {
+			width += 64
+		}
+	}
+	return width
+}
+
+// acquire blocks until proc may run; release must be called when it has finished.
+func (g *memoryGate) acquire(proc string) {
+	if g != nil && g.largeRows[proc] {
+		g.large <- struct{}{}
+	}
+}
+
+func (g *memoryGate) release(proc string) {
+	if g != nil && g.largeRows[proc] {
+		<-g.large
+	}
+}
+
+// waitForHeap holds back dispatch while the live heap exceeds the budget, returning false
+// if the run was cancelled meanwhile.
+func (g *memoryGate) waitForHeap(d *dispatchControl) bool {
+	if g == nil {
+		return true
+	}
+	warned := false
+	for heapBytes() > g.budget {
+		if d.Cancelled() {
+			return false
+		}
+		if !warned {
+			log.Warn("🐢 Heap over memory budget, slowing dispatch", "heap_mb", heapBytes()>>20, "budget_mb", g.budget>>20)
+			warned = true
+		}
+		runtime.GC()
+		time.Sleep(time.Second)
+	}
+	if warned {
+		log.Info("Heap back under memory budget, dispatch continues", "heap_mb", heapBytes()>>20)
+	}
+	return true
+}
+
+// heapBytes returns the bytes occupied by live and not yet swept heap objects.
+func heapBytes() uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
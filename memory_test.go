@@ -0,0 +1,70 @@
+package main
+
+import (
+	"runtime/debug"
+	"testing"
+	"time"
+)
+
+func TestMemoryGateLimitsLargeRowJobs(t *testing.T) {
+	limit := debug.SetMemoryLimit(-1)
+	t.Cleanup(func() { debug.SetMemoryLimit(limit) })
+
+	templates := map[string][]ColumnConfig{
+		"WIDE":   {{Name: "A", Length: 3000}, {Name: "B", Length: 2000}},
+		"NARROW": {{Name: "A", Length: 10}, {Name: "B"}},
+	}
+	if g := newMemoryGate(MemoryConfig{}, 8, templates); g != nil {
+		t.Fatal("gate without a budget")
+	}
+	g := newMemoryGate(MemoryConfig{MaxMB: 1 << 20, MaxLargeRowJobs: 1}, 8, templates)
+	if !g.largeRows["WIDE"] || g.largeRows["NARROW"] {
+		t.Errorf("large-row procedures = %v, want WIDE only", g.largeRows)
+	}
+	if got := debug.SetMemoryLimit(-1); got != 1<<40 {
+		t.Errorf("soft memory limit = %d, want the budget", got)
+	}
+
+	// A second large-row job waits for the first; other jobs do not.
+	g.acquire("WIDE")
+	started := make(chan struct{})
+	go func() {
+		g.acquire("WIDE")
+		close(started)
+	}()
+	g.acquire("NARROW")
+	select {
+	case <-started:
+		t.Fatal("two large-row jobs ran at once")
+	case <-time.After(50 * time.Millisecond):
+	}
+	g.release("WIDE")
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("large-row job not let through after the first finished")
+	}
+
+	// Without max_large_row_jobs a quarter of the workers may run large-row jobs.
+	if g := newMemoryGate(MemoryConfig{MaxMB: 1 << 20}, 8, templates); cap(g.large) != 2 {
+		t.Errorf("large-row slots = %d, want 2", cap(g.large))
+	}
+}
+
+func TestMemoryGateHoldsDispatchOverBudget(t *testing.T) {
+	d := newDispatchControl()
+	if !(&memoryGate{budget: 1 << 40}).waitForHeap(d) {
+		t.Error("dispatch held back under budget")
+	}
+	// Over budget, dispatch waits until the run is cancelled.
+	d.Cancel()
+	if (&memoryGate{budget: 1}).waitForHeap(d) {
+		t.Error("dispatch continued over budget")
+	}
+	var nilGate *memoryGate
+	nilGate.acquire("WIDE")
+	nilGate.release("WIDE")
+	if !nilGate.waitForHeap(d) {
+		t.Error("nil gate held back dispatch")
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// seqField finds a reference to the part number, which is only looked up for templates
+// that use it.
+var seqField = regexp.MustCompile(`\.Seq\b`)
+
+const (
+	defaultSpoolFileTemplate       = "{{.Proc}}_{{.SolID}}_{{.RunID}}.spool"
+	defaultOutputFileTemplate      = "{{.Proc}}.txt"
+	defaultSolOutputFileTemplate   = "{{.Proc}}_{{.SolID}}.txt"
+	defaultGroupOutputFileTemplate = "{{.Proc}}_{{.Group}}.txt"
+)
+
+// fileNameData is the data available to spool and output filename templates, e.g.
+// {{.Proc}}_{{.Date "20060102"}}_{{.RunID}}_{{.Seq 3}}.txt
+type fileNameData struct {
+	Proc     string
+	SolID    string
+	Group    string // output group, when SOLs are merged by group
+	RunID    string
+	seq      int
+	runStart time.Time
+	busDate  time.Time
+	wildcard bool
+	// marks, when set, collects the regular expressions of the run-dependent fields,
+	// which render as placeholders for filePattern to substitute.
+	marks *[]string
+}
+
+// Date formats the run start time with the given Go time layout.
+func (d fileNameData) Date(layout string) string {
+	if d.marks != nil {
+		return d.mark(layoutPattern(layout))
+	}
+	if d.wildcard {
+		return "*"
+	}
+	return d.runStart.In(timestamps.loc).Format(layout)
+}
+
+// BusinessDate formats the run's business date with the given Go time layout.
+func (d fileNameData) BusinessDate(layout string) string {
+	if d.marks != nil {
+		return d.mark(layoutPattern(layout))
+	}
+	if d.wildcard {
+		return "*"
+	}
+	return d.busDate.Format(layout)
+}
+
+// Seq formats the part number, zero-padded to width digits: the position of the SOL
+// among the run's SOLs for per-SOL files, of the group among the output groups for
+// grouped outputs, and 1 for a procedure's single merged file.
+func (d fileNameData) Seq(width int) string {
+	if d.marks != nil {
+		return d.mark(`([0-9]+)`)
+	}
+	if d.wildcard {
+		return "*"
+	}
+	return fmt.Sprintf("%0*d", width, d.seq)
+}
+
+// partSeq returns the part number of the file data names, or 0 for a SOL or group that
+// is not part of the run.
+func (c *ExtractionConfig) partSeq(data fileNameData) int {
+	switch {
+	case data.Group != "":
+		return slices.Index(c.groupNames(), data.Group) + 1
+	case data.SolID != "":
+		return slices.Index(c.sols, data.SolID) + 1
+	}
+	return 1
+}
+
+// mark records pattern and returns the placeholder filePattern replaces with it.
+func (d fileNameData) mark(pattern string) string {
+	*d.marks = append(*d.marks, pattern)
+	return fmt.Sprintf("\x00%d\x00", len(*d.marks)-1)
+}
+
+var markPlaceholder = regexp.MustCompile("\x00([0-9]+)\x00")
+
+// layoutPattern returns a regular expression matching any time formatted with layout:
+// each digit of a sample rendering may be any digit and each letter any letter.
+func layoutPattern(layout string) string {
+	var b strings.Builder
+	for _, r := range time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC).Format(layout) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteString("[0-9]")
+		case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+			b.WriteString("[A-Za-z]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
+
+// filePattern returns a regular expression matching the path of every file of proc the
+// templates name, from any run: the run ID matches any value and dates any date of their
+// layout, and the SOL ID and group are captured, so each SOL's or group's files can be
+// told apart.
+func (c *ExtractionConfig) filePattern(base string, dirTmpl, fileTmpl *template.Template, proc, suffix string) (*regexp.Regexp, error) {
+	var marks []string
+	data := fileNameData{Proc: proc, marks: &marks}
+	data.SolID = data.mark(`(.+?)`)
+	data.Group = data.mark(`(.+?)`)
+	data.RunID = data.mark(`[^/\\]+?`)
+	path, err := c.renderPath(base, dirTmpl, fileTmpl, data)
+	if err != nil {
+		return nil, err
+	}
+	pattern := markPlaceholder.ReplaceAllStringFunc(regexp.QuoteMeta(path), func(m string) string {
+		n, _ := strconv.Atoi(strings.Trim(m, "\x00"))
+		return marks[n]
+	})
+	return regexp.Compile("^" + pattern + suffix + "$")
+}
+
+// compileFileNames parses the configured filename templates, falling back to the
+// PROC_SOL_RUNID.spool / PROC.txt names when none are set.
+func (c *ExtractionConfig) compileFileNames() error {
+	spool := c.SpoolFileTemplate
+	if spool == "" {
+		spool = defaultSpoolFileTemplate
+	}
+	output := c.OutputFileTemplate
+	if output == "" {
+		output = defaultOutputFileTemplate
+		if c.PerSolOutput {
+			output = defaultSolOutputFileTemplate
+		} else if c.OutputGroups.enabled() {
+			output = defaultGroupOutputFileTemplate
+		}
+	}
+
+	c.usesSeq = false
+	for _, text := range []string{spool, output, c.SpoolDirTemplate, c.OutputDirTemplate} {
+		c.usesSeq = c.usesSeq || seqField.MatchString(text)
+	}
+
+	var err error
+	if c.spoolTmpl, err = template.New("spool").Option("missingkey=error").Parse(spool); err != nil {
+		return fmt.Errorf("invalid spool_file_template %q: %w", spool, err)
+	}
+	if c.outputTmpl, err = template.New("output").Option("missingkey=error").Parse(output); err != nil {
+		return fmt.Errorf("invalid output_file_template %q: %w", output, err)
+	}
+
+	if c.SpoolDirTemplate != "" {
+		if c.spoolDirTmpl, err = template.New("spool_dir").Option("missingkey=error").Parse(c.SpoolDirTemplate); err != nil {
+			return fmt.Errorf("invalid spool_dir_template %q: %w", c.SpoolDirTemplate, err)
+		}
+	}
+	if c.OutputDirTemplate != "" {
+		if c.outputDirTmpl, err = template.New("output_dir").Option("missingkey=error").Parse(c.OutputDirTemplate); err != nil {
+			return fmt.Errorf("invalid output_dir_template %q: %w", c.OutputDirTemplate, err)
+		}
+	}
+
+	// Render once with sample values so template errors surface before any job runs.
+	if _, err := c.renderFileName(c.spoolTmpl, fileNameData{Proc: "PROC", SolID: "SOL"}); err != nil {
+		return err
+	}
+	if _, err := c.renderFileName(c.outputTmpl, fileNameData{Proc: "PROC"}); err != nil {
+		return err
+	}
+	if _, err := c.spoolFilePath("PROC", "SOL"); err != nil {
+		return err
+	}
+	if _, err := c.outputFilePath("PROC"); err != nil {
+		return err
+	}
+	if c.PerSolOutput {
+		a, _ := c.compressedPath("PROC")(c.renderPath(c.outputDir(), c.outputDirTmpl, c.outputTmpl, fileNameData{Proc: "PROC", SolID: "A", seq: 1}))
+		b, _ := c.compressedPath("PROC")(c.renderPath(c.outputDir(), c.outputDirTmpl, c.outputTmpl, fileNameData{Proc: "PROC", SolID: "B", seq: 2}))
+		if a == b {
+			return fmt.Errorf("per_sol_output needs {{.SolID}} or {{.Seq}} in output_file_template or output_dir_template, got %q", output)
+		}
+	}
+	if c.OutputGroups.enabled() {
+		a, _ := c.compressedPath("PROC")(c.renderPath(c.outputDir(), c.outputDirTmpl, c.outputTmpl, fileNameData{Proc: "PROC", Group: "A", seq: 1}))
+		b, _ := c.compressedPath("PROC")(c.renderPath(c.outputDir(), c.outputDirTmpl, c.outputTmpl, fileNameData{Proc: "PROC", Group: "B", seq: 2}))
+		if a == b {
+			return fmt.Errorf("output_groups needs {{.Group}} or {{.Seq}} in output_file_template or output_dir_template, got %q", output)
+		}
+	}
+
+	a, _ := c.renderFileName(c.spoolTmpl, fileNameData{Proc: "PROC", SolID: "SOL", RunID: "A"})
+	b, _ := c.renderFileName(c.spoolTmpl, fileNameData{Proc: "PROC", SolID: "SOL", RunID: "B"})
+	if a == b {
+		log.Warn("spool_file_template does not include {{.RunID}}; spool files left by earlier runs cannot be told apart and will be merged")
+	}
+	return c.compileTrigger()
+}
+
+func (c *ExtractionConfig) renderFileName(tmpl *template.Template, data fileNameData) (string, error) {
+	if data.RunID == "" {
+		data.RunID = c.RunID
+	}
+	if data.seq == 0 && c.usesSeq {
+		data.seq = c.partSeq(data)
+	}
+	data.runStart = c.RunStart
+	data.busDate = c.BusinessDate
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render %s filename template: %w", tmpl.Name(), err)
+	}
+	name := sb.String()
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("%s filename template produced invalid file name %q", tmpl.Name(), name)
+	}
+	return name, nil
+}
+
+// renderDir renders a directory layout template into a path relative to the spool or
+// output directory. A nil template means the flat layout.
+func (c *ExtractionConfig) renderDir(tmpl *template.Template, data fileNameData) (string, error) {
+	if tmpl == nil {
+		return "", nil
+	}
+	if data.RunID == "" {
+		data.RunID = c.RunID
+	}
+	if data.seq == 0 && c.usesSeq {
+		data.seq = c.partSeq(data)
+	}
+	data.runStart = c.RunStart
+	data.busDate = c.BusinessDate
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render %s directory template: %w", tmpl.Name(), err)
+	}
+	dir := filepath.Clean(filepath.FromSlash(sb.String()))
+	if filepath.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s directory template produced a path outside its base directory: %q", tmpl.Name(), dir)
+	}
+	return dir, nil
+}
+
+// renderPath joins base, the rendered layout directory and file name.
+func (c *ExtractionConfig) renderPath(base string, dirTmpl, fileTmpl *template.Template, data fileNameData) (string, error) {
+	dir, err := c.renderDir(dirTmpl, data)
+	if err != nil {
+		return "", err
+	}
+	name, err := c.renderFileName(fileTmpl, data)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, dir, name), nil
+}
+
+// spoolFilePath returns the spool file path for a procedure and SOL ID.
+func (c *ExtractionConfig) spoolFilePath(proc, solID string) (string, error) {
+	return c.renderPath(c.SpoolOutputPath, c.spoolDirTmpl, c.spoolTmpl, fileNameData{Proc: proc, SolID: solID})
+}
+
+// staleSpoolGlob returns a glob pattern matching spool files of a procedure from any run.
+func (c *ExtractionConfig) staleSpoolGlob(proc string) (string, error) {
+	return c.renderPath(c.SpoolOutputPath, c.spoolDirTmpl, c.spoolTmpl, fileNameData{Proc: proc, SolID: "*", RunID: "*", wildcard: true})
+}
+
+// quarantineDir returns the directory stale spool files are moved to.
+func (c *ExtractionConfig) quarantineDir() string {
+	if c.QuarantinePath != "" {
+		return c.QuarantinePath
+	}
+	return filepath.Join(c.SpoolOutputPath, "quarantine")
+}
+
+// uploadStateDir returns the directory resumable uploads keep their session state in,
+// away from the output directory downstream systems watch.
+func (c *ExtractionConfig) uploadStateDir() string {
+	return filepath.Join(c.SpoolOutputPath, "uploads")
+}
+
+// outputDir returns the directory merged outputs are written to.
+func (c *ExtractionConfig) outputDir() string {
+	if c.FinalOutputPath != "" {
+		return c.FinalOutputPath
+	}
+	return c.SpoolOutputPath
+}
+
+// outputFilePath returns the merged output file path for a procedure.
+func (c *ExtractionConfig) outputFilePath(proc string) (string, error) {
+	return c.compressedPath(proc)(c.renderPath(c.outputDir(), c.outputDirTmpl, c.outputTmpl, fileNameData{Proc: proc}))
+}
+
+// solOutputFilePath returns the final file of one SOL of a procedure, for per-SOL outputs.
+func (c *ExtractionConfig) solOutputFilePath(proc, solID string) (string, error) {
+	return c.compressedPath(proc)(c.renderPath(c.outputDir(), c.outputDirTmpl, c.outputTmpl, fileNameData{Proc: proc, SolID: solID}))
+}
+
+// groupOutputFilePath returns the final file of one output group of a procedure.
+func (c *ExtractionConfig) groupOutputFilePath(proc, group string) (string, error) {
+	return c.compressedPath(proc)(c.renderPath(c.outputDir(), c.outputDirTmpl, c.outputTmpl, fileNameData{Proc: proc, Group: group}))
+}
+
+// compressedPath returns a function adding the extension of proc's codec to a rendered
+// output path.
+func (c *ExtractionConfig) compressedPath(proc string) func(string, error) (string, error) {
+	return func(path string, err error) (string, error) {
+		if err != nil {
+			return "", err
+		}
+		return path + c.outputExtension(proc), nil
+	}
+}
+
+// partitioned reports whether procedures have several final files, per SOL or per group.
+func (c *ExtractionConfig) partitioned() bool {
+	return c.PerSolOutput || c.OutputGroups.enabled()
+}
+
+// outputPart is one final file of a procedure and the SOLs merged into it; no SOLs
+// means every spool file of the procedure.
+type outputPart struct {
+	Path  string
+	SolID string
+	Group string
+	sols  []string
+}
+
+// outputParts returns the final files of proc: one per SOL of the run for per-SOL
+// outputs, one per group of SOLs for output groups, otherwise the single merged file.
+func (c *ExtractionConfig) outputParts(proc string) ([]outputPart, error) {
+	if c.OutputGroups.enabled() {
+		return c.groupParts(proc)
+	}
+	if !c.PerSolOutput {
+		path, err := c.outputFilePath(proc)
+		if err != nil {
+			return nil, err
+		}
+		return []outputPart{{Path: path}}, nil
+	}
+	parts := make([]outputPart, 0, len(c.sols))
+	for _, sol := range c.sols {
+		path, err := c.solOutputFilePath(proc, sol)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, outputPart{Path: path, SolID: sol, sols: []string{sol}})
+	}
+	return parts, nil
+}
+
+// outputFileGlob returns a pattern matching the merged output of a procedure from any run.
+func (c *ExtractionConfig) outputFileGlob(proc string) (string, error) {
+	return c.compressedPath(proc)(c.renderPath(c.outputDir(), c.outputDirTmpl, c.outputTmpl, fileNameData{Proc: proc, RunID: "*", wildcard: true}))
+}
+
+// ensureParentDir creates the layout directory of path when directory templates are used.
+func (c *ExtractionConfig) ensureParentDir(path string) error {
+	if c.spoolDirTmpl == nil && c.outputDirTmpl == nil {
+		return nil
+	}
+	mode := c.dirMode
+	if mode == 0 {
+		mode = defaultDirMode
+	}
+	if err := os.MkdirAll(filepath.Dir(path), mode); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFileNameTemplates(t *testing.T) {
+	cfg := ExtractionConfig{
+		SpoolOutputPath:    "out",
+		SpoolFileTemplate:  `{{.Proc}}_{{.SolID}}_{{.RunID}}.spool`,
+		OutputFileTemplate: `{{.Proc}}_{{.Date "20060102"}}_{{.RunID}}.txt`,
+		RunID:              "R1",
+		RunStart:           time.Date(2024, 3, 31, 23, 0, 0, 0, time.UTC),
+	}
+	if err := cfg.compileFileNames(); err != nil {
+		t.Fatalf("compileFileNames: %v", err)
+	}
+
+	spool, err := cfg.spoolFilePath("GAM", "0001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join("out", "GAM_0001_R1.spool"); spool != want {
+		t.Errorf("spool path = %q, want %q", spool, want)
+	}
+
+	final, err := cfg.outputFilePath("GAM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join("out", "GAM_20240331_R1.txt"); final != want {
+		t.Errorf("output path = %q, want %q", final, want)
+	}
+}
+
+func TestFileNameTemplateRejectsBadFields(t *testing.T) {
+	cfg := ExtractionConfig{OutputFileTemplate: "{{.Procedure}}.txt"}
+	if err := cfg.compileFileNames(); err == nil {
+		t.Fatal("expected error for unknown template field")
+	}
+}
+
+func TestSeqNumbersOutputParts(t *testing.T) {
+	cfg := ExtractionConfig{SpoolOutputPath: "out", PerSolOutput: true, OutputFileTemplate: "{{.Proc}}_{{.Seq 3}}.txt", sols: []string{"0007", "0002"}}
+	if err := cfg.compileFileNames(); err != nil {
+		t.Fatal(err)
+	}
+	parts, err := cfg.outputParts("GAM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range parts {
+		got = append(got, filepath.Base(p.Path))
+	}
+	if want := []string{"GAM_001.txt", "GAM_002.txt"}; !slices.Equal(got, want) {
+		t.Errorf("per-SOL parts = %v, want %v", got, want)
+	}
+
+	cfg = ExtractionConfig{SpoolOutputPath: "out", OutputFileTemplate: "{{.Proc}}_{{.Seq 2}}.txt"}
+	cfg.sols, cfg.solGroups = []string{"0001", "0002", "0003"}, map[string]string{"0001": "WEST", "0002": "EAST", "0003": "WEST"}
+	if err := cfg.compileFileNames(); err != nil {
+		t.Fatal(err)
+	}
+	west, _ := cfg.groupOutputFilePath("GAM", "WEST")
+	single, _ := cfg.outputFilePath("GAM")
+	if filepath.Base(west) != "GAM_02.txt" || filepath.Base(single) != "GAM_01.txt" {
+		t.Errorf("group WEST = %s, single file = %s, want GAM_02.txt and GAM_01.txt", west, single)
+	}
+}
+
+func TestDirectoryLayoutTemplates(t *testing.T) {
+	cfg := ExtractionConfig{
+		SpoolOutputPath:   "out",
+		SpoolDirTemplate:  `spool/{{.Proc}}/{{.Date "20060102"}}`,
+		OutputDirTemplate: `{{.Proc}}`,
+		RunID:             "R1",
+		RunStart:          time.Date(2024, 3, 31, 23, 0, 0, 0, time.UTC),
+	}
+	if err := cfg.compileFileNames(); err != nil {
+		t.Fatalf("compileFileNames: %v", err)
+	}
+
+	spool, err := cfg.spoolFilePath("GAM", "0001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join("out", "spool", "GAM", "20240331", "GAM_0001_R1.spool"); spool != want {
+		t.Errorf("spool path = %q, want %q", spool, want)
+	}
+	stale, err := cfg.staleSpoolGlob("GAM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join("out", "spool", "GAM", "*", "GAM_*_*.spool"); stale != want {
+		t.Errorf("stale spool glob = %q, want %q", stale, want)
+	}
+	final, err := cfg.outputFilePath("GAM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join("out", "GAM", "GAM.txt"); final != want {
+		t.Errorf("output path = %q, want %q", final, want)
+	}
+
+	escape := ExtractionConfig{OutputDirTemplate: "../{{.Proc}}"}
+	if err := escape.compileFileNames(); err == nil {
+		t.Error("expected error for a layout leaving the spool directory")
+	}
+}
+
+func TestSplitObjectName(t *testing.T) {
+	for in, want := range map[string][2]string{
+		"gam_view":         {"", "GAM_VIEW"},
+		"mig.gam_view":     {"MIG", "GAM_VIEW"},
+		`"Mig"."Gam View"`: {"Mig", "Gam View"},
+		`mig."Mixed_Case"`: {"MIG", "Mixed_Case"},
+	} {
+		owner, name := splitObjectName(in)
+		if owner != want[0] || name != want[1] {
+			t.Errorf("splitObjectName(%q) = %q, %q, want %q, %q", in, owner, name, want[0], want[1])
+		}
+	}
+}
+
+func TestBusinessDateBinds(t *testing.T) {
+	cfg := &ExtractionConfig{PackageName: "MIG_PKG", BusinessDateColumn: "TRAN_DATE", BusinessDateParam: true}
+	cfg.BusinessDate, _ = parseBusinessDate("2026-03-31")
+
+	if got, want := cfg.solFilter(), "SOL_ID = :1 AND TRAN_DATE = TO_DATE(:2, 'YYYY-MM-DD')"; got != want {
+		t.Errorf("solFilter() = %q, want %q", got, want)
+	}
+	if args := cfg.solArgs("001"); len(args) != 2 || args[1] != "2026-03-31" {
+		t.Errorf("solArgs() = %v", args)
+	}
+	if got, want := cfg.procedureCall("GAM"), "BEGIN MIG_PKG.GAM(:1, TO_DATE(:2, 'YYYY-MM-DD')); END;"; got != want {
+		t.Errorf("procedureCall() = %q, want %q", got, want)
+	}
+	if _, err := parseBusinessDate("31/03/2026"); err == nil {
+		t.Error("parseBusinessDate accepted a non-ISO date")
+	}
+}
+
+func TestChunkJobs(t *testing.T) {
+	cfg := &ExtractionConfig{BusinessDateColumn: "TRAN_DATE", SolChunks: map[string]ChunkConfig{"GAM": {Buckets: 4, Key: "ACID"}}}
+
+	jobs := cfg.procJobs("GAM", "001")
+	if len(jobs) != 4 || jobs[0].spoolID() != "001.001" || jobs[3].spoolID() != "001.004" {
+		t.Fatalf("procJobs(GAM) = %v", jobs)
+	}
+	if jobs := cfg.procJobs("HTD", "001"); len(jobs) != 1 || jobs[0].spoolID() != "001" {
+		t.Errorf("procJobs(HTD) = %v", jobs)
+	}
+	if got, want := cfg.selectQuery("GAM", []string{"ACID"}), "SELECT ACID FROM GAM WHERE SOL_ID = :1 AND TRAN_DATE = TO_DATE(:2, 'YYYY-MM-DD') AND ORA_HASH(ACID, 3) = :3"; got != want {
+		t.Errorf("selectQuery() = %q, want %q", got, want)
+	}
+	if args := cfg.jobArgs(jobs[2]); len(args) != 3 || args[2] != 2 {
+		t.Errorf("jobArgs() = %v", args)
+	}
+}
+
+func TestDescribeBinds(t *testing.T) {
+	cfg := &ExtractionConfig{BusinessDateColumn: "TRAN_DATE", DebugMaskBinds: []string{"sol_id"}}
+	cfg.BusinessDate, _ = parseBusinessDate("2026-03-31")
+
+	got := cfg.describeBinds("E", cfg.solArgs("001"))
+	if want := ":1 SOL_ID=****, :2 BUSINESS_DATE='2026-03-31'"; got != want {
+		t.Errorf("describeBinds() = %q, want %q", got, want)
+	}
+}
+
+func TestTimestampFormat(t *testing.T) {
+	ts := time.Date(2026, 3, 31, 23, 30, 0, 0, time.UTC)
+	f, err := newTimestampFormat(TimestampConfig{Timezone: "Asia/Kolkata"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.format(ts); got != "2026-04-01T05:00:00+05:30" {
+		t.Errorf("format() = %q", got)
+	}
+	if got := f.runID(ts); got != "20260401050000" {
+		t.Errorf("runID() = %q", got)
+	}
+	if back, err := f.parseRunID(f.runID(ts)); err != nil || !back.Equal(ts) {
+		t.Errorf("parseRunID() = %v, %v, want %v", back, err, ts)
+	}
+	if f, _ := newTimestampFormat(TimestampConfig{Format: "legacy", Timezone: "UTC"}); f.format(ts) != "31-03-2026 23:30:00" {
+		t.Errorf("legacy format() = %q", f.format(ts))
+	}
+	if _, err := newTimestampFormat(TimestampConfig{Timezone: "Mars/Olympus"}); err == nil {
+		t.Error("newTimestampFormat accepted an unknown timezone")
+	}
+}
+
+func TestPerSolOutputParts(t *testing.T) {
+	dir := t.TempDir()
+	cfg := ExtractionConfig{SpoolOutputPath: dir, PerSolOutput: true, RunID: "R1", sols: []string{"0001", "0002"}}
+	if err := cfg.compileFileNames(); err != nil {
+		t.Fatal(err)
+	}
+	for sol, data := range map[string]string{"0001": "a\nb\n", "0002": "c"} {
+		spool, _ := cfg.spoolFilePath("GAM", sol)
+		os.WriteFile(spool, []byte(data), 0644)
+	}
+
+	if gaps, err := mergeProcedure(&cfg, "GAM"); err != nil || len(gaps) > 0 {
+		t.Fatalf("mergeProcedure = %v, %v", gaps, err)
+	}
+	for sol, want := range map[string]string{"0001": "a\nb\n", "0002": "c\n"} {
+		got, err := os.ReadFile(filepath.Join(dir, "GAM_"+sol+".txt"))
+		if err != nil || string(got) != want {
+			t.Errorf("GAM_%s.txt = %q (%v), want %q", sol, got, err, want)
+		}
+	}
+	summary := map[string]ProcSummary{"GAM": {Status: "SUCCESS", Rows: 3}}
+	describeOutputs(&cfg, summary)
+	if parts := summary["GAM"].Parts; len(parts) != 2 || parts[1].SolID != "0002" || parts[1].Records != 1 {
+		t.Errorf("parts = %+v, want two files, 0002 holding 1 record", parts)
+	}
+
+	noSol := ExtractionConfig{PerSolOutput: true, OutputFileTemplate: "{{.Proc}}.txt"}
+	if err := noSol.compileFileNames(); err == nil {
+		t.Error("per_sol_output accepted an output template without {{.SolID}}")
+	}
+}
+
+func TestOutputGroupParts(t *testing.T) {
+	dir := t.TempDir()
+	mapping := filepath.Join(dir, "groups.csv")
+	os.WriteFile(mapping, []byte("SOL_ID,REGION\n# north\n0001,NORTH\n0003, NORTH\n0002,SOUTH\n"), 0644)
+	cfg := ExtractionConfig{SpoolOutputPath: dir, RunID: "R1", sols: []string{"0001", "0002", "0003", "0004"}, OutputGroups: OutputGroupConfig{File: mapping}}
+	if err := cfg.compileFileNames(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.loadSolGroups(context.Background(), nil); err == nil {
+		t.Fatal("unmapped SOL 0004 accepted without a default group")
+	}
+	cfg.OutputGroups.Default = "OTHER"
+	if err := cfg.loadSolGroups(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, sol := range cfg.sols {
+		spool, _ := cfg.spoolFilePath("GAM", sol)
+		os.WriteFile(spool, []byte(sol+"\n"), 0644)
+	}
+
+	if gaps, err := mergeProcedure(&cfg, "GAM"); err != nil || len(gaps) > 0 {
+		t.Fatalf("mergeProcedure = %v, %v", gaps, err)
+	}
+	for group, want := range map[string]string{"NORTH": "0001\n0003\n", "SOUTH": "0002\n", "OTHER": "0004\n"} {
+		got, err := os.ReadFile(filepath.Join(dir, "GAM_"+group+".txt"))
+		if err != nil || string(got) != want {
+			t.Errorf("GAM_%s.txt = %q (%v), want %q", group, got, err, want)
+		}
+	}
+}
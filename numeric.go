@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Sign conventions of COBOL-style numeric columns in fixed-width output.
+const (
+	SignNone      = "none"      // unsigned; negative values are rejected
+	SignLeading   = "leading"   // explicit + or - before the digits
+	SignTrailing  = "trailing"  // explicit + or - after the digits
+	SignOverpunch = "overpunch" // sign overpunched on the last digit, as PIC S9 DISPLAY
+)
+
+func validSign(s string) bool {
+	return s == "" || s == SignNone || s == SignLeading || s == SignTrailing || s == SignOverpunch
+}
+
+// Overpunched last digits, indexed by digit, for positive and negative values.
+const (
+	overpunchPositive = "{ABCDEFGHI"
+	overpunchNegative = "}JKLMNOPQR"
+)
+
+// numeric reports whether the column is formatted as a COBOL-style number.
+func (c ColumnConfig) numeric() bool {
+	return c.Scale > 0 || c.ZeroFill || (c.Sign != "" && c.Sign != SignNone)
+}
+
+var pictureClause = regexp.MustCompile(`^(S?)((?:9(?:\(\d+\))?)+)(?:V((?:9(?:\(\d+\))?)+))?$`)
+
+// picture9Count returns the number of digits of a run of 9 and 9(n) symbols.
+func picture9Count(s string) int {
+	n := 0
+	for s != "" {
+		s = s[1:] // the 9
+		if strings.HasPrefix(s, "(") {
+			end := strings.IndexByte(s, ')')
+			count, _ := strconv.Atoi(s[1:end])
+			n += count
+			s = s[end+1:]
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// applyPicture sets the column's length, scale, sign and zero fill from a COBOL PIC
+// clause such as S9(13)V99. Signed pictures are overpunched, as in DISPLAY usage.
+func (c *ColumnConfig) applyPicture(pic string) error {
+	m := pictureClause.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(pic)))
+	if m == nil {
+		return fmt.Errorf("unsupported picture %q, want e.g. 9(7), S9(13)V99", pic)
+	}
+	c.Scale = picture9Count(m[3])
+	c.Length = picture9Count(m[2]) + c.Scale
+	c.ZeroFill = true
+	c.Sign = SignNone
+	if m[1] == "S" {
+		c.Sign = SignOverpunch
+	}
+	return nil
+}
+
+// formatNumeric lays out a decimal value as the column's number: scaled by its implied
+// decimal places, zero filled or right aligned, and signed as configured. A NULL is
+// written as zero. Values that need more digits or decimals than the column holds are
+// rejected rather than truncated.
+func formatNumeric(col ColumnConfig, val string) (string, error) {
+	val = strings.TrimSpace(val)
+	negative := false
+	switch {
+	case strings.HasPrefix(val, "-"):
+		negative, val = true, val[1:]
+	case strings.HasPrefix(val, "+"):
+		val = val[1:]
+	}
+	intPart, frac, _ := strings.Cut(val, ".")
+	if strings.Trim(intPart+frac, "0123456789") != "" {
+		return "", fmt.Errorf("value %q for numeric column %s is not a decimal number", val, col.Name)
+	}
+	if trimmed := strings.TrimRight(frac, "0"); len(trimmed) > col.Scale {
+		return "", fmt.Errorf("value %q for column %s has more than %d decimal places", val, col.Name, col.Scale)
+	}
+	frac += strings.Repeat("0", col.Scale)
+	digits := strings.TrimLeft(intPart+frac[:col.Scale], "0")
+	if digits == "" {
+		digits, negative = "0", false
+	}
+	if negative && (col.Sign == "" || col.Sign == SignNone) {
+		return "", fmt.Errorf("negative value %q for unsigned column %s", val, col.Name)
+	}
+
+	width := col.Length
+	if col.Sign == SignLeading || col.Sign == SignTrailing {
+		width--
+	}
+	if len(digits) > width {
+		return "", fmt.Errorf("value %q for column %s needs %d digits, holds %d", val, col.Name, len(digits), width)
+	}
+	if col.ZeroFill {
+		digits = strings.Repeat("0", width-len(digits)) + digits
+	}
+	sign := "+"
+	if negative {
+		sign = "-"
+	}
+	switch col.Sign {
+	case SignLeading:
+		digits = sign + digits
+	case SignTrailing:
+		digits += sign
+	case SignOverpunch:
+		punch := overpunchPositive
+		if negative {
+			punch = overpunchNegative
+		}
+		last := len(digits) - 1
+		digits = digits[:last] + string(punch[digits[last]-'0'])
+	}
+	return fmt.Sprintf("%*s", col.Length, digits), nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatNumeric(t *testing.T) {
+	gl := ColumnConfig{Name: "AMT"}
+	if err := gl.applyPicture("S9(13)V99"); err != nil {
+		t.Fatal(err)
+	}
+	if gl.Length != 15 || gl.Scale != 2 || !gl.ZeroFill || gl.Sign != SignOverpunch {
+		t.Fatalf("S9(13)V99 = %+v", gl)
+	}
+	for _, tc := range []struct {
+		col       ColumnConfig
+		val, want string
+	}{
+		{gl, "1234.5", "00000000012345{"},
+		{gl, "-1234.51", "00000000012345J"},
+		{gl, "", "00000000000000{"},
+		{gl, "-0.00", "00000000000000{"},
+		{ColumnConfig{Name: "N", Length: 6, Scale: 2, ZeroFill: true, Sign: SignLeading}, "-.5", "-00050"},
+		{ColumnConfig{Name: "N", Length: 6, Sign: SignTrailing}, "42", "   42+"},
+		{ColumnConfig{Name: "N", Length: 5, ZeroFill: true}, "+7", "00007"},
+	} {
+		if got, err := formatNumeric(tc.col, tc.val); err != nil || got != tc.want {
+			t.Errorf("formatNumeric(%+v, %q) = %q, %v, want %q", tc.col, tc.val, got, err, tc.want)
+		}
+	}
+	for _, val := range []string{"1.234", "-5", "123456", "1E5"} {
+		if got, err := formatNumeric(ColumnConfig{Name: "N", Length: 5, Scale: 2, ZeroFill: true}, val); err == nil {
+			t.Errorf("formatNumeric(%q) = %q, want an error", val, got)
+		}
+	}
+}
+
+func TestNumericTemplateColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "GL.csv")
+	os.WriteFile(path, []byte("name,length,picture,scale,zero_fill,sign\nAMT,,S9(13)V99,,,\nQTY,8,,3,true,leading\n"), 0644)
+	cols, err := readColumnsFromCSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cols[0].Length != 15 || cols[0].Sign != SignOverpunch || cols[1].Scale != 3 || !cols[1].ZeroFill || cols[1].Sign != SignLeading {
+		t.Errorf("columns = %+v", cols)
+	}
+	line, err := formatFixed(cols, []string{"-10.25", "1.5"}, "", map[string]int64{})
+	if want := "00000000000102N+0001500"; err != nil || line != want {
+		t.Errorf("formatFixed = %q, %v, want %q", line, err, want)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	log "github.com/charmbracelet/log"
+)
+
+var objectName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$#]*(\.[A-Za-z_][A-Za-z0-9_$#]*)?(@[A-Za-z_][A-Za-z0-9_$#.]*)?$`)
+
+// sourceObject returns the database object proc extracts from: its configured view or
+// synonym, otherwise the procedure name itself.
+func (c *ExtractionConfig) sourceObject(proc string) string {
+	if obj, ok := c.SourceObjects[proc]; ok {
+		return obj
+	}
+	return proc
+}
+
+// missingObject is a source object that cannot be selected from, and why.
+type missingObject struct {
+	Proc, Object, Connection string
+	Err                      error
+}
+
+// checkSourceObjects makes sure every procedure's source object exists and is readable
+// on its connection before any job starts. Missing objects are written to reportPath and
+// returned as one error, instead of every job of the procedure failing with ORA-00942.
+func checkSourceObjects(ctx context.Context, dbs map[string]*sql.DB, cfg *ExtractionConfig, reportPath string) error {
+	var missing []missingObject
+	for _, proc := range cfg.Procedures {
+		obj, conn := cfg.sourceObject(proc), cfg.ProcedureConnections[proc]
+		rows, err := dbs[conn].QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE 1 = 0", obj))
+		if err == nil {
+			err = rows.Close()
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			missing = append(missing, missingObject{Proc: proc, Object: obj, Connection: conn, Err: err})
+		}
+	}
+	if len(missing) == 0 {
+		log.Info("✅ Source objects checked", "objects", len(cfg.Procedures))
+		return nil
+	}
+
+	if err := writeMissingObjects(reportPath, missing); err != nil {
+		log.Warn("Failed to write missing objects report", "file", reportPath, "error", err)
+	}
+	names := make([]string, len(missing))
+	for i, m := range missing {
+		names[i] = m.Object
+		log.Error("❌ Source object missing or not accessible", "procedure", m.Proc, "object", m.Object, "error", m.Err)
+	}
+	return fmt.Errorf("%d source object(s) missing or not accessible, see %s: %s", len(missing), reportPath, strings.Join(names, ", "))
+}
+
+func writeMissingObjects(path string, missing []missingObject) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write([]string{"PROCEDURE", "OBJECT", "CONNECTION", "ERROR_CODE", "ERROR"})
+	for _, m := range missing {
+		w.Write([]string{m.Proc, m.Object, m.Connection, errorCode(m.Err), m.Err.Error()})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
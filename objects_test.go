@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSourceObjects(t *testing.T) {
+	cfg := &ExtractionConfig{SourceObjects: map[string]string{"GAM": "CUSTOM.GAM_V"}}
+	if got := cfg.sourceObject("GAM"); got != "CUSTOM.GAM_V" {
+		t.Errorf("sourceObject(GAM) = %s", got)
+	}
+	if got := cfg.sourceObject("HTD"); got != "HTD" {
+		t.Errorf("sourceObject(HTD) = %s", got)
+	}
+	if q := cfg.selectQuery("GAM", []string{"ACID"}); !strings.HasPrefix(q, "SELECT ACID FROM CUSTOM.GAM_V WHERE") {
+		t.Errorf("selectQuery = %s", q)
+	}
+	for name, want := range map[string]bool{"GAM_V": true, "CUSTOM.GAM_V": true, "GAM@CORE.EXAMPLE": true, "GAM; DROP TABLE X": false, "A.B.C": false} {
+		if objectName.MatchString(name) != want {
+			t.Errorf("objectName(%q) = %v, want %v", name, !want, want)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "PKG_missing_objects.csv")
+	err := writeMissingObjects(path, []missingObject{{Proc: "GAM", Object: "CUSTOM.GAM_V", Err: errors.New("ORA-00942: table or view does not exist")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "GAM,CUSTOM.GAM_V,,ORA-00942") {
+		t.Errorf("report = %q", data)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	log "github.com/charmbracelet/log"
+)
+
+// selectList returns the select-list expressions of a template selecting from object.
+// Optional columns missing from the object, as on older core versions, are replaced by their
+// default value under the column's name, so one template serves every region.
+func selectList(ctx context.Context, db *sql.DB, object string, cols []ColumnConfig) ([]string, error) {
+	exprs := make([]string, len(cols))
+	var present map[string]bool
+	var missing []string
+	for i, col := range cols {
+		exprs[i] = col.Name
+		if !col.Optional {
+			continue
+		}
+		if present == nil {
+			var err error
+			if present, err = sourceColumns(ctx, db, object); err != nil {
+				return nil, fmt.Errorf("failed to read the columns of %s for its optional columns: %w", object, err)
+			}
+		}
+		if present[strings.ToUpper(col.Name)] {
+			continue
+		}
+		exprs[i] = defaultLiteral(col.Default) + " AS " + col.Name
+		missing = append(missing, col.Name)
+	}
+	if len(missing) > 0 {
+		log.Warn("Optional columns missing from source, writing their defaults", "object", object, "columns", strings.Join(missing, ", "))
+	}
+	return exprs, nil
+}
+
+// sourceColumns returns the upper-cased column names of a source object.
+func sourceColumns(ctx context.Context, db *sql.DB, object string) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE 1 = 0", object))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	present := make(map[string]bool, len(names))
+	for _, name := range names {
+		present[strings.ToUpper(name)] = true
+	}
+	return present, nil
+}
+
+// defaultLiteral is the SQL literal of an optional column's default: NULL when empty.
+func defaultLiteral(value string) string {
+	if value == "" {
+		return "NULL"
+	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOptionalTemplateColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "GAM.csv")
+	os.WriteFile(path, []byte("name,optional,default\nACID,,\nLIMIT_B2K,true,N/A\n\"NVL(X, 1)\",true,\n"), 0644)
+	if _, err := readColumnsFromCSV(path); err == nil {
+		t.Error("optional expression column accepted")
+	}
+	os.WriteFile(path, []byte("name,optional,default\nACID,,\nLIMIT_B2K,true,O'Neil\nREGION,true,\n"), 0644)
+	cols, err := readColumnsFromCSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cols[0].Optional || !cols[1].Optional || cols[1].Default != "O'Neil" {
+		t.Fatalf("columns = %+v", cols)
+	}
+	if got := defaultLiteral(cols[1].Default); got != "'O''Neil'" {
+		t.Errorf("defaultLiteral = %s", got)
+	}
+	if got := defaultLiteral(cols[2].Default); got != "NULL" {
+		t.Errorf("defaultLiteral(empty) = %s", got)
+	}
+
+	// Without optional columns the source is never described.
+	exprs, err := selectList(context.Background(), nil, "GAM", cols[:1])
+	if err != nil || len(exprs) != 1 || exprs[0] != "ACID" {
+		t.Errorf("selectList = %v, %v", exprs, err)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var oraCodePattern = regexp.MustCompile(`ORA-\d{5}`)
+
+// oraCode extracts the first ORA- error code from a failure message, or "OTHER" when the
+// failure did not come from the database.
+func oraCode(msg string) string {
+	if code := oraCodePattern.FindString(msg); code != "" {
+		return code
+	}
+	return "OTHER"
+}
+
+// connectionLostCodes are errors raised when the session's instance went away (node
+// eviction, shutdown, switchover), after which the job can succeed on another connection.
+var connectionLostCodes = map[string]bool{
+	"ORA-00028": true, "ORA-01012": true, "ORA-01033": true, "ORA-01034": true,
+	"ORA-01089": true, "ORA-01092": true, "ORA-03113": true, "ORA-03114": true,
+	"ORA-03135": true, "ORA-12514": true, "ORA-12528": true, "ORA-12537": true,
+	"ORA-12541": true, "ORA-12545": true, "ORA-12571": true, "ORA-25408": true,
+}
+
+// isConnectionLost reports whether err means the database connection was lost.
+func isConnectionLost(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	msg := err.Error()
+	return connectionLostCodes[errorCode(err)] || strings.Contains(msg, "DPI-1080") || strings.Contains(msg, "DPI-1010")
+}
+
+// stateInvalidatedCodes are errors raised when a package or view a statement depends on
+// was recompiled under it. The session state is discarded with the error, so the call
+// succeeds once the statement is prepared again.
+var stateInvalidatedCodes = map[string]bool{
+	"ORA-04061": true, "ORA-04065": true, "ORA-04068": true, "ORA-04106": true,
+	"ORA-06508": true,
+}
+
+// isStateInvalidated reports whether err means a prepared statement was invalidated by a
+// recompile of the objects it uses.
+func isStateInvalidated(err error) bool {
+	return err != nil && stateInvalidatedCodes[errorCode(err)]
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFailuresByORACode(t *testing.T) {
+	for msg, want := range map[string]string{
+		"ORA-00942: table or view does not exist":                     "ORA-00942",
+		"ORA-06512: at line 1\nORA-01555: snapshot too old":           "ORA-06512",
+		"query failed: ORA-01555: snapshot too old: rollback segment": "ORA-01555",
+		"write GAM.txt: no space left on device":                      "OTHER",
+		"ORA-1555":                                                    "OTHER",
+	} {
+		if got := oraCode(msg); got != want {
+			t.Errorf("oraCode(%q) = %s, want %s", msg, got, want)
+		}
+	}
+
+	// The summary gives each procedure's failures by code, sorted.
+	path := filepath.Join(t.TempDir(), "PKG_summary.csv")
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	writeSummary(path, map[string]ProcSummary{
+		"GAM": {Procedure: "GAM", StartTime: start, EndTime: start.Add(time.Second), Status: "FAIL", FailuresByCode: map[string]int64{"ORA-01555": 2, "ORA-00942": 1}},
+		"HTD": {Procedure: "HTD", StartTime: start, EndTime: start, Status: "SUCCESS"},
+	})
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	col := slices.Index(records[0], "FAILURES_BY_CODE")
+	if col < 0 || len(records) != 3 {
+		t.Fatalf("summary = %v", records)
+	}
+	if got := records[1][col]; records[1][0] != "GAM" || got != "ORA-00942=1;ORA-01555=2" {
+		t.Errorf("GAM failures by code = %q", got)
+	}
+	if got := records[2][col]; got != "-" {
+		t.Errorf("HTD failures by code = %q", got)
+	}
+}
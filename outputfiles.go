@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/charmbracelet/log"
+)
+
+// Empty-output policies decide what happens to a procedure that produced no rows for any SOL.
+const (
+	EmptyOutputWarn = "warn"
+	EmptyOutputFail = "fail"
+	EmptyOutputEmit = "emit"
+)
+
+func validEmptyOutputPolicy(p string) bool {
+	return p == "" || p == EmptyOutputWarn || p == EmptyOutputFail || p == EmptyOutputEmit
+}
+
+// OutputFile describes a procedure's final output file for the run summary, the numbers
+// operations check a hand-off by.
+type OutputFile struct {
+	Path    string `json:"path"`
+	Bytes   int64  `json:"bytes"`
+	Records int64  `json:"records"`
+	SHA256  string `json:"sha256"`
+	SolID   string `json:"sol_id,omitempty"` // per-SOL outputs
+	Group   string `json:"group,omitempty"`  // grouped outputs
+	// UnchangedSince is the run whose output had the same checksum, and LinkedTo the
+	// file this one was replaced by a symlink to, when unchanged outputs are deduplicated.
+	UnchangedSince string `json:"unchanged_since,omitempty"`
+	LinkedTo       string `json:"linked_to,omitempty"`
+}
+
+// describeOutput reads path once to measure its size, count its records and checksum it.
+// Size and checksum are of the file as written; records are counted decompressed, by the
+// record terminator of the output cfg is resolved for.
+func describeOutput(cfg *ExtractionConfig, path string) (*OutputFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	out := &OutputFile{Path: path, Bytes: info.Size()}
+	h := sha256.New()
+	raw := io.TeeReader(f, h)
+	content, err := decompress(path, io.NopCloser(raw))
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+	reader := bufio.NewReader(cfg.recordReader(content))
+	buf := make([]byte, 64*1024)
+	var last byte = '\n'
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			out.Records += int64(bytes.Count(buf[:n], []byte{'\n'}))
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if last != '\n' {
+		out.Records++ // unterminated last record
+	}
+	// A decompressor may stop short of trailing bytes; the checksum covers the whole file.
+	if _, err := io.Copy(io.Discard, raw); err != nil {
+		return nil, err
+	}
+	out.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return out, nil
+}
+
+// describeOutputs attaches the final output file, or per-SOL or per-group files, of every
+// summarised procedure.
+func describeOutputs(cfg *ExtractionConfig, summary map[string]ProcSummary) {
+	for proc, s := range summary {
+		outs, err := describeParts(cfg, proc)
+		if err != nil {
+			log.Warn("Failed to resolve output file for summary", "procedure", proc, "error", err)
+			continue
+		}
+		if cfg.partitioned() {
+			s.Parts = outs
+			var bytes, records int64
+			for _, out := range outs {
+				bytes += out.Bytes
+				records += out.Records
+			}
+			log.Info("📄 Output files", "procedure", proc, "files", len(outs), "bytes", bytes, "records", records)
+		} else if len(outs) == 1 {
+			out := outs[0]
+			cfg.dedupeOutput(proc, out)
+			log.Info("📄 Output file", "procedure", proc, "file", out.Path, "bytes", out.Bytes, "records", out.Records, "sha256", out.SHA256)
+			s.Output = out
+		}
+		summary[proc] = s
+	}
+}
+
+// describeParts describes the final files of proc that exist.
+func describeParts(cfg *ExtractionConfig, proc string) ([]*OutputFile, error) {
+	parts, err := cfg.outputParts(proc)
+	if err != nil {
+		return nil, err
+	}
+	oc := cfg.forOutput(proc)
+	var outs []*OutputFile
+	for _, part := range parts {
+		out, err := describeOutput(oc, part.Path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Warn("Failed to describe output file for summary", "procedure", proc, "file", part.Path, "error", err)
+			}
+			continue
+		}
+		out.SolID, out.Group = part.SolID, part.Group
+		if oc.Format == "delimited" && oc.strictCSV(proc) {
+			// Quoted values may hold line breaks, so records are counted by parsing.
+			if out.Records, err = countStrictRecords(oc, part.Path); err != nil {
+				log.Warn("Failed to count records for summary", "procedure", proc, "file", part.Path, "error", err)
+			}
+		}
+		outs = append(outs, out)
+	}
+	return outs, nil
+}
+
+// countStrictRecords counts the CSV records of a strict RFC 4180 output.
+func countStrictRecords(cfg *ExtractionConfig, path string) (int64, error) {
+	f, err := cfg.openRecords(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return countRecords(cfg, f, nil, -1, 0, nil)
+}
+
+// outputs returns the procedure's final files: its merged file or its partitioned files.
+func (s ProcSummary) outputs() []*OutputFile {
+	if s.Output != nil {
+		return []*OutputFile{s.Output}
+	}
+	return s.Parts
+}
+
+// recordMergeGaps notes in the summary the spool files each procedure's merge left out,
+// marking the procedure MERGE_FAILED unless the merge was forced.
+func recordMergeGaps(summary map[string]ProcSummary, gaps map[string][]string, forced bool) {
+	for proc, files := range gaps {
+		s := summary[proc]
+		s.Procedure = proc
+		s.MergeGaps = files
+		if !forced {
+			s.Status = "MERGE_FAILED"
+		}
+		summary[proc] = s
+	}
+}
+
+// checkEmptyOutputs applies the empty-output policy to every procedure whose jobs all
+// succeeded without writing a record. Under the emit policy a missing output file is
+// created empty so downstream loads still find one; under fail an error names them.
+func checkEmptyOutputs(cfg *ExtractionConfig, summary map[string]ProcSummary) error {
+	var empty []string
+	for proc, s := range summary {
+		var records int64
+		for _, out := range s.outputs() {
+			records += out.Records
+		}
+		if s.Status == "SUCCESS" && records == 0 {
+			empty = append(empty, proc)
+		}
+	}
+	if len(empty) == 0 {
+		return nil
+	}
+	sort.Strings(empty)
+
+	switch cfg.EmptyOutputPolicy {
+	case EmptyOutputFail:
+		return fmt.Errorf("procedure(s) produced no rows for any SOL: %s", strings.Join(empty, ", "))
+	case EmptyOutputEmit:
+		for _, proc := range empty {
+			parts, err := cfg.outputParts(proc)
+			if err != nil {
+				return err
+			}
+			for _, part := range parts {
+				if err := cfg.ensureParentDir(part.Path); err != nil {
+					return err
+				}
+				if err := emitEmptyOutput(cfg, proc, part.Path); err != nil {
+					return err
+				}
+			}
+			outs, err := describeParts(cfg, proc)
+			if err != nil {
+				return err
+			}
+			s := summary[proc]
+			if cfg.partitioned() {
+				s.Parts = outs
+			} else if len(outs) == 1 {
+				s.Output = outs[0]
+			}
+			summary[proc] = s
+			log.Warn("📭 Procedure produced no rows, emitted empty output file", "procedure", proc, "files", len(parts))
+		}
+	default:
+		for _, proc := range empty {
+			log.Warn("📭 Procedure produced no rows for any SOL", "procedure", proc)
+		}
+	}
+	return nil
+}
+
+// emitEmptyOutput creates the missing final file of an empty procedure: an empty file, or
+// an empty stream of the procedure's codec so downstream decompression still succeeds,
+// holding only a BOM when the procedure's files start with one.
+func emitEmptyOutput(cfg *ExtractionConfig, proc, path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create empty output file %s: %w", path, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.Size() > 0 {
+		return err
+	}
+	var w io.WriteCloser = f
+	if cd := cfg.codecFor(proc); cd != nil {
+		if w, err = cd.NewWriter(f); err != nil {
+			return err
+		}
+	}
+	if cfg.writesBOM(proc) {
+		if _, err := w.Write(utf8BOM); err != nil {
+			return fmt.Errorf("failed to write empty output file %s: %w", path, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write empty output file %s: %w", path, err)
+	}
+	return nil
+}
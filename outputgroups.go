@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/charmbracelet/log"
+)
+
+// OutputGroupConfig merges the SOLs of each group (a region or zone, say) into a final
+// file of their own, the SOL to group mapping coming from a file or a query.
+type OutputGroupConfig struct {
+	File  string `json:"file"`  // CSV lines of SOL_ID,GROUP
+	Query string `json:"query"` // SELECT returning SOL_ID, GROUP
+	// Default is the group of SOLs the mapping leaves out; without it they fail the run.
+	Default string `json:"default"`
+}
+
+func (g OutputGroupConfig) enabled() bool {
+	return g.File != "" || g.Query != ""
+}
+
+// loadSolGroups reads the SOL to group mapping and assigns every SOL of the run a group.
+func (c *ExtractionConfig) loadSolGroups(ctx context.Context, db *sql.DB) error {
+	if !c.OutputGroups.enabled() {
+		return nil
+	}
+	var mapping map[string]string
+	var err error
+	if c.OutputGroups.File != "" {
+		mapping, err = readGroupFile(c.OutputGroups.File)
+	} else {
+		var query string
+		if query, err = c.expandVariables(c.OutputGroups.Query); err == nil {
+			mapping, err = queryGroups(ctx, db, query)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load output groups: %w", err)
+	}
+
+	c.solGroups = make(map[string]string, len(c.sols))
+	var unmapped []string
+	for _, sol := range c.sols {
+		group, ok := mapping[sol]
+		if !ok {
+			if c.OutputGroups.Default == "" {
+				unmapped = append(unmapped, sol)
+				continue
+			}
+			group = c.OutputGroups.Default
+		}
+		c.solGroups[sol] = group
+	}
+	if len(unmapped) > 0 {
+		return fmt.Errorf("%d SOL(s) have no output group and output_groups.default is not set: %s", len(unmapped), strings.Join(unmapped, ", "))
+	}
+	log.Info("Loaded output groups", "sols", len(c.solGroups), "groups", len(c.groupNames()))
+	return nil
+}
+
+// readGroupFile reads SOL_ID,GROUP lines, skipping a UTF-8 BOM, blank and # comment
+// lines and a SOL_ID header.
+func readGroupFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	if err := skipInputBOM(br, path); err != nil {
+		return nil, err
+	}
+	r := csv.NewReader(br)
+	r.Comment = '#'
+	r.FieldsPerRecord = 2
+	r.TrimLeadingSpace = true
+	mapping := make(map[string]string)
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		sol, group := strings.TrimSpace(rec[0]), strings.TrimSpace(rec[1])
+		if strings.EqualFold(sol, "SOL_ID") {
+			continue
+		}
+		if sol == "" || group == "" {
+			return nil, fmt.Errorf("%s: empty SOL ID or group in %q", path, strings.Join(rec, ","))
+		}
+		mapping[sol] = group
+	}
+	return mapping, nil
+}
+
+// queryGroups runs query, which returns SOL ID and group pairs.
+func queryGroups(ctx context.Context, db *sql.DB, query string) (map[string]string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	mapping := make(map[string]string)
+	for rows.Next() {
+		var sol, group sql.NullString
+		if err := rows.Scan(&sol, &group); err != nil {
+			return nil, err
+		}
+		if sol.Valid && group.Valid && group.String != "" {
+			mapping[strings.TrimSpace(sol.String)] = strings.TrimSpace(group.String)
+		}
+	}
+	return mapping, rows.Err()
+}
+
+// groupNames returns the groups the run's SOLs belong to, sorted.
+func (c *ExtractionConfig) groupNames() []string {
+	seen := make(map[string]bool)
+	var groups []string
+	for _, group := range c.solGroups {
+		if !seen[group] {
+			seen[group] = true
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// groupParts returns one final file of proc per output group, each merged from the
+// group's SOLs in SOL list order.
+func (c *ExtractionConfig) groupParts(proc string) ([]outputPart, error) {
+	members := make(map[string][]string)
+	for _, sol := range c.sols {
+		if group, ok := c.solGroups[sol]; ok {
+			members[group] = append(members[group], sol)
+		}
+	}
+	var parts []outputPart
+	for _, group := range c.groupNames() {
+		path, err := c.groupOutputFilePath(proc, group)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, outputPart{Path: path, Group: group, sols: members[group]})
+	}
+	return parts, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// encryptedPrefix marks a db_password value produced by the encrypt-password command.
+const encryptedPrefix = "enc:"
+
+// passwordKeyEnv holds the base64 AES-256 key used for encrypted passwords when no
+// password_key_file is configured.
+const passwordKeyEnv = "GEMINI_EXTRACT_KEY"
+
+// loadPasswordKey reads the 32-byte AES key from keyFile or, failing that, the environment.
+func loadPasswordKey(keyFile string) ([]byte, error) {
+	encoded := os.Getenv(passwordKeyEnv)
+	source := "$" + passwordKeyEnv
+	if keyFile != "" {
+		b, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read password key file: %w", err)
+		}
+		encoded, source = string(b), keyFile
+	}
+	if strings.TrimSpace(encoded) == "" {
+		return nil, fmt.Errorf("no password key configured (password_key_file or $%s)", passwordKeyEnv)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("password key in %s must be 32 bytes, base64 encoded", source)
+	}
+	return key, nil
+}
+
+// encryptPassword seals plaintext with AES-256-GCM and returns "enc:" + base64(nonce|ciphertext).
+func encryptPassword(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptPassword reverses encryptPassword; value may include the "enc:" prefix.
+func decryptPassword(key []byte, value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("encrypted password is not valid base64: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted password is too short")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt password, wrong key?")
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// runEncryptPassword implements the "encrypt-password" subcommand. The password is read
+// from the terminal without echo (or from stdin when piped) and the ciphertext printed
+// for pasting into db_password.
+func runEncryptPassword(args []string) error {
+	fs := flag.NewFlagSet("encrypt-password", flag.ContinueOnError)
+	keyFile := fs.String("key-file", "", "File holding the base64 AES-256 key (default $"+passwordKeyEnv+")")
+	newKey := fs.Bool("new-key", false, "Print a freshly generated key and exit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *newKey {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return err
+		}
+		fmt.Println(base64.StdEncoding.EncodeToString(key))
+		return nil
+	}
+
+	key, err := loadPasswordKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	password, err := readPassword("Password: ")
+	if err != nil {
+		return err
+	}
+	enc, err := encryptPassword(key, password)
+	if err != nil {
+		return err
+	}
+	fmt.Println(enc)
+	return nil
+}
+
+// readPassword prompts on stderr and reads a line from stdin, without echo on a terminal.
+func readPassword(prompt string) (string, error) {
+	if stdinIsTerminal() {
+		fmt.Fprint(os.Stderr, prompt)
+		b, err := term.ReadPassword(os.Stdin.Fd())
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+		return string(b), nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read password from stdin: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const defaultDirMode os.FileMode = 0755
+
+// parseDirMode converts the configured octal directory mode (e.g. "0750") to a FileMode.
+func parseDirMode(s string) (os.FileMode, error) {
+	if s == "" {
+		return defaultDirMode, nil
+	}
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m > 0777 {
+		return 0, fmt.Errorf("invalid dir_mode %q: must be an octal permission such as 0755", s)
+	}
+	return os.FileMode(m), nil
+}
+
+// ensureWritableDir creates dir if needed and verifies the process can create files in it.
+func ensureWritableDir(dir string, mode os.FileMode) error {
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, mode); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to stat directory %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s exists but is not a directory", dir)
+	}
+
+	probe, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return nil
+}
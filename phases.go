@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+
+	log "github.com/charmbracelet/log"
+)
+
+// PhaseConfig is an ordered group of procedures that run in parallel with each other,
+// after every job of the previous phase has finished.
+type PhaseConfig struct {
+	Name       string   `json:"name"`
+	Procedures []string `json:"procedures"`
+}
+
+// phase is a run-time phase with its pending jobs.
+type phase struct {
+	name  string
+	procs []string
+	jobs  []Job
+}
+
+// splitPhases groups pending jobs by the configured phases, keeping their order within
+// a phase. Procedures not named by any phase run in a final phase of their own; without
+// phases everything is a single phase.
+func splitPhases(pending []Job, cfgs []PhaseConfig, procedures []string) []phase {
+	index := make(map[string]int)
+	phases := make([]phase, 0, len(cfgs)+1)
+	for i, cfg := range cfgs {
+		phases = append(phases, phase{name: cfg.Name, procs: cfg.Procedures})
+		for _, proc := range cfg.Procedures {
+			index[proc] = i
+		}
+	}
+	rest := phase{name: "default"}
+	for _, proc := range procedures {
+		if _, ok := index[proc]; !ok {
+			index[proc] = len(cfgs)
+			rest.procs = append(rest.procs, proc)
+		}
+	}
+	phases = append(phases, rest)
+
+	for _, job := range pending {
+		i := index[job.Proc]
+		phases[i].jobs = append(phases[i].jobs, job)
+	}
+	kept := phases[:0]
+	for _, ph := range phases {
+		if len(ph.jobs) > 0 {
+			kept = append(kept, ph)
+		}
+	}
+	return kept
+}
+
+// dispatchPhases dispatches the phases in order, starting each only once every job of
+// the previous phase has finished. A phase with failed jobs stops the run, since later
+// phases depend on its data. It closes jobs when done and returns the number of jobs
+// that were never dispatched.
+func (d *dispatchControl) dispatchPhases(phases []phase, jobs chan<- Job, progress *progressTracker) int {
+	defer close(jobs)
+	remaining := func(from int) int {
+		var n int
+		for _, ph := range phases[from:] {
+			n += len(ph.jobs)
+		}
+		return n
+	}
+	for i, ph := range phases {
+		if i > 0 {
+			prev := phases[i-1]
+			if failed := progress.WaitProcs(prev.procs); failed > 0 {
+				log.Error("❌ Phase finished with failures, later phases are not started", "phase", prev.name, "failed_jobs", failed)
+				return remaining(i)
+			}
+			log.Info("✅ Phase complete", "phase", prev.name)
+		}
+		if len(phases) > 1 {
+			log.Info("🚦 Starting phase", "phase", ph.name, "procedures", strings.Join(ph.procs, ","), "jobs", len(ph.jobs))
+		}
+		if n := d.send(ph.jobs, jobs); n > 0 {
+			return n + remaining(i+1)
+		}
+	}
+	return 0
+}
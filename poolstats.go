@@ -0,0 +1,74 @@
+package main
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// defaultConnWaitWarn is the average connection wait that is warned about when not configured.
+const defaultConnWaitWarn = 5 * time.Second
+
+// poolMonitor reports how long jobs wait for a pooled connection, so a run that is slow
+// because it queues for connections (concurrency above the pool size, or a session cap)
+// is distinguishable from one that is slow in the database.
+type poolMonitor struct {
+	dbs       map[string]*sql.DB
+	last      map[string]sql.DBStats
+	warnAfter time.Duration
+}
+
+func newPoolMonitor(dbs map[string]*sql.DB, warnAfter time.Duration) *poolMonitor {
+	if warnAfter <= 0 {
+		warnAfter = defaultConnWaitWarn
+	}
+	return &poolMonitor{dbs: dbs, last: make(map[string]sql.DBStats), warnAfter: warnAfter}
+}
+
+// Report logs each pool's usage and the connection waits since the previous report.
+// running is the number of jobs in flight, used to estimate how many are queueing.
+func (m *poolMonitor) Report(running, concurrency int) {
+	if m == nil {
+		return
+	}
+	names := make([]string, 0, len(m.dbs))
+	for name := range m.dbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var inUse int
+	for _, name := range names {
+		stats := m.dbs[name].Stats()
+		prev := m.last[name]
+		m.last[name] = stats
+		inUse += stats.InUse
+
+		waits := stats.WaitCount - prev.WaitCount
+		var avgWait time.Duration
+		if waits > 0 {
+			avgWait = (stats.WaitDuration - prev.WaitDuration) / time.Duration(waits)
+		}
+		label := name
+		if label == "" {
+			label = "main"
+		}
+		log.Info("🔌 Connection pool", "connection", label, "open", stats.OpenConnections, "in_use", stats.InUse,
+			"idle", stats.Idle, "max_open", stats.MaxOpenConnections, "waits", waits, "avg_wait", avgWait.Round(time.Millisecond),
+			"total_wait", stats.WaitDuration.Round(time.Second))
+		if avgWait >= m.warnAfter {
+			log.Warn("Jobs are queueing for database connections", "connection", label, "avg_wait", avgWait.Round(time.Millisecond),
+				"waits", waits, "max_open", stats.MaxOpenConnections, "concurrency", concurrency)
+			if stats.MaxOpenConnections > 0 && concurrency > stats.MaxOpenConnections {
+				log.Warn("   More workers than pooled connections; lower concurrency or raise the pool size", "connection", label)
+			}
+		}
+	}
+	// Connections of the routed databases may be in use by other jobs, so this is an
+	// estimate of jobs that have been dispatched but hold no connection yet.
+	if queued := running - inUse; queued > 0 {
+		log.Info("   Jobs waiting for a connection", "queued", queued, "running", running)
+	}
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+const (
+	// progressWeight is the smoothing factor of the rolling per-procedure job duration.
+	progressWeight = 0.2
+	// slowestInFlight is how many of the longest-running jobs each progress report lists.
+	slowestInFlight = 3
+	// defaultRecentFailures is how many recent failures are kept when not configured.
+	defaultRecentFailures = 10
+)
+
+// failedJob is a recently failed job kept for the recent-failures listing.
+type failedJob struct {
+	Job     Job
+	Time    time.Time
+	ORACode string
+	Message string
+}
+
+// runningJob is a job currently being processed by a worker.
+type runningJob struct {
+	job       Job
+	start     time.Time
+	cancel    context.CancelCauseFunc
+	cancelled bool
+}
+
+// procProgress holds the counters of a single procedure.
+type procProgress struct {
+	total     int
+	completed int
+	failed    int
+	rows      int64
+}
+
+// progressTracker keeps run-wide counters so operators can see how far along a run is
+// and roughly when it will finish. It also tracks in-flight jobs for the heartbeat.
+type progressTracker struct {
+	mu          sync.Mutex
+	start       time.Time
+	total       int
+	completed   int
+	failed      int
+	rows        int64
+	concurrency int
+	procs       map[string]*procProgress
+	avgDuration map[string]time.Duration
+	running     map[int]*runningJob
+
+	stallThreshold time.Duration
+	cancelStalled  bool
+
+	keepTimeline bool
+	timeline     []jobSpan
+
+	maxRecentFailures int
+	recentFailures    []failedJob
+	newFailures       bool
+
+	// pools, when set, reports connection pool waits with each progress report.
+	pools *poolMonitor
+
+	// finished is signalled whenever a job finishes, for WaitProcs.
+	finished *sync.Cond
+
+	// procDone, when set, receives each procedure once all of its jobs have finished.
+	procDone chan<- string
+
+	// webhooks is told about each procedure whose jobs have all finished.
+	webhooks *webhookNotifier
+}
+
+func newProgressTracker(jobs []Job, concurrency int) *progressTracker {
+	p := &progressTracker{
+		start:       time.Now(),
+		total:       len(jobs),
+		concurrency: concurrency,
+		procs:       make(map[string]*procProgress),
+		avgDuration: make(map[string]time.Duration),
+		running:     make(map[int]*runningJob),
+
+		maxRecentFailures: defaultRecentFailures,
+	}
+	p.finished = sync.NewCond(&p.mu)
+	for _, job := range jobs {
+		pp, ok := p.procs[job.Proc]
+		if !ok {
+			pp = &procProgress{}
+			p.procs[job.Proc] = pp
+		}
+		pp.total++
+	}
+	return p
+}
+
+// WaitProcs blocks until every job of procs has finished and returns how many failed.
+func (p *progressTracker) WaitProcs(procs []string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		var pending, failed int
+		for _, proc := range procs {
+			if pp := p.procs[proc]; pp != nil {
+				pending += pp.total - pp.completed
+				failed += pp.failed
+			}
+		}
+		if pending == 0 {
+			return failed
+		}
+		p.finished.Wait()
+	}
+}
+
+// Begin registers a job picked up by a worker. cancel aborts the job if it stalls and
+// stalled jobs are configured to be cancelled.
+func (p *progressTracker) Begin(workerID int, job Job, cancel context.CancelCauseFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running[workerID] = &runningJob{job: job, start: time.Now(), cancel: cancel}
+}
+
+// Finish accounts for a job finished by a worker.
+func (p *progressTracker) Finish(workerID int, plog ProcLog) {
+	p.mu.Lock()
+	var procComplete func()
+	defer func() {
+		p.mu.Unlock()
+		// A full webhook queue blocks until an event is delivered, so the notifier is
+		// called without holding the lock status reports and other workers wait on.
+		if procComplete != nil {
+			procComplete()
+		}
+	}()
+
+	delete(p.running, workerID)
+	defer p.finished.Broadcast()
+	if p.keepTimeline {
+		p.timeline = append(p.timeline, jobSpan{WorkerID: workerID, Job: Job{SolID: plog.SolID, Proc: plog.Procedure}, Start: plog.StartTime, End: plog.EndTime, Status: plog.Status})
+	}
+
+	p.completed++
+	if plog.Status == "FAIL" {
+		p.failed++
+		p.recentFailures = append(p.recentFailures, failedJob{
+			Job:     Job{SolID: plog.SolID, Proc: plog.Procedure},
+			Time:    plog.EndTime,
+			ORACode: oraCode(plog.ErrorDetails),
+			Message: plog.ErrorDetails,
+		})
+		if len(p.recentFailures) > p.maxRecentFailures {
+			p.recentFailures = p.recentFailures[len(p.recentFailures)-p.maxRecentFailures:]
+		}
+		p.newFailures = true
+	}
+	p.rows += plog.Rows
+	if pp, ok := p.procs[plog.Procedure]; ok {
+		pp.completed++
+		pp.rows += plog.Rows
+		if plog.Status == "FAIL" {
+			pp.failed++
+		}
+		if pp.completed == pp.total {
+			total, failed, rows := pp.total, pp.failed, pp.rows
+			procComplete = func() { p.webhooks.ProcedureComplete(plog.Procedure, total, failed, rows) }
+			if p.procDone != nil {
+				p.procDone <- plog.Procedure
+			}
+		}
+	}
+
+	if avg, ok := p.avgDuration[plog.Procedure]; ok {
+		p.avgDuration[plog.Procedure] = time.Duration(progressWeight*float64(plog.ExecutionTime) + (1-progressWeight)*float64(avg))
+	} else {
+		p.avgDuration[plog.Procedure] = plog.ExecutionTime
+	}
+}
+
+// eta estimates the time left from the remaining jobs of each procedure and its rolling
+// average duration, spread over the worker pool. Procedures with no finished job yet
+// borrow the average of all finished jobs.
+func (p *progressTracker) eta() time.Duration {
+	if p.completed == 0 {
+		return 0
+	}
+	fallback := time.Since(p.start) * time.Duration(p.concurrency) / time.Duration(p.completed)
+
+	var work time.Duration
+	for proc, pp := range p.procs {
+		avg, ok := p.avgDuration[proc]
+		if !ok {
+			avg = fallback
+		}
+		work += time.Duration(pp.total-pp.completed) * avg
+	}
+	return work / time.Duration(p.concurrency)
+}
+
+// Report logs the current progress, throughput and ETA.
+func (p *progressTracker) Report() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	elapsed := time.Since(p.start)
+	var pct, rowsPerSec float64
+	if p.total > 0 {
+		pct = float64(p.completed) * 100 / float64(p.total)
+	}
+	if elapsed > 0 {
+		rowsPerSec = float64(p.rows) / elapsed.Seconds()
+	}
+	log.Info("⏳ Progress",
+		"completed", p.completed, "total", p.total, "percent", int(pct), "failed", p.failed,
+		"rows_per_sec", int64(rowsPerSec), "elapsed", elapsed.Round(time.Second), "eta", p.eta().Round(time.Second))
+	notifyService(fmt.Sprintf("STATUS=%d/%d jobs done, %d failed, eta %s", p.completed, p.total, p.failed, p.eta().Round(time.Second)))
+
+	// Per-procedure breakdown of unfinished procedures, most outstanding first, so a
+	// lagging procedure is visible rather than hidden in the global percentage.
+	var pending []string
+	for proc, pp := range p.procs {
+		if pp.completed < pp.total {
+			pending = append(pending, proc)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		a, b := p.procs[pending[i]], p.procs[pending[j]]
+		if ra, rb := a.total-a.completed, b.total-b.completed; ra != rb {
+			return ra > rb
+		}
+		return pending[i] < pending[j]
+	})
+	for _, proc := range pending {
+		pp := p.procs[proc]
+		log.Info("   Procedure progress", "procedure", proc, "completed", pp.completed, "total", pp.total,
+			"failed", pp.failed, "rows_per_sec", int64(float64(pp.rows)/elapsed.Seconds()))
+	}
+
+	p.pools.Report(len(p.running), p.concurrency)
+
+	// The slowest in-flight jobs.
+	running := make([]*runningJob, 0, len(p.running))
+	for _, r := range p.running {
+		running = append(running, r)
+	}
+	sort.Slice(running, func(i, j int) bool { return running[i].start.Before(running[j].start) })
+	for i, r := range running {
+		if i == slowestInFlight {
+			break
+		}
+		log.Info("   Slow in-flight job", "procedure", r.job.Proc, "sol_id", r.job.SolID, "elapsed", time.Since(r.start).Round(time.Second))
+	}
+
+	// Repeat the most recent failures whenever new ones arrived since the last report,
+	// so they are not lost in the scrollback.
+	if p.newFailures {
+		for _, f := range p.recentFailures {
+			log.Warn("   Recent failure", "procedure", f.Job.Proc, "sol_id", f.Job.SolID, "code", f.ORACode, "at", f.Time.Format("15:04:05"), "error", f.Message)
+		}
+		p.newFailures = false
+	}
+}
+
+// procedureStatus is one procedure's counters in a runStatus.
+type procedureStatus struct {
+	Procedure string `json:"procedure"`
+	Total     int    `json:"total"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
+	Rows      int64  `json:"rows"`
+}
+
+// failureStatus is a recent failure in a runStatus.
+type failureStatus struct {
+	Procedure string    `json:"procedure"`
+	SolID     string    `json:"sol_id"`
+	Time      time.Time `json:"time"`
+	Code      string    `json:"code,omitempty"`
+	Message   string    `json:"message"`
+}
+
+// inFlightStatus is a running job in a runStatus.
+type inFlightStatus struct {
+	Procedure      string  `json:"procedure"`
+	SolID          string  `json:"sol_id"`
+	Worker         int     `json:"worker"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+}
+
+// runStatus is a snapshot of the progress counters, served by the control API.
+type runStatus struct {
+	Total          int               `json:"total_jobs"`
+	Completed      int               `json:"completed_jobs"`
+	Failed         int               `json:"failed_jobs"`
+	Running        int               `json:"running_jobs"`
+	Rows           int64             `json:"rows"`
+	RowsPerSec     float64           `json:"rows_per_sec"`
+	ElapsedSeconds float64           `json:"elapsed_seconds"`
+	ETASeconds     float64           `json:"eta_seconds"`
+	Procedures     []procedureStatus `json:"procedures"`
+	SlowestJobs    []inFlightStatus  `json:"slowest_jobs"`
+	RecentFailures []failureStatus   `json:"recent_failures"`
+}
+
+// Status returns a snapshot of the run's progress, procedures sorted by name.
+func (p *progressTracker) Status() runStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	elapsed := time.Since(p.start)
+	s := runStatus{
+		Total: p.total, Completed: p.completed, Failed: p.failed, Running: len(p.running), Rows: p.rows,
+		ElapsedSeconds: elapsed.Seconds(), ETASeconds: p.eta().Seconds(),
+		Procedures: make([]procedureStatus, 0, len(p.procs)), RecentFailures: make([]failureStatus, 0, len(p.recentFailures)),
+	}
+	if elapsed > 0 {
+		s.RowsPerSec = float64(p.rows) / elapsed.Seconds()
+	}
+	for proc, pp := range p.procs {
+		s.Procedures = append(s.Procedures, procedureStatus{proc, pp.total, pp.completed, pp.failed, pp.rows})
+	}
+	sort.Slice(s.Procedures, func(i, j int) bool { return s.Procedures[i].Procedure < s.Procedures[j].Procedure })
+	s.SlowestJobs = make([]inFlightStatus, 0, len(p.running))
+	for id, r := range p.running {
+		s.SlowestJobs = append(s.SlowestJobs, inFlightStatus{r.job.Proc, r.job.SolID, id, time.Since(r.start).Seconds()})
+	}
+	sort.Slice(s.SlowestJobs, func(i, j int) bool { return s.SlowestJobs[i].ElapsedSeconds > s.SlowestJobs[j].ElapsedSeconds })
+	if len(s.SlowestJobs) > slowestInFlight {
+		s.SlowestJobs = s.SlowestJobs[:slowestInFlight]
+	}
+	for _, f := range p.recentFailures {
+		s.RecentFailures = append(s.RecentFailures, failureStatus{f.Job.Proc, f.Job.SolID, f.Time, f.ORACode, f.Message})
+	}
+	return s
+}
+
+// RecentFailures returns the most recent failed jobs, oldest first.
+func (p *progressTracker) RecentFailures() []failedJob {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]failedJob(nil), p.recentFailures...)
+}
+
+// Heartbeat logs the number of in-flight jobs and lists every job running longer than
+// the stall threshold, cancelling them when configured to.
+func (p *progressTracker) Heartbeat() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	workers := make([]int, 0, len(p.running))
+	for id := range p.running {
+		workers = append(workers, id)
+	}
+	sort.Ints(workers)
+
+	var stalled int
+	for _, id := range workers {
+		r := p.running[id]
+		elapsed := time.Since(r.start)
+		if p.stallThreshold <= 0 || elapsed < p.stallThreshold {
+			continue
+		}
+		stalled++
+		log.Warn("🐢 Job running longer than stall threshold", "worker", id, "procedure", r.job.Proc, "sol_id", r.job.SolID, "elapsed", elapsed.Round(time.Second))
+		if p.cancelStalled && !r.cancelled {
+			r.cancel(&TimeoutError{After: p.stallThreshold})
+			r.cancelled = true
+			log.Warn("Cancelled stalled job", "worker", id, "procedure", r.job.Proc, "sol_id", r.job.SolID)
+		}
+	}
+	log.Info("💓 Heartbeat", "running_jobs", len(p.running), "stalled_jobs", stalled)
+}
+
+// Timeline returns the spans of every finished job, when timeline recording is enabled.
+func (p *progressTracker) Timeline() []jobSpan {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.timeline
+}
+
+// Run reports progress and heartbeats on their intervals until stop is closed.
+func (p *progressTracker) Run(interval, heartbeatInterval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.Report()
+		case <-heartbeat.C:
+			p.Heartbeat()
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// countFileRecords counts the records in a procedure's merged output, or its per-SOL
+// files. Records are also counted per SOL when the template has a SOL_ID column, or by
+// file for per-SOL outputs.
+func countFileRecords(cfg *ExtractionConfig, proc string, cols []ColumnConfig) (int64, map[string]int64, error) {
+	parts, err := cfg.outputParts(proc)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	solCol, solStart := -1, 0
+	for i, c := range cols {
+		if strings.EqualFold(c.Name, "SOL_ID") {
+			solCol = i
+			break
+		}
+		solStart += c.Length
+	}
+	var perSol map[string]int64
+	if solCol >= 0 || cfg.PerSolOutput {
+		perSol = make(map[string]int64)
+	}
+
+	var total int64
+	for _, part := range parts {
+		f, err := cfg.forOutput(proc).openRecords(part.Path)
+		if os.IsNotExist(err) && part.sols != nil {
+			continue
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		byRecord := perSol
+		if part.SolID != "" {
+			byRecord = nil
+		}
+		n, err := countRecords(cfg, f, cols, solCol, solStart, byRecord)
+		f.Close()
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to read %s: %w", part.Path, err)
+		}
+		total += n
+		if part.SolID != "" {
+			perSol[part.SolID] += n
+		}
+	}
+	return total, perSol, nil
+}
+
+// countRecords counts the records read from r, adding them to perSol by their SOL_ID
+// column when perSol is set.
+func countRecords(cfg *ExtractionConfig, r io.Reader, cols []ColumnConfig, solCol, solStart int, perSol map[string]int64) (int64, error) {
+	if solCol < 0 {
+		perSol = nil
+	}
+	var total int64
+	if cfg.Format == "fixed" {
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				total++
+				if perSol != nil && len(line) >= solStart+cols[solCol].Length {
+					perSol[strings.TrimSpace(line[solStart:solStart+cols[solCol].Length])]++
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+		return total, nil
+	}
+
+	reader := csv.NewReader(bufio.NewReader(r))
+	reader.Comma = cfg.delimiterRune()
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		total++
+		if perSol != nil && solCol < len(rec) {
+			perSol[strings.TrimSpace(rec[solCol])]++
+		}
+	}
+	return total, nil
+}
+
+// reconcile compares the record counts of the merged outputs against live database counts
+// and writes a reconciliation report. It returns the number of mismatches found.
+func reconcile(ctx context.Context, dbs map[string]*sql.DB, appCfg *MainConfig, runCfg *ExtractionConfig, templates map[string][]ColumnConfig, sols []string) (int, error) {
+	log.Info("🔍 Reconciling output files against database counts", "procedures", len(runCfg.Procedures))
+	dbCounts, err := countRows(ctx, dbs, runCfg, sols, appCfg.Concurrency)
+	if err != nil {
+		return 0, err
+	}
+
+	path := filepath.Join(appCfg.LogFilePath, runCfg.PackageName+"_reconciliation.csv")
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create reconciliation report: %w", err)
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"PROCEDURE", "SOL_ID", "FILE_ROWS", "DB_ROWS", "DIFFERENCE", "STATUS"})
+
+	mismatches := 0
+	record := func(proc, sol string, fileRows, dbRows int64) {
+		status := "MATCH"
+		if fileRows != dbRows {
+			status = "MISMATCH"
+			mismatches++
+			log.Error("❌ Record count mismatch", "procedure", proc, "sol_id", sol, "file_rows", fileRows, "db_rows", dbRows)
+		}
+		writer.Write([]string{proc, sol, strconv.FormatInt(fileRows, 10), strconv.FormatInt(dbRows, 10), strconv.FormatInt(fileRows-dbRows, 10), status})
+	}
+
+	procs := append([]string(nil), runCfg.Procedures...)
+	sort.Strings(procs)
+	for _, proc := range procs {
+		total, perSol, err := countFileRecords(runCfg, proc, templates[proc])
+		if errors.Is(err, os.ErrNotExist) {
+			log.Warn("No output file to reconcile", "procedure", proc)
+		} else if err != nil {
+			return mismatches, fmt.Errorf("failed to count records for %s: %w", proc, err)
+		}
+		if perSol != nil {
+			for _, sol := range sols {
+				record(proc, sol, perSol[sol], dbCounts[proc][sol])
+			}
+		}
+		record(proc, "*", total, dbCounts.total(proc))
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return mismatches, err
+	}
+
+	if mismatches > 0 {
+		log.Error("Reconciliation found mismatches", "mismatches", mismatches, "report", path)
+	} else {
+		log.Info("✅ Reconciliation passed", "report", path)
+	}
+	return mismatches, nil
+}
+
+// runCompare implements the "compare" subcommand, reconciling the outputs of an earlier
+// extraction run against the database.
+func runCompare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	runID := fs.String("run-id", "", "Run ID (YYYYMMDDhhmmss) whose outputs to check, for date-based output names (default now)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	appCfg, runCfg, err := loadConfigs()
+	if err != nil {
+		return err
+	}
+	if err := validateConfigs(&appCfg, &runCfg, "E"); err != nil {
+		return err
+	}
+	runCfg.RunStart = time.Now()
+	if *runID != "" {
+		if runCfg.RunStart, err = timestamps.parseRunID(*runID); err != nil {
+			return fmt.Errorf("invalid -run-id %q: %w", *runID, err)
+		}
+	}
+	runCfg.RunID = timestamps.runID(runCfg.RunStart)
+	if err := runCfg.compileFileNames(); err != nil {
+		return err
+	}
+	templates, err := loadTemplates(&runCfg)
+	if err != nil {
+		return err
+	}
+	sols, err := readSols(appCfg.SolFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read SOL IDs: %w", err)
+	}
+	runCfg.sols = sols
+	dbs, err := openDatabases(&appCfg, &runCfg)
+	if err != nil {
+		return err
+	}
+	defer closeDatabases(dbs)
+	if err := runCfg.resolveBusinessDate(context.Background(), dbs[""], *busDate); err != nil {
+		return err
+	}
+	runCfg.environment = appCfg.Environment
+	if err := runCfg.loadSolGroups(context.Background(), dbs[""]); err != nil {
+		return err
+	}
+
+	mismatches, err := reconcile(context.Background(), dbs, &appCfg, &runCfg, templates, sols)
+	if err != nil {
+		return err
+	}
+	if mismatches > 0 {
+		return fmt.Errorf("reconciliation failed: %d mismatch(es)", mismatches)
+	}
+	return nil
+}
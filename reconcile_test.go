@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCountFileRecordsPerSol(t *testing.T) {
+	dir := t.TempDir()
+	cols := []ColumnConfig{{Name: "SOL_ID", Length: 4}, {Name: "NAME", Length: 6}}
+	for _, tc := range []struct {
+		format, content string
+	}{
+		{"delimited", "0001|alpha\n0001|\"be|ta\"\n0002|gamma\n"},
+		{"fixed", "0001alpha \n0001beta  \n0002gamma \n"},
+	} {
+		cfg := &ExtractionConfig{SpoolOutputPath: dir, Format: tc.format, Delimiter: "|"}
+		if err := cfg.compileFileNames(); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "GAM.txt"), []byte(tc.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		total, perSol, err := countFileRecords(cfg, "GAM", cols)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.format, err)
+		}
+		if total != 3 || perSol["0001"] != 2 || perSol["0002"] != 1 {
+			t.Errorf("%s: total=%d perSol=%v, want 3 and 0001=2 0002=1", tc.format, total, perSol)
+		}
+	}
+}
+
+func TestRunHistoryTrends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), historyFile)
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i, rows := range []int64{100, 120, 80, 300} {
+		rec := runRecord{Package: "PKG", Mode: "E", Start: start.AddDate(0, 0, i), Procedures: []procedureRecord{
+			{Procedure: "GAM", Status: "SUCCESS", Seconds: 10, Rows: rows},
+		}}
+		if err := appendRunHistory(path, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	appendRunHistory(path, runRecord{Package: "OTHER", Mode: "E", Start: start.AddDate(0, 1, 0)})
+
+	runs, err := readRunHistory(path, "PKG", "E")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 4 {
+		t.Fatalf("read %d runs, want 4", len(runs))
+	}
+	got := trends(runs, 10)
+	if len(got) != 1 || got[0].Baseline != 3 || got[0].AvgRows != 100 || got[0].volumeDeviation() != 200 || got[0].durationDeviation() != 0 {
+		t.Errorf("trends() = %+v", got)
+	}
+	if got := trends(runs, 1); got[0].AvgRows != 80 {
+		t.Errorf("trends(window 1) average = %v, want 80", got[0].AvgRows)
+	}
+}
+
+func TestCheckVolumeAnomalies(t *testing.T) {
+	previous := &runRecord{RunID: "20260301000000", Procedures: []procedureRecord{
+		{Procedure: "GAM", Status: "SUCCESS", Rows: 1000},
+		{Procedure: "HTD", Status: "SUCCESS", Rows: 1000},
+	}}
+	summary := map[string]ProcSummary{
+		"GAM": {Status: "SUCCESS", Rows: 1100},
+		"HTD": {Status: "SUCCESS", Rows: 400},
+	}
+	cfg := &ExtractionConfig{VolumeAlertPercent: 20}
+	if err := checkVolumeAnomalies(cfg, previous, summary); err != nil {
+		t.Errorf("warn policy failed the run: %v", err)
+	}
+	cfg.VolumeAlertPolicy = VolumeAlertFail
+	err := checkVolumeAnomalies(cfg, previous, summary)
+	if err == nil || !strings.Contains(err.Error(), "HTD (1000 -> 400 rows)") || strings.Contains(err.Error(), "GAM") {
+		t.Errorf("checkVolumeAnomalies() = %v, want only HTD flagged", err)
+	}
+	if err := checkVolumeAnomalies(cfg, nil, summary); err != nil {
+		t.Errorf("first run without history failed: %v", err)
+	}
+}
@@ -6,13 +6,13 @@ import (
 	"fmt"
 )
 
-// callProcedure executes a prepared statement for a given SOL ID.
+// callProcedure executes proc's prepared statement with a SOL's bind values.
 // It no longer contains logging, as that is handled by the worker function
 // which has more context.
-func callProcedure(ctx context.Context, stmt *sql.Stmt, solID string) error {
-	_, err := stmt.ExecContext(ctx, solID)
+func callProcedure(ctx context.Context, stmt *sql.Stmt, proc string, args []any) error {
+	_, err := stmt.ExecContext(ctx, args...)
 	if err != nil {
-		return fmt.Errorf("prepared statement execution failed: %w", err)
+		return newQueryError(proc, fmt.Errorf("prepared statement execution failed: %w", err))
 	}
 	return err
 }
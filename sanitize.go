@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Sanitisation rules for extracted values, combined with "+" (e.g. "control+whitespace").
+// Every rule set but none replaces line breaks with spaces, so no value can break a
+// record.
+const (
+	SanitizeNewlines   = "newlines"   // line breaks to spaces, the default
+	SanitizeControl    = "control"    // remove other control characters
+	SanitizeASCII      = "ascii"      // remove non-ASCII characters
+	SanitizeWhitespace = "whitespace" // collapse whitespace runs to one space and trim
+	SanitizeNone       = "none"       // leave values untouched, for already-clean columns
+)
+
+// sanitizer rewrites an extracted value according to a column's rules.
+type sanitizer func(string) string
+
+// parseSanitizeRules builds the sanitizer for a rule set; none gives a nil sanitizer.
+func parseSanitizeRules(rules string) (sanitizer, error) {
+	var control, ascii, whitespace bool
+	for _, rule := range strings.Split(strings.ToLower(rules), "+") {
+		switch strings.TrimSpace(rule) {
+		case "", SanitizeNewlines:
+		case SanitizeControl:
+			control = true
+		case SanitizeASCII:
+			ascii = true
+		case SanitizeWhitespace:
+			whitespace = true
+		case SanitizeNone:
+			if strings.Contains(rules, "+") {
+				return nil, fmt.Errorf("none cannot be combined with other rules in %q", rules)
+			}
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("unknown sanitize rule %q, want newlines, control, ascii, whitespace or none", rule)
+		}
+	}
+	newlines := strings.NewReplacer("\n", " ", "\r", " ")
+	return func(s string) string {
+		s = newlines.Replace(s)
+		if control || ascii {
+			s = strings.Map(func(r rune) rune {
+				if (control && unicode.IsControl(r)) || (ascii && r > unicode.MaxASCII) {
+					return -1
+				}
+				return r
+			}, s)
+		}
+		if whitespace {
+			s = strings.Join(strings.Fields(s), " ")
+		}
+		return s
+	}, nil
+}
+
+// columnSanitizers returns the sanitizer of each column: its own rules, or the run's.
+// Rules are checked by validation and template loading, so invalid ones fall back to the
+// default.
+func (c *ExtractionConfig) columnSanitizers(cols []ColumnConfig) []sanitizer {
+	out := make([]sanitizer, len(cols))
+	for i, col := range cols {
+		rules := col.Sanitize
+		if rules == "" {
+			rules = c.Sanitize
+		}
+		s, err := parseSanitizeRules(rules)
+		if err != nil {
+			s, _ = parseSanitizeRules("")
+		}
+		out[i] = s
+	}
+	return out
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestSanitizeRules(t *testing.T) {
+	for _, tc := range []struct{ rules, in, want string }{
+		{"", "a\r\nb", "a  b"},
+		{"newlines", "a\nb", "a b"},
+		{"control", "a\x00b\tc\nd", "abc d"},
+		{"ascii", "Zoë\n", "Zo "},
+		{"control+whitespace", "  a \x07 b\n\nc ", "a b c"},
+	} {
+		clean, err := parseSanitizeRules(tc.rules)
+		if err != nil {
+			t.Fatalf("parseSanitizeRules(%q): %v", tc.rules, err)
+		}
+		if got := clean(tc.in); got != tc.want {
+			t.Errorf("%s(%q) = %q, want %q", tc.rules, tc.in, got, tc.want)
+		}
+	}
+	if clean, err := parseSanitizeRules("none"); err != nil || clean != nil {
+		t.Errorf("none = %v, %v, want no sanitizer", clean != nil, err)
+	}
+	for _, rules := range []string{"none+ascii", "lowercase"} {
+		if _, err := parseSanitizeRules(rules); err == nil {
+			t.Errorf("parseSanitizeRules(%q) accepted", rules)
+		}
+	}
+
+	cfg := &ExtractionConfig{Sanitize: "whitespace"}
+	s := cfg.columnSanitizers([]ColumnConfig{{Name: "NAME"}, {Name: "AMT", Sanitize: "none"}})
+	if s[0] == nil || s[0](" a  b ") != "a b" || s[1] != nil {
+		t.Error("column rules do not override the run default")
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// ddlQuery fetches the definition of a table or view, in the current schema unless an
+// owner is given.
+const ddlQuery = `SELECT o.object_type, DBMS_METADATA.GET_DDL(REPLACE(o.object_type, ' ', '_'), o.object_name, o.owner)
+FROM all_objects o
+WHERE o.object_name = :1
+  AND o.owner = NVL(:2, SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA'))
+  AND o.object_type IN ('TABLE', 'VIEW', 'MATERIALIZED VIEW')`
+
+// snapshotSchema writes the DDL of every extracted object next to the outputs, so the
+// structure a file was produced from can be looked up long after the run. Objects whose
+// definition cannot be read are noted in the file rather than failing the run.
+func snapshotSchema(ctx context.Context, dbs map[string]*sql.DB, cfg *ExtractionConfig) (string, error) {
+	path := filepath.Join(cfg.outputDir(), fmt.Sprintf("%s_%s_schema.sql", cfg.PackageName, cfg.RunID))
+	if err := cfg.ensureParentDir(path); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Schema snapshot for package %s, run %s, taken %s\n", cfg.PackageName, cfg.RunID, time.Now().Format(time.RFC3339))
+
+	for _, proc := range cfg.Procedures {
+		owner, name := splitObjectName(proc)
+		rows, err := dbs[cfg.ProcedureConnections[proc]].QueryContext(ctx, ddlQuery, name, owner)
+		if err != nil {
+			return "", fmt.Errorf("failed to read definition of %s: %w", proc, err)
+		}
+		var found bool
+		for rows.Next() {
+			var objType, ddl string
+			if err := rows.Scan(&objType, &ddl); err != nil {
+				rows.Close()
+				return "", fmt.Errorf("failed to read definition of %s: %w", proc, err)
+			}
+			found = true
+			fmt.Fprintf(&b, "\n-- %s %s\n%s;\n", objType, proc, strings.TrimSpace(ddl))
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to read definition of %s: %w", proc, err)
+		}
+		if !found {
+			log.Warn("No table or view definition found for schema snapshot", "procedure", proc)
+			fmt.Fprintf(&b, "\n-- %s: no table or view definition found\n", proc)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write schema snapshot: %w", err)
+	}
+	return path, nil
+}
+
+// splitObjectName splits an optionally schema-qualified name into owner and object name,
+// upper-casing unquoted parts the way Oracle resolves them.
+func splitObjectName(name string) (owner, object string) {
+	object = name
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		owner, object = name[:i], name[i+1:]
+	}
+	return normaliseIdentifier(owner), normaliseIdentifier(object)
+}
+
+func normaliseIdentifier(s string) string {
+	if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+		return s[1 : len(s)-1]
+	}
+	return strings.ToUpper(s)
+}
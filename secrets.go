@@ -0,0 +1,231 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// VaultConfig configures resolution of vault:<path>#<key> secret references. The token is
+// read from the environment (or a Vault Agent token file), never from the config itself.
+type VaultConfig struct {
+	Address   string `json:"address"`
+	Namespace string `json:"namespace"`
+	TokenEnv  string `json:"token_env"`
+	TokenFile string `json:"token_file"`
+	CAFile    string `json:"ca_file"`
+}
+
+// CyberArkConfig configures resolution of cyberark:Safe=..;Object=..[#field] references
+// through the Central Credential Provider (CCP) REST API.
+type CyberArkConfig struct {
+	URL      string `json:"url"`
+	AppID    string `json:"app_id"`
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+	CAFile   string `json:"ca_file"`
+}
+
+// resolveSecrets replaces secret references and encrypted values in the credential fields
+// with their plaintext.
+// Plain values are left untouched.
+func (c *MainConfig) resolveSecrets() error {
+	for _, f := range []struct {
+		name  string
+		value *string
+	}{
+		{"db_user", &c.DBUser},
+		{"db_password", &c.DBPassword},
+		{"control_token", &c.ControlToken},
+	} {
+		var (
+			v   string
+			err error
+		)
+		switch {
+		case strings.HasPrefix(*f.value, "vault:"):
+			v, err = c.Vault.lookup(strings.TrimPrefix(*f.value, "vault:"))
+		case strings.HasPrefix(*f.value, "cyberark:"):
+			v, err = c.CyberArk.lookup(strings.TrimPrefix(*f.value, "cyberark:"))
+		case strings.HasPrefix(*f.value, encryptedPrefix):
+			var key []byte
+			if key, err = loadPasswordKey(c.PasswordKeyFile); err == nil {
+				v, err = decryptPassword(key, *f.value)
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to resolve secret for %s: %w", f.name, err)
+		}
+		*f.value = v
+	}
+	return nil
+}
+
+// lookup reads key from the KV secret at path (mount/sub/path#key), supporting both KV v1
+// and v2 engines.
+func (v VaultConfig) lookup(ref string) (string, error) {
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || key == "" {
+		return "", fmt.Errorf("vault reference %q must have the form vault:<path>#<key>", ref)
+	}
+	addr := v.Address
+	if addr == "" {
+		addr = os.Getenv("VAULT_ADDR")
+	}
+	if addr == "" {
+		return "", fmt.Errorf("vault address not configured (vault.address or VAULT_ADDR)")
+	}
+	token, err := v.token()
+	if err != nil {
+		return "", err
+	}
+	client, err := secretClient(v.CAFile, "", "")
+	if err != nil {
+		return "", err
+	}
+
+	mount, rest, _ := strings.Cut(strings.Trim(path, "/"), "/")
+	// Try the KV v2 layout first and fall back to v1.
+	for _, p := range []string{mount + "/data/" + rest, mount + "/" + rest} {
+		req, err := http.NewRequest(http.MethodGet, strings.TrimRight(addr, "/")+"/v1/"+p, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("X-Vault-Token", token)
+		if v.Namespace != "" {
+			req.Header.Set("X-Vault-Namespace", v.Namespace)
+		}
+		var body struct {
+			Data map[string]any `json:"data"`
+		}
+		status, err := getJSON(client, req, &body)
+		if err != nil {
+			return "", fmt.Errorf("vault request failed: %w", err)
+		}
+		if status == http.StatusNotFound {
+			continue
+		}
+		if status != http.StatusOK {
+			return "", fmt.Errorf("vault returned HTTP %d for %s", status, path)
+		}
+		data := body.Data
+		if inner, ok := data["data"].(map[string]any); ok {
+			data = inner
+		}
+		s, ok := data[key].(string)
+		if !ok {
+			return "", fmt.Errorf("vault secret %s has no string key %q", path, key)
+		}
+		return s, nil
+	}
+	return "", fmt.Errorf("vault secret %s not found", path)
+}
+
+func (v VaultConfig) token() (string, error) {
+	if v.TokenFile != "" {
+		b, err := os.ReadFile(v.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read vault token file: %w", err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	env := v.TokenEnv
+	if env == "" {
+		env = "VAULT_TOKEN"
+	}
+	if t := os.Getenv(env); t != "" {
+		return t, nil
+	}
+	return "", fmt.Errorf("vault token not found in $%s", env)
+}
+
+// lookup queries the CCP for the account identified by ref, e.g. Safe=ORA;Object=finacle,
+// returning the password (Content) or the property named after '#'.
+func (c CyberArkConfig) lookup(ref string) (string, error) {
+	query, field, _ := strings.Cut(ref, "#")
+	if field == "" {
+		field = "Content"
+	}
+	if c.URL == "" || c.AppID == "" {
+		return "", fmt.Errorf("cyberark.url and cyberark.app_id must be configured")
+	}
+	params := url.Values{"AppID": {c.AppID}}
+	for _, kv := range strings.Split(query, ";") {
+		k, val, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if !ok || k == "" {
+			return "", fmt.Errorf("cyberark reference %q must be a list of Key=Value pairs separated by ';'", ref)
+		}
+		params.Set(k, val)
+	}
+	client, err := secretClient(c.CAFile, c.CertFile, c.KeyFile)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(c.URL, "/")+"/AIMWebService/api/Accounts?"+params.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	var body map[string]any
+	status, err := getJSON(client, req, &body)
+	if err != nil {
+		return "", fmt.Errorf("cyberark request failed: %w", err)
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("cyberark returned HTTP %d: %v", status, body["ErrorMsg"])
+	}
+	s, ok := body[field].(string)
+	if !ok {
+		return "", fmt.Errorf("cyberark account has no property %q", field)
+	}
+	return s, nil
+}
+
+func secretClient(caFile, certFile, keyFile string) (*http.Client, error) {
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return &http.Client{
+		Timeout:   15 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: tlsCfg, Proxy: http.ProxyFromEnvironment},
+	}, nil
+}
+
+// getJSON performs req and decodes a JSON response body into out, returning the status.
+func getJSON(client *http.Client, req *http.Request, out any) (int, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return resp.StatusCode, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && resp.StatusCode == http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("invalid response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/kv/data/oracle/finacle":
+			if r.Header.Get("X-Vault-Token") != "s.test" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"data": map[string]any{"password": "vault-pw"}}})
+		case "/AIMWebService/api/Accounts":
+			q := r.URL.Query()
+			if q.Get("AppID") != "extract" || q.Get("Safe") != "ORA" || q.Get("Object") != "finacle" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"Content": "ccp-pw", "UserName": "ccp-user"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("VAULT_TOKEN", "s.test")
+
+	cfg := MainConfig{
+		DBUser:     "cyberark:Safe=ORA;Object=finacle#UserName",
+		DBPassword: "vault:kv/oracle/finacle#password",
+		Vault:      VaultConfig{Address: srv.URL},
+		CyberArk:   CyberArkConfig{URL: srv.URL, AppID: "extract"},
+	}
+	if err := cfg.resolveSecrets(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DBUser != "ccp-user" || cfg.DBPassword != "vault-pw" {
+		t.Errorf("got user=%q password=%q", cfg.DBUser, cfg.DBPassword)
+	}
+
+	cfg.DBPassword = "vault:kv/oracle/missing#password"
+	if err := cfg.resolveSecrets(); err == nil {
+		t.Error("expected error for missing vault secret")
+	}
+}
+
+func TestEncryptedPasswordRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	t.Setenv(passwordKeyEnv, base64.StdEncoding.EncodeToString(key))
+
+	enc, err := encryptPassword(key, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := MainConfig{DBPassword: enc}
+	if err := cfg.resolveSecrets(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DBPassword != "s3cret" {
+		t.Errorf("decrypted password = %q, want s3cret", cfg.DBPassword)
+	}
+
+	key[0] ^= 0xff
+	if _, err := decryptPassword(key, enc); err == nil {
+		t.Error("expected decryption with the wrong key to fail")
+	}
+}
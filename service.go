@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"sync"
+)
+
+// serviceName is the name the tool runs under as a Windows service.
+const serviceName = "gemini_extract"
+
+// serviceStops holds the channels a stop request from the service manager is delivered
+// to, as an interrupt, so a service stop drains the run like Ctrl+C does.
+var serviceStops = struct {
+	sync.Mutex
+	chans map[chan<- os.Signal]bool
+}{chans: make(map[chan<- os.Signal]bool)}
+
+// notifyServiceStop relays service stop requests to c.
+func notifyServiceStop(c chan<- os.Signal) {
+	serviceStops.Lock()
+	defer serviceStops.Unlock()
+	serviceStops.chans[c] = true
+}
+
+// stopServiceNotify stops relaying service stop requests to c.
+func stopServiceNotify(c chan<- os.Signal) {
+	serviceStops.Lock()
+	defer serviceStops.Unlock()
+	delete(serviceStops.chans, c)
+}
+
+// stopService delivers a stop request to every registered channel without blocking.
+func stopService() {
+	serviceStops.Lock()
+	defer serviceStops.Unlock()
+	for c := range serviceStops.chans {
+		select {
+		case c <- os.Interrupt:
+		default:
+		}
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+func TestNotifyService(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unix datagram sockets unavailable: %v", err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	notifyService("READY=1")
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil || string(buf[:n]) != "READY=1" {
+		t.Errorf("systemd received %q, %v", buf[:n], err)
+	}
+}
@@ -0,0 +1,64 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// runAsService runs the tool directly; under systemd it reports through notifyService.
+func runAsService(run func() error) error {
+	return run()
+}
+
+// notifyService sends a state such as READY=1, STATUS=... or STOPPING=1 to systemd when
+// the tool runs as a Type=notify unit. Outside systemd it does nothing.
+func notifyService(state string) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return
+	}
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:] // abstract namespace
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		log.Debug("Failed to notify systemd", "state", state, "error", err)
+		return
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		log.Debug("Failed to notify systemd", "state", state, "error", err)
+	}
+}
+
+// startServiceWatchdog pings the systemd watchdog at half its WatchdogSec interval until
+// the returned function is called. Without a watchdog it does nothing.
+func startServiceWatchdog() (stop func()) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return func() {}
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Duration(usec) * time.Microsecond / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				notifyService("WATCHDOG=1")
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
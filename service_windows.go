@@ -0,0 +1,62 @@
+//go:build windows
+
+package main
+
+import (
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// windowsService runs the tool under the service control manager, turning a stop or
+// shutdown request into a graceful cancel and reporting the run's exit code on failure.
+type windowsService struct {
+	run func() error
+	err error
+}
+
+func (s *windowsService) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	done := make(chan error, 1)
+	go func() { done <- s.run() }()
+	running := svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	status <- running
+	for {
+		select {
+		case s.err = <-done:
+			if s.err != nil {
+				return true, uint32(exitCode(s.err))
+			}
+			return false, 0
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				// In-flight jobs finish before the run returns; ask the SCM for time.
+				status <- svc.Status{State: svc.StopPending, WaitHint: uint32(time.Minute / time.Millisecond)}
+				stopService()
+			}
+		}
+	}
+}
+
+// runAsService runs the tool under the service control manager when started as a Windows
+// service, and directly otherwise.
+func runAsService(run func() error) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return run()
+	}
+	s := &windowsService{run: run}
+	if err := svc.Run(serviceName, s); err != nil {
+		return err
+	}
+	return s.err
+}
+
+// notifyService does nothing on Windows, where run state is reported to the service
+// control manager by windowsService.
+func notifyService(string) {}
+
+func startServiceWatchdog() (stop func()) { return func() {} }
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// sessionStatNames are the v$sesstat statistics sampled around each job.
+var sessionStatNames = []string{"session logical reads", "consistent gets", "db block gets", "physical reads", "CPU used by this session", "bytes sent via SQL*Net to client"}
+
+// sessionStats is a sample of the session's statistics and its last SQL's cursor metrics.
+type sessionStats struct {
+	values     map[string]int64
+	sqlID      string
+	elapsedUS  int64
+	executions int64
+}
+
+// since returns the statistics accumulated between before and s. The cursor metrics
+// are kept as they are, since they describe the shared cursor rather than the session.
+func (s sessionStats) since(before sessionStats) sessionStats {
+	d := s
+	d.values = make(map[string]int64, len(s.values))
+	for name, v := range s.values {
+		d.values[name] = v - before.values[name]
+	}
+	return d
+}
+
+const sessionStatsQuery = `SELECT n.name, s.value
+FROM v$mystat s JOIN v$statname n ON n.statistic# = s.statistic#
+WHERE n.name IN ('session logical reads', 'consistent gets', 'db block gets', 'physical reads', 'CPU used by this session', 'bytes sent via SQL*Net to client')`
+
+// readSessionStats samples the session's statistics and, first, the cursor metrics of
+// the SQL it ran last, before the sampling queries replace it.
+func readSessionStats(ctx context.Context, c *sql.Conn) (sessionStats, error) {
+	s := sessionStats{values: make(map[string]int64)}
+	err := c.QueryRowContext(ctx, `SELECT NVL(s.prev_sql_id, '-'), NVL(q.elapsed_time, 0), NVL(q.executions, 0)
+FROM v$session s LEFT JOIN v$sql q ON q.sql_id = s.prev_sql_id AND q.child_number = s.prev_child_number
+WHERE s.sid = SYS_CONTEXT('USERENV', 'SID')`).Scan(&s.sqlID, &s.elapsedUS, &s.executions)
+	if err != nil {
+		return s, fmt.Errorf("failed to read session SQL: %w", err)
+	}
+	rows, err := c.QueryContext(ctx, sessionStatsQuery)
+	if err != nil {
+		return s, fmt.Errorf("failed to read session statistics: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var v int64
+		if err := rows.Scan(&name, &v); err != nil {
+			return s, err
+		}
+		s.values[name] = v
+	}
+	return s, rows.Err()
+}
+
+// sessionStatsLog is the extended per-job log of session statistics.
+type sessionStatsLog struct {
+	mu     sync.Mutex
+	file   *os.File
+	w      *csv.Writer
+	warned bool
+}
+
+func openSessionStatsLog(path string) (*sessionStatsLog, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session statistics log: %w", err)
+	}
+	l := &sessionStatsLog{file: f, w: csv.NewWriter(f)}
+	header := []string{"SOL_ID", "PROCEDURE", "TIME"}
+	for _, name := range sessionStatNames {
+		header = append(header, name)
+	}
+	l.w.Write(append(header, "SQL_ID", "SQL_ELAPSED_MS_PER_EXEC", "SQL_EXECUTIONS"))
+	return l, nil
+}
+
+// Write appends the statistics of one job.
+func (l *sessionStatsLog) Write(job Job, s sessionStats) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	record := []string{job.SolID, job.Proc, timestamps.format(time.Now())}
+	for _, name := range sessionStatNames {
+		record = append(record, strconv.FormatInt(s.values[name], 10))
+	}
+	var perExec float64
+	if s.executions > 0 {
+		perExec = float64(s.elapsedUS) / float64(s.executions) / 1000
+	}
+	record = append(record, s.sqlID, fmt.Sprintf("%.3f", perExec), strconv.FormatInt(s.executions, 10))
+	if err := l.w.Write(record); err != nil {
+		log.Warn("Failed to write session statistics", "error", err)
+	}
+	l.w.Flush()
+}
+
+// failed warns, once, that statistics could not be sampled.
+func (l *sessionStatsLog) failed(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.warned {
+		l.warned = true
+		log.Warn("Session statistics unavailable, jobs run without them (needs SELECT on V$MYSTAT, V$STATNAME, V$SESSION, V$SQL)", "error", err)
+	}
+}
+
+func (l *sessionStatsLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Flush()
+	return l.file.Close()
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// UploadSFTP copies outputs to a directory on an SFTP server.
+const UploadSFTP = "sftp"
+
+// sftpPartSize is the size of the parts a file is written to an SFTP server in.
+const sftpPartSize = 32 << 20
+
+// sftpAuth returns the authentication methods for an SFTP destination: its key file,
+// the keys of the SSH agent at SSH_AUTH_SOCK and the SFTP_PASSWORD password, in that
+// order of preference.
+func sftpAuth(dest UploadConfig) ([]ssh.AuthMethod, func(), error) {
+	var methods []ssh.AuthMethod
+	closeAgent := func() {}
+	if dest.KeyFile != "" {
+		pem, err := os.ReadFile(dest.KeyFile)
+		if err != nil {
+			return nil, closeAgent, err
+		}
+		signer, err := ssh.ParsePrivateKey(pem)
+		if err != nil {
+			return nil, closeAgent, fmt.Errorf("invalid key_file %s: %w", dest.KeyFile, err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+			closeAgent = func() { conn.Close() }
+		}
+	}
+	if pw := os.Getenv("SFTP_PASSWORD"); pw != "" {
+		methods = append(methods, ssh.Password(pw))
+	}
+	if len(methods) == 0 {
+		return nil, closeAgent, fmt.Errorf("no SFTP credentials (key_file, SSH agent or SFTP_PASSWORD)")
+	}
+	return methods, closeAgent, nil
+}
+
+// sftpHostKeys returns the check of the server's host key against known_hosts, by
+// default ~/.ssh/known_hosts. Unknown hosts are refused.
+func sftpHostKeys(dest UploadConfig) (ssh.HostKeyCallback, error) {
+	file := dest.KnownHosts
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		file = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	return knownhosts.New(file)
+}
+
+// dialSFTP connects to the destination's server, port 22 unless host names another.
+func dialSFTP(ctx context.Context, dest UploadConfig) (*sftp.Client, func(), error) {
+	auth, closeAgent, err := sftpAuth(dest)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer closeAgent()
+	hostKeys, err := sftpHostKeys(dest)
+	if err != nil {
+		return nil, nil, err
+	}
+	addr := dest.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, &ssh.ClientConfig{User: dest.User, Auth: auth, HostKeyCallback: hostKeys})
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	// A cancelled run closes the connection, ending the transfers in flight.
+	stop := context.AfterFunc(ctx, func() { client.Close() })
+	sc, err := sftp.NewClient(client)
+	if err != nil {
+		stop()
+		client.Close()
+		return nil, nil, err
+	}
+	return sc, func() {
+		stop()
+		sc.Close()
+		client.Close()
+	}, nil
+}
+
+// uploadSFTP writes src to name.partial on the server, up to ParallelParts parts at a
+// time, and renames it to name once every part is written, so pollers never see a
+// partial file under its final name. The parts written are recorded in stateDir, keyed
+// by the file's path, size and modification time and the destination, so a retry, in
+// this run or the next, writes only the parts still missing.
+func uploadSFTP(ctx context.Context, dest UploadConfig, name, src, stateDir string, limit *rateLimiter) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	statePath, err := sftpStatePath(stateDir, src, info, dest, name)
+	if err != nil {
+		return err
+	}
+	written := readSFTPState(statePath)
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return err
+	}
+	state, err := os.OpenFile(statePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer state.Close()
+
+	client, closeClient, err := dialSFTP(ctx, dest)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+	if dir := path.Dir(name); dir != "." {
+		if err := client.MkdirAll(dir); err != nil {
+			return err
+		}
+	}
+	partial := name + ".partial"
+	if _, err := client.Stat(partial); err != nil {
+		// Parts recorded for a partial file that is gone are no longer held.
+		clear(written)
+	}
+
+	// The first part that fails cancels the others, in flight or not yet started.
+	partCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	var stateMu sync.Mutex
+	parts := int(max(1, (info.Size()+sftpPartSize-1)/sftpPartSize))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range max(1, dest.ParallelParts) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range work {
+				if written[n] {
+					continue
+				}
+				off := int64(n) * sftpPartSize
+				size := min(sftpPartSize, info.Size()-off)
+				err := withRetries(partCtx, dest.Retries, func() error {
+					rf, err := client.OpenFile(partial, os.O_WRONLY|os.O_CREATE)
+					if err != nil {
+						return err
+					}
+					_, err = io.Copy(io.NewOffsetWriter(rf, off), limit.reader(partCtx, io.NewSectionReader(f, off, size)))
+					if cerr := rf.Close(); err == nil {
+						err = cerr
+					}
+					return err
+				})
+				if err == nil {
+					stateMu.Lock()
+					_, err = fmt.Fprintln(state, n)
+					stateMu.Unlock()
+				}
+				if err != nil {
+					cancel(err)
+					return
+				}
+			}
+		}()
+	}
+feed:
+	for n := range parts {
+		select {
+		case work <- n:
+		case <-partCtx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+	if err := context.Cause(partCtx); err != nil {
+		return err
+	}
+
+	// A partial file left by an earlier, larger version of the file is cut to size.
+	if err := client.Truncate(partial, info.Size()); err != nil {
+		return err
+	}
+	if err := sftpRename(client, partial, name); err != nil {
+		return err
+	}
+	state.Close()
+	return os.Remove(statePath)
+}
+
+// sftpRename moves from to to, replacing to. Servers without the POSIX rename extension
+// refuse to rename over an existing file, so it is removed first.
+func sftpRename(client *sftp.Client, from, to string) error {
+	if _, ok := client.HasExtension("posix-rename@openssh.com"); ok {
+		return client.PosixRename(from, to)
+	}
+	if err := client.Remove(to); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return client.Rename(from, to)
+}
+
+// sftpStatePath returns the file that records the parts written of the upload of src,
+// as it is now, to name at dest.
+func sftpStatePath(stateDir, src string, info os.FileInfo, dest UploadConfig, name string) (string, error) {
+	abs, err := filepath.Abs(src)
+	if err != nil {
+		return "", err
+	}
+	tag := sha256.Sum256(fmt.Appendf(nil, "%s|%s@%s:%s|%d|%d", abs, dest.User, dest.Host, name, info.Size(), info.ModTime().UnixNano()))
+	return filepath.Join(stateDir, fmt.Sprintf("%s.sftp-%x", filepath.Base(src), tag[:8])), nil
+}
+
+// readSFTPState returns the parts an earlier upload recorded as written.
+func readSFTPState(statePath string) map[int]bool {
+	written := make(map[int]bool)
+	f, err := os.Open(statePath)
+	if err != nil {
+		return written
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if n, err := strconv.Atoi(sc.Text()); err == nil {
+			written[n] = true
+		}
+	}
+	return written
+}
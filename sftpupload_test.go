@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// startSFTPServer serves SFTP on the local file system to user "extract" with password
+// "pw", returning its address and a known_hosts file holding its host key.
+func startSFTPServer(t *testing.T) (addr, knownHosts string) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &ssh.ServerConfig{PasswordCallback: func(c ssh.ConnMetadata, pw []byte) (*ssh.Permissions, error) {
+		if c.User() == "extract" && string(pw) == "pw" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("access denied")
+	}}
+	cfg.AddHostKey(signer)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lis.Close() })
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go serveSFTP(conn, cfg)
+		}
+	}()
+
+	addr = lis.Addr().String()
+	knownHosts = filepath.Join(t.TempDir(), "known_hosts")
+	os.WriteFile(knownHosts, []byte(knownhosts.Line([]string{knownhosts.Normalize(addr)}, signer.PublicKey())+"\n"), 0644)
+	return addr, knownHosts
+}
+
+func serveSFTP(conn net.Conn, cfg *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		ch, reqs, err := nc.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			for req := range reqs {
+				ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					if srv, err := sftp.NewServer(ch); err == nil {
+						srv.Serve()
+					}
+					ch.Close()
+				}
+			}
+		}()
+	}
+}
+
+func TestUploadSFTP(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	t.Setenv("SFTP_PASSWORD", "pw")
+	addr, knownHosts := startSFTPServer(t)
+	remote := t.TempDir()
+	src := filepath.Join(t.TempDir(), "GAM.txt")
+	os.WriteFile(src, []byte("a\nb\n"), 0644)
+
+	dest := UploadConfig{Provider: UploadSFTP, Host: addr, User: "extract", KnownHosts: knownHosts, Prefix: filepath.ToSlash(remote) + "/landing/", ParallelParts: 2}
+	uri, err := uploadFile(context.Background(), dest, src, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(remote, "landing", "GAM.txt")); string(got) != "a\nb\n" {
+		t.Errorf("uploaded %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(remote, "landing", "GAM.txt.partial")); !os.IsNotExist(err) {
+		t.Error("partial file left behind")
+	}
+	if !strings.HasPrefix(uri, "sftp://extract@"+addr+"/") {
+		t.Errorf("uri = %s", uri)
+	}
+
+	// A host whose key is not in known_hosts is refused.
+	dest.KnownHosts = filepath.Join(t.TempDir(), "empty")
+	os.WriteFile(dest.KnownHosts, nil, 0644)
+	if _, err := uploadFile(context.Background(), dest, src, t.TempDir()); err == nil || !strings.Contains(err.Error(), "key is unknown") {
+		t.Errorf("upload to an unknown host: %v", err)
+	}
+}
+
+func TestUploadSFTPResumesWrittenParts(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	t.Setenv("SFTP_PASSWORD", "pw")
+	addr, knownHosts := startSFTPServer(t)
+	remote := t.TempDir()
+	src := filepath.Join(t.TempDir(), "GAM.txt")
+	os.WriteFile(src, []byte("a\nb\n"), 0644)
+	dest := UploadConfig{Provider: UploadSFTP, Host: addr, User: "extract", KnownHosts: knownHosts, Prefix: filepath.ToSlash(remote) + "/"}
+
+	// An interrupted upload wrote the only part and recorded it; the retry must not
+	// write it again, which the partial file's content tells.
+	stateDir := t.TempDir()
+	info, _ := os.Stat(src)
+	state, err := sftpStatePath(stateDir, src, info, dest, dest.Prefix+"GAM.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(state, []byte("0\n"), 0644)
+	os.WriteFile(filepath.Join(remote, "GAM.txt.partial"), []byte("held"), 0644)
+
+	if _, err := uploadFile(context.Background(), dest, src, stateDir); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(remote, "GAM.txt")); string(got) != "held" {
+		t.Errorf("uploaded %q, want the part already written kept", got)
+	}
+	if _, err := os.Stat(state); !os.IsNotExist(err) {
+		t.Error("finished upload kept its state")
+	}
+}
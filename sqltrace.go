@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+
+	log "github.com/charmbracelet/log"
+)
+
+// TraceJob selects jobs to run with Oracle SQL trace; an empty SOL ID traces every SOL
+// of the procedure.
+type TraceJob struct {
+	Procedure string `json:"procedure"`
+	SolID     string `json:"sol_id"`
+}
+
+// sessionRunner runs jobs that need a session of their own: those selected for
+// DBMS_MONITOR tracing (waits and binds, as with event 10046 level 12), so DBAs get
+// trace files for just the slow jobs, and every job when session statistics are
+// collected. A nil runner runs nothing on its own session.
+type sessionRunner struct {
+	dbs   map[string]*sql.DB
+	trace map[string]map[string]bool
+	stats *sessionStatsLog
+}
+
+func newSessionRunner(dbs map[string]*sql.DB, cfg *ExtractionConfig, stats *sessionStatsLog) *sessionRunner {
+	if len(cfg.TraceJobs) == 0 && stats == nil {
+		return nil
+	}
+	r := &sessionRunner{dbs: dbs, trace: make(map[string]map[string]bool), stats: stats}
+	for _, tj := range cfg.TraceJobs {
+		if r.trace[tj.Procedure] == nil {
+			r.trace[tj.Procedure] = make(map[string]bool)
+		}
+		r.trace[tj.Procedure][tj.SolID] = true
+	}
+	return r
+}
+
+// wants reports whether job must run on its own session.
+func (r *sessionRunner) wants(job Job) bool {
+	return r != nil && (r.stats != nil || r.traced(job))
+}
+
+func (r *sessionRunner) traced(job Job) bool {
+	sols := r.trace[job.Proc]
+	return sols[""] || sols[job.SolID]
+}
+
+var traceIdentChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// run prepares query on a dedicated session of connection conn and hands the statement
+// to fn, tracing and measuring the session as configured. When tracing or statistics
+// are unavailable (e.g. missing privileges) the job still runs without them.
+func (r *sessionRunner) run(ctx context.Context, job Job, conn, query string, fn func(*sql.Stmt) error) error {
+	c, err := r.dbs[conn].Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if r.traced(job) {
+		r.enableTrace(ctx, c, job)
+		defer func() {
+			if _, err := c.ExecContext(context.Background(), "BEGIN DBMS_MONITOR.SESSION_TRACE_DISABLE; END;"); err != nil {
+				log.Warn("Failed to disable SQL trace", "procedure", job.Proc, "sol_id", job.SolID, "error", err)
+			}
+		}()
+	}
+
+	stmt, err := c.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement on dedicated session: %w", err)
+	}
+	defer stmt.Close()
+
+	if r.stats == nil {
+		return fn(stmt)
+	}
+	before, statsErr := readSessionStats(ctx, c)
+	err = fn(stmt)
+	if statsErr == nil {
+		var after sessionStats
+		if after, statsErr = readSessionStats(context.Background(), c); statsErr == nil {
+			r.stats.Write(job, after.since(before))
+		}
+	}
+	if statsErr != nil {
+		r.stats.failed(statsErr)
+	}
+	return err
+}
+
+func (r *sessionRunner) enableTrace(ctx context.Context, c *sql.Conn, job Job) {
+	ident := traceIdentChars.ReplaceAllString(fmt.Sprintf("GE_%s_%s", job.Proc, job.SolID), "_")
+	if _, err := c.ExecContext(ctx, fmt.Sprintf("ALTER SESSION SET TRACEFILE_IDENTIFIER = '%.200s'", ident)); err != nil {
+		log.Warn("Failed to set trace file identifier", "procedure", job.Proc, "sol_id", job.SolID, "error", err)
+	}
+	if _, err := c.ExecContext(ctx, "BEGIN DBMS_MONITOR.SESSION_TRACE_ENABLE(waits => TRUE, binds => TRUE); END;"); err != nil {
+		log.Warn("Failed to enable SQL trace, running job untraced", "procedure", job.Proc, "sol_id", job.SolID, "error", err)
+		return
+	}
+	var file string
+	if err := c.QueryRowContext(ctx, "SELECT value FROM v$diag_info WHERE name = 'Default Trace File'").Scan(&file); err != nil {
+		file = ident
+	}
+	log.Info("🔬 SQL trace enabled", "procedure", job.Proc, "sol_id", job.SolID, "trace_file", file)
+}
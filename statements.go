@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// Statement strategies: one set of statements shared by every worker, a set per worker
+// prepared as it needs them, or a statement per job left to the driver's statement cache.
+const (
+	StatementsShared = "shared"
+	StatementsWorker = "worker"
+	StatementsCached = "cached"
+)
+
+func validStatementStrategy(s string) bool {
+	return s == "" || s == StatementsShared || s == StatementsWorker || s == StatementsCached
+}
+
+// preparesUpFront reports whether the statements are prepared before the workers start.
+func (c *ExtractionConfig) preparesUpFront() bool {
+	return !c.LazyPrepare && (c.StatementStrategy == "" || c.StatementStrategy == StatementsShared)
+}
+
+// maxReprepareRetries is how often a job re-prepares its statement after the objects it
+// uses were recompiled before the failure is reported.
+const maxReprepareRetries = 1
+
+// statementSet holds the prepared statements shared by the workers and replaces one when
+// a recompile of the underlying package or view invalidates it mid-run.
+type statementSet struct {
+	mu      sync.Mutex
+	stmts   map[string]*sql.Stmt
+	retired []*sql.Stmt
+	dbs     map[string]*sql.DB
+	cfg     *ExtractionConfig
+}
+
+func newStatementSet(stmts map[string]*sql.Stmt, dbs map[string]*sql.DB, cfg *ExtractionConfig) *statementSet {
+	return &statementSet{stmts: stmts, dbs: dbs, cfg: cfg}
+}
+
+// forWorker returns the statements a worker runs its jobs with: a set of its own under
+// the worker strategy, otherwise s itself.
+func (s *statementSet) forWorker() *statementSet {
+	if s.cfg.StatementStrategy != StatementsWorker {
+		return s
+	}
+	return newStatementSet(make(map[string]*sql.Stmt), s.dbs, s.cfg)
+}
+
+// get returns the current statement for key, preparing it on proc's connection first
+// when it was left for the first job to prepare. Under the cached strategy every call
+// prepares a statement of its own, to be given back with release.
+func (s *statementSet) get(ctx context.Context, key, proc string) (*sql.Stmt, error) {
+	if !s.keeps() {
+		return s.prepare(ctx, key, proc)
+	}
+	s.mu.Lock()
+	stmt, ok := s.stmts[key]
+	s.mu.Unlock()
+	if ok {
+		return stmt, nil
+	}
+	// Prepared without the lock, so workers needing other statements are not held up by
+	// the round trip; a worker that lost the race closes its copy.
+	stmt, err := s.prepare(ctx, key, proc)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := s.stmts[key]; ok {
+		stmt.Close()
+		return current, nil
+	}
+	s.stmts[key] = stmt
+	return stmt, nil
+}
+
+// keeps reports whether the set keeps the statements it prepares, which it does unless
+// each job prepares its own under the cached strategy.
+func (s *statementSet) keeps() bool {
+	return s.cfg.StatementStrategy != StatementsCached
+}
+
+func (s *statementSet) prepare(ctx context.Context, key, proc string) (*sql.Stmt, error) {
+	stmt, err := s.dbs[s.cfg.ProcedureConnections[proc]].PrepareContext(ctx, s.cfg.statementText[key])
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare statement for %s: %w", key, err)
+	}
+	return stmt, nil
+}
+
+// release gives back a statement returned by get once the job is done with it.
+func (s *statementSet) release(stmt *sql.Stmt) {
+	if stmt != nil && !s.keeps() {
+		stmt.Close()
+	}
+}
+
+// reprepare prepares key again on proc's connection, unless another worker already
+// replaced the failed statement. The old statement stays open until close, since other
+// workers may still be running it. Under the cached strategy there is nothing to
+// replace: the failed statement was already released and the retry prepares its own.
+func (s *statementSet) reprepare(ctx context.Context, key, proc string, failed *sql.Stmt) error {
+	if !s.keeps() {
+		return nil
+	}
+	s.mu.Lock()
+	current := s.stmts[key]
+	s.mu.Unlock()
+	if current != failed {
+		return nil
+	}
+	stmt, err := s.dbs[s.cfg.ProcedureConnections[proc]].PrepareContext(ctx, s.cfg.statementText[key])
+	if err != nil {
+		return fmt.Errorf("failed to re-prepare statement for %s: %w", key, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stmts[key] != failed {
+		stmt.Close()
+		return nil
+	}
+	s.retired = append(s.retired, failed)
+	s.stmts[key] = stmt
+	return nil
+}
+
+// close closes every statement the set has handed out.
+func (s *statementSet) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stmt := range s.stmts {
+		stmt.Close()
+	}
+	for _, stmt := range s.retired {
+		stmt.Close()
+	}
+}
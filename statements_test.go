@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// prepareCounter is a database/sql connector whose connections only prepare statements,
+// counting how many they prepared.
+type prepareCounter struct{ prepared atomic.Int32 }
+
+func (d *prepareCounter) Connect(context.Context) (driver.Conn, error) { return countingConn{d}, nil }
+func (d *prepareCounter) Driver() driver.Driver                        { return nil }
+
+type countingConn struct{ d *prepareCounter }
+
+func (c countingConn) Prepare(string) (driver.Stmt, error) {
+	c.d.prepared.Add(1)
+	return countingStmt{}, nil
+}
+func (c countingConn) Close() error              { return nil }
+func (c countingConn) Begin() (driver.Tx, error) { return nil, errors.ErrUnsupported }
+
+type countingStmt struct{}
+
+func (countingStmt) Close() error                               { return nil }
+func (countingStmt) NumInput() int                              { return -1 }
+func (countingStmt) Exec([]driver.Value) (driver.Result, error) { return nil, errors.ErrUnsupported }
+func (countingStmt) Query([]driver.Value) (driver.Rows, error)  { return nil, errors.ErrUnsupported }
+
+// countingSet returns an empty statement set for strategy on a prepareCounter.
+func countingSet(t *testing.T, strategy string) (*statementSet, *prepareCounter) {
+	counter := &prepareCounter{}
+	db := sql.OpenDB(counter)
+	t.Cleanup(func() { db.Close() })
+	cfg := &ExtractionConfig{StatementStrategy: strategy, statementText: map[string]string{"GAM": "SELECT 1 FROM DUAL"}}
+	return newStatementSet(make(map[string]*sql.Stmt), map[string]*sql.DB{"": db}, cfg), counter
+}
+
+func TestIsStateInvalidated(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("ORA-04068: existing state of packages has been discarded"), true},
+		{fmt.Errorf("extract: %w", errors.New("ORA-04061: existing state of package body \"X.Y\" has been invalidated")), true},
+		{&QueryError{ORACode: "ORA-04065", Err: errors.New("not executed, altered or dropped")}, true},
+		{errors.New("ORA-00942: table or view does not exist"), false},
+		{errors.New("ORA-03113: end-of-file on communication channel"), false},
+	}
+	for _, c := range cases {
+		if got := isStateInvalidated(c.err); got != c.want {
+			t.Errorf("isStateInvalidated(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestStatementSetKeepsReplacedStatement(t *testing.T) {
+	s := newStatementSet(map[string]*sql.Stmt{"P": {}}, nil, &ExtractionConfig{})
+	// Another worker already replaced the statement, so nothing is prepared.
+	if err := s.reprepare(t.Context(), "P", "P", nil); err != nil {
+		t.Fatalf("reprepare of an already replaced statement: %v", err)
+	}
+	if len(s.retired) != 0 {
+		t.Fatalf("retired = %d, want 0", len(s.retired))
+	}
+}
+
+func TestStatementSetForWorker(t *testing.T) {
+	shared := newStatementSet(map[string]*sql.Stmt{"P": {}}, nil, &ExtractionConfig{})
+	if shared.forWorker() != shared {
+		t.Error("shared strategy gave a worker its own statements")
+	}
+	shared.cfg.StatementStrategy = StatementsWorker
+	own := shared.forWorker()
+	if own == shared || len(own.stmts) != 0 {
+		t.Errorf("worker strategy: own set %p of shared %p has %d statements, want an empty set of its own", own, shared, len(own.stmts))
+	}
+	if shared.cfg.preparesUpFront() {
+		t.Error("worker strategy prepared statements up front")
+	}
+}
+
+func TestStatementSetSharesOnePreparedStatement(t *testing.T) {
+	s, _ := countingSet(t, StatementsShared)
+	got := make([]*sql.Stmt, 8)
+	var wg sync.WaitGroup
+	for i := range got {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got[i], _ = s.get(t.Context(), "GAM", "GAM")
+		}()
+	}
+	wg.Wait()
+	for _, stmt := range got {
+		if stmt == nil || stmt != s.stmts["GAM"] {
+			t.Fatalf("concurrent gets returned %v, want the one kept statement %p", got, s.stmts["GAM"])
+		}
+	}
+}
+
+func TestStatementSetCachedPreparesPerJob(t *testing.T) {
+	s, counter := countingSet(t, StatementsCached)
+	a, err := s.get(t.Context(), "GAM", "GAM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := s.get(t.Context(), "GAM", "GAM")
+	if a == b || counter.prepared.Load() != 2 || len(s.stmts) != 0 {
+		t.Errorf("cached strategy: distinct %v, prepared %d, kept %d; want 2 statements of their own, none kept", a != b, counter.prepared.Load(), len(s.stmts))
+	}
+	s.release(a)
+	s.release(b)
+}
+
+func TestStatementSetCachedSkipsReprepare(t *testing.T) {
+	s, counter := countingSet(t, StatementsCached)
+	stmt, err := s.get(t.Context(), "GAM", "GAM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.release(stmt) // the job is done with it before the retry is decided
+	if err := s.reprepare(t.Context(), "GAM", "GAM", stmt); err != nil {
+		t.Fatal(err)
+	}
+	if counter.prepared.Load() != 1 || len(s.stmts) != 0 || len(s.retired) != 0 {
+		t.Errorf("cached reprepare: prepared %d, kept %d, retired %d; want nothing replaced", counter.prepared.Load(), len(s.stmts), len(s.retired))
+	}
+}
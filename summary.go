@@ -0,0 +1,132 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// slowestJobs is how many of the slowest jobs the run summary lists.
+const slowestJobs = 10
+
+// slowJob is one entry of the slowest-jobs report.
+type slowJob struct {
+	Procedure string        `json:"procedure"`
+	SolID     string        `json:"sol_id"`
+	Duration  time.Duration `json:"-"`
+	Seconds   float64       `json:"seconds"`
+	Status    string        `json:"status"`
+}
+
+// summaryAggregator folds finished jobs into per-procedure summaries on a goroutine of
+// its own, so workers hand off a ProcLog instead of contending for a shared map.
+type summaryAggregator struct {
+	events    chan ProcLog
+	done      chan struct{}
+	summary   map[string]ProcSummary
+	durations map[string][]time.Duration
+	slowest   []slowJob
+}
+
+func newSummaryAggregator() *summaryAggregator {
+	a := &summaryAggregator{
+		events:    make(chan ProcLog, 1000),
+		done:      make(chan struct{}),
+		summary:   make(map[string]ProcSummary),
+		durations: make(map[string][]time.Duration),
+	}
+	go a.run()
+	return a
+}
+
+// Add queues a finished job for aggregation.
+func (a *summaryAggregator) Add(plog ProcLog) {
+	a.events <- plog
+}
+
+// Close waits for every queued job to be aggregated and returns the summaries, with
+// their duration percentiles, and the slowest jobs of the run. No job may be added
+// afterwards.
+func (a *summaryAggregator) Close() (map[string]ProcSummary, []slowJob) {
+	close(a.events)
+	<-a.done
+	for proc, d := range a.durations {
+		sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+		s := a.summary[proc]
+		s.P50, s.P95, s.MaxDuration = percentile(d, 0.50), percentile(d, 0.95), d[len(d)-1]
+		a.summary[proc] = s
+	}
+	return a.summary, a.slowest
+}
+
+// percentile returns the nearest-rank q-th percentile of sorted durations.
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	i := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return sorted[max(i, 0)]
+}
+
+// noteSlow keeps plog among the slowest jobs when it qualifies.
+func (a *summaryAggregator) noteSlow(plog ProcLog) {
+	n := len(a.slowest)
+	if n == slowestJobs && plog.ExecutionTime <= a.slowest[n-1].Duration {
+		return
+	}
+	job := slowJob{Procedure: plog.Procedure, SolID: plog.SolID, Duration: plog.ExecutionTime, Seconds: plog.ExecutionTime.Seconds(), Status: plog.Status}
+	i := sort.Search(n, func(i int) bool { return a.slowest[i].Duration < job.Duration })
+	a.slowest = append(a.slowest, slowJob{})
+	copy(a.slowest[i+1:], a.slowest[i:])
+	a.slowest[i] = job
+	if len(a.slowest) > slowestJobs {
+		a.slowest = a.slowest[:slowestJobs]
+	}
+}
+
+func (a *summaryAggregator) run() {
+	defer close(a.done)
+	for plog := range a.events {
+		s, exists := a.summary[plog.Procedure]
+		if !exists {
+			s = ProcSummary{Procedure: plog.Procedure, StartTime: plog.StartTime, EndTime: plog.EndTime, Status: plog.Status, Truncations: make(map[string]int64), Sanitized: make(map[string]int64), FailuresByCode: make(map[string]int64)}
+		} else {
+			if plog.StartTime.Before(s.StartTime) {
+				s.StartTime = plog.StartTime
+			}
+			if plog.EndTime.After(s.EndTime) {
+				s.EndTime = plog.EndTime
+			}
+			if s.Status != "FAIL" && plog.Status == "FAIL" {
+				s.Status = "FAIL"
+			}
+		}
+		s.Rows += plog.Rows
+		s.BadRecords += plog.BadRecords
+		if plog.Status == "FAIL" {
+			code := plog.ErrorCode
+			if code == "" {
+				code = oraCode(plog.ErrorDetails)
+			}
+			s.FailuresByCode[code]++
+		}
+		if plog.Status == "SUCCESS" && len(plog.Profile) > 0 {
+			s.Profile = mergeProfiles(s.Profile, plog.Profile)
+		}
+		for col, n := range plog.Truncations {
+			s.Truncations[col] += n
+		}
+		for col, n := range plog.Sanitized {
+			s.Sanitized[col] += n
+		}
+		a.summary[plog.Procedure] = s
+		a.durations[plog.Procedure] = append(a.durations[plog.Procedure], plog.ExecutionTime)
+		a.noteSlow(plog)
+	}
+}
+
+// logSlowestJobs reports the slowest jobs of the run, the SOLs an average hides.
+func logSlowestJobs(slowest []slowJob) {
+	for i, j := range slowest {
+		log.Info("🐌 Slowest job", "rank", i+1, "procedure", j.Procedure, "sol_id", j.SolID, "duration", j.Duration.Round(time.Millisecond), "status", j.Status)
+	}
+}
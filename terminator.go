@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Record terminators. Any other terminator is given as hex bytes, e.g. "0x15".
+const (
+	TerminatorLF   = "lf"
+	TerminatorCRLF = "crlf"
+	TerminatorNone = "none" // fixed-length records, fixed-width format only
+)
+
+// parseRecordTerminator returns the bytes ending each record; none is empty.
+func parseRecordTerminator(s string) ([]byte, error) {
+	switch strings.ToLower(s) {
+	case "", TerminatorLF:
+		return []byte("\n"), nil
+	case TerminatorCRLF:
+		return []byte("\r\n"), nil
+	case TerminatorNone:
+		return []byte{}, nil
+	}
+	digits, ok := strings.CutPrefix(strings.ToLower(s), "0x")
+	if !ok {
+		return nil, fmt.Errorf("want lf, crlf, none or hex bytes such as 0x15, got %q", s)
+	}
+	term, err := hex.DecodeString(digits)
+	if err != nil || len(term) == 0 {
+		return nil, fmt.Errorf("invalid hex bytes %q", s)
+	}
+	return term, nil
+}
+
+// recordTerminator returns the terminator setting of the named output: its own, its
+// procedure's, then the run default.
+func (c *ExtractionConfig) recordTerminator(name string) string {
+	if t, ok := c.ProcedureRecordTerminators[name]; ok {
+		return t
+	}
+	if t, ok := c.ProcedureRecordTerminators[c.sourceProcedure(name)]; ok {
+		return t
+	}
+	return c.RecordTerminator
+}
+
+// recordEnd returns the bytes ending each record of the output c is resolved for by
+// forOutput. Strict RFC 4180 output always ends records with CRLF.
+func (c *ExtractionConfig) recordEnd() []byte {
+	if c.Format == "delimited" && c.strictCSV(c.output) {
+		return []byte("\r\n")
+	}
+	term, err := parseRecordTerminator(c.RecordTerminator)
+	if err != nil {
+		return []byte("\n") // rejected by validation
+	}
+	return term
+}
+
+// recordEndWriter writes records ending in '\n', as the CSV writer ends them, with the
+// configured terminator instead. Values are sanitised, so every '\n' ends a record.
+type recordEndWriter struct {
+	w    io.Writer
+	term []byte
+}
+
+func (r *recordEndWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			n, err := r.w.Write(p)
+			return written + n, err
+		}
+		if _, err := r.w.Write(p[:i]); err != nil {
+			return written, err
+		}
+		if _, err := r.w.Write(r.term); err != nil {
+			return written, err
+		}
+		written += i + 1
+		p = p[i+1:]
+	}
+	return written, nil
+}
+
+// recordReader returns r with the output's records ending in '\n', the way output
+// readers split them: a BOM is dropped, terminators are translated and fixed-length
+// records are split by the template's record width.
+func (c *ExtractionConfig) recordReader(r io.Reader) io.Reader {
+	src := bufio.NewReader(r)
+	dropBOM(src)
+	term := c.recordEnd()
+	if bytes.Equal(term, []byte("\n")) {
+		return src
+	}
+	return &newlineRecords{src: src, term: term, width: c.recordWidths[c.output]}
+}
+
+// newlineRecords translates records ending in term, or of width characters when term
+// is empty, into records ending in '\n'.
+type newlineRecords struct {
+	src   *bufio.Reader
+	term  []byte
+	width int
+	col   int // characters of the current fixed-length record read so far
+}
+
+func (r *newlineRecords) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		b, err := r.src.ReadByte()
+		if err != nil {
+			return n, err
+		}
+		if len(r.term) == 0 {
+			if r.width == 0 || !utf8.RuneStart(b) {
+				p[n] = b
+				n++
+				continue
+			}
+			if r.col == r.width {
+				r.src.UnreadByte()
+				b, r.col = '\n', -1
+			}
+			r.col++
+		} else if b == r.term[0] {
+			if next, _ := r.src.Peek(len(r.term) - 1); bytes.Equal(next, r.term[1:]) {
+				r.src.Discard(len(r.term) - 1)
+				b = '\n'
+			}
+		}
+		p[n] = b
+		n++
+	}
+	return n, nil
+}
+
+// recordWidth returns the length of a fixed-width record of cols.
+func recordWidth(cols []ColumnConfig) int {
+	width := 0
+	for _, c := range cols {
+		width += c.Length
+	}
+	return width
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRecordTerminator(t *testing.T) {
+	for in, want := range map[string]string{"": "\n", "LF": "\n", "crlf": "\r\n", "none": "", "0x15": "\x15", "0x0D25": "\r%"} {
+		if got, err := parseRecordTerminator(in); err != nil || string(got) != want {
+			t.Errorf("parseRecordTerminator(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"cr", "0x", "0x1"} {
+		if _, err := parseRecordTerminator(in); err == nil {
+			t.Errorf("parseRecordTerminator(%q) accepted", in)
+		}
+	}
+}
+
+func TestCustomTerminatorMerge(t *testing.T) {
+	dir := t.TempDir()
+	cfg := ExtractionConfig{
+		SpoolOutputPath:            dir,
+		Format:                     "delimited",
+		Delimiter:                  "|",
+		RunID:                      "R1",
+		ProcedureRecordTerminators: map[string]string{"GL": "0x15"},
+	}
+	if err := cfg.compileFileNames(); err != nil {
+		t.Fatal(err)
+	}
+	oc := cfg.forOutput("GL")
+	var spool strings.Builder
+	w := csv.NewWriter(&recordEndWriter{w: &spool, term: oc.recordEnd()})
+	w.Comma = '|'
+	w.WriteAll([][]string{{"A1", "1.00"}, {"A2", "2.00"}})
+
+	files := []string{filepath.Join(dir, "1.spool"), filepath.Join(dir, "2.spool")}
+	os.WriteFile(files[0], []byte(spool.String()), 0644)
+	os.WriteFile(files[1], []byte("A3|3.00"), 0644)
+	final, _ := cfg.outputFilePath("GL")
+	if _, err := mergeSpools(&cfg, "GL", files, final); err != nil {
+		t.Fatal(err)
+	}
+	want := "A1|1.00\x15A2|2.00\x15A3|3.00\x15"
+	if got, err := os.ReadFile(final); err != nil || string(got) != want {
+		t.Errorf("merged = %q (%v), want %q", got, err, want)
+	}
+	cols := []ColumnConfig{{Name: "ACID"}, {Name: "AMT"}}
+	if n, err := verifyOutput(oc, final, cols); err != nil || n != 3 {
+		t.Errorf("verifyOutput = %d, %v, want 3 records", n, err)
+	}
+	if out, err := describeOutput(oc, final); err != nil || out.Records != 3 {
+		t.Errorf("describeOutput = %+v, %v, want 3 records", out, err)
+	}
+}
+
+func TestFixedLengthRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "GL.txt")
+	os.WriteFile(path, []byte("0001Zoë0002Ann"), 0644)
+	cols := []ColumnConfig{{Name: "ACID", Length: 4}, {Name: "NAME", Length: 3}}
+	cfg := &ExtractionConfig{Format: "fixed", RecordTerminator: TerminatorNone, recordWidths: map[string]int{"GL": recordWidth(cols)}}
+	oc := cfg.forOutput("GL")
+
+	if n, err := verifyOutput(oc, path, cols); err != nil || n != 2 {
+		t.Errorf("verifyOutput = %d, %v, want 2 records", n, err)
+	}
+	if out, err := describeOutput(oc, path); err != nil || out.Records != 2 {
+		t.Errorf("describeOutput = %+v, %v, want 2 records", out, err)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// jobSpan is one finished job on the run timeline.
+type jobSpan struct {
+	WorkerID int
+	Job      Job
+	Start    time.Time
+	End      time.Time
+	Status   string
+}
+
+// traceEvent is a Chrome trace "complete" event, as understood by chrome://tracing,
+// Perfetto and speedscope.
+type traceEvent struct {
+	Name  string            `json:"name"`
+	Cat   string            `json:"cat"`
+	Phase string            `json:"ph"`
+	TS    int64             `json:"ts"`
+	Dur   int64             `json:"dur"`
+	PID   int               `json:"pid"`
+	TID   int               `json:"tid"`
+	Args  map[string]string `json:"args,omitempty"`
+}
+
+// writeTimeline exports job spans as a Chrome trace file with one track per worker,
+// so worker utilisation and tail-end serialisation can be inspected visually.
+func writeTimeline(path string, runStart time.Time, spans []jobSpan) error {
+	events := make([]traceEvent, 0, len(spans))
+	for _, s := range spans {
+		events = append(events, traceEvent{
+			Name:  s.Job.Proc,
+			Cat:   "job",
+			Phase: "X",
+			TS:    s.Start.Sub(runStart).Microseconds(),
+			Dur:   s.End.Sub(s.Start).Microseconds(),
+			PID:   1,
+			TID:   s.WorkerID,
+			Args:  map[string]string{"sol_id": s.Job.SolID, "status": s.Status},
+		})
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create timeline file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	doc := struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{events, "ms"}
+	if err := json.NewEncoder(f).Encode(doc); err != nil {
+		return fmt.Errorf("failed to write timeline file %s: %w", path, err)
+	}
+	return f.Close()
+}
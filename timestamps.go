@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimestampConfig sets how times are written to logs and summaries and which timezone
+// run IDs and dated file names use.
+type TimestampConfig struct {
+	Format   string `json:"format"`
+	Timezone string `json:"timezone"`
+}
+
+// runIDLayout is the layout of run IDs, in the configured timezone.
+const runIDLayout = "20060102150405"
+
+// timestampFormat formats times for logs, summaries and file names.
+type timestampFormat struct {
+	layout string
+	loc    *time.Location
+}
+
+// timestamps is the format in effect, set from the main config when it is loaded.
+var timestamps = timestampFormat{layout: time.RFC3339, loc: time.Local}
+
+// newTimestampFormat resolves cfg. The format is a Go time layout or one of rfc3339
+// (default), rfc3339nano and legacy (DD-MM-YYYY hh:mm:ss without zone); the timezone is
+// local (default), UTC or an IANA zone name.
+func newTimestampFormat(cfg TimestampConfig) (timestampFormat, error) {
+	t := timestampFormat{layout: cfg.Format, loc: time.Local}
+	switch strings.ToLower(cfg.Format) {
+	case "", "rfc3339":
+		t.layout = time.RFC3339
+	case "rfc3339nano":
+		t.layout = time.RFC3339Nano
+	case "legacy":
+		t.layout = "02-01-2006 15:04:05"
+	}
+	switch strings.ToLower(cfg.Timezone) {
+	case "", "local":
+	default:
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return t, fmt.Errorf("invalid timestamps.timezone %q: %w", cfg.Timezone, err)
+		}
+		t.loc = loc
+	}
+	return t, nil
+}
+
+func (t timestampFormat) format(ts time.Time) string {
+	return ts.In(t.loc).Format(t.layout)
+}
+
+// runID returns the run ID of a run started at start.
+func (t timestampFormat) runID(start time.Time) string {
+	return start.In(t.loc).Format(runIDLayout)
+}
+
+// parseRunID returns the start time encoded in a run ID.
+func (t timestampFormat) parseRunID(id string) (time.Time, error) {
+	return time.ParseInLocation(runIDLayout, id, t.loc)
+}
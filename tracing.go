@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tracer is a minimal OpenTelemetry exporter that batches spans and sends them to an
+// OTLP/HTTP collector using the JSON encoding, so runs show up in Jaeger or Tempo
+// without pulling the full OTel SDK into the binary.
+type tracer struct {
+	mu       sync.Mutex
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+	traceID  string
+	spans    []otlpSpan
+}
+
+// span is an in-progress trace span. A nil *span is a valid no-op span.
+type span struct {
+	t        *tracer
+	id       string
+	parentID string
+	name     string
+	start    time.Time
+	attrs    []otlpKeyValue
+}
+
+type spanKey struct{}
+
+// newTracer returns a tracer exporting to endpoint (e.g. http://tempo:4318), falling back
+// to OTEL_EXPORTER_OTLP_ENDPOINT. It returns nil, disabling tracing, when neither is set.
+func newTracer(endpoint string, headers map[string]string) *tracer {
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if endpoint == "" {
+		return nil
+	}
+	return &tracer{
+		endpoint: strings.TrimRight(endpoint, "/") + "/v1/traces",
+		headers:  headers,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		traceID:  randomHex(16),
+	}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// withTracer makes t the tracer used by startSpan for ctx and its children.
+func withTracer(ctx context.Context, t *tracer) context.Context {
+	if t == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, &span{t: t})
+}
+
+// startSpan starts a child of the span in ctx. attrs are key/value pairs.
+func startSpan(ctx context.Context, name string, attrs ...string) (context.Context, *span) {
+	parent, _ := ctx.Value(spanKey{}).(*span)
+	if parent == nil {
+		return ctx, nil
+	}
+	s := &span{t: parent.t, id: randomHex(8), parentID: parent.id, name: name, start: time.Now()}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		s.attrs = append(s.attrs, otlpString(attrs[i], attrs[i+1]))
+	}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// End finishes the span, recording err as an error status when non-nil.
+func (s *span) End(err error) {
+	if s == nil {
+		return
+	}
+	out := otlpSpan{
+		TraceID:           s.t.traceID,
+		SpanID:            s.id,
+		ParentSpanID:      s.parentID,
+		Name:              s.name,
+		Kind:              1, // SPAN_KIND_INTERNAL
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(time.Now().UnixNano(), 10),
+		Attributes:        s.attrs,
+	}
+	if err != nil {
+		out.Status = &otlpStatus{Code: 2, Message: err.Error()} // STATUS_CODE_ERROR
+	}
+
+	s.t.mu.Lock()
+	s.t.spans = append(s.t.spans, out)
+	s.t.mu.Unlock()
+}
+
+// Flush exports every finished span in one OTLP request.
+func (t *tracer) Flush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	payload := map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{"attributes": []otlpKeyValue{otlpString("service.name", "gemini_extract")}},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]string{"name": "gemini_extract"},
+				"spans": spans,
+			}},
+		}},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build OTLP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans to %s: %w", t.endpoint, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("OTLP collector %s returned %s", t.endpoint, resp.Status)
+	}
+	return nil
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func otlpString(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: map[string]string{"stringValue": value}}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTracerExportsParentedSpans(t *testing.T) {
+	var got struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []otlpSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("path = %s, want /v1/traces", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	tr := newTracer(srv.URL, nil)
+	ctx, root := startSpan(withTracer(context.Background(), tr), "run")
+	_, job := startSpan(ctx, "job", "procedure", "GAM")
+	job.End(errors.New("ORA-00942"))
+	root.End(nil)
+	if err := tr.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if spans[0].ParentSpanID != spans[1].SpanID {
+		t.Errorf("job span parent = %s, want run span %s", spans[0].ParentSpanID, spans[1].SpanID)
+	}
+	if spans[0].Status == nil || spans[0].Status.Code != 2 {
+		t.Errorf("job span status = %+v, want error", spans[0].Status)
+	}
+}
+
+func TestTracerDisabledIsNoop(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	tr := newTracer("", nil)
+	_, s := startSpan(withTracer(context.Background(), tr), "run")
+	s.End(nil)
+	if err := tr.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// Trigger file formats.
+const (
+	TriggerText = "text"
+	TriggerJSON = "json"
+)
+
+// TriggerFileConfig writes a companion marker next to every finalised output file, for
+// downstream jobs that poll for one before picking the file up.
+type TriggerFileConfig struct {
+	Enabled bool `json:"enabled"`
+	// Suffix is appended to the output file name (default .done).
+	Suffix string `json:"suffix"`
+	// Format is text (key=value lines, default) or json; Template, a Go template over the
+	// output file's fields, overrides it.
+	Format   string `json:"format"`
+	Template string `json:"template"`
+}
+
+func validTriggerFormat(f string) bool {
+	return f == "" || f == TriggerText || f == TriggerJSON
+}
+
+// triggerData is what a trigger file describes.
+type triggerData struct {
+	File     string    `json:"file"`
+	Path     string    `json:"path"`
+	Records  int64     `json:"records"`
+	Bytes    int64     `json:"bytes"`
+	SHA256   string    `json:"sha256"`
+	RunID    string    `json:"run_id"`
+	Business string    `json:"business_date"`
+	Time     time.Time `json:"time"`
+}
+
+// compileTrigger parses the trigger file template, if any.
+func (c *ExtractionConfig) compileTrigger() (err error) {
+	if c.TriggerFile.Template == "" {
+		return nil
+	}
+	c.triggerTmpl, err = template.New("trigger").Option("missingkey=error").Parse(c.TriggerFile.Template)
+	if err != nil {
+		return fmt.Errorf("invalid trigger_file.template: %w", err)
+	}
+	return nil
+}
+
+// triggerFilePath returns the trigger file of the output file at path.
+func (c *ExtractionConfig) triggerFilePath(path string) string {
+	if c.TriggerFile.Suffix == "" {
+		return path + ".done"
+	}
+	return path + c.TriggerFile.Suffix
+}
+
+// writeTriggerFile writes the marker for a finalised output file. It is written under a
+// temporary name and renamed, so a poller never sees it half written.
+func writeTriggerFile(cfg *ExtractionConfig, out *OutputFile) (string, error) {
+	path := cfg.triggerFilePath(out.Path)
+	data := triggerData{
+		File: filepath.Base(out.Path), Path: out.Path, Records: out.Records, Bytes: out.Bytes, SHA256: out.SHA256,
+		RunID: cfg.RunID, Business: cfg.BusinessDate.Format(businessDateLayout), Time: time.Now(),
+	}
+
+	var buf bytes.Buffer
+	switch {
+	case cfg.triggerTmpl != nil:
+		if err := cfg.triggerTmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("failed to render trigger file for %s: %w", out.Path, err)
+		}
+	case cfg.TriggerFile.Format == TriggerJSON:
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		enc.Encode(data)
+	default:
+		fmt.Fprintf(&buf, "file=%s\nrecords=%d\nbytes=%d\nsha256=%s\nrun_id=%s\nbusiness_date=%s\n",
+			data.File, data.Records, data.Bytes, data.SHA256, data.RunID, data.Business)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("failed to write trigger file %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to write trigger file %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// publishOutput hands a finalised output file to downstream systems: its trigger file,
+// the cloud uploads and the file_ready webhook, as configured.
+func publishOutput(ctx context.Context, cfg *ExtractionConfig, hooks *webhookNotifier, proc string, out *OutputFile) error {
+	if out.UnchangedSince != "" && cfg.UnchangedOutputPolicy == UnchangedSkip {
+		return nil
+	}
+	files := []string{out.Path}
+	if cfg.TriggerFile.Enabled {
+		path, err := writeTriggerFile(cfg, out)
+		if err != nil {
+			return err
+		}
+		log.Info("🏁 Trigger file written", "procedure", proc, "file", path)
+		files = append(files, path)
+	}
+	// A linked output is already downstream from the run it is linked to.
+	var uploaded []string
+	if out.UnchangedSince == "" {
+		var err error
+		if uploaded, err = uploadOutputs(ctx, cfg, files...); err != nil {
+			return err
+		}
+	}
+	hooks.FileReady(proc, out, uploaded)
+	return nil
+}
+
+// publishes reports whether finalised outputs are handed to anything downstream.
+func (c *ExtractionConfig) publishes(hooks *webhookNotifier) bool {
+	return c.TriggerFile.Enabled || len(c.Uploads) > 0 || hooks != nil
+}
+
+// publishMerged describes the merged output, or partitioned files, of proc and its views
+// and publishes them.
+func publishMerged(ctx context.Context, cfg *ExtractionConfig, hooks *webhookNotifier, proc string) error {
+	for _, name := range cfg.outputNames(proc) {
+		outs, err := describeParts(cfg, name)
+		if err != nil {
+			return err
+		}
+		for _, out := range outs {
+			if !cfg.partitioned() {
+				cfg.dedupeOutput(name, out)
+			}
+			if err := publishOutput(ctx, cfg, hooks, name, out); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
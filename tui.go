@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	log "github.com/charmbracelet/log"
+)
+
+const (
+	// dashboardRefresh is how often the dashboard redraws from the progress counters.
+	dashboardRefresh = time.Second
+	// dashboardLogLines is how many of the latest log lines the dashboard shows.
+	dashboardLogLines = 6
+	// dashboardBarWidth is the width of the progress bars.
+	dashboardBarWidth = 24
+	// dashboardFailureLines is how many recent failures are visible at once; the rest
+	// are reached by scrolling.
+	dashboardFailureLines = 5
+)
+
+var (
+	titleStyle = lipgloss.NewStyle().Bold(true)
+	dimStyle   = lipgloss.NewStyle().Faint(true)
+	failStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	doneStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+)
+
+// stderrIsTerminal reports whether stderr is attached to a terminal.
+func stderrIsTerminal() bool {
+	info, err := os.Stderr.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// logPane keeps the latest log lines for the dashboard while it owns the terminal.
+type logPane struct {
+	mu    sync.Mutex
+	lines []string
+	max   int
+}
+
+func (l *logPane) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		l.lines = append(l.lines, line)
+	}
+	if len(l.lines) > l.max {
+		l.lines = l.lines[len(l.lines)-l.max:]
+	}
+	return len(p), nil
+}
+
+func (l *logPane) Lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.lines...)
+}
+
+type dashboardTick time.Time
+
+func dashboardTicker() tea.Cmd {
+	return tea.Tick(dashboardRefresh, func(t time.Time) tea.Msg { return dashboardTick(t) })
+}
+
+// dashboard is the bubbletea model of the live run view: overall progress, a row per
+// procedure, the slowest in-flight jobs and a scrolling pane of recent failures. Its keys
+// pause, resume and cancel the dispatching of new jobs; in-flight jobs always finish.
+type dashboard struct {
+	progress *progressTracker
+	control  *dispatchControl
+	logs     *logPane
+	status   runStatus
+	failures []failedJob
+	// scroll is how many failures the pane is scrolled back from the newest.
+	scroll int
+	width  int
+	// forceExit is set by a second Ctrl+C, which exits at once like a second interrupt.
+	forceExit bool
+}
+
+func newDashboard(progress *progressTracker, control *dispatchControl, logs *logPane) dashboard {
+	return dashboard{progress: progress, control: control, logs: logs, status: progress.Status(), failures: progress.RecentFailures()}
+}
+
+func (m dashboard) Init() tea.Cmd {
+	return dashboardTicker()
+}
+
+func (m dashboard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case dashboardTick:
+		m.status = m.progress.Status()
+		m.failures = m.progress.RecentFailures()
+		m.scroll = min(m.scroll, m.maxScroll())
+		return m, dashboardTicker()
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "p":
+			m.control.Pause()
+		case "r":
+			m.control.Resume()
+		case "up", "k":
+			m.scroll = min(m.scroll+1, m.maxScroll())
+		case "down", "j":
+			m.scroll = max(m.scroll-1, 0)
+		case "c", "ctrl+c":
+			// The terminal is in raw mode, so Ctrl+C arrives as a key rather than an interrupt.
+			if m.control.Cancelled() {
+				if msg.String() == "ctrl+c" {
+					m.forceExit = true
+					return m, tea.Quit
+				}
+				break
+			}
+			m.control.Cancel()
+		}
+	}
+	return m, nil
+}
+
+// maxScroll is how far back the failure pane can scroll.
+func (m dashboard) maxScroll() int {
+	return max(len(m.failures)-dashboardFailureLines, 0)
+}
+
+// bar draws a progress bar of done out of total.
+func bar(done, total, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = done * width / total
+	}
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+func (m dashboard) state() string {
+	switch {
+	case m.control.Cancelled():
+		return failStyle.Render("CANCELLING")
+	case m.control.Paused():
+		return "PAUSED"
+	}
+	return doneStyle.Render("RUNNING")
+}
+
+// help lists the keys that apply in the current state.
+func (m dashboard) help() string {
+	switch {
+	case m.control.Cancelled():
+		return "waiting for in-flight jobs • ↑/↓ scroll failures • ctrl+c exit now"
+	case m.control.Paused():
+		return "r resume dispatching • c cancel run • ↑/↓ scroll failures"
+	}
+	return "p pause dispatching • c cancel run • ↑/↓ scroll failures"
+}
+
+func (m dashboard) View() string {
+	s := m.status
+	var b strings.Builder
+	pct := 0
+	if s.Total > 0 {
+		pct = s.Completed * 100 / s.Total
+	}
+	fmt.Fprintf(&b, "%s  %s\n", titleStyle.Render("gemini_extract"), m.state())
+	fmt.Fprintf(&b, "%s %3d%%  %d/%d jobs  %s  %d running  %d rows/s  elapsed %s  eta %s\n\n",
+		bar(s.Completed, s.Total, dashboardBarWidth), pct, s.Completed, s.Total,
+		failStyle.Render(fmt.Sprintf("%d failed", s.Failed)), s.Running, int64(s.RowsPerSec),
+		(time.Duration(s.ElapsedSeconds) * time.Second).Round(time.Second), (time.Duration(s.ETASeconds) * time.Second).Round(time.Second))
+
+	// Unfinished procedures first, most outstanding at the top, so the one lagging
+	// behind is visible rather than hidden in the overall percentage.
+	procs := append([]procedureStatus(nil), s.Procedures...)
+	sort.SliceStable(procs, func(i, j int) bool {
+		ri, rj := procs[i].Total-procs[i].Completed, procs[j].Total-procs[j].Completed
+		if (ri == 0) != (rj == 0) {
+			return ri != 0
+		}
+		return ri > rj
+	})
+	nameWidth := len("PROCEDURE")
+	for _, p := range procs {
+		nameWidth = max(nameWidth, len(p.Procedure))
+	}
+	fmt.Fprintln(&b, titleStyle.Render(fmt.Sprintf("%-*s  %-*s  %11s  %6s  %9s", nameWidth, "PROCEDURE", dashboardBarWidth, "PROGRESS", "SOLS", "FAILED", "ROWS/S")))
+	for _, p := range procs {
+		var rowsPerSec int64
+		if s.ElapsedSeconds > 0 {
+			rowsPerSec = int64(float64(p.Rows) / s.ElapsedSeconds)
+		}
+		failed := fmt.Sprintf("%6d", p.Failed)
+		if p.Failed > 0 {
+			failed = failStyle.Render(failed)
+		}
+		line := fmt.Sprintf("%-*s  %s  %11s  %s  %9d", nameWidth, p.Procedure, bar(p.Completed, p.Total, dashboardBarWidth),
+			fmt.Sprintf("%d/%d", p.Completed, p.Total), failed, rowsPerSec)
+		if p.Completed == p.Total {
+			line = dimStyle.Render(line)
+		}
+		fmt.Fprintln(&b, line)
+	}
+
+	if len(s.SlowestJobs) > 0 {
+		fmt.Fprintln(&b, "\n"+titleStyle.Render("Slowest in-flight jobs"))
+		for _, j := range s.SlowestJobs {
+			fmt.Fprintf(&b, "  worker %-3d %-*s  %-12s %s\n", j.Worker, nameWidth, j.Procedure, j.SolID,
+				(time.Duration(j.ElapsedSeconds) * time.Second).Round(time.Second))
+		}
+	}
+
+	if len(m.failures) > 0 {
+		end := len(m.failures) - m.scroll
+		start := max(end-dashboardFailureLines, 0)
+		fmt.Fprintln(&b, "\n"+titleStyle.Render(fmt.Sprintf("Recent failures (%d-%d of %d)", start+1, end, len(m.failures))))
+		for _, f := range m.failures[start:end] {
+			code := f.ORACode
+			if code == "OTHER" {
+				code = ""
+			}
+			line := fmt.Sprintf("  %s %-*s  %-12s %-9s %s", f.Time.Format("15:04:05"), nameWidth, f.Job.Proc, f.Job.SolID, code,
+				strings.Join(strings.Fields(f.Message), " "))
+			fmt.Fprintln(&b, failStyle.Render(truncateLine(line, m.width)))
+		}
+	}
+
+	if lines := m.logs.Lines(); len(lines) > 0 {
+		fmt.Fprintln(&b, "\n"+titleStyle.Render("Log"))
+		for _, line := range lines {
+			fmt.Fprintln(&b, dimStyle.Render(truncateLine(line, m.width)))
+		}
+	}
+	fmt.Fprint(&b, "\n"+dimStyle.Render(m.help()))
+	return b.String()
+}
+
+// truncateLine cuts line to width columns, when the terminal width is known.
+func truncateLine(line string, width int) string {
+	if width <= 0 || lipgloss.Width(line) <= width {
+		return line
+	}
+	r := []rune(line)
+	for len(r) > 0 && lipgloss.Width(string(r)) > width-1 {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "…"
+}
+
+// dashboardUI runs the dashboard on the terminal for the duration of the dispatch.
+type dashboardUI struct {
+	program *tea.Program
+	done    chan struct{}
+}
+
+// startDashboard takes over the terminal with the live run view. Log output goes to the
+// dashboard's log pane until Stop gives the terminal back.
+func startDashboard(progress *progressTracker, control *dispatchControl) *dashboardUI {
+	logs := &logPane{max: dashboardLogLines}
+	u := &dashboardUI{
+		program: tea.NewProgram(newDashboard(progress, control, logs), tea.WithOutput(os.Stderr), tea.WithAltScreen()),
+		done:    make(chan struct{}),
+	}
+	setConsole(logs)
+	go func() {
+		defer close(u.done)
+		final, err := u.program.Run()
+		setConsole(os.Stderr)
+		if err != nil {
+			log.Error("Dashboard failed, continuing without it", "error", err)
+			return
+		}
+		if m, ok := final.(dashboard); ok && m.forceExit {
+			log.Error("Received second interrupt, exiting immediately")
+			os.Exit(130)
+		}
+	}()
+	return u
+}
+
+// Stop closes the dashboard and gives the terminal back to the log.
+func (u *dashboardUI) Stop() {
+	if u == nil {
+		return
+	}
+	u.program.Quit()
+	<-u.done
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestDashboardShowsLaggingProcedureFirst(t *testing.T) {
+	jobs := []Job{{Proc: "GAM", SolID: "1"}, {Proc: "GAM", SolID: "2"}, {Proc: "HTD", SolID: "1"}, {Proc: "HTD", SolID: "2"}}
+	progress := newProgressTracker(jobs, 2)
+	progress.Begin(1, Job{Proc: "HTD", SolID: "2"}, nil)
+	progress.Finish(2, ProcLog{Procedure: "GAM", SolID: "1", Status: "SUCCESS", Rows: 10, ExecutionTime: time.Second})
+	progress.Finish(2, ProcLog{Procedure: "GAM", SolID: "2", Status: "SUCCESS", Rows: 10, ExecutionTime: time.Second})
+
+	m := newDashboard(progress, newDispatchControl(), &logPane{max: dashboardLogLines})
+	next, _ := m.Update(dashboardTick(time.Now()))
+	view := next.View()
+	htd, gam := strings.Index(view, "HTD "), strings.Index(view, "GAM ")
+	if htd < 0 || gam < 0 || htd > gam {
+		t.Errorf("expected the unfinished HTD above the finished GAM:\n%s", view)
+	}
+	if !strings.Contains(view, "Slowest in-flight jobs") || !strings.Contains(view, "2/4 jobs") {
+		t.Errorf("missing overall progress or in-flight jobs:\n%s", view)
+	}
+}
+
+func TestDashboardCtrlCCancelsThenExits(t *testing.T) {
+	control := newDispatchControl()
+	m := newDashboard(newProgressTracker(nil, 1), control, &logPane{max: 1})
+	ctrlC := tea.KeyMsg{Type: tea.KeyCtrlC}
+	next, cmd := m.Update(ctrlC)
+	if !control.Cancelled() || cmd != nil {
+		t.Fatal("first Ctrl+C should cancel the run and keep the dashboard")
+	}
+	next, cmd = next.Update(ctrlC)
+	if !next.(dashboard).forceExit || cmd == nil {
+		t.Error("second Ctrl+C should exit at once")
+	}
+}
+
+func TestLogPaneKeepsLatestLines(t *testing.T) {
+	p := &logPane{max: 2}
+	p.Write([]byte("one\ntwo\n"))
+	p.Write([]byte("three\n"))
+	if got := strings.Join(p.Lines(), ","); got != "two,three" {
+		t.Errorf("lines = %s, want two,three", got)
+	}
+}
+
+func TestDashboardKeysControlDispatch(t *testing.T) {
+	control := newDispatchControl()
+	var m tea.Model = newDashboard(newProgressTracker(nil, 1), control, &logPane{max: 1})
+	key := func(s string) { m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}) }
+
+	key("p")
+	if !control.Paused() || !strings.Contains(m.View(), "r resume") {
+		t.Fatal("p should pause dispatching")
+	}
+	key("r")
+	if control.Paused() {
+		t.Fatal("r should resume dispatching")
+	}
+	key("c")
+	if !control.Cancelled() || m.(dashboard).forceExit {
+		t.Fatal("c should cancel gracefully")
+	}
+	key("c")
+	if m.(dashboard).forceExit {
+		t.Error("repeating c must not force an exit")
+	}
+}
+
+func TestDashboardScrollsRecentFailures(t *testing.T) {
+	progress := newProgressTracker(nil, 1)
+	for i := 1; i <= 7; i++ {
+		progress.Finish(1, ProcLog{Procedure: "GAM", SolID: fmt.Sprintf("S%d", i), Status: "FAIL", ErrorDetails: "ORA-01555: snapshot too old"})
+	}
+	var m tea.Model = newDashboard(progress, newDispatchControl(), &logPane{max: 1})
+	view := m.View()
+	if !strings.Contains(view, "Recent failures (3-7 of 7)") || !strings.Contains(view, "ORA-01555") || strings.Contains(view, "S2 ") {
+		t.Fatalf("expected the newest five failures:\n%s", view)
+	}
+	for range 5 {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	}
+	if view := m.View(); !strings.Contains(view, "(1-5 of 7)") || !strings.Contains(view, "S1 ") {
+		t.Errorf("scrolling up should stop at the oldest failure:\n%s", view)
+	}
+}
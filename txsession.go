@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	log "github.com/charmbracelet/log"
+)
+
+// Rollback policies for insertion calls that fail inside a batched transaction.
+const (
+	RollbackCall  = "call"
+	RollbackBatch = "batch"
+)
+
+func validRollbackPolicy(p string) bool {
+	return p == "" || p == RollbackCall || p == RollbackBatch
+}
+
+// insertSession runs one worker's insertion calls in transactions that are committed
+// every interval calls per connection, rather than each call committing on its own.
+// Under the call policy a failed call is rolled back to a savepoint taken before it;
+// under the batch policy the whole uncommitted batch is rolled back with it.
+type insertSession struct {
+	dbs      map[string]*sql.DB
+	interval int
+	policy   string
+	txs      map[string]*sql.Tx
+	pending  map[string][]Job
+}
+
+// newInsertSession returns nil when calls are to commit on their own.
+func newInsertSession(dbs map[string]*sql.DB, cfg *ExtractionConfig) *insertSession {
+	if cfg.CommitInterval <= 0 {
+		return nil
+	}
+	return &insertSession{
+		dbs:      dbs,
+		interval: cfg.CommitInterval,
+		policy:   cfg.RollbackPolicy,
+		txs:      make(map[string]*sql.Tx),
+		pending:  make(map[string][]Job),
+	}
+}
+
+// call runs stmt for job in the transaction of connection conn. It returns the jobs
+// whose work was committed by this call, and those whose uncommitted work was rolled
+// back because of its failure.
+func (s *insertSession) call(ctx context.Context, conn string, stmt *sql.Stmt, job Job, args []any) (committed, rolledBack []Job, err error) {
+	tx := s.txs[conn]
+	if tx == nil {
+		// The transaction outlives the job, so it must not use the job's context.
+		if tx, err = s.dbs[conn].BeginTx(context.Background(), nil); err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		s.txs[conn] = tx
+	}
+	if s.policy != RollbackBatch {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT before_call"); err != nil {
+			return nil, s.rollback(conn), fmt.Errorf("failed to set savepoint: %w", err)
+		}
+	}
+	if _, err := tx.StmtContext(ctx, stmt).ExecContext(ctx, args...); err != nil {
+		err = newQueryError(job.Proc, fmt.Errorf("prepared statement execution failed: %w", err))
+		if s.policy == RollbackBatch || isConnectionLost(err) {
+			return nil, s.rollback(conn), err
+		}
+		if _, rerr := tx.ExecContext(context.Background(), "ROLLBACK TO SAVEPOINT before_call"); rerr != nil {
+			return nil, s.rollback(conn), err
+		}
+		return nil, nil, err
+	}
+	s.pending[conn] = append(s.pending[conn], job)
+	if len(s.pending[conn]) < s.interval {
+		return nil, nil, nil
+	}
+	return s.commit(conn)
+}
+
+// commit commits conn's transaction and returns its jobs, or on failure returns them
+// as rolled back.
+func (s *insertSession) commit(conn string) (committed, rolledBack []Job, err error) {
+	tx, jobs := s.txs[conn], s.pending[conn]
+	delete(s.txs, conn)
+	delete(s.pending, conn)
+	if tx == nil {
+		return nil, nil, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, jobs, fmt.Errorf("commit failed: %w", err)
+	}
+	return jobs, nil, nil
+}
+
+// rollback abandons conn's transaction and returns the jobs whose work it undid.
+func (s *insertSession) rollback(conn string) []Job {
+	tx, jobs := s.txs[conn], s.pending[conn]
+	delete(s.txs, conn)
+	delete(s.pending, conn)
+	if tx != nil {
+		if err := tx.Rollback(); err != nil {
+			log.Warn("Rollback failed", "error", err)
+		}
+	}
+	return jobs
+}
+
+// finish commits every open transaction once the worker has no more jobs.
+func (s *insertSession) finish() (committed, rolledBack []Job, err error) {
+	for conn := range s.txs {
+		c, r, cerr := s.commit(conn)
+		committed, rolledBack = append(committed, c...), append(rolledBack, r...)
+		if cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return committed, rolledBack, err
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// txRecorder is a database/sql connector that records the statements, commits and
+// rollbacks run on its connections. Calls with the argument "fail" fail.
+type txRecorder struct {
+	mu  sync.Mutex
+	log []string
+}
+
+func (r *txRecorder) record(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log = append(r.log, fmt.Sprintf(format, args...))
+}
+
+func (r *txRecorder) Connect(context.Context) (driver.Conn, error) { return recordingConn{r}, nil }
+func (r *txRecorder) Driver() driver.Driver                        { return nil }
+
+type recordingConn struct{ r *txRecorder }
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return recordingStmt{c.r, query}, nil
+}
+func (c recordingConn) Close() error { return nil }
+func (c recordingConn) Begin() (driver.Tx, error) {
+	c.r.record("BEGIN")
+	return recordingTx{c.r}, nil
+}
+
+type recordingTx struct{ r *txRecorder }
+
+func (t recordingTx) Commit() error   { t.r.record("COMMIT"); return nil }
+func (t recordingTx) Rollback() error { t.r.record("ROLLBACK"); return nil }
+
+type recordingStmt struct {
+	r     *txRecorder
+	query string
+}
+
+func (s recordingStmt) Close() error  { return nil }
+func (s recordingStmt) NumInput() int { return -1 }
+func (s recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if len(args) > 0 {
+		s.r.record("%s %v", s.query, args[0])
+		if args[0] == "fail" {
+			return nil, errors.New("ORA-00001: unique constraint violated")
+		}
+	} else {
+		s.r.record("%s", s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+func (s recordingStmt) Query([]driver.Value) (driver.Rows, error) { return nil, errors.ErrUnsupported }
+
+func TestInsertSessionBatchesCommits(t *testing.T) {
+	for _, tc := range []struct {
+		policy                string
+		committed, rolledBack []string
+		log                   string
+	}{
+		// A failed call is undone alone; the batch commits once it holds two calls.
+		{RollbackCall, []string{"0001", "0003", "0004"}, nil,
+			"BEGIN|SAVEPOINT before_call|CALL 0001|SAVEPOINT before_call|CALL fail|ROLLBACK TO SAVEPOINT before_call|SAVEPOINT before_call|CALL 0003|COMMIT|BEGIN|SAVEPOINT before_call|CALL 0004|COMMIT"},
+		// A failed call undoes the batch it was in.
+		{RollbackBatch, []string{"0003", "0004"}, []string{"0001"},
+			"BEGIN|CALL 0001|CALL fail|ROLLBACK|BEGIN|CALL 0003|CALL 0004|COMMIT"},
+	} {
+		rec := &txRecorder{}
+		db := sql.OpenDB(rec)
+		stmt, err := db.Prepare("CALL")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := newInsertSession(map[string]*sql.DB{"": db}, &ExtractionConfig{CommitInterval: 2, RollbackPolicy: tc.policy})
+
+		var committed, rolledBack []string
+		for _, sol := range []string{"0001", "fail", "0003", "0004"} {
+			c, r, err := s.call(context.Background(), "", stmt, Job{SolID: sol, Proc: "GAM"}, []any{sol})
+			if (err != nil) != (sol == "fail") {
+				t.Errorf("%s: call for %s: %v", tc.policy, sol, err)
+			}
+			for _, job := range c {
+				committed = append(committed, job.SolID)
+			}
+			for _, job := range r {
+				rolledBack = append(rolledBack, job.SolID)
+			}
+		}
+		c, _, err := s.finish()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, job := range c {
+			committed = append(committed, job.SolID)
+		}
+		db.Close()
+
+		if !slices.Equal(committed, tc.committed) {
+			t.Errorf("%s: committed %v, want %v", tc.policy, committed, tc.committed)
+		}
+		if !slices.Equal(rolledBack, tc.rolledBack) {
+			t.Errorf("%s: rolled back %v, want %v", tc.policy, rolledBack, tc.rolledBack)
+		}
+		if got := strings.Join(rec.log, "|"); got != tc.log {
+			t.Errorf("%s: statements\n%s\nwant\n%s", tc.policy, got, tc.log)
+		}
+	}
+
+	if newInsertSession(nil, &ExtractionConfig{}) != nil {
+		t.Error("session without a commit interval")
+	}
+}
@@ -10,17 +10,76 @@ type ProcLog struct {
 	ExecutionTime time.Duration
 	Status        string
 	ErrorDetails  string
+	ErrorCode     string // ORA- code or failure class, see errorCode
+	Rows          int64
+	BadRecords    int64
+	Truncations   map[string]int64
+	Sanitized     map[string]int64 // values changed by sanitisation, per column
+	Profile       []ColumnProfile  // column stats of the job's rows, when enabled
+}
+
+// JobStats carries per-job counters from extraction back to the worker.
+type JobStats struct {
+	Rows        int64
+	BadRecords  int64
+	Truncations map[string]int64
+	Sanitized   map[string]int64
+	Profile     []ColumnProfile // set when column stats are enabled
 }
 
 type ColumnConfig struct {
-	Name   string
-	Length int
-	Align  string
+	Name         string
+	Length       int
+	Align        string
+	LengthPolicy string
+	// COBOL-style numbers in fixed-width output, see formatNumeric: implied decimal
+	// places, leading zero fill and the sign convention.
+	Scale    int
+	ZeroFill bool
+	Sign     string
+	Sanitize string // sanitisation rules, overriding the run's
+	// Optional columns missing from the source are written as Default, see selectList.
+	Optional bool
+	Default  string
+}
+
+// Length policies for values that do not fit a fixed-width column.
+const (
+	LengthPolicyTruncate = "truncate"
+	LengthPolicyWarn     = "warn"
+	LengthPolicyFail     = "fail"
+)
+
+func validLengthPolicy(p string) bool {
+	return p == "" || p == LengthPolicyTruncate || p == LengthPolicyWarn || p == LengthPolicyFail
+}
+
+// effectiveLengthPolicy returns the column's own policy, falling back to the run default.
+func (c ColumnConfig) effectiveLengthPolicy(defaultPolicy string) string {
+	if c.LengthPolicy != "" {
+		return c.LengthPolicy
+	}
+	if defaultPolicy != "" {
+		return defaultPolicy
+	}
+	return LengthPolicyTruncate
 }
 
 type ProcSummary struct {
-	Procedure string
-	StartTime time.Time
-	EndTime   time.Time
-	Status    string
+	Procedure      string
+	StartTime      time.Time
+	EndTime        time.Time
+	Status         string
+	Rows           int64
+	P50            time.Duration // job duration percentiles
+	P95            time.Duration
+	MaxDuration    time.Duration
+	BadRecords     int64
+	Truncations    map[string]int64
+	Sanitized      map[string]int64
+	FailuresByCode map[string]int64
+	Output         *OutputFile   // final output file, extraction only
+	Parts          []*OutputFile // per-SOL or per-group final files instead of Output
+	MergeGaps      []string      // quarantined spool files missing from the output
+	Profile        []ColumnProfile
 }
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/godror/godror"
+)
+
+// driverParamsSet are the connection parameters built from dedicated settings, which
+// driver_params must not repeat.
+var driverParamsSet = map[string]bool{
+	"user": true, "password": true, "connectString": true, "configDir": true,
+	"stmtCacheSize": true, "initOnNewConnection": true, "onInit": true,
+}
+
+// configErrors accumulates validation problems so they can all be reported at once.
+type configErrors []error
+
+func (e *configErrors) add(field, format string, args ...any) {
+	*e = append(*e, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+}
+
+// validateConfigs checks every field of both configurations for the given mode and
+// reports all problems together, instead of stopping at the first one mid-run.
+func validateConfigs(appCfg *MainConfig, runCfg *ExtractionConfig, mode string) error {
+	var errs configErrors
+
+	if appCfg.DBUser == "" {
+		errs.add("db_user", "must be set")
+	}
+	if appCfg.ProxyUser != "" && strings.ContainsAny(appCfg.DBUser+appCfg.ProxyUser, "[]") {
+		errs.add("proxy_user", "set the proxy user here or as db_user[schema], not both")
+	}
+	if appCfg.ConsumerGroup != "" && !plainIdentifier.MatchString(appCfg.ConsumerGroup) {
+		errs.add("consumer_group", "%q is not a valid consumer group name", appCfg.ConsumerGroup)
+	}
+	if appCfg.ConnectString == "" {
+		if appCfg.DBHost == "" {
+			errs.add("db_host", "must be set unless connect_string is used")
+		}
+		if appCfg.DBPort < 1 || appCfg.DBPort > 65535 {
+			errs.add("db_port", "%d is outside the valid range 1-65535", appCfg.DBPort)
+		}
+		if appCfg.DBSid == "" {
+			errs.add("db_sid", "must be set unless connect_string is used")
+		}
+	} else if appCfg.DBHost != "" || appCfg.DBSid != "" {
+		errs.add("connect_string", "cannot be combined with db_host/db_sid")
+	}
+	if appCfg.TNSAdmin != "" {
+		if info, err := os.Stat(appCfg.TNSAdmin); err != nil {
+			errs.add("tns_admin", "%v", err)
+		} else if !info.IsDir() {
+			errs.add("tns_admin", "%s is not a directory", appCfg.TNSAdmin)
+		}
+	}
+	if appCfg.JobRetries < 0 {
+		errs.add("job_retries", "must not be negative, got %d", appCfg.JobRetries)
+	}
+	if appCfg.ConnectionWaitWarnSeconds < 0 {
+		errs.add("connection_wait_warn_seconds", "must not be negative, got %d", appCfg.ConnectionWaitWarnSeconds)
+	}
+	if appCfg.StatementCacheSize < -1 {
+		errs.add("statement_cache_size", "must be -1 (disabled), 0 (default) or positive, got %d", appCfg.StatementCacheSize)
+	}
+	badParams := 0
+	for name, value := range appCfg.DriverParams {
+		badParams++
+		switch {
+		case driverParamsSet[name]:
+			errs.add("driver_params", "%s is set from the other settings and cannot be passed through", name)
+		case !plainIdentifier.MatchString(name):
+			errs.add("driver_params", "%q is not a valid parameter name", name)
+		case strings.ContainsAny(value, `"\`):
+			errs.add("driver_params", "value of %s must not contain quotes or backslashes", name)
+		default:
+			badParams--
+		}
+	}
+	if len(appCfg.DriverParams) > 0 && badParams == 0 {
+		if _, err := godror.ParseDSN(appCfg.dataSourceName(appCfg.connectString())); err != nil {
+			errs.add("driver_params", "%v", err)
+		}
+	}
+	if appCfg.OpenCursorsWarnPercent < 0 || appCfg.OpenCursorsWarnPercent > 100 {
+		errs.add("open_cursors_warn_percent", "must be between 0 and 100, got %d", appCfg.OpenCursorsWarnPercent)
+	}
+	if appCfg.HealthListen != "" {
+		if _, _, err := net.SplitHostPort(appCfg.HealthListen); err != nil {
+			errs.add("health_listen", "must be host:port or :port, got %q", appCfg.HealthListen)
+		}
+	}
+	if appCfg.ShutdownGraceSeconds < 0 {
+		errs.add("shutdown_grace_seconds", "must not be negative, got %d", appCfg.ShutdownGraceSeconds)
+	}
+	if !validRerunPolicy(appCfg.JobLog.Rerun) {
+		errs.add("job_log.rerun", "must be overwrite, append or suffix, got %q", appCfg.JobLog.Rerun)
+	}
+	if appCfg.JobLog.Rerun == RerunAppend && appCfg.LogRotation.enabled() {
+		errs.add("job_log.rerun", "append cannot be combined with log_rotation")
+	}
+	for _, sink := range appCfg.JobLog.Sinks {
+		switch sink {
+		case JobLogCSV, JobLogJSONL:
+		case JobLogDB:
+			if !qualifiedIdentifier.MatchString(appCfg.JobLog.Table) {
+				errs.add("job_log.table", "must name the table for the db sink, got %q", appCfg.JobLog.Table)
+			}
+		default:
+			errs.add("job_log.sinks", "unknown sink %q (want csv, jsonl or db)", sink)
+		}
+	}
+	if appCfg.Concurrency <= 0 {
+		errs.add("concurrency", "must be greater than 0, got %d", appCfg.Concurrency)
+	}
+	if appCfg.SolFilePath == "" {
+		errs.add("sol_list_path", "must be set")
+	} else if info, err := os.Stat(appCfg.SolFilePath); err != nil {
+		errs.add("sol_list_path", "%v", err)
+	} else if info.IsDir() {
+		errs.add("sol_list_path", "%s is a directory", appCfg.SolFilePath)
+	}
+	checkCreatableDir(&errs, "log_path", appCfg.LogFilePath)
+	if _, err := parseDirMode(appCfg.DirMode); err != nil {
+		errs.add("dir_mode", "%v", err)
+	}
+
+	if len(runCfg.Procedures) == 0 {
+		errs.add("procedures", "must list at least one procedure")
+	}
+	for proc, name := range runCfg.ProcedureConnections {
+		if _, err := appCfg.connectionConfig(name); err != nil {
+			errs.add("procedure_connections", "%s: %v", proc, err)
+		}
+	}
+	for proc, p := range runCfg.ProcedurePriorities {
+		if !validPriority(p) {
+			errs.add("procedure_priorities", "%s: %q must be critical, normal or low", proc, p)
+		}
+	}
+	if runCfg.LoadConnection != "" {
+		if _, err := appCfg.connectionConfig(runCfg.LoadConnection); err != nil {
+			errs.add("load_connection", "%v", err)
+		}
+	}
+	for _, name := range runCfg.DebugMaskBinds {
+		if n := strings.ToUpper(name); n != "SOL_ID" && n != "BUSINESS_DATE" {
+			errs.add("debug_mask_binds", "unknown bind %q, must be SOL_ID or BUSINESS_DATE", name)
+		}
+	}
+	if runCfg.CommitInterval < 0 {
+		errs.add("commit_interval", "must not be negative, got %d", runCfg.CommitInterval)
+	}
+	if !validRollbackPolicy(runCfg.RollbackPolicy) {
+		errs.add("rollback_policy", "must be call or batch, got %q", runCfg.RollbackPolicy)
+	}
+	if !validStatementStrategy(runCfg.StatementStrategy) {
+		errs.add("statement_strategy", "must be shared, worker or cached, got %q", runCfg.StatementStrategy)
+	}
+	if runCfg.LoadBatchSize < 0 {
+		errs.add("load_batch_size", "must not be negative, got %d", runCfg.LoadBatchSize)
+	}
+	listed := make(map[string]bool, len(runCfg.Procedures))
+	for _, proc := range runCfg.Procedures {
+		listed[proc] = true
+	}
+	if !validTriggerFormat(runCfg.TriggerFile.Format) {
+		errs.add("trigger_file.format", "must be text or json, got %q", runCfg.TriggerFile.Format)
+	}
+	if !validUnchangedPolicy(runCfg.UnchangedOutputPolicy) {
+		errs.add("unchanged_output_policy", "must be ship, skip or link, got %q", runCfg.UnchangedOutputPolicy)
+	}
+	if !validArchiveFormat(runCfg.Archive.Format) {
+		errs.add("archive.format", "must be zip or tar.gz, got %q", runCfg.Archive.Format)
+	} else if runCfg.Archive.PasswordEnv != "" && runCfg.Archive.Format == ArchiveTarGz {
+		errs.add("archive.password_env", "encryption needs the zip format")
+	}
+	for i, u := range runCfg.Uploads {
+		switch u.Provider {
+		case UploadAzure:
+			if (u.Account == "" && u.Endpoint == "") || u.Container == "" {
+				errs.add("uploads", "upload %d: azure needs account (or endpoint) and container", i+1)
+			}
+		case UploadGCS:
+			if u.Bucket == "" {
+				errs.add("uploads", "upload %d: gcs needs bucket", i+1)
+			}
+		case UploadSFTP:
+			if u.Host == "" || u.User == "" {
+				errs.add("uploads", "upload %d: sftp needs host and user", i+1)
+			}
+		default:
+			errs.add("uploads", "upload %d: provider must be azure, gcs or sftp, got %q", i+1, u.Provider)
+		}
+		if u.MaxMbps < 0 || u.ParallelParts < 0 || u.Retries < 0 {
+			errs.add("uploads", "upload %d: max_mbps, parallel_parts and retries must not be negative", i+1)
+		}
+	}
+	for i, hook := range runCfg.Webhooks {
+		if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.add("webhooks", "webhook %d: url must be an http(s) URL, got %q", i+1, hook.URL)
+		}
+		for _, ev := range hook.Events {
+			if !slices.Contains(webhookEvents, ev) {
+				errs.add("webhooks", "webhook %d: unknown event %q (want %s)", i+1, ev, strings.Join(webhookEvents, ", "))
+			}
+		}
+		if hook.Retries < 0 || hook.TimeoutSeconds < 0 {
+			errs.add("webhooks", "webhook %d: retries and timeout_seconds must not be negative", i+1)
+		}
+	}
+	for _, tj := range runCfg.TraceJobs {
+		if !listed[tj.Procedure] {
+			errs.add("trace_jobs", "procedure %q is not in procedures", tj.Procedure)
+		}
+	}
+	inPhase := make(map[string]string)
+	for i, ph := range runCfg.Phases {
+		if ph.Name == "" {
+			errs.add("phases", "phase %d has no name", i+1)
+		}
+		for _, proc := range ph.Procedures {
+			if !listed[proc] {
+				errs.add("phases", "%s: procedure %s is not in procedures", ph.Name, proc)
+			}
+			if other, ok := inPhase[proc]; ok {
+				errs.add("phases", "procedure %s is in both %s and %s", proc, other, ph.Name)
+			}
+			inPhase[proc] = ph.Name
+		}
+	}
+	if runCfg.PackageName == "" {
+		errs.add("package_name", "must be set")
+	}
+
+	if mode == "E" {
+		switch runCfg.Format {
+		case "delimited":
+			if runCfg.Delimiter == "" {
+				errs.add("delimiter", "must be set when format is delimited")
+			}
+		case "fixed":
+		default:
+			errs.add("format", "must be 'delimited' or 'fixed', got %q", runCfg.Format)
+		}
+		if runCfg.SpoolOutputPath == "" {
+			errs.add("spool_output_path", "must be set")
+		} else {
+			checkCreatableDir(&errs, "spool_output_path", runCfg.SpoolOutputPath)
+		}
+		if runCfg.FinalOutputPath != "" {
+			checkCreatableDir(&errs, "final_output_path", runCfg.FinalOutputPath)
+		}
+		if !validLengthPolicy(runCfg.LengthPolicy) {
+			errs.add("length_policy", "must be truncate, warn or fail, got %q", runCfg.LengthPolicy)
+		}
+		if _, err := parseSanitizeRules(runCfg.Sanitize); err != nil {
+			errs.add("sanitize", "%v", err)
+		}
+		if !validDelimiterPolicy(runCfg.DelimiterPolicy) {
+			errs.add("delimiter_policy", "must be escape, replace or fail, got %q", runCfg.DelimiterPolicy)
+		}
+		if !validFsyncPolicy(runCfg.FsyncPolicy) {
+			errs.add("fsync_policy", "must be never, close or rows, got %q", runCfg.FsyncPolicy)
+		}
+		if runCfg.FsyncRows < 0 {
+			errs.add("fsync_rows", "must not be negative")
+		}
+		if runCfg.OutputGroups.File != "" && runCfg.OutputGroups.Query != "" {
+			errs.add("output_groups", "set file or query, not both")
+		}
+		if runCfg.PerSolOutput && runCfg.OutputGroups.enabled() {
+			errs.add("output_groups", "cannot be combined with per_sol_output")
+		}
+		for proc, views := range runCfg.Views {
+			if !listed[proc] {
+				errs.add("views", "procedure %s is not in procedures", proc)
+			}
+			names := make(map[string]bool)
+			for i, v := range views {
+				if !plainIdentifier.MatchString(v.Name) {
+					errs.add("views", "%s: view %d needs a plain name, got %q", proc, i+1, v.Name)
+				} else if names[v.Name] {
+					errs.add("views", "%s: view %s is defined twice", proc, v.Name)
+				}
+				names[v.Name] = true
+				if !validViewFormat(v.Format) {
+					errs.add("views", "%s: view %s format must be delimited, fixed or jsonl, got %q", proc, v.Name, v.Format)
+				}
+				if len(v.Columns) == 0 && v.Format == "" && v.Delimiter == "" {
+					errs.add("views", "%s: view %s needs columns or a format of its own", proc, v.Name)
+				}
+			}
+		}
+		for _, proc := range runCfg.RollingFeeds {
+			if !listed[proc] {
+				errs.add("rolling_feeds", "procedure %s is not in procedures", proc)
+			}
+		}
+		// A rolling feed's file must keep its name from run to run.
+		if len(runCfg.RollingFeeds) > 0 && (strings.Contains(runCfg.OutputFileTemplate, ".RunID") || strings.Contains(runCfg.OutputDirTemplate, ".RunID")) {
+			errs.add("rolling_feeds", "output_file_template and output_dir_template must not use .RunID when rolling feeds are set")
+		}
+		for _, name := range runCfg.BOMOutputs {
+			if !listed[runCfg.sourceProcedure(name)] {
+				errs.add("bom_outputs", "procedure %s is not in procedures", name)
+			}
+		}
+		for _, name := range runCfg.RFC4180 {
+			if !listed[runCfg.sourceProcedure(name)] {
+				errs.add("rfc4180", "procedure %s is not in procedures", name)
+			}
+		}
+		for _, q := range []struct{ field, sql string }{
+			{"business_date_column", runCfg.BusinessDateColumn},
+			{"output_groups.query", runCfg.OutputGroups.Query},
+		} {
+			if _, err := runCfg.expandVariables(q.sql); err != nil {
+				errs.add(q.field, "%v", err)
+			}
+		}
+		for proc, chunk := range runCfg.SolChunks {
+			if _, err := runCfg.expandVariables(chunk.Key); err != nil {
+				errs.add("sol_chunks", "%s: %v", proc, err)
+			}
+		}
+		for proc, obj := range runCfg.SourceObjects {
+			if !listed[proc] {
+				errs.add("source_objects", "procedure %s is not in procedures", proc)
+			}
+			if !objectName.MatchString(obj) {
+				errs.add("source_objects", "%s: %q is not an object name like SCHEMA.VIEW", proc, obj)
+			}
+		}
+		if _, err := parseRecordTerminator(runCfg.RecordTerminator); err != nil {
+			errs.add("record_terminator", "%v", err)
+		}
+		for name, t := range runCfg.ProcedureRecordTerminators {
+			if !listed[runCfg.sourceProcedure(name)] {
+				errs.add("procedure_record_terminators", "procedure %s is not in procedures", name)
+			}
+			if _, err := parseRecordTerminator(t); err != nil {
+				errs.add("procedure_record_terminators", "%s: %v", name, err)
+			}
+		}
+		for _, proc := range runCfg.Procedures {
+			for _, name := range runCfg.outputNames(proc) {
+				if oc := runCfg.forOutput(name); strings.EqualFold(oc.RecordTerminator, TerminatorNone) && oc.Format != "fixed" {
+					errs.add("record_terminator", "%s: none needs fixed-width records, format is %s", name, oc.Format)
+				}
+			}
+		}
+		if !validCodec(runCfg.Compression) {
+			errs.add("compression", "unknown codec %q, want one of: %s", runCfg.Compression, codecNames())
+		}
+		for name, choice := range runCfg.ProcedureCompression {
+			if !listed[runCfg.sourceProcedure(name)] {
+				errs.add("procedure_compression", "procedure %s is not in procedures", name)
+			}
+			if !validCodec(choice) {
+				errs.add("procedure_compression", "%s: unknown codec %q, want one of: %s", name, choice, codecNames())
+			}
+		}
+		for _, proc := range runCfg.RollingFeeds {
+			for _, name := range runCfg.outputNames(proc) {
+				if cd := runCfg.codecFor(name); cd != nil && !cd.Appendable {
+					errs.add("rolling_feeds", "%s: %s output cannot be appended to, use gzip or no compression", name, cd.Name)
+				}
+			}
+		}
+		for proc, chunk := range runCfg.SolChunks {
+			if !listed[proc] {
+				errs.add("sol_chunks", "procedure %s is not in procedures", proc)
+			}
+			if chunk.Buckets < 2 || chunk.Buckets > maxChunkBuckets {
+				errs.add("sol_chunks", "%s: buckets must be between 2 and %d, got %d", proc, maxChunkBuckets, chunk.Buckets)
+			}
+			if chunk.Key != "" && !plainIdentifier.MatchString(chunk.Key) {
+				errs.add("sol_chunks", "%s: key must be a plain column name, got %q", proc, chunk.Key)
+			}
+		}
+		if runCfg.BusinessDateColumn != "" && !plainIdentifier.MatchString(runCfg.BusinessDateColumn) {
+			errs.add("business_date_column", "must be a plain column name, got %q", runCfg.BusinessDateColumn)
+		}
+		if runCfg.VolumeAlertPercent < 0 {
+			errs.add("volume_alert_percent", "must not be negative")
+		}
+		if !validVolumeAlertPolicy(runCfg.VolumeAlertPolicy) {
+			errs.add("volume_alert_policy", "must be warn or fail, got %q", runCfg.VolumeAlertPolicy)
+		}
+		if !validEmptyOutputPolicy(runCfg.EmptyOutputPolicy) {
+			errs.add("empty_output_policy", "must be warn, fail or emit, got %q", runCfg.EmptyOutputPolicy)
+		}
+		for _, proc := range runCfg.Procedures {
+			tmplPath := filepath.Join(runCfg.TemplatePath, fmt.Sprintf("%s.csv", proc))
+			if _, err := os.Stat(tmplPath); err != nil {
+				errs.add("template_path", "template for procedure %s: %v", proc, err)
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("configuration has %d problem(s):\n%w", len(errs), errors.Join(errs...))
+}
+
+// checkCreatableDir accepts an existing directory, or a path whose nearest existing
+// ancestor is a directory so that it can be created at startup.
+func checkCreatableDir(errs *configErrors, field, dir string) {
+	if dir == "" {
+		return // current directory
+	}
+	for p := filepath.Clean(dir); ; p = filepath.Dir(p) {
+		info, err := os.Stat(p)
+		if err == nil {
+			if !info.IsDir() {
+				errs.add(field, "%s is not a directory", p)
+			}
+			return
+		}
+		if !os.IsNotExist(err) {
+			errs.add(field, "%v", err)
+			return
+		}
+		if parent := filepath.Dir(p); parent == p {
+			return
+		}
+	}
+}
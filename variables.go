@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var runVariable = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// runVariables returns the values {{name}} references in templates and configured SQL
+// resolve to: the run's own, then those configured, which may override them.
+func (c *ExtractionConfig) runVariables() map[string]string {
+	d := c.BusinessDate
+	vars := map[string]string{
+		"business_date": d.Format(businessDateLayout),
+		"month_start":   time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, d.Location()).Format(businessDateLayout),
+		"month_end":     time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, d.Location()).Format(businessDateLayout),
+		"run_id":        c.RunID,
+		"package":       c.PackageName,
+		"environment":   c.environment,
+	}
+	for name, value := range c.Variables {
+		vars[strings.ToLower(name)] = value
+	}
+	return vars
+}
+
+// expandVariables replaces the {{name}} references in s, an extraction query or count
+// statement, at prepare time. An unknown name is an error rather than being sent to the
+// database as written.
+func (c *ExtractionConfig) expandVariables(s string) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	vars := c.runVariables()
+	var unknown []string
+	out := runVariable.ReplaceAllStringFunc(s, func(ref string) string {
+		name := strings.ToLower(runVariable.FindStringSubmatch(ref)[1])
+		value, ok := vars[name]
+		if !ok {
+			unknown = append(unknown, name)
+		}
+		return value
+	})
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", fmt.Errorf("unknown variable(s) %s", strings.Join(unknown, ", "))
+	}
+	return out, nil
+}
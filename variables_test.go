@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandVariables(t *testing.T) {
+	cfg := &ExtractionConfig{
+		PackageName:  "PKG",
+		RunID:        "R1",
+		BusinessDate: time.Date(2024, 2, 10, 0, 0, 0, 0, time.Local),
+		Variables:    map[string]string{"Ledger": "GL01"},
+		environment:  "UAT",
+	}
+	got, err := cfg.expandVariables("SELECT '{{environment}}', '{{ ledger }}' FROM GL WHERE D BETWEEN '{{month_start}}' AND '{{month_end}}' AND R = '{{run_id}}{{package}}'")
+	if want := "SELECT 'UAT', 'GL01' FROM GL WHERE D BETWEEN '2024-02-01' AND '2024-02-29' AND R = 'R1PKG'"; err != nil || got != want {
+		t.Errorf("expandVariables = %q, %v, want %q", got, err, want)
+	}
+	if _, err := cfg.expandVariables("SELECT {{nope}} FROM dual"); err == nil {
+		t.Error("unknown variable accepted")
+	}
+}
+
+func TestUnknownVariablesReportedInOrder(t *testing.T) {
+	runCfg := &ExtractionConfig{BusinessDateColumn: "{{nope}}", OutputGroups: OutputGroupConfig{Query: "SELECT {{nada}} FROM dual"}}
+	for range 10 {
+		err := validateConfigs(&MainConfig{}, runCfg, "E")
+		if err == nil {
+			t.Fatal("unknown variables accepted")
+		}
+		msg := err.Error()
+		first, second := strings.Index(msg, "business_date_column:"), strings.Index(msg, "output_groups.query:")
+		if first < 0 || second < first {
+			t.Fatalf("validation errors out of order: %v", err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	log "github.com/charmbracelet/log"
+)
+
+// verifyOutput re-reads a procedure's final file and checks that every record has the
+// template's shape: the full record length for fixed width, the column count for
+// delimited and JSON Lines. It returns the number of records read.
+func verifyOutput(cfg *ExtractionConfig, path string, cols []ColumnConfig) (int64, error) {
+	f, err := cfg.openRecords(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var records int64
+	if cfg.Format == FormatJSONL {
+		reader := bufio.NewReader(f)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				records++
+				var rec map[string]any
+				if err := json.Unmarshal(line, &rec); err != nil {
+					return records, fmt.Errorf("record %d: %w", records, err)
+				}
+				if len(rec) != len(cols) {
+					return records, fmt.Errorf("record %d has %d fields, want %d", records, len(rec), len(cols))
+				}
+			}
+			if err == io.EOF {
+				return records, nil
+			}
+			if err != nil {
+				return records, err
+			}
+		}
+	}
+	if cfg.Format == "fixed" {
+		width := 0
+		for _, c := range cols {
+			width += c.Length
+		}
+		reader := bufio.NewReader(f)
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				records++
+				if n := utf8.RuneCountInString(strings.TrimSuffix(line, "\n")); n != width {
+					return records, fmt.Errorf("record %d is %d characters, want %d", records, n, width)
+				}
+			}
+			if err == io.EOF {
+				return records, nil
+			}
+			if err != nil {
+				return records, err
+			}
+		}
+	}
+
+	reader := csv.NewReader(bufio.NewReader(f))
+	reader.Comma = cfg.delimiterRune()
+	reader.FieldsPerRecord = len(cols)
+	for {
+		_, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		records++
+		if err != nil {
+			return records, fmt.Errorf("record %d: %w", records, err)
+		}
+	}
+}
+
+// verifyOutputs runs verifyOutput over the final files of every procedure of the run and
+// compares their record count with the rows extraction reported. Counts are not compared
+// when the output was appended to, since it also holds earlier runs' rows.
+func verifyOutputs(cfg *ExtractionConfig, templates map[string][]ColumnConfig, summary map[string]ProcSummary) error {
+	procs := make([]string, 0, len(summary))
+	for proc := range summary {
+		procs = append(procs, proc)
+	}
+	sort.Strings(procs)
+
+	var errs []error
+	for _, proc := range procs {
+		parts, err := cfg.outputParts(proc)
+		if err != nil {
+			return err
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		var records int64
+		var failed, found bool
+		for _, part := range parts {
+			n, err := verifyOutput(cfg.forOutput(proc), part.Path, templates[proc])
+			if os.IsNotExist(err) && (cfg.partitioned() || summary[proc].Rows == 0) {
+				continue
+			}
+			found = true
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s (%s): %w", proc, part.Path, err))
+				failed = true
+				continue
+			}
+			records += n
+		}
+		if failed || (!found && summary[proc].Rows == 0) {
+			continue
+		}
+		where := parts[0].Path
+		if len(parts) > 1 {
+			where = fmt.Sprintf("%d files", len(parts))
+		}
+		if want := summary[proc].Rows; !cfg.appends(proc) && records != want {
+			errs = append(errs, fmt.Errorf("%s (%s): %d records, extraction reported %d rows", proc, where, records, want))
+			continue
+		}
+		log.Info("✔️ Output verified", "procedure", proc, "file", where, "records", records)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("output verification failed: %w", errors.Join(errs...))
+	}
+	return nil
+}
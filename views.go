@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ViewConfig is a projection of a procedure's template, a subset or reordering of its
+// columns, written to a final file of its own from the same extraction. A view may use a
+// format of its own, so one query pass feeds e.g. a delimited and a fixed-width consumer.
+type ViewConfig struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"` // default all template columns
+	// Format is delimited, fixed or jsonl, with Delimiter for delimited; both default to
+	// the procedure's.
+	Format    string `json:"format"`
+	Delimiter string `json:"delimiter"`
+}
+
+// FormatJSONL writes every record as a JSON object of column names to values, one per
+// line; NULLs are written as null. It is available to views.
+const FormatJSONL = "jsonl"
+
+func validViewFormat(f string) bool {
+	return f == "" || f == "delimited" || f == "fixed" || f == FormatJSONL
+}
+
+// forOutput returns a copy of the configuration resolved for the named output, a
+// procedure or view: the view's format and the output's record terminator.
+func (c *ExtractionConfig) forOutput(name string) *ExtractionConfig {
+	vc := *c
+	vc.output = name
+	vc.RecordTerminator = c.recordTerminator(name)
+	for proc, views := range c.Views {
+		for _, v := range views {
+			if viewOutputName(proc, v.Name) != name {
+				continue
+			}
+			if v.Format != "" {
+				vc.Format = v.Format
+			}
+			if v.Delimiter != "" {
+				vc.Delimiter = v.Delimiter
+			}
+		}
+	}
+	return &vc
+}
+
+// viewOutputName is the name a view's output goes by in file names and summaries.
+func viewOutputName(proc, view string) string {
+	return proc + "_" + view
+}
+
+// viewSpoolSuffix is appended to a job's spool file name for the view's spool.
+func viewSpoolSuffix(view string) string {
+	if view == "" {
+		return ""
+	}
+	return "." + view
+}
+
+// addViewSummaries gives each view the summary of the procedure it was extracted with,
+// less the procedure's output file and column profile.
+func (c *ExtractionConfig) addViewSummaries(summary map[string]ProcSummary) {
+	for proc, views := range c.Views {
+		s, ok := summary[proc]
+		if !ok {
+			continue
+		}
+		for _, v := range views {
+			vs := s
+			vs.Procedure = viewOutputName(proc, v.Name)
+			vs.Output, vs.Parts, vs.Profile, vs.MergeGaps = nil, nil, nil, nil
+			summary[vs.Procedure] = vs
+		}
+	}
+}
+
+// isViewSpool reports whether the spool file f belongs to one of proc's views.
+func (c *ExtractionConfig) isViewSpool(proc, f string) bool {
+	for _, v := range c.Views[proc] {
+		if strings.HasSuffix(f, viewSpoolSuffix(v.Name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// outputNames returns the outputs proc's extraction produces: its own and its views'.
+func (c *ExtractionConfig) outputNames(proc string) []string {
+	names := []string{proc}
+	for _, v := range c.Views[proc] {
+		names = append(names, viewOutputName(proc, v.Name))
+	}
+	return names
+}
+
+// sourceProcedure returns the procedure an output belongs to: name itself, or the
+// procedure name is a view of.
+func (c *ExtractionConfig) sourceProcedure(name string) string {
+	for proc, views := range c.Views {
+		for _, v := range views {
+			if viewOutputName(proc, v.Name) == name {
+				return proc
+			}
+		}
+	}
+	return name
+}
+
+// addViewTemplates adds each view's columns to templates under the view's output name,
+// taking widths and alignment from the procedure's template.
+func (c *ExtractionConfig) addViewTemplates(templates map[string][]ColumnConfig) error {
+	for proc, views := range c.Views {
+		cols, ok := templates[proc]
+		if !ok {
+			continue
+		}
+		for _, v := range views {
+			idx, err := viewIndexes(cols, v)
+			if err != nil {
+				return fmt.Errorf("view %s of %s: %w", v.Name, proc, err)
+			}
+			viewCols := make([]ColumnConfig, len(idx))
+			for i, j := range idx {
+				viewCols[i] = cols[j]
+			}
+			templates[viewOutputName(proc, v.Name)] = viewCols
+		}
+	}
+	return nil
+}
+
+// viewIndexes returns the template positions of the view's columns.
+func viewIndexes(cols []ColumnConfig, v ViewConfig) ([]int, error) {
+	if len(v.Columns) == 0 {
+		idx := make([]int, len(cols))
+		for i := range idx {
+			idx[i] = i
+		}
+		return idx, nil
+	}
+	idx := make([]int, len(v.Columns))
+	for i, name := range v.Columns {
+		idx[i] = -1
+		for j, col := range cols {
+			if strings.EqualFold(col.Name, name) {
+				idx[i] = j
+				break
+			}
+		}
+		if idx[i] < 0 {
+			return nil, fmt.Errorf("column %s is not in the template", name)
+		}
+	}
+	return idx, nil
+}
+
+// viewSink writes the projection of every extracted row to a view's spool file.
+type viewSink struct {
+	cfg *ExtractionConfig // the view's format
+	// strict views write values as scanned; others sanitise what a strict procedure
+	// output left as is.
+	strict   bool
+	sanitize []sanitizer
+	term     []byte // record terminator
+	file     *os.File
+	buf      *bufio.Writer
+	csv      *csv.Writer
+	cols     []ColumnConfig
+	index    []int
+	values   []string
+}
+
+// openViewSinks creates the spool files of proc's views next to spoolPath.
+func openViewSinks(cfg *ExtractionConfig, proc, spoolPath string, cols []ColumnConfig) ([]*viewSink, error) {
+	var sinks []*viewSink
+	for _, v := range cfg.Views[proc] {
+		idx, err := viewIndexes(cols, v)
+		if err == nil {
+			var f *os.File
+			if f, err = os.Create(spoolPath + viewSpoolSuffix(v.Name)); err == nil {
+				vc := cfg.forOutput(viewOutputName(proc, v.Name))
+				s := &viewSink{cfg: vc, term: vc.recordEnd(), file: f, buf: bufio.NewWriter(f), index: idx, values: make([]string, len(idx))}
+				for _, j := range idx {
+					s.cols = append(s.cols, cols[j])
+				}
+				if vc.Format == "delimited" {
+					s.strict = vc.strictCSV(viewOutputName(proc, v.Name))
+					s.csv = csv.NewWriter(s.buf)
+					if !s.strict && string(s.term) != "\n" {
+						s.csv = csv.NewWriter(&recordEndWriter{w: s.buf, term: s.term})
+					}
+					s.csv.Comma = vc.delimiterRune()
+					s.csv.UseCRLF = s.strict
+				}
+				if !s.strict && cfg.Format == "delimited" && cfg.strictCSV(proc) {
+					s.sanitize = cfg.columnSanitizers(s.cols)
+				}
+				sinks = append(sinks, s)
+				continue
+			}
+		}
+		for _, s := range sinks {
+			s.file.Close()
+		}
+		return nil, fmt.Errorf("failed to open view %s: %w", v.Name, err)
+	}
+	return sinks, nil
+}
+
+// write writes the view's columns of a row, given as scanned and as formatted.
+func (s *viewSink) write(raw []sql.NullString, strValues []string) error {
+	for i, j := range s.index {
+		switch {
+		case s.strict:
+			s.values[i] = raw[j].String
+		case s.sanitize != nil && s.sanitize[i] != nil:
+			s.values[i] = s.sanitize[i](strValues[j])
+		default:
+			s.values[i] = strValues[j]
+		}
+	}
+	switch {
+	case s.csv != nil:
+		return s.csv.Write(s.values)
+	case s.cfg.Format == FormatJSONL:
+		s.buf.WriteByte('{')
+		for i, j := range s.index {
+			if i > 0 {
+				s.buf.WriteByte(',')
+			}
+			name, _ := json.Marshal(s.cols[i].Name)
+			s.buf.Write(name)
+			s.buf.WriteByte(':')
+			if !raw[j].Valid {
+				s.buf.WriteString("null")
+				continue
+			}
+			val, _ := json.Marshal(raw[j].String)
+			s.buf.Write(val)
+		}
+		s.buf.WriteByte('}')
+		_, err := s.buf.Write(s.term)
+		return err
+	}
+	// Truncations are counted once, on the procedure's own output.
+	line, err := formatFixed(s.cols, s.values, s.cfg.LengthPolicy, make(map[string]int64))
+	if err != nil {
+		return err
+	}
+	s.buf.WriteString(line)
+	_, err = s.buf.Write(s.term)
+	return err
+}
+
+func (s *viewSink) flush() error {
+	if s.csv != nil {
+		s.csv.Flush()
+		if err := s.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return s.buf.Flush()
+}
+
+// closeViewSinks flushes and closes the view spools, returning the first error.
+func closeViewSinks(cfg *ExtractionConfig, sinks []*viewSink) error {
+	var first error
+	for _, s := range sinks {
+		err := s.flush()
+		if err != nil {
+			s.file.Close()
+		} else {
+			err = cfg.closeOutput(s.file)
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestViewSpoolsMergeSeparately(t *testing.T) {
+	dir := t.TempDir()
+	cfg := ExtractionConfig{
+		SpoolOutputPath: dir,
+		Format:          "delimited",
+		Delimiter:       "|",
+		RunID:           "R1",
+		sols:            []string{"0001"},
+		Views:           map[string][]ViewConfig{"GAM": {{Name: "BRANCH", Columns: []string{"sol_id", "ACID"}}, {Name: "JSON", Format: FormatJSONL}}},
+	}
+	if err := cfg.compileFileNames(); err != nil {
+		t.Fatal(err)
+	}
+	templates := map[string][]ColumnConfig{"GAM": {{Name: "ACID"}, {Name: "NAME"}, {Name: "SOL_ID"}}}
+	if err := cfg.addViewTemplates(templates); err != nil {
+		t.Fatal(err)
+	}
+	if cols := templates["GAM_BRANCH"]; len(cols) != 2 || cols[0].Name != "SOL_ID" {
+		t.Fatalf("view template = %+v, want SOL_ID, ACID", cols)
+	}
+
+	spool, _ := cfg.spoolFilePath("GAM", "0001")
+	os.WriteFile(spool, []byte("A1||0001\n"), 0644)
+	sinks, err := openViewSinks(&cfg, "GAM", spool, templates["GAM"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := []sql.NullString{{String: "A1", Valid: true}, {}, {String: "0001", Valid: true}}
+	for _, s := range sinks {
+		if err := s.write(raw, []string{"A1", "", "0001"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := closeViewSinks(&cfg, sinks); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := mergeProcedure(&cfg, "GAM"); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"GAM.txt":        "A1||0001\n",
+		"GAM_BRANCH.txt": "0001|A1\n",
+		"GAM_JSON.txt":   `{"ACID":"A1","NAME":null,"SOL_ID":"0001"}` + "\n",
+	} {
+		if got, err := os.ReadFile(filepath.Join(dir, name)); err != nil || string(got) != want {
+			t.Errorf("%s = %q (%v), want %q", name, got, err, want)
+		}
+	}
+
+	if n, err := verifyOutput(cfg.forOutput("GAM_JSON"), filepath.Join(dir, "GAM_JSON.txt"), templates["GAM_JSON"]); err != nil || n != 1 {
+		t.Errorf("verifyOutput(jsonl) = %d, %v, want 1 record", n, err)
+	}
+
+	bad := ExtractionConfig{Views: map[string][]ViewConfig{"GAM": {{Name: "X", Columns: []string{"MISSING"}}}}}
+	if err := bad.addViewTemplates(templates); err == nil {
+		t.Error("view of a column missing from the template accepted")
+	}
+}
+
+func TestStrictCSVView(t *testing.T) {
+	dir := t.TempDir()
+	cfg := ExtractionConfig{
+		SpoolOutputPath: dir,
+		Format:          "delimited",
+		Delimiter:       ",",
+		RunID:           "R1",
+		sols:            []string{"0001"},
+		Views:           map[string][]ViewConfig{"GAM": {{Name: "NOTES"}}},
+		RFC4180:         []string{"GAM_NOTES"},
+	}
+	if err := cfg.compileFileNames(); err != nil {
+		t.Fatal(err)
+	}
+	cols := []ColumnConfig{{Name: "ACID"}, {Name: "REMARKS"}}
+	spool, _ := cfg.spoolFilePath("GAM", "0001")
+	sinks, err := openViewSinks(&cfg, "GAM", spool, cols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := []sql.NullString{{String: "A1", Valid: true}, {String: "say \"hi\",\nbye", Valid: true}}
+	if err := sinks[0].write(raw, []string{"A1", "say \"hi\", bye"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := closeViewSinks(&cfg, sinks); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mergeOutput(&cfg, "GAM", "NOTES"); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "GAM_NOTES.txt")
+	want := "A1,\"say \"\"hi\"\",\r\nbye\"\r\n"
+	if got, err := os.ReadFile(path); err != nil || string(got) != want {
+		t.Errorf("strict view = %q (%v), want %q", got, err, want)
+	}
+	outs, err := describeParts(&cfg, "GAM_NOTES")
+	if err != nil || len(outs) != 1 || outs[0].Records != 1 {
+		t.Errorf("describeParts = %+v, %v, want 1 record", outs, err)
+	}
+	if n, err := verifyOutput(&cfg, path, cols); err != nil || n != 1 {
+		t.Errorf("verifyOutput = %d, %v, want 1 record", n, err)
+	}
+}
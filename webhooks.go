@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	log "github.com/charmbracelet/log"
+)
+
+// Webhook lifecycle events.
+const (
+	EventRunStart          = "run_start"
+	EventProcedureComplete = "procedure_complete"
+	EventFileReady         = "file_ready"
+	EventRunEnd            = "run_end"
+)
+
+var webhookEvents = []string{EventRunStart, EventProcedureComplete, EventFileReady, EventRunEnd}
+
+// WebhookConfig is an HTTP endpoint that receives a JSON POST for each of Events (all
+// events when empty).
+type WebhookConfig struct {
+	URL            string            `json:"url"`
+	Events         []string          `json:"events"`
+	Headers        map[string]string `json:"headers"`
+	TimeoutSeconds int               `json:"timeout_seconds"`
+	Retries        int               `json:"retries"`
+}
+
+func (w WebhookConfig) wants(event string) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, event)
+}
+
+// webhookProcedure is a procedure's outcome in a run_end payload.
+type webhookProcedure struct {
+	Procedure string        `json:"procedure"`
+	Status    string        `json:"status"`
+	Rows      int64         `json:"rows"`
+	File      *OutputFile   `json:"file,omitempty"`
+	Files     []*OutputFile `json:"files,omitempty"` // per-SOL or per-group outputs
+}
+
+// webhookEvent is the JSON payload posted to webhooks. Fields not relevant to the event
+// are left out.
+type webhookEvent struct {
+	Event        string             `json:"event"`
+	Time         time.Time          `json:"time"`
+	RunID        string             `json:"run_id"`
+	Package      string             `json:"package"`
+	Mode         string             `json:"mode"`
+	BusinessDate string             `json:"business_date"`
+	Procedure    string             `json:"procedure,omitempty"`
+	Jobs         int                `json:"jobs,omitempty"`
+	FailedJobs   int                `json:"failed_jobs,omitempty"`
+	Rows         int64              `json:"rows,omitempty"`
+	File         *OutputFile        `json:"file,omitempty"`
+	Uploaded     []string           `json:"uploaded,omitempty"`
+	Status       string             `json:"status,omitempty"`
+	Error        string             `json:"error,omitempty"`
+	Procedures   []webhookProcedure `json:"procedures,omitempty"`
+}
+
+// webhookNotifier posts lifecycle events to the configured webhooks in the background,
+// in order, so a slow endpoint never holds up the run. A nil notifier does nothing.
+type webhookNotifier struct {
+	hooks  []WebhookConfig
+	runCfg *ExtractionConfig
+	mode   string
+	client *http.Client
+	queue  chan webhookEvent
+	done   chan struct{}
+}
+
+func newWebhookNotifier(runCfg *ExtractionConfig, mode string) *webhookNotifier {
+	if len(runCfg.Webhooks) == 0 {
+		return nil
+	}
+	n := &webhookNotifier{
+		hooks:  runCfg.Webhooks,
+		runCfg: runCfg,
+		mode:   mode,
+		client: &http.Client{},
+		queue:  make(chan webhookEvent, 1000),
+		done:   make(chan struct{}),
+	}
+	go n.run()
+	return n
+}
+
+func (n *webhookNotifier) run() {
+	defer close(n.done)
+	for ev := range n.queue {
+		for _, hook := range n.hooks {
+			if hook.wants(ev.Event) {
+				n.deliver(hook, ev)
+			}
+		}
+	}
+}
+
+// deliver posts ev to hook, retrying with a growing delay. Failures are logged, never fatal.
+func (n *webhookNotifier) deliver(hook WebhookConfig, ev webhookEvent) {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		log.Warn("Failed to encode webhook payload", "event", ev.Event, "error", err)
+		return
+	}
+	timeout := 10 * time.Second
+	if hook.TimeoutSeconds > 0 {
+		timeout = time.Duration(hook.TimeoutSeconds) * time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		if err = n.post(hook, body, timeout); err == nil {
+			return
+		}
+		if attempt >= hook.Retries {
+			break
+		}
+		time.Sleep(time.Duration(attempt+1) * 2 * time.Second)
+	}
+	log.Warn("Webhook delivery failed", "url", hook.URL, "event", ev.Event, "procedure", ev.Procedure, "error", err)
+}
+
+func (n *webhookNotifier) post(hook WebhookConfig, body []byte, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range hook.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+	return nil
+}
+
+// send queues ev, filling in the run fields.
+func (n *webhookNotifier) send(ev webhookEvent) {
+	if n == nil {
+		return
+	}
+	ev.Time = time.Now()
+	ev.RunID, ev.Package, ev.Mode = n.runCfg.RunID, n.runCfg.PackageName, n.mode
+	ev.BusinessDate = n.runCfg.BusinessDate.Format(businessDateLayout)
+	n.queue <- ev
+}
+
+// RunStart announces a run about to dispatch jobs.
+func (n *webhookNotifier) RunStart(jobs int) {
+	n.send(webhookEvent{Event: EventRunStart, Jobs: jobs})
+}
+
+// ProcedureComplete announces that every job of proc has finished.
+func (n *webhookNotifier) ProcedureComplete(proc string, jobs, failed int, rows int64) {
+	n.send(webhookEvent{Event: EventProcedureComplete, Procedure: proc, Jobs: jobs, FailedJobs: failed, Rows: rows})
+}
+
+// FileReady announces a merged output file downstream systems may pick up, with where it
+// was uploaded to.
+func (n *webhookNotifier) FileReady(proc string, file *OutputFile, uploaded []string) {
+	n.send(webhookEvent{Event: EventFileReady, Procedure: proc, File: file, Rows: file.Records, Uploaded: uploaded})
+}
+
+// RunEnd announces the outcome of the run with every procedure's result.
+func (n *webhookNotifier) RunEnd(summary map[string]ProcSummary, runErr error) {
+	if n == nil {
+		return
+	}
+	ev := webhookEvent{Event: EventRunEnd, Status: "SUCCESS"}
+	if runErr != nil {
+		ev.Status, ev.Error = "FAIL", runErr.Error()
+	}
+	for _, proc := range n.runCfg.Procedures {
+		if s, ok := summary[proc]; ok {
+			ev.Procedures = append(ev.Procedures, webhookProcedure{proc, s.Status, s.Rows, s.Output, s.Parts})
+			ev.Rows += s.Rows
+		}
+		// Views repeat their procedure's rows, so only their files are added.
+		for _, name := range n.runCfg.outputNames(proc)[1:] {
+			if s, ok := summary[name]; ok {
+				ev.Procedures = append(ev.Procedures, webhookProcedure{name, s.Status, s.Rows, s.Output, s.Parts})
+			}
+		}
+	}
+	n.send(ev)
+}
+
+// Close delivers the queued events and stops the notifier.
+func (n *webhookNotifier) Close() {
+	if n == nil {
+		return
+	}
+	close(n.queue)
+	<-n.done
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookDelivery(t *testing.T) {
+	var mu sync.Mutex
+	var got []webhookEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev webhookEvent
+		json.NewDecoder(r.Body).Decode(&ev)
+		mu.Lock()
+		got = append(got, ev)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	runCfg := &ExtractionConfig{PackageName: "PKG", RunID: "20260301000000", Procedures: []string{"GAM"},
+		Webhooks: []WebhookConfig{{URL: srv.URL, Events: []string{EventFileReady, EventRunEnd}}}}
+	hooks := newWebhookNotifier(runCfg, "E")
+	hooks.RunStart(2)
+	hooks.FileReady("GAM", &OutputFile{Path: "/out/GAM.txt", Records: 10, SHA256: "abc"}, nil)
+	hooks.RunEnd(map[string]ProcSummary{"GAM": {Status: "SUCCESS", Rows: 10}}, nil)
+	hooks.Close()
+
+	if len(got) != 2 {
+		t.Fatalf("delivered %d events, want file_ready and run_end: %+v", len(got), got)
+	}
+	if got[0].Event != EventFileReady || got[0].File == nil || got[0].File.SHA256 != "abc" || got[0].Package != "PKG" {
+		t.Errorf("file_ready = %+v", got[0])
+	}
+	if got[1].Event != EventRunEnd || got[1].Status != "SUCCESS" || got[1].Rows != 10 || len(got[1].Procedures) != 1 {
+		t.Errorf("run_end = %+v", got[1])
+	}
+}
+
+func TestProcedureCompleteOutsideProgressLock(t *testing.T) {
+	// A notifier whose queue is full: nothing takes its events.
+	hooks := &webhookNotifier{runCfg: &ExtractionConfig{}, queue: make(chan webhookEvent)}
+	progress := newProgressTracker([]Job{{SolID: "0001", Proc: "GAM"}}, 1)
+	progress.webhooks = hooks
+	go progress.Finish(0, ProcLog{SolID: "0001", Procedure: "GAM", Status: "SUCCESS", Rows: 5})
+
+	// The job is counted while its event still waits to be queued.
+	deadline := time.After(5 * time.Second)
+	for {
+		done := make(chan runStatus, 1)
+		go func() { done <- progress.Status() }()
+		select {
+		case st := <-done:
+			if st.Completed != 1 {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+		case <-deadline:
+			t.Fatal("Status blocked while a webhook event waited to be queued")
+		}
+		break
+	}
+	ev := <-hooks.queue
+	if ev.Event != EventProcedureComplete || ev.Procedure != "GAM" || ev.Rows != 5 {
+		t.Errorf("procedure_complete = %+v", ev)
+	}
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -11,84 +13,226 @@ import (
 	log "github.com/charmbracelet/log"
 )
 
-// Job represents a single unit of work: a procedure to be run for a specific SOL ID.
+// Job represents a single unit of work: a procedure to be run for a specific SOL ID, or
+// for one chunk of it when the procedure is chunked.
 type Job struct {
 	SolID string
 	Proc  string
+	Chunk int
+}
+
+// workerDeps are the per-run settings and collaborators a worker works with. All but
+// session are shared by every worker; session is the worker's own batched insert
+// session, if any.
+type workerDeps struct {
+	runCfg    *ExtractionConfig
+	templates map[string][]ColumnConfig
+	mode      string
+	retries   int // reconnects of a job whose connection was lost
+	slicePool *sync.Pool
+	procLogCh chan<- ProcLog
+	summaries *summaryAggregator
+	stmts     *statementSet
+	bad       *badRecordWriter
+	completed *completionStore
+	progress  *progressTracker
+	mem       *memoryGate
+	limiter   *concurrencyLimiter
+	session   *insertSession
+	sessions  *sessionRunner
 }
 
 // worker is a single goroutine that processes jobs from the jobs channel.
-func worker(
-	id int,
-	ctx context.Context,
-	wg *sync.WaitGroup,
-	runCfg *ExtractionConfig,
-	jobs <-chan Job,
-	procLogCh chan<- ProcLog,
-	summaryMu *sync.Mutex,
-	procSummary map[string]ProcSummary,
-	stmts map[string]*sql.Stmt,
-	slicePool *sync.Pool,
-	templates map[string][]ColumnConfig,
-	mode string,
-) {
+func worker(ctx context.Context, id int, wg *sync.WaitGroup, jobs <-chan Job, d workerDeps) {
 	defer wg.Done()
+	if own := d.stmts.forWorker(); own != d.stmts {
+		d.stmts = own
+		defer own.close()
+	}
+	// With batched commits a job only counts as done once its batch is committed.
+	settle := func(committed, rolledBack []Job, err error) {
+		for _, job := range committed {
+			if err := d.completed.MarkDone(job); err != nil {
+				log.Warn("Failed to record job completion", "worker", id, "procedure", job.Proc, "sol_id", job.SolID, "error", err)
+			}
+		}
+		for _, job := range rolledBack {
+			log.Warn("↩️ Uncommitted work rolled back, rerun with -skip-existing to redo", "worker", id, "procedure", job.Proc, "sol_id", job.SolID)
+		}
+		if err != nil {
+			log.Error("Batched commit failed", "worker", id, "error", err)
+		}
+	}
+	if d.session != nil {
+		defer func() { settle(d.session.finish()) }()
+	}
 	for job := range jobs {
 		start := time.Now()
 		var err error
+		var stats JobStats
 
-		if mode == "E" {
-			log.Debug("Starting extraction", "worker", id, "procedure", job.Proc, "sol_id", job.SolID)
-			stmt := stmts[job.Proc]
-			err = extractData(ctx, stmt, slicePool, job.Proc, job.SolID, runCfg, templates)
-		} else { // mode == "I"
+		d.mem.acquire(job.Proc)
+		jobCtx, cancel := context.WithCancelCause(ctx)
+		d.progress.Begin(id, job, cancel)
+		if err := d.completed.Dispatched(job); err != nil {
+			log.Warn("Failed to journal job dispatch", "worker", id, "procedure", job.Proc, "sol_id", job.SolID, "error", err)
+		}
+		jobCtx, jobSpan := startSpan(jobCtx, "job", "procedure", job.Proc, "sol_id", job.SolID, "worker", strconv.Itoa(id))
+
+		key := job.Proc
+		if d.mode == "I" {
+			key = d.runCfg.PackageName + "." + job.Proc
+		}
+		// Traced and measured jobs run on a session of their own, outside any batched
+		// transaction.
+		dedicated := d.sessions.wants(job)
+		batched := d.session != nil && !dedicated
+		runJob := func(stmt *sql.Stmt) (err error) {
+			if d.mode == "E" {
+				log.Debug("Starting extraction", "worker", id, "procedure", job.Proc, "sol_id", job.SolID)
+				stats, err = extractData(jobCtx, stmt, d.slicePool, job, d.runCfg, d.templates, d.bad)
+				return err
+			}
 			log.Debug("Starting insertion", "worker", id, "procedure", job.Proc, "sol_id", job.SolID)
-			stmt := stmts[runCfg.PackageName+"."+job.Proc]
-			err = callProcedure(ctx, stmt, job.SolID)
+			if batched {
+				committed, rolledBack, err := d.session.call(jobCtx, d.runCfg.ProcedureConnections[job.Proc], stmt, job, d.runCfg.procedureArgs(job.SolID))
+				settle(committed, rolledBack, nil)
+				return err
+			}
+			return callProcedure(jobCtx, stmt, job.Proc, d.runCfg.procedureArgs(job.SolID))
+		}
+
+		d.limiter.acquire()
+		var lostRetries, limitRetries, invalidRetries int
+	retry:
+		for {
+			var stmt *sql.Stmt
+			if dedicated {
+				err = d.sessions.run(jobCtx, job, d.runCfg.ProcedureConnections[job.Proc], d.runCfg.statementText[key], runJob)
+			} else {
+				if stmt, err = d.stmts.get(jobCtx, key, job.Proc); err == nil {
+					err = runJob(stmt)
+					d.stmts.release(stmt)
+				}
+			}
+			if err == nil || jobCtx.Err() != nil {
+				break
+			}
+			var delay time.Duration
+			switch {
+			case isSessionLimit(err) && limitRetries < maxSessionLimitRetries:
+				// The job never got a session, so it is safe to run again once fewer
+				// workers compete for sessions. Give up the slot while waiting.
+				limitRetries++
+				d.limiter.backoff()
+				log.Warn("⏳ Session limit reached, retrying job", "worker", id, "procedure", job.Proc, "sol_id", job.SolID, "attempt", limitRetries, "error", err)
+				delay = time.Duration(limitRetries) * 5 * time.Second
+			case isConnectionLost(err) && lostRetries < d.retries:
+				lostRetries++
+				log.Warn("🔁 Connection lost, retrying job", "worker", id, "procedure", job.Proc, "sol_id", job.SolID, "attempt", lostRetries, "error", err)
+				delay = time.Duration(lostRetries) * 2 * time.Second
+			case isStateInvalidated(err) && invalidRetries < maxReprepareRetries:
+				// A package or view was recompiled under the statement. Dedicated
+				// sessions prepare afresh on every run, so only the set's one is replaced.
+				invalidRetries++
+				if stmt != nil {
+					if rerr := d.stmts.reprepare(jobCtx, key, job.Proc, stmt); rerr != nil {
+						log.Error("Failed to re-prepare statement invalidated by a recompile", "worker", id, "key", key, "error", rerr)
+						break retry
+					}
+				}
+				log.Warn("♻️ Statement invalidated by a recompile, retrying job", "worker", id, "procedure", job.Proc, "sol_id", job.SolID, "error", err)
+				continue
+			}
+			if delay == 0 {
+				break
+			}
+			d.limiter.release(false)
+			select {
+			case <-jobCtx.Done():
+			case <-time.After(delay):
+			}
+			d.limiter.acquire()
+		}
+		d.limiter.release(err == nil)
+		if err != nil && jobCtx.Err() != nil {
+			var timeout *TimeoutError
+			if cause := context.Cause(jobCtx); errors.As(cause, &timeout) {
+				err = &TimeoutError{After: timeout.After, Err: err}
+			} else if cause != nil && cause != jobCtx.Err() {
+				err = fmt.Errorf("%w (%v)", err, cause)
+			}
 		}
+		jobSpan.End(err)
+		cancel(nil)
+		d.mem.release(job.Proc)
 		end := time.Now()
 		duration := end.Sub(start)
 
 		plog := ProcLog{
-			SolID:         job.SolID,
+			SolID:         job.spoolID(),
 			Procedure:     job.Proc,
 			StartTime:     start,
 			EndTime:       end,
 			ExecutionTime: duration,
+			Rows:          stats.Rows,
+			BadRecords:    stats.BadRecords,
+			Truncations:   stats.Truncations,
+			Sanitized:     stats.Sanitized,
+			Profile:       stats.Profile,
 		}
 		if err != nil {
 			plog.Status = "FAIL"
 			plog.ErrorDetails = err.Error()
+			plog.ErrorCode = errorCode(err)
 			log.Error("Job failed", "worker", id, "procedure", job.Proc, "sol_id", job.SolID, "error", err)
+			if d.runCfg.DebugStatements {
+				args := d.runCfg.solArgs(job.SolID)
+				if d.mode == "I" {
+					args = d.runCfg.procedureArgs(job.SolID)
+				}
+				log.Error("   Failed statement", "worker", id, "key", key, "binds", d.runCfg.describeBinds(d.mode, args), "sql", d.runCfg.statementText[key])
+			}
 		} else {
 			plog.Status = "SUCCESS"
-			log.Debug("Job completed", "worker", id, "procedure", job.Proc, "sol_id", job.SolID, "duration", duration.Round(time.Millisecond))
-		}
-		procLogCh <- plog
-
-		summaryMu.Lock()
-		s, exists := procSummary[job.Proc]
-		if !exists {
-			s = ProcSummary{Procedure: job.Proc, StartTime: start, EndTime: end, Status: plog.Status}
-		} else {
-			if start.Before(s.StartTime) {
-				s.StartTime = start
+			if !batched {
+				if err := d.completed.MarkDone(job); err != nil {
+					log.Warn("Failed to record job completion", "worker", id, "procedure", job.Proc, "sol_id", job.SolID, "error", err)
+				}
 			}
-			if end.After(s.EndTime) {
-				s.EndTime = end
-			}
-			if s.Status != "FAIL" && plog.Status == "FAIL" {
-				s.Status = "FAIL"
+			if stats.BadRecords > 0 {
+				log.Warn("Job completed with bad records", "worker", id, "procedure", job.Proc, "sol_id", job.SolID, "bad_records", stats.BadRecords)
 			}
+			log.Debug("Job completed", "worker", id, "procedure", job.Proc, "sol_id", job.SolID, "duration", duration.Round(time.Millisecond))
 		}
-		procSummary[job.Proc] = s
-		summaryMu.Unlock()
+		d.procLogCh <- plog
+		d.progress.Finish(id, plog)
+		d.summaries.Add(plog)
 	}
 }
 
-// prepareStatements creates all the necessary prepared statements before starting the workers.
-func prepareStatements(ctx context.Context, db *sql.DB, runCfg *ExtractionConfig, templates map[string][]ColumnConfig, mode string) (map[string]*sql.Stmt, error) {
-	stmts := make(map[string]*sql.Stmt)
+// selectQuery builds the extraction query for proc, applying the chunk filter, rehearsal
+// sample and row limit when set.
+func (c *ExtractionConfig) selectQuery(proc string, colNames []string) string {
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(colNames, ", "), c.sourceObject(proc))
+	if c.samplePercent > 0 {
+		query += fmt.Sprintf(" SAMPLE (%g)", c.samplePercent)
+	}
+	query += " WHERE " + c.solFilter() + c.chunkFilter(proc, len(c.solArgs(""))+1)
+	if c.limitRows > 0 {
+		query += fmt.Sprintf(" FETCH FIRST %d ROWS ONLY", c.limitRows)
+	}
+	return query
+}
+
+// prepareStatements creates all the necessary prepared statements before starting the workers,
+// each on the connection its procedure is routed to.
+func prepareStatements(ctx context.Context, dbs map[string]*sql.DB, runCfg *ExtractionConfig, templates map[string][]ColumnConfig, mode string) (stmts map[string]*sql.Stmt, err error) {
+	ctx, span := startSpan(ctx, "prepareStatements", "mode", mode, "procedures", strconv.Itoa(len(runCfg.Procedures)))
+	defer func() { span.End(err) }()
+
+	stmts = make(map[string]*sql.Stmt)
+	runCfg.statementText = make(map[string]string)
 
 	for _, proc := range runCfg.Procedures {
 		var query, key string
@@ -98,18 +242,31 @@ func prepareStatements(ctx context.Context, db *sql.DB, runCfg *ExtractionConfig
 			if !ok {
 				return nil, fmt.Errorf("missing template for procedure %s", proc)
 			}
-			colNames := make([]string, len(cols))
-			for i, col := range cols {
-				colNames[i] = col.Name
+			colNames, err := selectList(ctx, dbs[runCfg.ProcedureConnections[proc]], runCfg.sourceObject(proc), cols)
+			if err != nil {
+				for _, s := range stmts {
+					s.Close()
+				}
+				return nil, err
 			}
-			query = fmt.Sprintf("SELECT %s FROM %s WHERE SOL_ID = :1", strings.Join(colNames, ", "), proc)
+			query = runCfg.selectQuery(proc, colNames)
 			key = proc
 		} else { // mode == "I"
-			query = fmt.Sprintf("BEGIN %s.%s(:1); END;", runCfg.PackageName, proc)
+			query = runCfg.procedureCall(proc)
 			key = runCfg.PackageName + "." + proc
 		}
 
-		stmt, err := db.PrepareContext(ctx, query)
+		if query, err = runCfg.expandVariables(query); err != nil {
+			for _, s := range stmts {
+				s.Close()
+			}
+			return nil, fmt.Errorf("failed to prepare statement for %s: %w", key, err)
+		}
+		runCfg.statementText[key] = query
+		if !runCfg.preparesUpFront() {
+			continue
+		}
+		stmt, err := dbs[runCfg.ProcedureConnections[proc]].PrepareContext(ctx, query)
 		if err != nil {
 			// Close any statements that were successfully created before the error
 			for _, s := range stmts {
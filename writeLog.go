@@ -2,50 +2,33 @@ package main
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"os"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	log "github.com/charmbracelet/log"
 )
 
-// Write procedure logs to CSV file
-func writeLog(path string, logCh <-chan ProcLog) {
-	file, err := os.Create(path)
-	if err != nil {
-		log.Errorf("Failed to create procedure log file, logging will be disabled: %v", err)
-		// Drain the channel to prevent the main application from blocking
-		for range logCh {
+// writeLog writes every job record from logCh to each sink. It returns once logCh is
+// closed and every record has been written and the sinks closed.
+func writeLog(sinks []LogSink, logCh <-chan ProcLog) {
+	defer func() {
+		for _, sink := range sinks {
+			if err := sink.Close(); err != nil {
+				log.Warnf("Failed to close procedure log: %v", err)
+			}
 		}
-		return
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// Write header
-	if err := writer.Write([]string{"SOL_ID", "PROCEDURE", "START_TIME", "END_TIME", "EXECUTION_SECONDS", "STATUS", "ERROR_DETAILS"}); err != nil {
-		log.Warnf("Failed to write header to procedure log: %v", err)
-	}
+	}()
 
 	for plog := range logCh {
-		errDetails := plog.ErrorDetails
-		if errDetails == "" {
-			errDetails = "-"
-		}
-		timeFormat := "02-01-2006 15:04:05"
-		record := []string{
-			plog.SolID,
-			plog.Procedure,
-			plog.StartTime.Format(timeFormat),
-			plog.EndTime.Format(timeFormat),
-			fmt.Sprintf("%.3f", plog.ExecutionTime.Seconds()),
-			plog.Status,
-			errDetails,
-		}
-		if err := writer.Write(record); err != nil {
-			log.Warnf("Failed to write record to procedure log: %v", err)
+		for _, sink := range sinks {
+			if err := sink.Write(plog); err != nil {
+				log.Warnf("Failed to write record to procedure log: %v", err)
+			}
 		}
 	}
 }
@@ -63,7 +46,7 @@ func writeSummary(path string, summary map[string]ProcSummary) {
 	defer writer.Flush()
 
 	// Header
-	if err := writer.Write([]string{"PROCEDURE", "EARLIEST_START_TIME", "LATEST_END_TIME", "EXECUTION_SECONDS", "STATUS"}); err != nil {
+	if err := writer.Write([]string{"PROCEDURE", "EARLIEST_START_TIME", "LATEST_END_TIME", "EXECUTION_SECONDS", "STATUS", "P50_SECONDS", "P95_SECONDS", "MAX_SECONDS", "BAD_RECORDS", "TRUNCATIONS", "FAILURES_BY_CODE", "OUTPUT_FILE", "OUTPUT_BYTES", "OUTPUT_RECORDS", "OUTPUT_SHA256", "MERGE_GAPS"}); err != nil {
 		log.Warnf("Failed to write header to summary log: %v", err)
 	}
 
@@ -74,19 +57,138 @@ func writeSummary(path string, summary map[string]ProcSummary) {
 	}
 	sort.Strings(procs)
 
+	failures := make(map[string]int64)
+	for _, s := range summary {
+		for code, n := range s.FailuresByCode {
+			failures[code] += n
+		}
+	}
+	if len(failures) > 0 {
+		log.Warn("Failures by error code", "codes", formatCounts(failures))
+	}
+
 	for _, p := range procs {
 		s := summary[p]
 		execSeconds := s.EndTime.Sub(s.StartTime).Seconds()
-		timeFormat := "02-01-2006 15:04:05"
 		record := []string{
 			p,
-			s.StartTime.Format(timeFormat),
-			s.EndTime.Format(timeFormat),
+			timestamps.format(s.StartTime),
+			timestamps.format(s.EndTime),
 			fmt.Sprintf("%.3f", execSeconds),
 			s.Status,
+			fmt.Sprintf("%.3f", s.P50.Seconds()),
+			fmt.Sprintf("%.3f", s.P95.Seconds()),
+			fmt.Sprintf("%.3f", s.MaxDuration.Seconds()),
+			strconv.FormatInt(s.BadRecords, 10),
+			formatCounts(s.Truncations),
+			formatCounts(s.FailuresByCode),
 		}
+		if o := s.Output; o != nil {
+			record = append(record, o.Path, strconv.FormatInt(o.Bytes, 10), strconv.FormatInt(o.Records, 10), o.SHA256)
+		} else if len(s.Parts) > 0 {
+			// Partitioned files are listed individually in the JSON summary.
+			var bytes, records int64
+			for _, o := range s.Parts {
+				bytes += o.Bytes
+				records += o.Records
+			}
+			record = append(record, fmt.Sprintf("%d files", len(s.Parts)), strconv.FormatInt(bytes, 10), strconv.FormatInt(records, 10), "-")
+		} else {
+			record = append(record, "-", "-", "-", "-")
+		}
+		record = append(record, strings.Join(s.MergeGaps, ";"))
 		if err := writer.Write(record); err != nil {
 			log.Warnf("Failed to write record to summary log: %v", err)
 		}
 	}
 }
+
+// writeJSONSummary writes the procedure summary as JSON, for tooling that checks a run.
+func writeJSONSummary(path string, cfg *ExtractionConfig, summary map[string]ProcSummary, slowest []slowJob) {
+	type procJSON struct {
+		Procedure        string           `json:"procedure"`
+		StartTime        time.Time        `json:"start_time"`
+		EndTime          time.Time        `json:"end_time"`
+		ExecutionSeconds float64          `json:"execution_seconds"`
+		Status           string           `json:"status"`
+		P50Seconds       float64          `json:"p50_seconds"`
+		P95Seconds       float64          `json:"p95_seconds"`
+		MaxSeconds       float64          `json:"max_seconds"`
+		BadRecords       int64            `json:"bad_records"`
+		Truncations      map[string]int64 `json:"truncations,omitempty"`
+		Sanitized        map[string]int64 `json:"sanitized,omitempty"`
+		FailuresByCode   map[string]int64 `json:"failures_by_code,omitempty"`
+		Output           *OutputFile      `json:"output,omitempty"`
+		Files            []*OutputFile    `json:"files,omitempty"`
+		MergeGaps        []string         `json:"merge_gaps,omitempty"`
+	}
+	report := struct {
+		RunID        string     `json:"run_id"`
+		BusinessDate string     `json:"business_date"`
+		Procedures   []procJSON `json:"procedures"`
+		SlowestJobs  []slowJob  `json:"slowest_jobs"`
+	}{RunID: cfg.RunID, BusinessDate: cfg.BusinessDate.Format(businessDateLayout), Procedures: []procJSON{}, SlowestJobs: slowest}
+
+	var procs []string
+	for p := range summary {
+		procs = append(procs, p)
+	}
+	sort.Strings(procs)
+	for _, p := range procs {
+		s := summary[p]
+		report.Procedures = append(report.Procedures, procJSON{
+			Procedure:        p,
+			StartTime:        s.StartTime,
+			EndTime:          s.EndTime,
+			ExecutionSeconds: s.EndTime.Sub(s.StartTime).Seconds(),
+			Status:           s.Status,
+			P50Seconds:       s.P50.Seconds(),
+			P95Seconds:       s.P95.Seconds(),
+			MaxSeconds:       s.MaxDuration.Seconds(),
+			BadRecords:       s.BadRecords,
+			Truncations:      s.Truncations,
+			Sanitized:        s.Sanitized,
+			FailuresByCode:   s.FailuresByCode,
+			Output:           s.Output,
+			Files:            s.Parts,
+			MergeGaps:        s.MergeGaps,
+		})
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Errorf("Failed to encode JSON summary: %v", err)
+		return
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		log.Errorf("Failed to write JSON summary file: %v", err)
+	}
+}
+
+// formatCounts renders per-column counters as "COL_A=3;COL_B=1", sorted by column name.
+func formatCounts(counts map[string]int64) string {
+	var keys []string
+	for k, n := range counts {
+		if n > 0 {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return "-"
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%d", k, counts[k])
+	}
+	return strings.Join(parts, ";")
+}
+
+func sumCounts(counts map[string]int64) int64 {
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	return total
+}